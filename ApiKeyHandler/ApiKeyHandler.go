@@ -7,15 +7,42 @@ import (
 	"crypto/rand"
 	"crypto/sha512"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"sync"
+	"time"
 )
 
+// lastUsedInterval is the time after which the last use of an ApiKey is written again
+const lastUsedInterval = time.Minute
+
+// ErrApiKeyNotFound is returned if no ApiKey matches a key to delete
+var ErrApiKeyNotFound = errors.New("ApiKey does not exist")
+
 // ApiKeyHandler struct
 type ApiKeyHandler struct {
-	ApiKeyHashes map[string]bool
-	Mut          sync.RWMutex
+	ApiKeys map[string]*ApiKey // The keys by their hash
+	Mut     sync.RWMutex
+}
+
+// ApiKey is a stored ApiKey, only the hash of the key is kept
+type ApiKey struct {
+	Id          string     `json:"id"` // The start of the hash, it identifies the key without revealing it
+	Hash        string     `json:"-"`
+	Name        string     `json:"name,omitempty"`
+	Role        string     `json:"role"`                  // reader, writer or admin
+	Collections []string   `json:"collections,omitempty"` // The collections the key may use, empty for all
+	Tenant      string     `json:"tenant,omitempty"`      // Keys of a tenant only see the collections of the tenant
+	Created     time.Time  `json:"created"`
+	Expires     *time.Time `json:"expires,omitempty"`
+	LastUsed    *time.Time `json:"last_used,omitempty"` // Written at most once per minute
+}
+
+// apiKeyFile is the content of the file collections/__apikeys, older files hold only a map of the hashes
+type apiKeyFile struct {
+	Keys []*ApiKey
 }
 
 // ApiHandler is the global ApiKeyHandler
@@ -23,7 +50,12 @@ var ApiHandler *ApiKeyHandler
 
 // init initializes the ApiKeyHandler
 func init() {
+	ApiHandler = &ApiKeyHandler{ApiKeys: make(map[string]*ApiKey), Mut: sync.RWMutex{}}
+}
 
+// Start loads the ApiKeys of the file collections/__apikeys and creates the file if there is none. If -createapikey
+// is set a new admin ApiKey is created and printed.
+func (ap *ApiKeyHandler) Start() {
 	// If collections directory does not exist, create it
 	if _, err := os.Stat("collections"); os.IsNotExist(err) {
 		err := os.Mkdir("collections", 0755)
@@ -32,20 +64,19 @@ func init() {
 		}
 	}
 
-	ApiHandler = &ApiKeyHandler{ApiKeyHashes: make(map[string]bool), Mut: sync.RWMutex{}}
-	if ApiHandler.CheckActive() {
-		err := ApiHandler.CreateApiKeyFile()
+	if ap.CheckActive() {
+		err := ap.CreateApiKeyFile()
 		if err != nil {
 			Logger.Log.Log("Error creating file collections/__apikeys")
 			panic(err) // we cannot create the file - kill the server
 		}
 	}
-	ApiHandler.LoadApiKeys()
+	ap.LoadApiKeys()
 	Logger.Log.Log("ApiKeyHandler initialized")
 
 	// Argument Createapikey is set - create a new ApiKey
 	if *ArgsParser.Ap.CreateApiKey {
-		apiKey, err := ApiHandler.CreateApiKey()
+		apiKey, _, err := ap.CreateApiKey(ApiKey{Role: Admin, Tenant: *ArgsParser.Ap.Tenant})
 		if err != nil {
 			Logger.Log.Log("Error creating ApiKey")
 			panic(err)
@@ -76,106 +107,263 @@ func (ap *ApiKeyHandler) CreateApiKeyFile() error {
 	return nil
 }
 
-// CreateApiKey will add a new ApiKey to the ApiKeyHandler
-func (ap *ApiKeyHandler) CreateApiKey() (string, error) {
+// CreateApiKey will add a new ApiKey with the name, role, collections, tenant and expiry of the given ApiKey to the
+// ApiKeyHandler. It returns the key and the stored ApiKey, the key itself is not stored.
+func (ap *ApiKeyHandler) CreateApiKey(options ApiKey) (string, ApiKey, error) {
+	if err := options.Validate(); err != nil {
+		return "", ApiKey{}, err
+	}
 	ap.Mut.Lock()
 	defer ap.Mut.Unlock()
 	// Generate a (pseudo) random STRING - salted with crypto/rand
 	b := make([]byte, 16)
 	_, err := rand.Read(b)
 	if err != nil {
-		return "", err
+		return "", ApiKey{}, err
 	}
 	id := fmt.Sprintf("%X%X%X%X%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 
 	// hash the ApiKey
-	h := sha512.New()
-	h.Write([]byte(id))
-	k := fmt.Sprintf("%x", h.Sum(nil))
-	ap.ApiKeyHashes[k] = true
+	k := hashApiKey(id)
+	key := newApiKey(k, options.Tenant)
+	key.Name, key.Role, key.Collections, key.Expires = options.Name, options.Role, options.Collections, options.Expires
+	ap.ApiKeys[k] = key
 
-	// Write the changes to the file using gob
-	file, err := os.OpenFile("collections/__apikeys", os.O_APPEND|os.O_WRONLY, 0644)
+	// Write the changes to the file
+	err = ap.writeApiKeys()
 	if err != nil {
-		Logger.Log.Log("Error opening file collections/__apikeys")
-		return "", err
+		delete(ap.ApiKeys, k)
+		return "", ApiKey{}, err
 	}
-	defer file.Close()
+	return id, *key, nil
+}
 
-	// Gob encode the map
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err = enc.Encode(ap.ApiKeyHashes)
-	if err != nil {
-		Logger.Log.Log("Error encoding map to file")
-		return "", err
-	}
+// DeleteApiKey will delete an ApiKey from the ApiKeyHandler
+func (ap *ApiKeyHandler) DeleteApiKey(apiKey string) error {
+	ap.Mut.Lock()
+	defer ap.Mut.Unlock()
+	return ap.deleteApiKey(hashApiKey(apiKey))
+}
 
-	// Write the map to the file
-	_, err = file.Write(buf.Bytes())
-	if err != nil {
-		Logger.Log.Log("Error writing map to file")
-		return "", err
+// RevokeApiKey will delete the ApiKey with the given id, a tenant can only revoke the keys of the tenant
+func (ap *ApiKeyHandler) RevokeApiKey(id string, tenant string) error {
+	ap.Mut.Lock()
+	defer ap.Mut.Unlock()
+	for k, key := range ap.ApiKeys {
+		if key.Id == id && (tenant == "" || key.Tenant == tenant) {
+			return ap.deleteApiKey(k)
+		}
 	}
+	return fmt.Errorf("ApiKey with id %s does not exist", id)
+}
 
-	return id, nil
+// ListApiKeys returns copies of the ApiKeys sorted by their creation, a tenant only gets the keys of the tenant
+func (ap *ApiKeyHandler) ListApiKeys(tenant string) []ApiKey {
+	ap.Mut.RLock()
+	defer ap.Mut.RUnlock()
+	keys := make([]ApiKey, 0, len(ap.ApiKeys))
+	for _, key := range ap.ApiKeys {
+		if tenant == "" || key.Tenant == tenant {
+			keys = append(keys, *key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Created.Before(keys[j].Created)
+	})
+	return keys
 }
 
-// DeleteApiKey will delete an ApiKey from the ApiKeyHandler
-func (ap *ApiKeyHandler) DeleteApiKey(apiKey string) error {
+// RenameCollection renames a collection in the collections of the ApiKeys, so the keys keep their access to the
+// renamed collection and do not get access to a new collection with the old name
+func (ap *ApiKeyHandler) RenameCollection(name string, newName string) error {
 	ap.Mut.Lock()
 	defer ap.Mut.Unlock()
-	// hash the ApiKey
-	h := sha512.New()
-	h.Write([]byte(apiKey))
-	k := fmt.Sprintf("%x", h.Sum(nil))
-	delete(ap.ApiKeyHashes, k)
+	renamed := false
+	for _, key := range ap.ApiKeys {
+		if !key.CanUse(name) || len(key.Collections) == 0 {
+			continue
+		}
+		// The copies of the key that were looked up keep their slice
+		collections := make([]string, len(key.Collections))
+		for i, c := range key.Collections {
+			if c == name {
+				c = newName
+			}
+			collections[i] = c
+		}
+		key.Collections = collections
+		renamed = true
+	}
+	if !renamed {
+		return nil
+	}
+	return ap.writeApiKeys()
+}
 
-	// Write the changes to the file using gob
-	file, err := os.OpenFile("collections/__apikeys", os.O_TRUNC|os.O_WRONLY, 0644)
+// deleteApiKey deletes the ApiKey of a hash - the caller holds the lock. The last global admin key can not be
+// deleted, without it nobody could manage the ApiKeys.
+func (ap *ApiKeyHandler) deleteApiKey(k string) error {
+	key, ok := ap.ApiKeys[k]
+	if !ok {
+		return ErrApiKeyNotFound
+	}
+	if key.IsGlobal() && !key.IsExpired() {
+		admins := 0
+		for _, other := range ap.ApiKeys {
+			if other.IsGlobal() && !other.IsExpired() {
+				admins++
+			}
+		}
+		if admins == 1 {
+			return fmt.Errorf("the last admin ApiKey without tenant and collections can not be deleted")
+		}
+	}
+	delete(ap.ApiKeys, k)
+
+	// Write the changes to the file
+	err := ap.writeApiKeys()
 	if err != nil {
-		Logger.Log.Log("Error opening file collections/__apikeys")
+		ap.ApiKeys[k] = key
 		return err
 	}
-	defer file.Close()
+	return nil
+}
+
+// writeApiKeys writes all ApiKeys gob encoded to the file, the file is replaced at once - the caller holds the lock
+func (ap *ApiKeyHandler) writeApiKeys() error {
+	keys := make([]*ApiKey, 0, len(ap.ApiKeys))
+	for _, key := range ap.ApiKeys {
+		keys = append(keys, key)
+	}
 
-	// Gob encode the map
+	// Gob encode the keys
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
-	err = enc.Encode(ap.ApiKeyHashes)
+	err := enc.Encode(apiKeyFile{Keys: keys})
 	if err != nil {
-		Logger.Log.Log("Error encoding map to file")
+		Logger.Log.Log("Error encoding ApiKeys to file")
 		return err
 	}
 
-	// Write the map to the file
-	_, err = file.Write(buf.Bytes())
+	// Write the keys to the file
+	err = os.WriteFile("collections/__apikeys.tmp", buf.Bytes(), 0644)
 	if err != nil {
-		Logger.Log.Log("Error writing map to file")
+		Logger.Log.Log("Error writing file collections/__apikeys.tmp")
 		return err
 	}
-
-	return nil
+	return os.Rename("collections/__apikeys.tmp", "collections/__apikeys")
 }
 
-// LoadApiKeys will load all ApiKeys Hashes from the file
+// LoadApiKeys will load all ApiKeys from the file, a file of an older version is migrated
 func (ap *ApiKeyHandler) LoadApiKeys() error {
-	// Open the file collections/__apikeys
-	file, err := os.Open("collections/__apikeys")
+	ap.Mut.Lock()
+	defer ap.Mut.Unlock()
+	// Read the file collections/__apikeys
+	data, err := os.ReadFile("collections/__apikeys")
 	if err != nil {
 		Logger.Log.Log("Error opening file collections/__apikeys")
 		return err
 	}
-	defer file.Close()
+	if len(data) == 0 {
+		return nil
+	}
 
 	// Read the file using gob decoding
-	dec := gob.NewDecoder(file)
-	err = dec.Decode(&ap.ApiKeyHashes)
-	if err != nil {
-		Logger.Log.Log("Error decoding file collections/__apikeys - EOF Error OK on first run!")
+	content := apiKeyFile{}
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&content)
+	if err == nil {
+		for _, key := range content.Keys {
+			// Keys of older versions had all rights
+			if key.Role == "" {
+				key.Role = Admin
+			}
+			ap.ApiKeys[key.Hash] = key
+		}
+		return nil
+	}
+
+	// Older versions appended the whole map of the hashes with a new encoder on every new key, the last map is the
+	// current one
+	var hashes map[string]bool
+	r := bytes.NewReader(data)
+	for {
+		next := make(map[string]bool)
+		if gob.NewDecoder(r).Decode(&next) != nil {
+			break
+		}
+		hashes = next
+	}
+	if hashes == nil {
+		Logger.Log.Log("Error decoding file collections/__apikeys")
 		return err
 	}
-	return nil
+	for k := range hashes {
+		ap.ApiKeys[k] = newApiKey(k, "")
+	}
+	Logger.Log.Log(fmt.Sprintf("Migrating %d ApiKeys of file collections/__apikeys", len(hashes)))
+	return ap.writeApiKeys()
+}
+
+// Lookup returns a copy of the stored ApiKey of a key, expired keys are not valid. If there are no ApiKeys every key
+// is valid and nil is returned.
+func (ap *ApiKeyHandler) Lookup(apiKey string) (*ApiKey, bool) {
+	ap.Mut.RLock()
+	if len(ap.ApiKeys) == 0 {
+		ap.Mut.RUnlock()
+		return nil, true
+	}
+	hash := hashApiKey(apiKey)
+	key, ok := ap.ApiKeys[hash]
+	if !ok || key.IsExpired() {
+		ap.Mut.RUnlock()
+		return nil, false
+	}
+	k := *key
+	ap.Mut.RUnlock()
+
+	if k.LastUsed == nil || time.Since(*k.LastUsed) > lastUsedInterval {
+		ap.touch(hash)
+	}
+	return &k, true
+}
+
+// touch sets the last use of the ApiKey of a hash and writes it to the file
+func (ap *ApiKeyHandler) touch(hash string) {
+	ap.Mut.Lock()
+	defer ap.Mut.Unlock()
+	key, ok := ap.ApiKeys[hash]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	key.LastUsed = &now
+	if err := ap.writeApiKeys(); err != nil {
+		Logger.Log.Log("Error writing the last use of ApiKey " + key.Id + ": " + err.Error())
+	}
+}
+
+// HasTenant returns true if an ApiKey is bound to the tenant
+func (ap *ApiKeyHandler) HasTenant(tenant string) bool {
+	ap.Mut.RLock()
+	defer ap.Mut.RUnlock()
+	for _, key := range ap.ApiKeys {
+		if key.Tenant == tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// newApiKey returns the stored admin ApiKey of a hash
+func newApiKey(hash string, tenant string) *ApiKey {
+	return &ApiKey{Id: hash[:16], Hash: hash, Role: Admin, Tenant: tenant, Created: time.Now()}
+}
+
+// hashApiKey returns the SHA-512 hash of a key in hex
+func hashApiKey(apiKey string) string {
+	h := sha512.New()
+	h.Write([]byte(apiKey))
+	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
 // CheckApiKey will check if the ApiKey is valid
@@ -183,15 +371,12 @@ func (ap *ApiKeyHandler) CheckApiKey(apiKey string) bool {
 	ap.Mut.RLock()
 	defer ap.Mut.RUnlock()
 	// If the map is empty we return true
-	if len(ap.ApiKeyHashes) == 0 {
+	if len(ap.ApiKeys) == 0 {
 		return true
 	}
 
 	// hash the ApiKey
-	h := sha512.New()
-	h.Write([]byte(apiKey))
-	k := fmt.Sprintf("%x", h.Sum(nil))
-	if _, ok := ap.ApiKeyHashes[k]; ok {
+	if key, ok := ap.ApiKeys[hashApiKey(apiKey)]; ok && !key.IsExpired() {
 		return true
 	}
 	return false
@@ -201,7 +386,7 @@ func (ap *ApiKeyHandler) CheckApiKey(apiKey string) bool {
 func (ap *ApiKeyHandler) CheckIfEmpty() bool {
 	ap.Mut.RLock()
 	defer ap.Mut.RUnlock()
-	if len(ap.ApiKeyHashes) == 0 {
+	if len(ap.ApiKeys) == 0 {
 		return true
 	}
 	return false
@@ -0,0 +1,136 @@
+package ApiKeyHandler
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestHandler returns an ApiKeyHandler without ApiKeys, it writes to the file of the ApiHandler
+func newTestHandler(t *testing.T) *ApiKeyHandler {
+	t.Helper()
+	ap := &ApiKeyHandler{ApiKeys: make(map[string]*ApiKey), Mut: sync.RWMutex{}}
+	t.Cleanup(func() {
+		ApiHandler.Mut.Lock()
+		defer ApiHandler.Mut.Unlock()
+		ApiHandler.writeApiKeys()
+	})
+	return ap
+}
+
+func TestRenameCollectionMovesScopes(t *testing.T) {
+	ap := newTestHandler(t)
+	scoped, _, err := ap.CreateApiKey(ApiKey{Role: Writer, Collections: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	global, _, err := ap.CreateApiKey(ApiKey{Role: Admin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := ap.Lookup(scoped)
+
+	if err = ap.RenameCollection("a", "c"); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := ap.Lookup(scoped)
+	if !reflect.DeepEqual(key.Collections, []string{"c", "b"}) {
+		t.Errorf("the key has the collections %v", key.Collections)
+	}
+	if key.CanUse("a") || !key.CanUse("c") {
+		t.Error("the key did not follow the collection")
+	}
+	if !reflect.DeepEqual(before.Collections, []string{"a", "b"}) {
+		t.Error("a key that was looked up before changed")
+	}
+	if key, _ = ap.Lookup(global); len(key.Collections) != 0 {
+		t.Errorf("the global key got the collections %v", key.Collections)
+	}
+
+	// The renamed collections are written to the file
+	loaded := newTestHandler(t)
+	if err = loaded.LoadApiKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ = loaded.Lookup(scoped); !key.CanUse("c") {
+		t.Error("the renamed collection was not written")
+	}
+}
+
+func TestLookupRejectsExpiredKeys(t *testing.T) {
+	ap := newTestHandler(t)
+	expires := time.Now().Add(time.Hour)
+	key, _, err := ap.CreateApiKey(ApiKey{Role: Reader, Expires: &expires})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, ok := ap.Lookup(key)
+	if !ok || found.Role != Reader {
+		t.Fatal("the key was not found")
+	}
+	if found.LastUsed == nil {
+		// The copy is taken before the use is written, the next lookup has it
+		if found, _ = ap.Lookup(key); found.LastUsed == nil {
+			t.Error("the last use was not written")
+		}
+	}
+
+	ap.ApiKeys[hashApiKey(key)].Expires = &time.Time{}
+	if _, ok = ap.Lookup(key); ok {
+		t.Error("an expired key was found")
+	}
+	if _, ok = ap.Lookup("unknown"); ok {
+		t.Error("an unknown key was found")
+	}
+}
+
+func TestLastGlobalAdminCanNotBeDeleted(t *testing.T) {
+	ap := newTestHandler(t)
+	admin, _, err := ap.CreateApiKey(ApiKey{Role: Admin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scoped, _, err := ap.CreateApiKey(ApiKey{Role: Admin, Collections: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ap.DeleteApiKey(admin); err == nil {
+		t.Fatal("the last global admin key was deleted")
+	}
+	if err = ap.DeleteApiKey(scoped); err != nil {
+		t.Fatal(err)
+	}
+	if err = ap.DeleteApiKey(scoped); !errors.Is(err, ErrApiKeyNotFound) {
+		t.Fatalf("deleting a deleted key returned %v", err)
+	}
+	if err = ap.DeleteApiKey(""); !errors.Is(err, ErrApiKeyNotFound) {
+		t.Fatalf("deleting an empty key returned %v", err)
+	}
+}
+
+func TestTenantsRevokeAndListOnlyTheirKeys(t *testing.T) {
+	ap := newTestHandler(t)
+	ap.CreateApiKey(ApiKey{Role: Admin})
+	_, own, err := ap.CreateApiKey(ApiKey{Role: Reader, Tenant: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := ap.CreateApiKey(ApiKey{Role: Reader, Tenant: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := ap.ListApiKeys("acme"); len(keys) != 1 || keys[0].Id != own.Id {
+		t.Errorf("the tenant lists %v", keys)
+	}
+	if keys := ap.ListApiKeys(""); len(keys) != 3 {
+		t.Errorf("%d keys are listed", len(keys))
+	}
+	if err = ap.RevokeApiKey(other.Id, "acme"); err == nil {
+		t.Error("a tenant revoked the key of another tenant")
+	}
+	if err = ap.RevokeApiKey(own.Id, "acme"); err != nil {
+		t.Error(err)
+	}
+}
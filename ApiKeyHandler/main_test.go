@@ -0,0 +1,21 @@
+package ApiKeyHandler
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain starts the ApiHandler in a temporary directory, its file and the log file are removed afterwards
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "vreedb")
+	if err != nil {
+		panic(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	ApiHandler.Start()
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,63 @@
+package ApiKeyHandler
+
+import (
+	"fmt"
+	"time"
+)
+
+// The roles of the ApiKeys, every role has the rights of the roles before it
+const (
+	Reader = "reader" // Searches and reads points and collections
+	Writer = "writer" // Adds and deletes points
+	Admin  = "admin"  // Manages collections, classifiers, indexes, aliases and ApiKeys
+)
+
+// roleRanks orders the roles
+var roleRanks = map[string]int{Reader: 1, Writer: 2, Admin: 3}
+
+// ValidRole returns true if the role exists
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// Can returns true if the ApiKey has the role or a higher one. A nil ApiKey is a session of the web interface or a
+// database without ApiKeys, it can do everything.
+func (k *ApiKey) Can(role string) bool {
+	return k == nil || roleRanks[k.Role] >= roleRanks[role]
+}
+
+// CanUse returns true if the ApiKey may use the collection, a key without collections may use all of them
+func (k *ApiKey) CanUse(collection string) bool {
+	if k == nil || len(k.Collections) == 0 {
+		return true
+	}
+	for _, c := range k.Collections {
+		if c == collection {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGlobal returns true if the ApiKey is an admin key of neither a tenant nor restricted collections, only those keys
+// manage the whole database
+func (k *ApiKey) IsGlobal() bool {
+	return k == nil || (k.Role == Admin && k.Tenant == "" && len(k.Collections) == 0)
+}
+
+// IsExpired returns true if the ApiKey has expired
+func (k *ApiKey) IsExpired() bool {
+	return k.Expires != nil && !k.Expires.After(time.Now())
+}
+
+// Validate checks the role and the expiry of a new ApiKey
+func (k *ApiKey) Validate() error {
+	if !ValidRole(k.Role) {
+		return fmt.Errorf("unknown role %q, use %s, %s or %s", k.Role, Reader, Writer, Admin)
+	}
+	if k.IsExpired() {
+		return fmt.Errorf("expires must be in the future")
+	}
+	return nil
+}
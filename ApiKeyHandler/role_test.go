@@ -0,0 +1,60 @@
+package ApiKeyHandler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRolesIncludeTheLowerRoles(t *testing.T) {
+	for _, c := range []struct {
+		key  string
+		role string
+		can  bool
+	}{
+		{Reader, Reader, true}, {Reader, Writer, false}, {Reader, Admin, false},
+		{Writer, Reader, true}, {Writer, Writer, true}, {Writer, Admin, false},
+		{Admin, Reader, true}, {Admin, Writer, true}, {Admin, Admin, true},
+	} {
+		if got := (&ApiKey{Role: c.key}).Can(c.role); got != c.can {
+			t.Errorf("%s can %s: %v", c.key, c.role, got)
+		}
+	}
+	var session *ApiKey
+	if !session.Can(Admin) || !session.CanUse("any") || !session.IsGlobal() {
+		t.Error("a session without ApiKey is restricted")
+	}
+}
+
+func TestCollectionsRestrictTheKey(t *testing.T) {
+	key := &ApiKey{Role: Admin, Collections: []string{"a"}}
+	if !key.CanUse("a") || key.CanUse("b") {
+		t.Error("the key does not use only its collection")
+	}
+	if key.IsGlobal() {
+		t.Error("a key of restricted collections is global")
+	}
+	if (&ApiKey{Role: Admin, Tenant: "acme"}).IsGlobal() {
+		t.Error("a key of a tenant is global")
+	}
+	if !(&ApiKey{Role: Admin}).IsGlobal() {
+		t.Error("an admin key without restrictions is not global")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	for _, c := range []struct {
+		key   ApiKey
+		valid bool
+	}{
+		{ApiKey{Role: Reader}, true},
+		{ApiKey{Role: "owner"}, false},
+		{ApiKey{Role: Writer, Expires: &future}, true},
+		{ApiKey{Role: Writer, Expires: &past}, false},
+	} {
+		if err := c.key.Validate(); (err == nil) != c.valid {
+			t.Errorf("Validate(%+v) = %v", c.key.Role, err)
+		}
+	}
+}
@@ -6,19 +6,43 @@ import (
 
 // ArgsParser struct
 type ArgsParser struct {
-	Ip           *string
-	Port         *int
-	Secure       *bool
-	CertFile     *string
-	KeyFile      *string
-	CreateApiKey *bool
-	Loglocation  *string
-	FileStore    *string
+	Ip            *string
+	Port          *int
+	Secure        *bool
+	CertFile      *string
+	KeyFile       *string
+	CreateApiKey  *bool
+	Tenant        *string
+	Loglocation   *string
+	FileStore     *string
+	ReapInterval  *int
+	IngestQueue   *int
+	IngestWorkers *int
+	SegmentSize   *int
+	MergeInterval *int
+	Fsck          *bool
+	FsckRepair    *bool
+	SnapshotDir   *string
+	Export        *string
+	Import        *string
+	File          *string
+	Filter        *string
+	ImportOffset  *int
+	DatasetDir    *string
+	Load          *string
+	Evaluate      *string
+	Queries       *string
+	GroundTruth   *string
+	K             *int
+	Distance      *string
+	RandomIds     *bool
+	Limit         *int
 }
 
 // Ap is a global ArgsParser
 var Ap *ArgsParser
 
+// init defines the flags with their defaults, Parse reads the command line
 func init() {
 	// Create a new ArgsParser
 	Ap = &ArgsParser{}
@@ -32,7 +56,33 @@ func init() {
 	Ap.CertFile = flag.String("certfile", "", "The path to the certificate file")
 	Ap.KeyFile = flag.String("keyfile", "", "The path to the key file")
 	Ap.CreateApiKey = flag.Bool("createapikey", false, "Create a new API key")
+	Ap.Tenant = flag.String("tenant", "", "The tenant of the API key of -createapikey, empty for a key without tenant")
+	Ap.ReapInterval = flag.Int("reapinterval", 60, "The interval in seconds in which expired points are deleted")
+	Ap.IngestQueue = flag.Int("ingestqueue", 16, "The number of point batches that can wait for insertion")
+	Ap.IngestWorkers = flag.Int("ingestworkers", 2, "The number of workers that insert point batches")
+	Ap.SegmentSize = flag.Int("segmentsize", 100000, "The number of points after which the active segment of a collection is sealed")
+	Ap.MergeInterval = flag.Int("mergeinterval", 300, "The interval in seconds in which small sealed segments are merged")
+	Ap.Fsck = flag.Bool("fsck", false, "Check the collection files, print the report as JSON and exit")
+	Ap.FsckRepair = flag.Bool("fsckrepair", false, "Rewrite the meta files with problems during -fsck")
+	Ap.SnapshotDir = flag.String("snapshotdir", "snapshots/", "The directory of the collection snapshots")
+	Ap.Export = flag.String("export", "", "Export the points of this collection as JSON lines and exit")
+	Ap.Import = flag.String("import", "", "Import points as JSON lines into this collection and exit")
+	Ap.File = flag.String("file", "", "The file of -export and -import, default stdout and stdin")
+	Ap.Filter = flag.String("filter", "", "A JSON list of filters, only the points that pass are exported by -export")
+	Ap.ImportOffset = flag.Int("importoffset", 0, "The number of lines -import skips, to resume an import")
+	Ap.DatasetDir = flag.String("datasetdir", "datasets/", "The directory of the dataset files that /loaddataset can load")
+	Ap.Load = flag.String("load", "", "Load the vectors of the -file (.fvecs, .bvecs or .npy) into this collection and exit")
+	Ap.Evaluate = flag.String("evaluate", "", "Evaluate the recall@k of the search in this collection and exit")
+	Ap.Queries = flag.String("queries", "", "The query vectors of -evaluate (.fvecs, .bvecs or .npy)")
+	Ap.GroundTruth = flag.String("groundtruth", "", "The rows of the true nearest neighbours of the -queries (.ivecs)")
+	Ap.K = flag.Int("k", 10, "The number of nearest neighbours -evaluate compares")
+	Ap.Distance = flag.String("distance", "cosine", "The distance function of a collection created by -load, cosine or euclid")
+	Ap.RandomIds = flag.Bool("randomids", false, "Generate the ids of -load, otherwise the row of a vector is its id")
+	Ap.Limit = flag.Int("limit", 0, "The number of vectors -load and queries -evaluate use, 0 is all")
+}
 
+// Parse parses the command line into Ap - main calls it before the database is started
+func Parse() {
 	// Parse
 	flag.Parse()
 
@@ -40,4 +90,10 @@ func init() {
 	if (*Ap.FileStore)[len(*Ap.FileStore)-1] != '/' {
 		*Ap.FileStore += "/"
 	}
+	if (*Ap.SnapshotDir)[len(*Ap.SnapshotDir)-1] != '/' {
+		*Ap.SnapshotDir += "/"
+	}
+	if (*Ap.DatasetDir)[len(*Ap.DatasetDir)-1] != '/' {
+		*Ap.DatasetDir += "/"
+	}
 }
@@ -3,10 +3,12 @@ package Boot
 import (
 	"VreeDB/Collection"
 	"VreeDB/FileMapper"
+	"VreeDB/Format"
 	"VreeDB/Logger"
 	"VreeDB/Utils"
 	"VreeDB/Vector"
 	"encoding/json"
+	"errors"
 	"os"
 	"strings"
 )
@@ -50,43 +52,107 @@ func (b *BootUp) RestoreCollections() map[string]*Collection.Collection {
 				Logger.Log.Log("Error decoding file: " + err.Error())
 				continue
 			}
-			// Enter the collection into the map
-			collections[strings.Split(entry.Name(), ".")[0]] = Collection.NewCollection(c.Name, c.VectorDimension, c.DistanceFuncName)
-
-			// Set the DiagonalLength
-			collections[c.Name].DiagonalLength = c.DiagonalLength
-
-			// Create the collection in the Filemapper
-			FileMapper.Mapper.AddCollection(c.Name)
+			file.Close()
 
-			// Restore vectors (if any)
-			vectors, err := b.RestoreVectors(c.Name, collections[c.Name].VectorDimension)
-			if err != nil {
-				Logger.Log.Log("Error restoring vectors: " + err.Error())
+			// Restore the collection - we refuse to start on files of a newer version
+			collection, err := b.RestoreCollection(c)
+			if errors.Is(err, Format.ErrNewerVersion) {
+				panic(err)
+			} else if err != nil {
+				Logger.Log.Log("Error restoring collection: " + err.Error())
 				continue
 			}
-			// Set the vectors
-			collections[c.Name].Space = vectors
+			collections[strings.Split(entry.Name(), ".")[0]] = collection
+		}
+	}
+	return collections
+}
 
-			// Recreate the KD-Tree
-			collections[c.Name].Recreate()
+// RestoreCollection restores a collection from its config and its files, files of an older format version are migrated
+func (b *BootUp) RestoreCollection(c Utils.CollectionConfig) (*Collection.Collection, error) {
+	// Upgrade the files of an older format version
+	migrated, err := b.Migrate(&c)
+	if err != nil {
+		return nil, err
+	}
 
-			// Set ClassifierReady
-			collections[c.Name].ClassifierReady = true
+	// Create the collection - this will also set the DiagonalLength and the sparse fields
+	collection := Collection.NewCollectionFromConfig(c)
+	if migrated {
+		err = collection.WriteConfig()
+		if err != nil {
+			Logger.Log.Log("Error writing config: " + err.Error())
+		}
+	}
 
-			// recreate the SVMs (if present)
-			err = collections[c.Name].ReadClassifiers()
-			if err != nil {
-				Logger.Log.Log("Error reading SVMs: " + err.Error())
-			}
-			Logger.Log.Log("Collection " + c.Name + " classifiers restored")
+	// Create the segments in the Filemapper and restore their vectors (if any)
+	vectors, err := b.RestoreSegments(collection)
+	if err != nil {
+		return nil, err
+	}
+	// Set the vectors
+	collection.Space = vectors
 
-			// Close the file
-			file.Close()
-			Logger.Log.Log("Collection " + c.Name + " restored")
+	// Recreate the KD-Trees of the segments
+	collection.Recreate()
+
+	// Restore the inverted indexes of the sparse fields
+	err = b.RestoreSparseVectors(collection)
+	if err != nil {
+		Logger.Log.Log("Error restoring sparse vectors: " + err.Error())
+	}
+
+	// Restore the KD-Trees of the named vector fields and the multi vector fields
+	b.RestoreNamedVectors(collection)
+	b.RestoreMultiVectors(collection)
+
+	// Restore the packed bits of the binary vector fields
+	err = b.RestoreBinaryVectors(collection)
+	if err != nil {
+		Logger.Log.Log("Error restoring binary vectors: " + err.Error())
+	}
+
+	// Set ClassifierReady
+	collection.ClassifierReady = true
+
+	// recreate the SVMs (if present)
+	err = collection.ReadClassifiers()
+	if err != nil {
+		Logger.Log.Log("Error reading SVMs: " + err.Error())
+	}
+	Logger.Log.Log("Collection " + c.Name + " classifiers restored")
+
+	// Rebuild the payload indexes
+	err = collection.RestoreIndexes(c.Indexes)
+	if err != nil {
+		Logger.Log.Log("Error restoring indexes: " + err.Error())
+	}
+	err = collection.RestoreGeoIndexes(c.GeoIndexes)
+	if err != nil {
+		Logger.Log.Log("Error restoring geo indexes: " + err.Error())
+	}
+
+	Logger.Log.Log("Collection " + c.Name + " restored")
+	return collection, nil
+}
+
+// RestoreSegments will add all segments of a collection to the FileMapper and restore the vectors of all segments
+func (b *BootUp) RestoreSegments(collection *Collection.Collection) (*map[string]*Vector.Vector, error) {
+	vectors := make(map[string]*Vector.Vector)
+	for _, key := range collection.SegmentKeys() {
+		err := FileMapper.Mapper.AddCollection(key, collection.VectorDimension)
+		if err != nil {
+			return nil, err
+		}
+		segment, err := b.RestoreVectors(key, collection.VectorDimension)
+		if err != nil {
+			return nil, err
+		}
+		for id, v := range *segment {
+			vectors[id] = v
 		}
 	}
-	return collections
+	return &vectors, nil
 }
 
 // Restore Vectors will restore the vectors
@@ -108,7 +174,93 @@ func (b *BootUp) RestoreVectors(collection string, dimension int) (*map[string]*
 		vectors[v.VectorID].DataStart = v.DataStart
 		vectors[v.VectorID].PayloadStart = v.PayloadStart
 		vectors[v.VectorID].Length = dimension
+		vectors[v.VectorID].SparseStart = v.SparseStart
+		vectors[v.VectorID].VectorStart = v.VectorStart
+		vectors[v.VectorID].MultiVectorStart = v.MultiStart
+		vectors[v.VectorID].BinaryStart = v.BinaryStart
+		vectors[v.VectorID].RescoreStart = v.RescoreStart
+		vectors[v.VectorID].ExpiresAt = v.ExpiresAt
 		vectors[v.VectorID].Unindex()
 	}
 	return &vectors, nil
 }
+
+// RestoreSparseVectors will read the sparse vectors of all restored vectors and add them to the sparse fields
+func (b *BootUp) RestoreSparseVectors(collection *Collection.Collection) error {
+	for id, v := range *collection.Space {
+		for name, start := range v.SparseStart {
+			// Skip fields that are no longer part of the collection
+			if _, ok := collection.SparseFields[name]; !ok {
+				continue
+			}
+			indices, values, err := FileMapper.Mapper.ReadSparseVector(start, v.Collection)
+			if err != nil {
+				return err
+			}
+			collection.SparseFields[name].Add(id, &Vector.SparseVector{Indices: indices, Values: values})
+		}
+	}
+	return nil
+}
+
+// RestoreNamedVectors will read the named vectors of all restored vectors and insert them into their vector fields
+func (b *BootUp) RestoreNamedVectors(collection *Collection.Collection) {
+	for id, v := range *collection.Space {
+		for name, start := range v.VectorStart {
+			// Skip fields that are no longer part of the collection
+			field, ok := collection.VectorFields[name]
+			if !ok {
+				continue
+			}
+			named := Vector.NewVector(id, nil, nil, "")
+			named.Collection = v.Collection
+			named.DataStart = start
+			named.PayloadStart = v.PayloadStart
+			named.Length = field.VectorDimension
+			named.Unindex()
+			field.Insert(named)
+		}
+	}
+}
+
+// RestoreMultiVectors will read the token vectors of all restored vectors and insert them into their multi vector fields
+func (b *BootUp) RestoreMultiVectors(collection *Collection.Collection) {
+	for id, v := range *collection.Space {
+		for name, starts := range v.MultiVectorStart {
+			// Skip fields that are no longer part of the collection
+			field, ok := collection.MultiFields[name]
+			if !ok {
+				continue
+			}
+			tokens := make([]*Vector.Vector, len(starts))
+			for i, start := range starts {
+				tokens[i] = Vector.NewVector(id, nil, nil, "")
+				tokens[i].Collection = v.Collection
+				tokens[i].DataStart = start
+				tokens[i].PayloadStart = v.PayloadStart
+				tokens[i].Length = field.VectorDimension
+				tokens[i].Unindex()
+			}
+			field.Insert(id, tokens)
+		}
+	}
+}
+
+// RestoreBinaryVectors will read the packed bits of all restored vectors and insert them into their binary vector fields
+func (b *BootUp) RestoreBinaryVectors(collection *Collection.Collection) error {
+	for id, v := range *collection.Space {
+		for name, start := range v.BinaryStart {
+			// Skip fields that are no longer part of the collection
+			field, ok := collection.BinaryFields[name]
+			if !ok {
+				continue
+			}
+			bits, err := FileMapper.Mapper.ReadBinaryVector(start, (field.VectorDimension+63)/64, v.Collection)
+			if err != nil {
+				return err
+			}
+			field.Insert(id, bits, v.RescoreStart[name], v.Collection)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package Boot
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/FileMapper"
+	"VreeDB/Format"
+	"VreeDB/Logger"
+	"VreeDB/Utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// migrations upgrade the files of one segment by one format version, the key is the version they upgrade from. Every
+// migration has to be safe to run again on files it already upgraded, e.g. after a crash during the migration.
+var migrations = map[int]func(key string, dimension int) error{
+	Format.LegacyVersion: migrateLegacy,
+}
+
+// Migrate upgrades the files of a collection from an older format version in place, the old files are copied into a
+// backup directory first. Collections of a newer format version are refused. It returns true if the collection was
+// migrated, the config has to be written again then.
+func (b *BootUp) Migrate(config *Utils.CollectionConfig) (bool, error) {
+	version := config.FormatVersion
+	if version == 0 {
+		version = Format.LegacyVersion
+	}
+	if version > Format.Version {
+		return false, fmt.Errorf("Collection %s has format version %d, this build supports up to %d: %w", config.Name,
+			version, Format.Version, Format.ErrNewerVersion)
+	}
+	if version == Format.Version {
+		return false, nil
+	}
+
+	// Collections from before the segments have only one segment - the files of the collection itself
+	keys := config.Segments
+	if len(keys) == 0 {
+		keys = []string{config.Name}
+	}
+	backup, err := backupCollection(config.Name, version, keys)
+	if err != nil {
+		return false, fmt.Errorf("backup of Collection %s failed: %w", config.Name, err)
+	}
+	Logger.Log.Log("Collection " + config.Name + " backed up to " + backup)
+
+	for ; version < Format.Version; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return false, fmt.Errorf("no migration from format version %d", version)
+		}
+		for _, key := range keys {
+			err = migrate(key, config.VectorDimension)
+			if err != nil {
+				return false, fmt.Errorf("migration of segment %s from format version %d failed: %w", key, version, err)
+			}
+		}
+		Logger.Log.Log(fmt.Sprintf("Collection %s migrated to format version %d", config.Name, version+1))
+	}
+	config.FormatVersion = Format.Version
+	return true, nil
+}
+
+// backupCollection copies the config and the files of all segments of a collection into a new backup directory and
+// returns its path
+func backupCollection(name string, version int, keys []string) (string, error) {
+	dir := *ArgsParser.Ap.FileStore + "backup/" + name + "_v" + strconv.Itoa(version) + "_" +
+		time.Now().Format("20060102150405") + "/"
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	files := []string{name + ".json"}
+	for _, key := range keys {
+		files = append(files, key+".bin", key+"_meta.bin")
+	}
+	for _, file := range files {
+		err = copyFile(*ArgsParser.Ap.FileStore+file, dir+file)
+		if err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// copyFile copies the file src to dst, missing files are skipped
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// migrateLegacy upgrades a segment of the legacy layout: the data file gets a header in front of it and the newline
+// delimited JSON of the meta file is written as records, all positions are moved behind the header
+func migrateLegacy(key string, dimension int) error {
+	// The data file - files that already have a header were migrated before
+	path := *ArgsParser.Ap.FileStore + key + ".bin"
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err = Format.DecodeHeader(data); err == Format.ErrNoHeader {
+		err = writeFile(path, append(Format.NewHeader(Format.KindData, dimension).Encode(), data...))
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	// The meta file
+	path = *ArgsParser.Ap.FileStore + key + "_meta.bin"
+	meta, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if _, err = Format.DecodeHeader(meta); err != Format.ErrNoHeader {
+		return err
+	}
+	var vectors []FileMapper.SaveVector
+	decoder := json.NewDecoder(bytes.NewReader(meta))
+	for {
+		var sv FileMapper.SaveVector
+		if err := decoder.Decode(&sv); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		sv.Shift(Format.HeaderSize)
+		vectors = append(vectors, sv)
+	}
+	return FileMapper.WriteMetaFile(path, dimension, vectors)
+}
+
+// writeFile writes the data next to the file and renames it over the file
+func writeFile(path string, data []byte) error {
+	err := os.WriteFile(path+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
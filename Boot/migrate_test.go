@@ -0,0 +1,100 @@
+package Boot
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/FileMapper"
+	"VreeDB/Format"
+	"VreeDB/Utils"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeLegacyCollection writes a segment of the legacy layout with one point: the data file without header and a
+// meta file of newline delimited JSON
+func writeLegacyCollection(t *testing.T, name string) Utils.CollectionConfig {
+	t.Helper()
+	store := *ArgsParser.Ap.FileStore
+	if err := os.MkdirAll(store, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []float64{1.5, -2})
+	payload := map[string]interface{}{"n": 1.0}
+	if err := gob.NewEncoder(&data).Encode(&payload); err != nil {
+		t.Fatal(err)
+	}
+	meta, _ := json.Marshal(FileMapper.SaveVector{VectorID: "a", DataStart: 0, PayloadStart: 16})
+	config := Utils.CollectionConfig{Name: name, VectorDimension: 2, DistanceFuncName: "euclid"}
+	for file, content := range map[string][]byte{".bin": data.Bytes(), "_meta.bin": append(meta, '\n')} {
+		if err := os.WriteFile(store+name+file, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		FileMapper.Mapper.DelCollection(name)
+		backups, _ := filepath.Glob(store + "backup/" + name + "_v*")
+		for _, backup := range backups {
+			os.RemoveAll(backup)
+		}
+	})
+	return config
+}
+
+func TestLegacyCollectionIsMigrated(t *testing.T) {
+	config := writeLegacyCollection(t, "legacy")
+	legacy, err := os.ReadFile(*ArgsParser.Ap.FileStore + "legacy.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewBootUp().RestoreCollection(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Config().FormatVersion != Format.Version {
+		t.Errorf("collection has format version %d", c.Config().FormatVersion)
+	}
+	v, ok := (*c.Space)["a"]
+	if !ok {
+		t.Fatal("the point was not migrated")
+	}
+	if data := *v.GetData(); !reflect.DeepEqual(data, []float64{1.5, -2}) {
+		t.Errorf("vector reads %v", data)
+	}
+	payload, err := FileMapper.Mapper.ReadPayload(v.PayloadStart, v.Collection)
+	if err != nil || (*payload)["n"] != 1.0 {
+		t.Errorf("payload reads %v %v", payload, err)
+	}
+
+	// The files have headers now, the backup holds the legacy files
+	file, err := os.Open(*ArgsParser.Ap.FileStore + "legacy_meta.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err = Format.ReadHeader(file); err != nil {
+		t.Errorf("meta file: %v", err)
+	}
+	backups, _ := filepath.Glob(*ArgsParser.Ap.FileStore + "backup/legacy_v1_*/legacy.bin")
+	if len(backups) != 1 {
+		t.Fatalf("%d backups", len(backups))
+	}
+	if backup, _ := os.ReadFile(backups[0]); !bytes.Equal(backup, legacy) {
+		t.Error("the backup differs from the legacy data file")
+	}
+}
+
+func TestNewerFormatVersionIsRefused(t *testing.T) {
+	config := writeLegacyCollection(t, "newer")
+	config.FormatVersion = Format.Version + 1
+	if _, err := NewBootUp().RestoreCollection(config); !errors.Is(err, Format.ErrNewerVersion) {
+		t.Errorf("got %v, want %v", err, Format.ErrNewerVersion)
+	}
+}
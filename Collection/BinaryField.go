@@ -0,0 +1,148 @@
+package Collection
+
+import (
+	"VreeDB/FileMapper"
+	"VreeDB/Utils"
+	"VreeDB/Vector"
+	"container/heap"
+	"fmt"
+	"strings"
+)
+
+// BinaryField is a vector field that holds binary quantised vectors. Only the bit packed words are held in memory,
+// they are searched by a full scan with the hamming distance. A field with Rescore keeps the float vectors in the file,
+// they are used to rescore the candidates of the hamming scan.
+type BinaryField struct {
+	Name             string
+	VectorDimension  int
+	Rescore          bool
+	DistanceFunc     func(*Vector.Vector, *Vector.Vector) (float64, error)
+	DistanceFuncName string
+	Points           map[string]*BinaryPoint
+}
+
+// BinaryPoint is the binary vector of a point in a BinaryField
+type BinaryPoint struct {
+	Bits         []uint64
+	RescoreStart int64
+	Segment      string // the FileMapper key of the segment that holds the rescore vector
+}
+
+// NewBinaryField returns a new BinaryField
+func NewBinaryField(name string, vectorDimension int, rescore bool, distanceFuncName string) *BinaryField {
+	if strings.ToLower(distanceFuncName) == "euclid" {
+		distanceFuncName = "euclid"
+	} else {
+		distanceFuncName = "cosine"
+	}
+	return &BinaryField{Name: name, VectorDimension: vectorDimension, Rescore: rescore,
+		DistanceFunc: getDistanceFunc(distanceFuncName), DistanceFuncName: distanceFuncName,
+		Points: make(map[string]*BinaryPoint)}
+}
+
+// Check will check if the given binary vector fits into the BinaryField
+func (f *BinaryField) Check(binary *Vector.BinaryVector) error {
+	if binary.Length != f.VectorDimension {
+		return fmt.Errorf("Binary vector %s has length %d, expected %d", f.Name, binary.Length, f.VectorDimension)
+	}
+	return nil
+}
+
+// Insert inserts the binary vector of a point into the BinaryField
+func (f *BinaryField) Insert(id string, bits []uint64, rescoreStart int64, segment string) {
+	f.Points[id] = &BinaryPoint{Bits: bits, RescoreStart: rescoreStart, Segment: segment}
+}
+
+// Delete removes the binary vector of a point from the BinaryField
+func (f *BinaryField) Delete(id string) {
+	delete(f.Points, id)
+}
+
+// Nearest returns the ids of the n points with the smallest hamming distance to the given bits, only the given ids are
+// taken if they are not nil
+func (f *BinaryField) Nearest(bits []uint64, n int, only map[string]bool) []string {
+	h := &hammingHeap{}
+	for id, point := range f.Points {
+		if only != nil && !only[id] {
+			continue
+		}
+		distance := Utils.Utils.HammingDistance(point.Bits, bits)
+		if h.Len() < n {
+			heap.Push(h, hammingItem{id: id, distance: distance})
+		} else if n > 0 && distance < (*h)[0].distance {
+			(*h)[0] = hammingItem{id: id, distance: distance}
+			heap.Fix(h, 0)
+		}
+	}
+	ids := make([]string, h.Len())
+	for i := len(ids) - 1; i >= 0; i-- {
+		ids[i] = heap.Pop(h).(hammingItem).id
+	}
+	return ids
+}
+
+// Similarity returns the similarity of a point to the target. Fields with Rescore compare the float vectors with the
+// distance function (the negative distance for euclid, 1 - distance for cosine), all others use 1 - the normalised
+// hamming distance.
+func (f *BinaryField) Similarity(id string, target *Vector.BinaryVector) (float64, error) {
+	point, ok := f.Points[id]
+	if !ok {
+		return 0, fmt.Errorf("point %s has no binary vector %s", id, f.Name)
+	}
+	if !f.Rescore {
+		return 1 - float64(Utils.Utils.HammingDistance(point.Bits, target.Bits))/float64(f.VectorDimension), nil
+	}
+	data := FileMapper.Mapper.ReadVector(point.RescoreStart, f.VectorDimension, point.Segment)
+	distance, err := f.DistanceFunc(&Vector.Vector{Data: *data, Length: len(*data)},
+		&Vector.Vector{Data: target.Data, Length: target.Length})
+	if err != nil {
+		return 0, err
+	}
+	if f.DistanceFuncName == "euclid" {
+		return -distance, nil
+	}
+	return 1 - distance, nil
+}
+
+// Config returns the BinaryFieldConfig of the BinaryField
+func (f *BinaryField) Config() Utils.BinaryFieldConfig {
+	return Utils.BinaryFieldConfig{VectorDimension: f.VectorDimension, Rescore: f.Rescore, DistanceFuncName: f.DistanceFuncName}
+}
+
+// hammingItem is an entry of the hammingHeap
+type hammingItem struct {
+	id       string
+	distance int
+}
+
+// hammingHeap is a max heap of hamming distances - the root is the worst of the best n points
+type hammingHeap []hammingItem
+
+// Len returns the length of the heap
+func (h hammingHeap) Len() int {
+	return len(h)
+}
+
+// Less compares two items in the heap > will be used to create a max heap
+func (h hammingHeap) Less(i, j int) bool {
+	return h[i].distance > h[j].distance
+}
+
+// Swap swaps two items in the heap
+func (h hammingHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+// Push pushes an item into the heap
+func (h *hammingHeap) Push(x interface{}) {
+	*h = append(*h, x.(hammingItem))
+}
+
+// Pop pops an item from the heap
+func (h *hammingHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
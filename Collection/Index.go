@@ -1,26 +1,47 @@
 package Collection
 
 import (
+	"VreeDB/Datetime"
 	"VreeDB/FileMapper"
+	"VreeDB/Geo"
 	"VreeDB/Node"
 	"VreeDB/Vector"
 	"fmt"
 	"sync"
 )
 
+// maxGeoCells is the number of geohash cells a geo filter looks up in a geo index
+const maxGeoCells = 256
+
 // Index is the type to index specific vector payloads
 type Index struct {
 	// Indexes are sub kd trees
 	Entries        map[any]*Node.Node
 	CollectionName string
 	Key            string
+	Precision      int // Geohash characters of a geo index, 0 for an index of the payload values
 	Mut            *sync.RWMutex
 }
 
 // NewIndex returns a new Index
 func NewIndex(payloadkey string, space *map[string]*Vector.Vector, collection string) (*Index, error) {
+	return newIndex(payloadkey, 0, space, collection)
+}
+
+// NewGeoIndex returns a new Index of the geo points of a payload key, the points are indexed under their geohash with
+// precision characters
+func NewGeoIndex(payloadkey string, precision int, space *map[string]*Vector.Vector, collection string) (*Index, error) {
+	if precision < 1 || precision > Geo.MaxPrecision {
+		return nil, fmt.Errorf("geohash precision must be between 1 and %d", Geo.MaxPrecision)
+	}
+	return newIndex(payloadkey, precision, space, collection)
+}
+
+// newIndex builds an Index of the vectors of the space
+func newIndex(payloadkey string, precision int, space *map[string]*Vector.Vector, collection string) (*Index, error) {
 	// Create the Indexstruct
-	index := &Index{Entries: make(map[any]*Node.Node), CollectionName: collection, Key: payloadkey, Mut: &sync.RWMutex{}}
+	index := &Index{Entries: make(map[any]*Node.Node), CollectionName: collection, Key: payloadkey,
+		Precision: precision, Mut: &sync.RWMutex{}}
 
 	// Create a vectorMap as starting point to create the subtrees
 	vectorMap, err := index.getVectorFromPayloadIndex(payloadkey, space)
@@ -31,7 +52,7 @@ func NewIndex(payloadkey string, space *map[string]*Vector.Vector, collection st
 	}
 
 	// Build the subtrees
-	for _, vectors := range *vectorMap {
+	for value, vectors := range *vectorMap {
 		// Create a new Node
 		n := &Node.Node{Depth: 0}
 		// Insert the vectors into the Node
@@ -40,18 +61,31 @@ func NewIndex(payloadkey string, space *map[string]*Vector.Vector, collection st
 		}
 
 		// Insert the Node into the Index
-		switch v := (*vectors[0].Payload)[payloadkey].(type) {
-		case int:
-		case float64:
-		case string:
-			index.Entries[v] = n
-		default:
-			return nil, fmt.Errorf("only string, float64 and int are allowed")
-		}
+		index.Entries[value] = n
 	}
 	return index, nil
 }
 
+// indexValue returns the value a payload value is indexed under - ints are indexed as float64 like the values of a
+// json request, geo points under their geohash
+func (i *Index) indexValue(value any) (any, error) {
+	if i.Precision > 0 {
+		p, ok := Geo.ParsePoint(value)
+		if !ok {
+			return nil, fmt.Errorf("only geo points with lat and lon are allowed")
+		}
+		return Geo.Geohash(p, i.Precision), nil
+	}
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case float64, string:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("only string, float64 and int are allowed")
+	}
+}
+
 // getVectorFromPayloadIndex returns a map for a specific payload
 func (i *Index) getVectorFromPayloadIndex(payloadkey string, space *map[string]*Vector.Vector) (*map[any][]*Vector.Vector, error) {
 	// Create the map
@@ -59,32 +93,25 @@ func (i *Index) getVectorFromPayloadIndex(payloadkey string, space *map[string]*
 
 	// Loop over all the entries
 	for _, vector := range *space {
+		// Load the payload from the hdd
+		payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
+		if err != nil {
+			return nil, err
+		}
 
 		// Check if key is in the Payload
-		if _, ok := (*vector.Payload)[payloadkey]; ok {
-
-			// Load the payload from the hdd
-			payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, i.CollectionName)
-			if err != nil {
-				return nil, err
-			}
-
-			// only string, int and float64 are allowed
-			switch v := (*payload)[payloadkey].(type) {
-			case int:
-			case float64:
-			case string:
-				if _, ok := vectorMap[v]; !ok {
-					vectorMap[v] = []*Vector.Vector{}
-				}
-				// Add to the vectorMap
-				vectorMap[v] = append(vectorMap[v], vector)
-			default:
-				return nil, fmt.Errorf("only string, float64 and int are allowed")
-			}
-		} else {
+		value, ok := (*payload)[payloadkey]
+		if !ok {
 			continue
 		}
+
+		// only string, int and float64 are allowed
+		v, err := i.indexValue(value)
+		if err != nil {
+			return nil, err
+		}
+		// Add to the vectorMap
+		vectorMap[v] = append(vectorMap[v], vector)
 	}
 	return &vectorMap, nil
 }
@@ -93,18 +120,78 @@ func (i *Index) getVectorFromPayloadIndex(payloadkey string, space *map[string]*
 func (i *Index) AddToIndex(vector *Vector.Vector) error {
 
 	// Get the Payload from the hdd
-	payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, i.CollectionName)
+	payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
+	if err != nil {
+		return err
+	}
+	value, err := i.indexValue((*payload)[i.Key])
 	if err != nil {
 		return err
 	}
 
 	// Check if the key is in the Payload
-	if _, ok := i.Entries[(*payload)[i.Key]]; !ok {
+	if _, ok := i.Entries[value]; !ok {
 		// Add the key to the Index
-		i.Entries[(*payload)[i.Key]] = &Node.Node{Depth: 0}
+		i.Entries[value] = &Node.Node{Depth: 0}
 	}
 
 	// add it to the Node
-	i.Entries[(*vector.Payload)[i.Key]].Insert(vector)
+	i.Entries[value].Insert(vector)
 	return nil
 }
+
+// geoIDs returns the IDs of the live vectors of the geohash cells that touch the box. The cells are looked up with a
+// shorter geohash if the box needs too many cells, nil is returned if the box is too large for the index to help -
+// the caller holds the read lock of the Collection.
+func (i *Index) geoIDs(box Geo.Box, space map[string]*Vector.Vector) map[string]bool {
+	i.Mut.RLock()
+	defer i.Mut.RUnlock()
+	cells, precision := Geo.Cover(box, i.Precision, maxGeoCells)
+	if precision == 0 {
+		return nil
+	}
+	cover := make(map[string]bool, len(cells))
+	for _, cell := range cells {
+		cover[cell] = true
+	}
+	ids := make(map[string]bool)
+	for value, n := range i.Entries {
+		if hash, ok := value.(string); ok && cover[hash[:precision]] {
+			addLiveIDs(n, space, ids)
+		}
+	}
+	return ids
+}
+
+// addLiveIDs adds the IDs of the vectors of the KD-Tree that are still in the space
+func addLiveIDs(n *Node.Node, space map[string]*Vector.Vector, ids map[string]bool) {
+	if n == nil {
+		return
+	}
+	addLiveIDs(n.Left, space, ids)
+	addLiveIDs(n.Right, space, ids)
+	if n.Vector != nil && space[n.Vector.Id] == n.Vector {
+		ids[n.Vector.Id] = true
+	}
+}
+
+// rangeIDs returns the IDs of the live vectors of the values within [from, to]. With datetime the values are RFC 3339
+// strings or Unix seconds, otherwise only numbers - the caller holds the read lock of the Collection.
+func (i *Index) rangeIDs(from, to float64, datetime bool, space map[string]*Vector.Vector) map[string]bool {
+	i.Mut.RLock()
+	defer i.Mut.RUnlock()
+	ids := make(map[string]bool)
+	for value, n := range i.Entries {
+		var v float64
+		var ok bool
+		if datetime {
+			v, ok = Datetime.Parse(value)
+		} else {
+			v, ok = value.(float64)
+		}
+		if ok && v >= from && v <= to {
+			addLiveIDs(n, space, ids)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,241 @@
+package Collection
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/FileMapper"
+	"VreeDB/NN"
+	"VreeDB/Node"
+	"VreeDB/Schema"
+	"VreeDB/Svm"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// CollectionInfo holds the statistics of a Collection
+type CollectionInfo struct {
+	Name           string           `json:"name"`
+	Dimension      int              `json:"dimension"`
+	DistanceFunc   string           `json:"distance_func"`
+	Points         int              `json:"points"`
+	Deleted        int              `json:"deleted"` // Deleted points whose data is still in the files, a merge removes it
+	DiagonalLength float64          `json:"diagonal_length"`
+	DiskBytes      int64            `json:"disk_bytes"`
+	Files          []FileInfo       `json:"files"`
+	Segments       []SegmentInfo    `json:"segments"`
+	Dimensions     []DimensionInfo  `json:"dimensions"`
+	Indexes        []IndexInfo      `json:"indexes"`
+	Classifiers    []ClassifierInfo `json:"classifiers"`
+	Schema         *Schema.Schema   `json:"schema,omitempty"`
+	LastInsert     *time.Time       `json:"last_insert,omitempty"`
+}
+
+// FileInfo is a file of a Collection and its size on disk
+type FileInfo struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+// SegmentInfo holds the statistics of a Segment and its KD-Tree. The balance is the depth of a perfectly balanced tree
+// with the same number of nodes divided by the depth of the tree, 1 is perfectly balanced.
+type SegmentInfo struct {
+	Key     string  `json:"key"`
+	Points  int     `json:"points"`
+	Deleted int     `json:"deleted"`
+	Sealed  bool    `json:"sealed"`
+	Depth   int     `json:"depth"`
+	Balance float64 `json:"balance"`
+}
+
+// DimensionInfo holds the statistics of a dimension, min and max are the bounds the Collection keeps for its diagonal
+// length and the mean is taken over all points
+type DimensionInfo struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Range float64 `json:"range"`
+	Mean  float64 `json:"mean"`
+}
+
+// IndexInfo holds the payload key of an Index and its number of distinct values
+type IndexInfo struct {
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	Values    int    `json:"values"`              // Distinct values, the geohash cells of a geo index
+	Precision int    `json:"precision,omitempty"` // Geohash characters of a geo index
+}
+
+// ClassifierInfo holds the type and the training status of a classifier
+type ClassifierInfo struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Status   string  `json:"status"` // untrained, training or trained
+	Progress float64 `json:"progress,omitempty"`
+	Loss     float64 `json:"loss,omitempty"`
+}
+
+// Info returns the statistics of the Collection
+func (c *Collection) Info() (*CollectionInfo, error) {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	info := &CollectionInfo{Name: c.Name, Dimension: c.VectorDimension, DistanceFunc: c.DistanceFuncName,
+		Points: len(*c.Space), DiagonalLength: c.DiagonalLength, Schema: c.Schema, Files: []FileInfo{}, Segments: []SegmentInfo{},
+		Dimensions: []DimensionInfo{}, Indexes: []IndexInfo{}, Classifiers: []ClassifierInfo{}}
+
+	// The files of the segments, the config and the classifiers - the ID map files are written with every insert
+	var lastWrite time.Time
+	path := *ArgsParser.Ap.FileStore
+	for _, name := range c.fileNames() {
+		stat, err := os.Stat(path + name)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		info.Files = append(info.Files, FileInfo{Name: name, Bytes: stat.Size()})
+		info.DiskBytes += stat.Size()
+		if stat.ModTime().After(lastWrite) {
+			lastWrite = stat.ModTime()
+		}
+	}
+
+	for _, segment := range c.Segments {
+		deleted, err := deletedRecords(path+segment.Key+"_meta.bin", c.VectorDimension)
+		if err != nil {
+			return nil, err
+		}
+		info.Deleted += deleted
+		depth, nodes := treeDepth(segment.Nodes)
+		s := SegmentInfo{Key: segment.Key, Points: len(segment.Space), Deleted: deleted, Sealed: segment.Sealed,
+			Depth: depth}
+		if depth > 0 {
+			s.Balance = math.Ceil(math.Log2(float64(nodes+1))) / float64(depth)
+		}
+		info.Segments = append(info.Segments, s)
+	}
+
+	// The mean of every dimension over all points
+	if c.VectorDimension > 0 {
+		sum := make([]float64, c.VectorDimension)
+		for _, v := range *c.Space {
+			for i, value := range *v.GetData() {
+				sum[i] += value
+			}
+		}
+		for i := range sum {
+			d := DimensionInfo{Min: c.MinVector.Data[i], Max: c.MaxVector.Data[i], Range: c.DimensionDiff.Data[i]}
+			if len(*c.Space) > 0 {
+				d.Mean = sum[i] / float64(len(*c.Space))
+			}
+			info.Dimensions = append(info.Dimensions, d)
+		}
+	}
+
+	for name, index := range c.Indexes {
+		index.Mut.RLock()
+		info.Indexes = append(info.Indexes, IndexInfo{Name: name, Key: index.Key, Values: len(index.Entries),
+			Precision: index.Precision})
+		index.Mut.RUnlock()
+	}
+	sort.Slice(info.Indexes, func(i, j int) bool {
+		return info.Indexes[i].Name < info.Indexes[j].Name
+	})
+
+	for name, classifier := range c.Classifiers {
+		info.Classifiers = append(info.Classifiers, classifierInfo(name, classifier))
+	}
+	sort.Slice(info.Classifiers, func(i, j int) bool {
+		return info.Classifiers[i].Name < info.Classifiers[j].Name
+	})
+
+	// After a restart the last write of the files stands in for the last insert
+	if !c.LastInsert.IsZero() {
+		lastInsert := c.LastInsert
+		info.LastInsert = &lastInsert
+	} else if !lastWrite.IsZero() && info.Points > 0 {
+		info.LastInsert = &lastWrite
+	}
+	return info, nil
+}
+
+// DiskBytes returns the bytes of the files of the Collection
+func (c *Collection) DiskBytes() int64 {
+	c.Mut.RLock()
+	files := c.fileNames()
+	c.Mut.RUnlock()
+	var bytes int64
+	for _, name := range files {
+		if stat, err := os.Stat(*ArgsParser.Ap.FileStore + name); err == nil {
+			bytes += stat.Size()
+		}
+	}
+	return bytes
+}
+
+// fileNames returns the files of the segments, the config and the classifiers - the caller holds the lock
+func (c *Collection) fileNames() []string {
+	files := []string{c.Name + ".json", c.Name + "_classifiers.gob"}
+	for _, segment := range c.Segments {
+		files = append(files, segment.Key+".bin", segment.Key+"_meta.bin")
+	}
+	return files
+}
+
+// classifierInfo returns the type and the training status of a classifier
+func classifierInfo(name string, classifier Classifier) ClassifierInfo {
+	switch v := classifier.(type) {
+	case *Svm.MultiClassSVM:
+		info := ClassifierInfo{Name: name, Type: "svm", Status: "untrained"}
+		if v.Training {
+			info.Status = "training"
+		} else if len(v.Classifiers) > 0 {
+			info.Status = "trained"
+		}
+		return info
+	case *NN.Network:
+		info := ClassifierInfo{Name: name, Type: "nn", Status: "untrained"}
+		if phase := v.GetTrainPhase(); len(phase) > 0 {
+			last := phase[len(phase)-1]
+			info.Status = "training"
+			if last.Progress >= 1 {
+				info.Status = "trained"
+			}
+			info.Progress = last.Progress
+			info.Loss = last.Loss
+		}
+		return info
+	}
+	return ClassifierInfo{Name: name, Type: "unknown", Status: "trained"}
+}
+
+// deletedRecords returns the number of vectors in an ID map file whose last record flags them as deleted
+func deletedRecords(path string, dimension int) (int, error) {
+	records, _, err := FileMapper.ReadMetaFile(path, dimension)
+	if err != nil {
+		return 0, err
+	}
+	deleted := make(map[string]bool)
+	for _, sv := range records {
+		deleted[sv.VectorID] = sv.DataStart < 0
+	}
+	n := 0
+	for _, d := range deleted {
+		if d {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// treeDepth returns the depth of a KD-Tree and its number of nodes, an empty tree has depth 0
+func treeDepth(n *Node.Node) (int, int) {
+	if n == nil || n.Vector == nil {
+		return 0, 0
+	}
+	left, leftNodes := treeDepth(n.Left)
+	right, rightNodes := treeDepth(n.Right)
+	if right > left {
+		left = right
+	}
+	return left + 1, leftNodes + rightNodes + 1
+}
@@ -0,0 +1,34 @@
+package Collection
+
+import (
+	"VreeDB/Node"
+	"VreeDB/Vector"
+	"testing"
+)
+
+func TestTreeDepth(t *testing.T) {
+	leaf := func() *Node.Node {
+		return &Node.Node{Vector: &Vector.Vector{}}
+	}
+	chain := leaf()
+	chain.Right = leaf()
+	chain.Right.Left = leaf()
+	balanced := leaf()
+	balanced.Left, balanced.Right = leaf(), leaf()
+
+	for name, test := range map[string]struct {
+		node  *Node.Node
+		depth int
+		nodes int
+	}{
+		"nil":      {nil, 0, 0},
+		"empty":    {&Node.Node{}, 0, 0},
+		"leaf":     {leaf(), 1, 1},
+		"chain":    {chain, 3, 3},
+		"balanced": {balanced, 2, 3},
+	} {
+		if depth, nodes := treeDepth(test.node); depth != test.depth || nodes != test.nodes {
+			t.Errorf("%s: depth %d and %d nodes, want %d and %d", name, depth, nodes, test.depth, test.nodes)
+		}
+	}
+}
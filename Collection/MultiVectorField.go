@@ -0,0 +1,92 @@
+package Collection
+
+import (
+	"VreeDB/FileMapper"
+	"VreeDB/Node"
+	"VreeDB/Vector"
+	"fmt"
+	"math"
+)
+
+// MultiVectorField is a vector field where every point holds a variable length list of vectors (e.g. the token
+// embeddings of a ColBERT model). The KD-Tree of the embedded VectorField holds every single token vector and is used
+// to fetch the candidates, the candidates are then scored by MaxSim.
+type MultiVectorField struct {
+	*VectorField
+	Points map[string][]*Vector.Vector
+}
+
+// NewMultiVectorField returns a new MultiVectorField
+func NewMultiVectorField(name string, vectorDimension int, distanceFuncName string) *MultiVectorField {
+	return &MultiVectorField{VectorField: NewVectorField(name, vectorDimension, distanceFuncName),
+		Points: make(map[string][]*Vector.Vector)}
+}
+
+// Check will check if all token vectors fit into the MultiVectorField
+func (f *MultiVectorField) Check(tokens []*Vector.Vector) error {
+	if len(tokens) == 0 {
+		return fmt.Errorf("Multi vector %s is empty", f.Name)
+	}
+	for _, token := range tokens {
+		if err := f.VectorField.Check(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Insert inserts all token vectors of a point into the KD-Tree of the MultiVectorField
+func (f *MultiVectorField) Insert(id string, tokens []*Vector.Vector) {
+	for _, token := range tokens {
+		f.Nodes.Insert(token)
+		f.setDiaSpace(token)
+	}
+	f.Points[id] = tokens
+}
+
+// Delete removes the token vectors of a point from the MultiVectorField - the KD-Tree has to be rebuild afterwards
+func (f *MultiVectorField) Delete(id string) {
+	delete(f.Points, id)
+}
+
+// Rebuild will rebuild the KD-Tree of the MultiVectorField from its Points
+func (f *MultiVectorField) Rebuild() {
+	f.Nodes = &Node.Node{Depth: 0}
+	for _, tokens := range f.Points {
+		for _, token := range tokens {
+			f.Nodes.Insert(token)
+			f.setDiaSpace(token)
+		}
+	}
+}
+
+// MaxSim returns the late interaction score of a point: the sum over all query vectors of their best dot product with
+// one of the token vectors of the point
+func (f *MultiVectorField) MaxSim(id string, query []*Vector.Vector) float64 {
+	tokens, ok := f.Points[id]
+	if !ok {
+		return 0
+	}
+
+	// Read the token vectors of the point from the file
+	data := make([][]float64, len(tokens))
+	for i, token := range tokens {
+		data[i] = *FileMapper.Mapper.ReadVector(token.DataStart, token.Length, token.Collection)
+	}
+
+	score := 0.0
+	for _, q := range query {
+		best := math.Inf(-1)
+		for _, d := range data {
+			dot := 0.0
+			for i := range d {
+				dot += q.Data[i] * d[i]
+			}
+			if dot > best {
+				best = dot
+			}
+		}
+		score += best
+	}
+	return score
+}
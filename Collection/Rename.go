@@ -0,0 +1,74 @@
+package Collection
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/FileMapper"
+	"VreeDB/Logger"
+	"VreeDB/Svm"
+	"os"
+	"strings"
+)
+
+// Rename renames the Collection and moves its files - the segments, the classifiers and the config - to the new name.
+// The Collection stays usable, its vectors keep their offsets and only change the key of their segment.
+func (c *Collection) Rename(name string) error {
+	// No merge may add a segment under the old name
+	c.mergeMut.Lock()
+	defer c.mergeMut.Unlock()
+	c.Mut.Lock()
+	defer c.Mut.Unlock()
+	old := c.Name
+
+	// Rename the segments - segmentKey(name, 0) is the name of the collection
+	keys := make(map[string]string, len(c.Segments))
+	for i, segment := range c.Segments {
+		key := name + strings.TrimPrefix(segment.Key, old)
+		err := FileMapper.Mapper.RenameSegment(segment.Key, key)
+		if err != nil {
+			// Move the renamed segments back
+			for _, renamed := range c.Segments[:i] {
+				FileMapper.Mapper.RenameSegment(keys[renamed.Key], renamed.Key)
+			}
+			return err
+		}
+		keys[segment.Key] = key
+	}
+	for _, v := range *c.Space {
+		c.moveVector(v, &FileMapper.SaveVector{VectorID: v.Id, DataStart: v.DataStart, PayloadStart: v.PayloadStart,
+			SparseStart: v.SparseStart, VectorStart: v.VectorStart, MultiStart: v.MultiVectorStart,
+			BinaryStart: v.BinaryStart, RescoreStart: v.RescoreStart}, keys[v.Collection])
+	}
+	for _, segment := range c.Segments {
+		segment.Key = keys[segment.Key]
+	}
+
+	// Move the classifiers
+	path := *ArgsParser.Ap.FileStore
+	if _, err := os.Stat(path + old + "_classifiers.gob"); err == nil {
+		err = os.Rename(path+old+"_classifiers.gob", path+name+"_classifiers.gob")
+		if err != nil {
+			Logger.Log.Log("Error renaming classifiers: " + err.Error())
+		}
+	}
+	for _, classifier := range c.Classifiers {
+		if svm, ok := classifier.(*Svm.MultiClassSVM); ok {
+			svm.Collection = name
+		}
+	}
+	for _, index := range c.Indexes {
+		index.CollectionName = name
+	}
+
+	// The config is written under the new name before the old one is removed
+	c.Name = name
+	err := c.writeConfig()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path + old + ".json")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	Logger.Log.Log("Collection " + old + " renamed to " + name)
+	return nil
+}
@@ -0,0 +1,390 @@
+package Collection
+
+import (
+	"VreeDB/FileMapper"
+	"VreeDB/Logger"
+	"VreeDB/Node"
+	"VreeDB/Vector"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Segment is a part of a Collection with its own data, payload and ID map file and its own KD-Tree. The Key is the
+// FileMapper key of the segment files, the vectors of the segment carry it as their Collection. New vectors are
+// written to the active segment - the last segment of the Collection. A full active segment is sealed, sealed segments
+// never get new vectors, deletes only rebuild the KD-Tree of their own segment.
+type Segment struct {
+	Key    string
+	Nodes  *Node.Node
+	Space  map[string]*Vector.Vector
+	Sealed bool
+	// Collections without a dimension only use named vector fields, their segments have no KD-Tree
+	HasTree bool
+}
+
+// NewSegment returns a new Segment
+func NewSegment(key string, hasTree bool, sealed bool) *Segment {
+	return &Segment{Key: key, Nodes: &Node.Node{Depth: 0}, Space: make(map[string]*Vector.Vector), Sealed: sealed,
+		HasTree: hasTree}
+}
+
+// segmentKey returns the FileMapper key of the nth segment of a collection - the first segment uses the files of the
+// collection itself, so collections from before the segments are their first segment
+func segmentKey(collection string, n int) string {
+	if n == 0 {
+		return collection
+	}
+	return collection + "_seg" + strconv.Itoa(n)
+}
+
+const (
+	// RestoreSuffix is appended to the name of a Collection while a snapshot is restored into it
+	RestoreSuffix = "_restore"
+	// ReplacedSuffix is appended to the name of a Collection while it is replaced by a restored snapshot
+	ReplacedSuffix = "_replaced"
+)
+
+// ReservedName returns an error if the name of a Collection ends like the files of the segments or the meta file of
+// another Collection - "x_seg1" would share its files with the second segment of "x", "x_meta" its data file with the
+// meta file of "x". The names of a Collection while a snapshot is restored into it are reserved as well.
+func ReservedName(name string) error {
+	for _, suffix := range []string{"_meta", RestoreSuffix, ReplacedSuffix} {
+		if strings.HasSuffix(name, suffix) {
+			return fmt.Errorf("Collection name %s must not end with %s", name, suffix)
+		}
+	}
+	i := strings.LastIndex(name, "_seg")
+	if i < 0 || i+4 == len(name) {
+		return nil
+	}
+	for _, r := range name[i+4:] {
+		if r < '0' || r > '9' {
+			return nil
+		}
+	}
+	return fmt.Errorf("Collection name %s must not end with _seg and a number", name)
+}
+
+// Insert adds a vector to the Segment and its KD-Tree
+func (s *Segment) Insert(vector *Vector.Vector) {
+	if s.HasTree {
+		s.Nodes.Insert(vector)
+	}
+	s.Space[vector.Id] = vector
+}
+
+// Delete removes a vector from the Segment - the KD-Tree has to be rebuild afterwards
+func (s *Segment) Delete(id string) {
+	delete(s.Space, id)
+}
+
+// Rebuild will rebuild the KD-Tree of the Segment from its Space
+func (s *Segment) Rebuild() {
+	s.Nodes = &Node.Node{Depth: 0}
+	if !s.HasTree {
+		return
+	}
+	for _, v := range s.Space {
+		s.Nodes.Insert(v)
+	}
+}
+
+// segment returns the Segment with the given key or nil - the caller holds the lock
+func (c *Collection) segment(key string) *Segment {
+	for _, segment := range c.Segments {
+		if segment.Key == key {
+			return segment
+		}
+	}
+	return nil
+}
+
+// activeSegment returns the Segment new vectors are written to - the caller holds the lock
+func (c *Collection) activeSegment() *Segment {
+	return c.Segments[len(c.Segments)-1]
+}
+
+// WriteActiveSegment calls write with the FileMapper key new vectors of the Collection have to be written to. The
+// read lock is held while write runs, so the segment is not sealed or merged before the vector is in its files.
+func (c *Collection) WriteActiveSegment(write func(key string) error) error {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	return write(c.activeSegment().Key)
+}
+
+// SegmentKeys returns the FileMapper keys of all segments of the Collection
+func (c *Collection) SegmentKeys() []string {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	return c.segmentKeys()
+}
+
+// segmentKeys returns the FileMapper keys of all segments - the caller holds the lock
+func (c *Collection) segmentKeys() []string {
+	keys := make([]string, len(c.Segments))
+	for i, segment := range c.Segments {
+		keys[i] = segment.Key
+	}
+	return keys
+}
+
+// SegmentNodes returns the KD-Trees of all segments, a search has to fan out over all of them - the caller holds the lock
+func (c *Collection) SegmentNodes() []*Node.Node {
+	nodes := make([]*Node.Node, len(c.Segments))
+	for i, segment := range c.Segments {
+		nodes[i] = segment.Nodes
+	}
+	return nodes
+}
+
+// sealActiveSegment seals the active segment and opens a new one - the caller holds the lock
+func (c *Collection) sealActiveSegment() error {
+	// Open the new active segment
+	key := segmentKey(c.Name, c.NextSegment)
+	c.NextSegment++
+	err := FileMapper.Mapper.AddCollection(key, c.VectorDimension)
+	if err != nil {
+		return err
+	}
+	active := c.activeSegment()
+	active.Sealed = true
+	FileMapper.Mapper.Seal(active.Key)
+	c.Segments = append(c.Segments, NewSegment(key, c.VectorDimension > 0, false))
+	Logger.Log.Log("Segment " + active.Key + " sealed, new active segment " + key)
+
+	// The config holds the list of the segments
+	return c.writeConfig()
+}
+
+// MergeSegments merges the small sealed segments of the Collection into one new sealed segment, a segment is small if
+// it holds less than half of the segment size (e.g. after deletes). The vectors are copied while the Collection can
+// still be searched, the segments are only swapped at the end - the files of the merged segments are deleted afterwards.
+func (c *Collection) MergeSegments() error {
+	// Only one merge per Collection at a time
+	c.mergeMut.Lock()
+	defer c.mergeMut.Unlock()
+
+	// Find the small segments and reserve the key of the merged segment
+	c.Mut.Lock()
+	var small []*Segment
+	for _, segment := range c.Segments {
+		if segment.Sealed && len(segment.Space) < c.SegmentSize/2 {
+			small = append(small, segment)
+		}
+	}
+	if len(small) < 2 {
+		c.Mut.Unlock()
+		return nil
+	}
+	key := segmentKey(c.Name, c.NextSegment)
+	c.NextSegment++
+	err := c.writeConfig()
+	c.Mut.Unlock()
+	if err != nil {
+		return err
+	}
+	err = FileMapper.Mapper.AddCollection(key, c.VectorDimension)
+	if err != nil {
+		return err
+	}
+
+	// Copy the vectors of the small segments into the files of the merged segment
+	c.Mut.RLock()
+	copies := make(map[string]*FileMapper.SaveVector)
+	for _, segment := range small {
+		for id, v := range segment.Space {
+			sv, err := c.copyVector(v, key)
+			if err != nil {
+				c.Mut.RUnlock()
+				FileMapper.Mapper.DelSegment(key)
+				return err
+			}
+			copies[id] = sv
+		}
+	}
+	c.Mut.RUnlock()
+
+	// Swap the segments
+	c.Mut.Lock()
+	merged := NewSegment(key, c.VectorDimension > 0, true)
+	for id, sv := range copies {
+		v, ok := (*c.Space)[id]
+		if !ok || !containsSegment(small, c.segment(v.Collection)) {
+			// The vector was deleted (and maybe inserted again) while it was copied
+			err = FileMapper.Mapper.SaveVectorWriter(FileMapper.SaveVector{VectorID: id, DataStart: -1}, key)
+			if err != nil {
+				Logger.Log.Log("Error saving deleted vector to file: " + err.Error())
+			}
+			continue
+		}
+		c.moveVector(v, sv, key)
+		merged.Space[id] = v
+	}
+	merged.Rebuild()
+	FileMapper.Mapper.Seal(key)
+	segments := make([]*Segment, 0, len(c.Segments)-len(small)+1)
+	for _, segment := range c.Segments {
+		if !containsSegment(small, segment) {
+			segments = append(segments, segment)
+		}
+	}
+	// The merged segment goes in front of the active segment
+	c.Segments = append(segments[:len(segments)-1:len(segments)-1], merged, segments[len(segments)-1])
+	err = c.writeConfig()
+	c.Mut.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Nothing references the merged segments anymore
+	for _, segment := range small {
+		FileMapper.Mapper.DelSegment(segment.Key)
+	}
+	Logger.Log.Log(fmt.Sprintf("Merged %d segments of Collection %s into %s", len(small), c.Name, key))
+	return nil
+}
+
+// containsSegment returns true if the segment is part of the slice
+func containsSegment(segments []*Segment, segment *Segment) bool {
+	for _, s := range segments {
+		if s == segment {
+			return true
+		}
+	}
+	return false
+}
+
+// copyVector copies the data, the payload and all field vectors of a vector into the files of the segment with the
+// given key and writes its new ID map entry - the caller holds the read lock
+func (c *Collection) copyVector(v *Vector.Vector, key string) (*FileMapper.SaveVector, error) {
+	sv := &FileMapper.SaveVector{VectorID: v.Id, ExpiresAt: v.ExpiresAt}
+	var err error
+
+	// Copy the vector and the payload
+	sv.DataStart, _, err = FileMapper.Mapper.WriteVector(*FileMapper.Mapper.ReadVector(v.DataStart, v.Length, v.Collection), key)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := FileMapper.Mapper.ReadPayload(v.PayloadStart, v.Collection)
+	if err != nil {
+		return nil, err
+	}
+	sv.PayloadStart, err = FileMapper.Mapper.WritePayload(payload, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the sparse vectors
+	for name, start := range v.SparseStart {
+		indices, values, err := FileMapper.Mapper.ReadSparseVector(start, v.Collection)
+		if err != nil {
+			return nil, err
+		}
+		if sv.SparseStart == nil {
+			sv.SparseStart = make(map[string]int64)
+		}
+		sv.SparseStart[name], err = FileMapper.Mapper.WriteSparseVector(indices, values, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Copy the named vectors of the fields that still exist
+	for name, start := range v.VectorStart {
+		field, ok := c.VectorFields[name]
+		if !ok {
+			continue
+		}
+		if sv.VectorStart == nil {
+			sv.VectorStart = make(map[string]int64)
+		}
+		sv.VectorStart[name], _, err = FileMapper.Mapper.WriteVector(
+			*FileMapper.Mapper.ReadVector(start, field.VectorDimension, v.Collection), key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Copy the token vectors
+	for name, starts := range v.MultiVectorStart {
+		field, ok := c.MultiFields[name]
+		if !ok {
+			continue
+		}
+		if sv.MultiStart == nil {
+			sv.MultiStart = make(map[string][]int64)
+		}
+		sv.MultiStart[name] = make([]int64, len(starts))
+		for i, start := range starts {
+			sv.MultiStart[name][i], _, err = FileMapper.Mapper.WriteVector(
+				*FileMapper.Mapper.ReadVector(start, field.VectorDimension, v.Collection), key)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Copy the binary vectors and their rescore vectors
+	for name, start := range v.BinaryStart {
+		field, ok := c.BinaryFields[name]
+		if !ok {
+			continue
+		}
+		bits, err := FileMapper.Mapper.ReadBinaryVector(start, (field.VectorDimension+63)/64, v.Collection)
+		if err != nil {
+			return nil, err
+		}
+		if sv.BinaryStart == nil {
+			sv.BinaryStart = make(map[string]int64)
+		}
+		sv.BinaryStart[name], err = FileMapper.Mapper.WriteBinaryVector(bits, key)
+		if err != nil {
+			return nil, err
+		}
+		if rescoreStart, ok := v.RescoreStart[name]; ok {
+			if sv.RescoreStart == nil {
+				sv.RescoreStart = make(map[string]int64)
+			}
+			sv.RescoreStart[name], _, err = FileMapper.Mapper.WriteVector(
+				*FileMapper.Mapper.ReadVector(rescoreStart, field.VectorDimension, v.Collection), key)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sv, FileMapper.Mapper.SaveVectorWriter(*sv, key)
+}
+
+// moveVector points a vector and its field vectors to their copies in the segment with the given key - the caller
+// holds the lock
+func (c *Collection) moveVector(v *Vector.Vector, sv *FileMapper.SaveVector, key string) {
+	v.Collection = key
+	v.DataStart = sv.DataStart
+	v.PayloadStart = sv.PayloadStart
+	v.SparseStart = sv.SparseStart
+	v.VectorStart = sv.VectorStart
+	v.MultiVectorStart = sv.MultiStart
+	v.BinaryStart = sv.BinaryStart
+	v.RescoreStart = sv.RescoreStart
+	for name, start := range sv.VectorStart {
+		if named, ok := c.VectorFields[name].Space[v.Id]; ok {
+			named.Collection = key
+			named.DataStart = start
+			named.PayloadStart = sv.PayloadStart
+		}
+	}
+	for name, starts := range sv.MultiStart {
+		for i, token := range c.MultiFields[name].Points[v.Id] {
+			token.Collection = key
+			token.DataStart = starts[i]
+			token.PayloadStart = sv.PayloadStart
+		}
+	}
+	for name := range sv.BinaryStart {
+		if point, ok := c.BinaryFields[name].Points[v.Id]; ok {
+			point.Segment = key
+			point.RescoreStart = sv.RescoreStart[name]
+		}
+	}
+}
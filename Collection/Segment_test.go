@@ -0,0 +1,24 @@
+package Collection
+
+import "testing"
+
+func TestReservedName(t *testing.T) {
+	for name, reserved := range map[string]bool{
+		"x":           false,
+		"x_seg":       false,
+		"x_segment":   false,
+		"x_seg1a":     false,
+		"x_seg1":      true,
+		"x_seg12":     true,
+		"x_meta":      true,
+		"x_seg1_meta": true,
+		"metadata":    false,
+		"x_restore":   true,
+		"x_replaced":  true,
+		"x_restored":  false,
+	} {
+		if err := ReservedName(name); (err != nil) != reserved {
+			t.Errorf("ReservedName(%q) = %v", name, err)
+		}
+	}
+}
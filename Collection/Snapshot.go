@@ -0,0 +1,124 @@
+package Collection
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/Format"
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// SnapshotManifest describes the content of a snapshot archive, it is the first entry of the archive
+type SnapshotManifest struct {
+	Name          string    `json:"name"`
+	Collection    string    `json:"collection"`
+	Created       time.Time `json:"created"`
+	FormatVersion int       `json:"format_version"`
+	Points        int       `json:"points"`
+	Files         []string  `json:"files"`
+}
+
+// ManifestFile is the name of the manifest in a snapshot archive
+const ManifestFile = "manifest.json"
+
+// snapshotFile is a file of the Collection that goes into a snapshot, only the first size bytes are copied
+type snapshotFile struct {
+	name string
+	file *os.File
+	data []byte
+	size int64
+}
+
+// WriteSnapshot writes a point-in-time snapshot of the Collection as a gzipped tar archive to w. Writes are only
+// paused while the files are opened and their sizes are taken - the data and the meta files are append only and
+// rewrites replace them by a rename, so the open files keep the state of the snapshot while they are copied.
+func (c *Collection) WriteSnapshot(w io.Writer, name string) (*SnapshotManifest, error) {
+	files, manifest, err := c.openSnapshotFiles(name)
+	defer func() {
+		for _, f := range files {
+			if f.file != nil {
+				f.file.Close()
+			}
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	m, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files = append([]snapshotFile{{name: ManifestFile, data: m, size: int64(len(m))}}, files...)
+	for _, f := range files {
+		err = tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: f.size, ModTime: manifest.Created})
+		if err != nil {
+			return nil, err
+		}
+		if f.file != nil {
+			_, err = io.Copy(tw, io.LimitReader(f.file, f.size))
+		} else {
+			_, err = tw.Write(f.data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("snapshot of %s: %w", f.name, err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, gz.Close()
+}
+
+// openSnapshotFiles opens all files of the Collection under the lock and returns them with the manifest of the snapshot
+func (c *Collection) openSnapshotFiles(name string) ([]snapshotFile, *SnapshotManifest, error) {
+	c.Mut.Lock()
+	defer c.Mut.Unlock()
+
+	// The config carries the segments, the fields and the index definitions
+	config, err := json.Marshal(c.config())
+	if err != nil {
+		return nil, nil, err
+	}
+	files := []snapshotFile{{name: c.Name + ".json", data: config, size: int64(len(config))}}
+
+	// The meta file is opened before the data file, every record it holds has its data in the file then
+	for _, key := range c.segmentKeys() {
+		for _, suffix := range []string{"_meta.bin", ".bin"} {
+			file, err := os.Open(*ArgsParser.Ap.FileStore + key + suffix)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return files, nil, err
+			}
+			info, err := file.Stat()
+			if err != nil {
+				file.Close()
+				return files, nil, err
+			}
+			files = append(files, snapshotFile{name: key + suffix, file: file, size: info.Size()})
+		}
+	}
+
+	// The classifiers are small and only written as a whole
+	classifiers, err := os.ReadFile(*ArgsParser.Ap.FileStore + c.Name + "_classifiers.gob")
+	if err == nil {
+		files = append(files, snapshotFile{name: c.Name + "_classifiers.gob", data: classifiers,
+			size: int64(len(classifiers))})
+	} else if !os.IsNotExist(err) {
+		return files, nil, err
+	}
+
+	manifest := &SnapshotManifest{Name: name, Collection: c.Name, Created: time.Now().UTC(),
+		FormatVersion: Format.Version, Points: len(*c.Space)}
+	for _, f := range files {
+		manifest.Files = append(manifest.Files, f.name)
+	}
+	return files, manifest, nil
+}
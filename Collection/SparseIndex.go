@@ -0,0 +1,72 @@
+package Collection
+
+import (
+	"VreeDB/Vector"
+	"sync"
+)
+
+// SparseIndex is an inverted index over a sparse vector field of the collection
+type SparseIndex struct {
+	Name string
+	// Postings maps a sparse dimension to all vectors that have a value in that dimension
+	Postings map[int][]SparsePosting
+	// Docs holds the dimensions of every indexed vector - needed to remove a vector again
+	Docs map[string][]int
+	Mut  *sync.RWMutex
+}
+
+// SparsePosting is a single entry in the posting list of a sparse dimension
+type SparsePosting struct {
+	Id    string
+	Value float64
+}
+
+// NewSparseIndex returns a new SparseIndex
+func NewSparseIndex(name string) *SparseIndex {
+	return &SparseIndex{Name: name, Postings: make(map[int][]SparsePosting), Docs: make(map[string][]int),
+		Mut: &sync.RWMutex{}}
+}
+
+// Add adds a sparse vector to the inverted index
+func (s *SparseIndex) Add(id string, sparse *Vector.SparseVector) {
+	s.Mut.Lock()
+	defer s.Mut.Unlock()
+	for i, idx := range sparse.Indices {
+		s.Postings[idx] = append(s.Postings[idx], SparsePosting{Id: id, Value: sparse.Values[i]})
+	}
+	s.Docs[id] = append([]int{}, sparse.Indices...)
+}
+
+// Remove removes the sparse vector with the given id from the inverted index
+func (s *SparseIndex) Remove(id string) {
+	s.Mut.Lock()
+	defer s.Mut.Unlock()
+	for _, idx := range s.Docs[id] {
+		postings := s.Postings[idx]
+		for i := range postings {
+			if postings[i].Id == id {
+				postings = append(postings[:i], postings[i+1:]...)
+				break
+			}
+		}
+		if len(postings) == 0 {
+			delete(s.Postings, idx)
+		} else {
+			s.Postings[idx] = postings
+		}
+	}
+	delete(s.Docs, id)
+}
+
+// Score returns the dot product of the query with every vector that shares at least one dimension with it
+func (s *SparseIndex) Score(query *Vector.SparseVector) map[string]float64 {
+	s.Mut.RLock()
+	defer s.Mut.RUnlock()
+	scores := make(map[string]float64)
+	for i, idx := range query.Indices {
+		for _, p := range s.Postings[idx] {
+			scores[p.Id] += query.Values[i] * p.Value
+		}
+	}
+	return scores
+}
@@ -0,0 +1,85 @@
+package Collection
+
+import (
+	"VreeDB/Node"
+	"VreeDB/Utils"
+	"VreeDB/Vector"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// VectorField is a named vector field of the collection, it has its own dimension, distance function and KD-Tree
+type VectorField struct {
+	Name             string
+	Nodes            *Node.Node
+	VectorDimension  int
+	DistanceFunc     func(*Vector.Vector, *Vector.Vector) (float64, error)
+	DistanceFuncName string
+	Space            map[string]*Vector.Vector
+	MaxVector        *Vector.Vector
+	MinVector        *Vector.Vector
+	DimensionDiff    *Vector.Vector
+	DiagonalLength   float64
+}
+
+// NewVectorField returns a new VectorField
+func NewVectorField(name string, vectorDimension int, distanceFuncName string) *VectorField {
+	// Only euclid and cosine are supported - like in the collection itself
+	if strings.ToLower(distanceFuncName) == "euclid" {
+		distanceFuncName = "euclid"
+	} else {
+		distanceFuncName = "cosine"
+	}
+	return &VectorField{Name: name, Nodes: &Node.Node{Depth: 0}, VectorDimension: vectorDimension,
+		DistanceFunc: getDistanceFunc(distanceFuncName), DistanceFuncName: distanceFuncName,
+		Space:         make(map[string]*Vector.Vector),
+		MaxVector:     &Vector.Vector{Data: make([]float64, vectorDimension), Length: vectorDimension},
+		MinVector:     &Vector.Vector{Data: make([]float64, vectorDimension), Length: vectorDimension},
+		DimensionDiff: &Vector.Vector{Data: make([]float64, vectorDimension), Length: vectorDimension}}
+}
+
+// Check will check if the given vector fits into the VectorField
+func (f *VectorField) Check(vector *Vector.Vector) error {
+	if vector.Length != f.VectorDimension {
+		return fmt.Errorf("Vector %s has length %d, expected %d", f.Name, vector.Length, f.VectorDimension)
+	}
+	return nil
+}
+
+// Insert inserts a named vector into the KD-Tree of the VectorField
+func (f *VectorField) Insert(vector *Vector.Vector) {
+	f.Nodes.Insert(vector)
+	f.setDiaSpace(vector)
+	f.Space[vector.Id] = vector
+}
+
+// Delete removes a named vector from the VectorField - the KD-Tree has to be rebuild afterwards
+func (f *VectorField) Delete(id string) {
+	delete(f.Space, id)
+}
+
+// Rebuild will rebuild the KD-Tree of the VectorField from its Space
+func (f *VectorField) Rebuild() {
+	f.Nodes = &Node.Node{Depth: 0}
+	for _, v := range f.Space {
+		f.Nodes.Insert(v)
+		f.setDiaSpace(v)
+	}
+}
+
+// setDiaSpace will set the diagonal space of the VectorField
+func (f *VectorField) setDiaSpace(vector *Vector.Vector) {
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go Utils.Utils.GetMaxDimension(f.MaxVector, vector, &wg)
+	go Utils.Utils.GetMinDimension(f.MinVector, vector, &wg)
+	wg.Wait()
+	Utils.Utils.CalculateDimensionDiff(f.VectorDimension, f.DimensionDiff, f.MaxVector, f.MinVector)
+	Utils.Utils.CalculateDiogonalLength(&f.DiagonalLength, f.VectorDimension, f.DimensionDiff)
+}
+
+// Config returns the VectorFieldConfig of the VectorField
+func (f *VectorField) Config() Utils.VectorFieldConfig {
+	return Utils.VectorFieldConfig{VectorDimension: f.VectorDimension, DistanceFuncName: f.DistanceFuncName}
+}
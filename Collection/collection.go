@@ -3,9 +3,13 @@ package Collection
 import (
 	"VreeDB/ArgsParser"
 	"VreeDB/FileMapper"
+	"VreeDB/Format"
+	"VreeDB/Fsck"
+	"VreeDB/Geo"
 	"VreeDB/Logger"
 	"VreeDB/NN"
 	"VreeDB/Node"
+	"VreeDB/Schema"
 	"VreeDB/Svm"
 	"VreeDB/Utils"
 	"VreeDB/Vector"
@@ -13,14 +17,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Collection is a struct that holds a name, a pointer to a Node, a vector dimension and a distance function
 type Collection struct {
 	Name               string
-	Nodes              *Node.Node
+	Segments           []*Segment
+	NextSegment        int
+	SegmentSize        int
+	mergeMut           sync.Mutex
 	VectorDimension    int
 	DistanceFunc       func(*Vector.Vector, *Vector.Vector) (float64, error)
 	Mut                sync.RWMutex
@@ -34,6 +43,15 @@ type Collection struct {
 	ClassifierReady    bool
 	Indexes            map[string]*Index
 	ClassifierTraining map[string]Classifier
+	SparseFields       map[string]*SparseIndex
+	VectorFields       map[string]*VectorField
+	MultiFields        map[string]*MultiVectorField
+	BinaryFields       map[string]*BinaryField
+	DefaultTTL         int64
+	LastInsert         time.Time
+	Schema             *Schema.Schema
+	Tenant             string // The tenant that owns the Collection
+	TenantField        string // The payload key of the tenant of every point of a shared Collection
 }
 
 // Interface for the Classifier
@@ -43,56 +61,187 @@ type Classifier interface {
 
 // NewCollection returns a new Collection
 func NewCollection(name string, vectorDimension int, distanceFuncName string) *Collection {
-	// Vars
-	var distanceFunc func(*Vector.Vector, *Vector.Vector) (float64, error)
-
 	// Create the max,min and diff vectors
 	ma := &Vector.Vector{Data: make([]float64, vectorDimension), Length: vectorDimension}
 	mi := &Vector.Vector{Data: make([]float64, vectorDimension), Length: vectorDimension}
 	dd := &Vector.Vector{Data: make([]float64, vectorDimension), Length: vectorDimension}
 
+	distanceFunc := getDistanceFunc(distanceFuncName)
+
+	// The first segment is the active one
+	segments := []*Segment{NewSegment(segmentKey(name, 0), vectorDimension > 0, false)}
+
+	return &Collection{Name: name, VectorDimension: vectorDimension, Segments: segments, NextSegment: 1,
+		SegmentSize: *ArgsParser.Ap.SegmentSize, DistanceFunc: distanceFunc, Space: &map[string]*Vector.Vector{},
+		MaxVector: ma, MinVector: mi, DimensionDiff: dd, DistanceFuncName: distanceFuncName, Classifiers: make(map[string]Classifier),
+		ClassifierReady: false, ClassifierTraining: make(map[string]Classifier), SparseFields: make(map[string]*SparseIndex),
+		VectorFields: make(map[string]*VectorField), MultiFields: make(map[string]*MultiVectorField),
+		BinaryFields: make(map[string]*BinaryField), Indexes: make(map[string]*Index)}
+}
+
+// getDistanceFunc returns the distance function for the given name - cosine is the default
+func getDistanceFunc(distanceFuncName string) func(*Vector.Vector, *Vector.Vector) (float64, error) {
 	if strings.ToLower(distanceFuncName) == "euclid" {
-		distanceFunc = Utils.Utils.EuclideanDistance
-	} else {
-		distanceFunc = Utils.Utils.CosineDistance
+		return Utils.Utils.EuclideanDistance
 	}
+	return Utils.Utils.CosineDistance
+}
 
-	return &Collection{Name: name, VectorDimension: vectorDimension, Nodes: &Node.Node{Depth: 0}, DistanceFunc: distanceFunc, Space: &map[string]*Vector.Vector{},
-		MaxVector: ma, MinVector: mi, DimensionDiff: dd, DistanceFuncName: distanceFuncName, Classifiers: make(map[string]Classifier),
-		ClassifierReady: false, ClassifierTraining: make(map[string]Classifier)}
+// NewCollectionFromConfig returns a new Collection described by the given CollectionConfig
+func NewCollectionFromConfig(config Utils.CollectionConfig) *Collection {
+	c := NewCollection(config.Name, config.VectorDimension, config.DistanceFuncName)
+	c.DiagonalLength = config.DiagonalLength
+	c.DefaultTTL = config.DefaultTTL
+	c.Schema = config.Schema
+	c.Tenant = config.Tenant
+	c.TenantField = config.TenantField
+	// Restore the segments - the last one is the active segment
+	if len(config.Segments) > 0 {
+		c.Segments = make([]*Segment, len(config.Segments))
+		for i, key := range config.Segments {
+			c.Segments[i] = NewSegment(key, c.VectorDimension > 0, i < len(config.Segments)-1)
+		}
+		c.NextSegment = config.NextSegment
+	}
+	// Create the sparse vector fields
+	for _, name := range config.SparseFields {
+		c.SparseFields[name] = NewSparseIndex(name)
+	}
+	// Create the named vector fields
+	for name, field := range config.VectorFields {
+		c.VectorFields[name] = NewVectorField(name, field.VectorDimension, field.DistanceFuncName)
+	}
+	// Create the multi vector fields
+	for name, field := range config.MultiFields {
+		c.MultiFields[name] = NewMultiVectorField(name, field.VectorDimension, field.DistanceFuncName)
+	}
+	// Create the binary vector fields
+	for name, field := range config.BinaryFields {
+		c.BinaryFields[name] = NewBinaryField(name, field.VectorDimension, field.Rescore, field.DistanceFuncName)
+	}
+	return c
 }
 
 // Insert inserts a vector into the collection
 func (c *Collection) Insert(vector *Vector.Vector) error {
+	return c.InsertBatch([]*Vector.Vector{vector})[0]
+}
+
+// InsertBatch inserts the vectors into the collection, the records of the vectors of a segment are saved with a single
+// write. Every vector gets its own error, a failing vector does not stop the others. A full active segment is sealed
+// after the batch.
+func (c *Collection) InsertBatch(vectors []*Vector.Vector) []error {
 	c.Mut.Lock()
 	defer c.Mut.Unlock()
+	errs := make([]error, len(vectors))
+	records := make(map[string][]FileMapper.SaveVector)
+	inserted := make(map[string][]int)
+	for i, vector := range vectors {
+		errs[i] = c.insert(vector)
+		if errs[i] == nil {
+			records[vector.Collection] = append(records[vector.Collection], FileMapper.SaveVector{VectorID: vector.Id,
+				DataStart: vector.DataStart, PayloadStart: vector.PayloadStart, SparseStart: vector.SparseStart,
+				VectorStart: vector.VectorStart, MultiStart: vector.MultiVectorStart, BinaryStart: vector.BinaryStart,
+				RescoreStart: vector.RescoreStart, ExpiresAt: vector.ExpiresAt})
+			inserted[vector.Collection] = append(inserted[vector.Collection], i)
+		}
+	}
+
+	// Save the Collection to the FS
+	saved := false
+	for key, svs := range records {
+		err := FileMapper.Mapper.SaveVectorsWriter(svs, key)
+		if err != nil {
+			Logger.Log.Log("Error saving vector to file: " + err.Error())
+			for _, i := range inserted[key] {
+				errs[i] = err
+			}
+			continue
+		}
+		saved = true
+	}
+	if !saved {
+		return errs
+	}
+
+	// Seal the active segment if it is full
+	if segment := c.activeSegment(); len(inserted[segment.Key]) > 0 && len(segment.Space) >= c.SegmentSize {
+		err := c.sealActiveSegment()
+		if err != nil {
+			Logger.Log.Log("Error sealing segment: " + err.Error())
+		}
+	}
+
+	// Set classifier ready to true
+	c.ClassifierReady = true
+	c.LastInsert = time.Now()
+
+	// Check if there is an Index with a key from the Payload - if so add the vector to the Index
+	for i, vector := range vectors {
+		if errs[i] == nil {
+			go c.CheckIndex(vector)
+		}
+	}
+	return errs
+}
+
+// insert adds a vector to its segment, the Space and the fields of the collection - the caller holds the lock and
+// saves the record of the vector
+func (c *Collection) insert(vector *Vector.Vector) error {
 	if vector.Length != c.VectorDimension {
 		return fmt.Errorf("Vector length is %d, expected %d", vector.Length, c.VectorDimension)
 	} else if c.CheckID(vector.Id) {
 		return fmt.Errorf("Vector with ID %s already exists", vector.Id)
+	} else if err := c.CheckSparseFields(vector.Sparse); err != nil {
+		return err
+	} else if err := c.checkNamedVectors(vector.Vectors); err != nil {
+		return err
+	} else if err := c.checkMultiVectors(vector.MultiVectors); err != nil {
+		return err
+	} else if err := c.checkBinaryVectors(vector.Binary); err != nil {
+		return err
+	}
+	// The vector was written to the files of a segment - it may have been sealed since, it still holds the vector
+	segment := c.segment(vector.Collection)
+	if segment == nil {
+		return fmt.Errorf("Segment %s does not exist in Collection %s", vector.Collection, c.Name)
 	}
 
-	// Insert the vector into the KD-Tree
-	c.Nodes.Insert(vector)
+	// Insert the vector into the KD-Tree of its segment
+	segment.Insert(vector)
 
-	// Set diagonal Space
-	c.SetDiaSpace(vector)
+	// Collections without a dimension only use named vector fields
+	if c.VectorDimension > 0 {
+		// Set diagonal Space
+		c.SetDiaSpace(vector)
+	}
 
 	// add it to the Space
 	(*c.Space)[vector.Id] = vector
 
-	// Save the Collection to the FS
-	err := FileMapper.Mapper.SaveVectorWriter(vector.Id, vector.DataStart, vector.PayloadStart, c.Name)
-	if err != nil {
-		Logger.Log.Log("Error saving vector to file: " + err.Error())
-		return err
+	// Add the sparse vectors to their inverted indexes - the values are kept there, so the vector can drop them
+	for name, sparse := range vector.Sparse {
+		c.SparseFields[name].Add(vector.Id, sparse)
 	}
+	vector.Sparse = nil
 
-	// Set classifier ready to true
-	c.ClassifierReady = true
+	// Add the named vectors to the KD-Trees of their fields
+	for name, named := range vector.Vectors {
+		c.VectorFields[name].Insert(named)
+	}
+	vector.Vectors = nil
 
-	// Check if there is an Index with a key from the Payload - if so add the vector to the Index
-	go c.CheckIndex(vector)
+	// Add the token vectors to the KD-Trees of their multi vector fields
+	for name, tokens := range vector.MultiVectors {
+		c.MultiFields[name].Insert(vector.Id, tokens)
+	}
+	vector.MultiVectors = nil
+
+	// Add the packed bits to their binary vector fields
+	for name, binary := range vector.Binary {
+		c.BinaryFields[name].Insert(vector.Id, binary.Bits, vector.RescoreStart[name], vector.Collection)
+	}
+	vector.Binary = nil
 	return nil
 }
 
@@ -107,16 +256,127 @@ func (c *Collection) Delete(id string) error {
 	if _, ok := (*c.Space)[id]; !ok {
 		return fmt.Errorf("Vector with ID %s does not exist", id)
 	}
+	dirty := newRebuildSet()
+	err := c.remove(id, dirty)
+	if err != nil {
+		return err
+	}
+	// Rebuild the KD-Trees
+	c.rebuildFields(dirty)
+	return nil
+}
+
+// DeleteBatch deletes all given vectors from the collection, the KD-Trees are only rebuild once.
+// IDs that do not exist are skipped, the IDs of the deleted vectors are returned
+func (c *Collection) DeleteBatch(ids []string) ([]string, error) {
+	c.Mut.Lock()
+	defer c.Mut.Unlock()
+
+	deleted := make([]string, 0, len(ids))
+	dirty := newRebuildSet()
+	for _, id := range ids {
+		if _, ok := (*c.Space)[id]; !ok {
+			continue
+		}
+		err := c.remove(id, dirty)
+		if err != nil {
+			// Rebuild what is already deleted
+			c.rebuildFields(dirty)
+			return deleted, err
+		}
+		deleted = append(deleted, id)
+	}
+	if len(deleted) > 0 {
+		c.rebuildFields(dirty)
+	}
+	return deleted, nil
+}
+
+// remove flags the vector as deleted in the meta file and removes it from the Space and all fields. The segments and
+// fields that lost a vector are added to dirty - the caller holds the lock and has to rebuild the KD-Trees afterwards
+func (c *Collection) remove(id string, dirty *rebuildSet) error {
+	vector := (*c.Space)[id]
+	// Save a deleted flag in the ID map of its segment, the vector will not be restored on the next boot
+	err := FileMapper.Mapper.SaveVectorWriter(FileMapper.SaveVector{VectorID: id, DataStart: -1}, vector.Collection)
+	if err != nil {
+		Logger.Log.Log("Error saving deleted vector to file: " + err.Error())
+		return err
+	}
 	// Set the datastart to -1
-	(*c.Space)[id].DataStart = -1
+	vector.DataStart = -1
 
-	// Delete the vector from the Space
+	// Delete the vector from the Space and its segment
 	delete(*c.Space, id)
-	// Rebuild the KD-Tree
-	c.Rebuild()
+	if segment := c.segment(vector.Collection); segment != nil {
+		segment.Delete(id)
+		dirty.segments[segment.Key] = true
+	}
+	// Delete the vector from the sparse indexes
+	for _, sparseIndex := range c.SparseFields {
+		sparseIndex.Remove(id)
+	}
+	// Delete the vector from the named and multi vector fields, only those will be rebuild
+	for _, field := range c.VectorFields {
+		if _, ok := field.Space[id]; ok {
+			field.Delete(id)
+			dirty.fields[field.Name] = true
+		}
+	}
+	for _, field := range c.MultiFields {
+		if _, ok := field.Points[id]; ok {
+			field.Delete(id)
+			dirty.fields[field.Name] = true
+		}
+	}
+	// The binary vector fields are scanned, so they need no rebuild
+	for _, field := range c.BinaryFields {
+		field.Delete(id)
+	}
 	return nil
 }
 
+// rebuildSet holds the segments and the vector fields whose KD-Trees have to be rebuild after a delete
+type rebuildSet struct {
+	segments map[string]bool
+	fields   map[string]bool
+}
+
+// newRebuildSet returns a new empty rebuildSet
+func newRebuildSet() *rebuildSet {
+	return &rebuildSet{segments: make(map[string]bool), fields: make(map[string]bool)}
+}
+
+// rebuildFields rebuilds the KD-Trees of the dirty segments and vector fields - the caller holds the lock
+func (c *Collection) rebuildFields(dirty *rebuildSet) {
+	for key := range dirty.segments {
+		if segment := c.segment(key); segment != nil {
+			segment.Rebuild()
+		}
+	}
+	for name := range dirty.fields {
+		if field, ok := c.VectorFields[name]; ok {
+			field.Rebuild()
+		}
+		if field, ok := c.MultiFields[name]; ok {
+			field.Rebuild()
+		}
+	}
+}
+
+// ExpiredIDs returns the IDs of all expired vectors of the Collection
+func (c *Collection) ExpiredIDs() []string {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+
+	var ids []string
+	for id, v := range *c.Space {
+		if v.IsExpired() {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 // SetDIaSpace will set the diagonal space of the Collection
 func (c *Collection) SetDiaSpace(vector *Vector.Vector) {
 	// Update the max and min vectors
@@ -142,33 +402,108 @@ func (c *Collection) GetNodeCount() int64 {
 func (c *Collection) WriteConfig() error {
 	c.Mut.RLock()
 	defer c.Mut.RUnlock()
+	return c.writeConfig()
+}
+
+// Config returns the CollectionConfig of the Collection
+func (c *Collection) Config() Utils.CollectionConfig {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	return c.config()
+}
 
+// writeConfig will write the Collection config to the file system - the caller holds the lock
+func (c *Collection) writeConfig() error {
 	// We need to save the CollectionConfig, this will be done via a struct that saves the important configs of the Collection
-	file, err := os.Create(*ArgsParser.Ap.FileStore + c.Name + ".json")
+	// It is written to a temporary file that replaces the config, so a crash can not leave a half written config
+	path := *ArgsParser.Ap.FileStore + c.Name + ".json"
+	file, err := os.Create(path + ".tmp")
 	if err != nil {
 		return err
 	}
 	// Save the struct to it
-	err = json.NewEncoder(file).Encode(Utils.CollectionConfig{
+	err = json.NewEncoder(file).Encode(c.config())
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// config returns the CollectionConfig of the Collection - the caller holds the lock
+func (c *Collection) config() Utils.CollectionConfig {
+	return Utils.CollectionConfig{
 		Name:             c.Name,
 		VectorDimension:  c.VectorDimension,
 		DistanceFuncName: c.DistanceFuncName,
 		DiagonalLength:   c.DiagonalLength,
-	})
-	if err != nil {
-		return err
+		SparseFields:     c.SparseFieldNames(),
+		VectorFields:     c.vectorFieldConfigs(),
+		MultiFields:      c.multiFieldConfigs(),
+		BinaryFields:     c.binaryFieldConfigs(),
+		DefaultTTL:       c.DefaultTTL,
+		Segments:         c.segmentKeys(),
+		NextSegment:      c.NextSegment,
+		FormatVersion:    Format.Version,
+		Indexes:          c.indexConfigs(),
+		GeoIndexes:       c.geoIndexConfigs(),
+		Schema:           c.Schema,
+		Tenant:           c.Tenant,
+		TenantField:      c.TenantField,
 	}
-	return nil
 }
 
-// Recreate will recreate the KD-Tree from the SpaceMap
+// Check runs the integrity check over the files of the Collection. With repair the broken meta files are rewritten and
+// the vectors that have no valid entry left are removed from the Collection.
+func (c *Collection) Check(repair bool) *Fsck.Report {
+	// Nothing is inserted or deleted while the files are checked
+	c.Mut.Lock()
+	defer c.Mut.Unlock()
+	report := Fsck.Check(c.config(), repair)
+	if len(report.Removed) == 0 {
+		return report
+	}
+	dirty := newRebuildSet()
+	for _, id := range report.Removed {
+		if _, ok := (*c.Space)[id]; !ok {
+			continue
+		}
+		err := c.remove(id, dirty)
+		if err != nil {
+			Logger.Log.Log("Error removing vector " + id + ": " + err.Error())
+		}
+	}
+	c.rebuildFields(dirty)
+	return report
+}
+
+// Recreate will recreate the KD-Trees of the segments from the SpaceMap
 func (c *Collection) Recreate() {
 	c.Mut.Lock()
 	defer c.Mut.Unlock()
-	c.Nodes = &Node.Node{Depth: 0}
-	for _, v := range *c.Space {
+	for _, segment := range c.Segments {
+		segment.Space = make(map[string]*Vector.Vector)
+		segment.Nodes = &Node.Node{Depth: 0}
+	}
+	for id, v := range *c.Space {
 		v.RecreateMut() // This needed to recreate the vector mut, it will not be saved in the gob file
-		c.Nodes.Insert(v)
+		segment := c.segment(v.Collection)
+		if segment == nil {
+			Logger.Log.Log("Vector " + id + " belongs to the unknown segment " + v.Collection)
+			delete(*c.Space, id)
+			continue
+		}
+		segment.Insert(v)
+		// Collections without a dimension have no KD-Tree
+		if c.VectorDimension == 0 {
+			continue
+		}
 		c.SetDiaSpace(v)
 	}
 }
@@ -176,11 +511,205 @@ func (c *Collection) Recreate() {
 // Rebuild is like Recreate but it does not use the Mut and will not use the RecreateMut function
 func (c *Collection) Rebuild() {
 	// Mut already blocked in Delete
-	c.Nodes = &Node.Node{Depth: 0}
-	for _, v := range *c.Space {
-		c.Nodes.Insert(v)
-		c.SetDiaSpace(v)
+	for _, segment := range c.Segments {
+		segment.Rebuild()
+	}
+}
+
+// SetSchema declares the payload fields of the Collection, the payloads of all points have to match the schema. Every
+// change of the schema gets the next version.
+func (c *Collection) SetSchema(schema *Schema.Schema) error {
+	err := schema.Check()
+	if err != nil {
+		return err
+	}
+	c.Mut.Lock()
+	defer c.Mut.Unlock()
+
+	// The points that are already in the Collection have to match the schema
+	ids := make([]string, 0, len(*c.Space))
+	for id := range *c.Space {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		vector := (*c.Space)[id]
+		payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
+		if err != nil {
+			return err
+		}
+		if err = schema.Validate(*payload); err != nil {
+			return fmt.Errorf("point %s: %w", id, err)
+		}
+	}
+
+	old := c.Schema
+	schema.Version = 1
+	if old != nil {
+		schema.Version = old.Version + 1
+	}
+	c.Schema = schema
+	err = c.writeConfig()
+	if err != nil {
+		c.Schema = old
+		return err
+	}
+	Logger.Log.Log(fmt.Sprintf("Schema version %d of Collection %s set", schema.Version, c.Name))
+	return nil
+}
+
+// GetSchema returns the schema of the payload fields, nil if none is declared
+func (c *Collection) GetSchema() *Schema.Schema {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	return c.Schema
+}
+
+// CheckSparseFields will check if all given sparse vectors belong to a sparse field of the Collection
+func (c *Collection) CheckSparseFields(sparse map[string]*Vector.SparseVector) error {
+	for name := range sparse {
+		if _, ok := c.SparseFields[name]; !ok {
+			return fmt.Errorf("Sparse field %s does not exist in Collection %s", name, c.Name)
+		}
+	}
+	return nil
+}
+
+// CheckVectorFields will check if all given named vectors belong to a vector field of the Collection and have its dimension
+func (c *Collection) CheckVectorFields(vectors map[string][]float64) error {
+	for name, data := range vectors {
+		field, ok := c.VectorFields[name]
+		if !ok {
+			return fmt.Errorf("Vector field %s does not exist in Collection %s", name, c.Name)
+		}
+		if len(data) != field.VectorDimension {
+			return fmt.Errorf("Vector %s has length %d, expected %d", name, len(data), field.VectorDimension)
+		}
+	}
+	return nil
+}
+
+// checkNamedVectors will check if all named vectors of a vector fit into their vector fields
+func (c *Collection) checkNamedVectors(vectors map[string]*Vector.Vector) error {
+	for name, named := range vectors {
+		field, ok := c.VectorFields[name]
+		if !ok {
+			return fmt.Errorf("Vector field %s does not exist in Collection %s", name, c.Name)
+		}
+		if err := field.Check(named); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckMultiFields will check if all given multi vectors belong to a multi vector field of the Collection and all their
+// vectors have its dimension
+func (c *Collection) CheckMultiFields(vectors map[string][][]float64) error {
+	for name, data := range vectors {
+		field, ok := c.MultiFields[name]
+		if !ok {
+			return fmt.Errorf("Multi vector field %s does not exist in Collection %s", name, c.Name)
+		}
+		if len(data) == 0 {
+			return fmt.Errorf("Multi vector %s is empty", name)
+		}
+		for _, d := range data {
+			if len(d) != field.VectorDimension {
+				return fmt.Errorf("Multi vector %s has length %d, expected %d", name, len(d), field.VectorDimension)
+			}
+		}
+	}
+	return nil
+}
+
+// checkMultiVectors will check if all multi vectors of a vector fit into their multi vector fields
+func (c *Collection) checkMultiVectors(vectors map[string][]*Vector.Vector) error {
+	for name, tokens := range vectors {
+		field, ok := c.MultiFields[name]
+		if !ok {
+			return fmt.Errorf("Multi vector field %s does not exist in Collection %s", name, c.Name)
+		}
+		if err := field.Check(tokens); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckBinaryFields will check if all given binary vectors belong to a binary vector field of the Collection and have
+// its dimension
+func (c *Collection) CheckBinaryFields(vectors map[string][]float64) error {
+	for name, data := range vectors {
+		field, ok := c.BinaryFields[name]
+		if !ok {
+			return fmt.Errorf("Binary vector field %s does not exist in Collection %s", name, c.Name)
+		}
+		if len(data) != field.VectorDimension {
+			return fmt.Errorf("Binary vector %s has length %d, expected %d", name, len(data), field.VectorDimension)
+		}
+	}
+	return nil
+}
+
+// checkBinaryVectors will check if all binary vectors of a vector fit into their binary vector fields
+func (c *Collection) checkBinaryVectors(vectors map[string]*Vector.BinaryVector) error {
+	for name, binary := range vectors {
+		field, ok := c.BinaryFields[name]
+		if !ok {
+			return fmt.Errorf("Binary vector field %s does not exist in Collection %s", name, c.Name)
+		}
+		if err := field.Check(binary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// binaryFieldConfigs returns the configs of all binary vector fields
+func (c *Collection) binaryFieldConfigs() map[string]Utils.BinaryFieldConfig {
+	if len(c.BinaryFields) == 0 {
+		return nil
+	}
+	configs := make(map[string]Utils.BinaryFieldConfig)
+	for name, field := range c.BinaryFields {
+		configs[name] = field.Config()
+	}
+	return configs
+}
+
+// multiFieldConfigs returns the configs of all multi vector fields
+func (c *Collection) multiFieldConfigs() map[string]Utils.VectorFieldConfig {
+	if len(c.MultiFields) == 0 {
+		return nil
 	}
+	configs := make(map[string]Utils.VectorFieldConfig)
+	for name, field := range c.MultiFields {
+		configs[name] = field.Config()
+	}
+	return configs
+}
+
+// vectorFieldConfigs returns the configs of all named vector fields
+func (c *Collection) vectorFieldConfigs() map[string]Utils.VectorFieldConfig {
+	if len(c.VectorFields) == 0 {
+		return nil
+	}
+	configs := make(map[string]Utils.VectorFieldConfig)
+	for name, field := range c.VectorFields {
+		configs[name] = field.Config()
+	}
+	return configs
+}
+
+// SparseFieldNames returns the names of all sparse fields of the Collection
+func (c *Collection) SparseFieldNames() []string {
+	var names []string
+	for name := range c.SparseFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // CheckID will Check if the given ID is already in the Collection Space
@@ -358,9 +887,114 @@ func (c *Collection) CreateIndex(name, key string) error {
 	}
 	// Add the index to the Collection
 	c.Indexes[name] = index
+	return c.writeConfig()
+}
+
+// CreateGeoIndex will create a new geohash Index of the geo points of a payload key
+func (c *Collection) CreateGeoIndex(name, key string, precision int) error {
+	c.Mut.Lock()
+	defer c.Mut.Unlock()
+
+	// Check if the Index already exists
+	if _, ok := c.Indexes[name]; ok {
+		return fmt.Errorf("Index with name %s already exists", name)
+	}
+
+	// Create the index
+	index, err := NewGeoIndex(key, precision, c.Space, c.Name)
+	if err != nil {
+		return err
+	}
+	// Add the index to the Collection
+	c.Indexes[name] = index
+	return c.writeConfig()
+}
+
+// RestoreGeoIndexes rebuilds the geohash indexes of the Collection
+func (c *Collection) RestoreGeoIndexes(indexes map[string]Utils.GeoIndexConfig) error {
+	c.Mut.Lock()
+	defer c.Mut.Unlock()
+	for name, config := range indexes {
+		index, err := NewGeoIndex(config.Key, config.Precision, c.Space, c.Name)
+		if err != nil {
+			return fmt.Errorf("index %s: %w", name, err)
+		}
+		c.Indexes[name] = index
+	}
 	return nil
 }
 
+// GeoCandidates returns the IDs of the points a geo index of the payload key finds within the box, nil if there is no
+// geo index of the key or the box is too large for it
+func (c *Collection) GeoCandidates(key string, box Geo.Box) map[string]bool {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	for _, index := range c.Indexes {
+		if index.Precision > 0 && index.Key == key {
+			return index.geoIDs(box, *c.Space)
+		}
+	}
+	return nil
+}
+
+// RangeCandidates returns the IDs of the points a payload index of the key holds with a value within [from, to], nil
+// if there is no payload index of the key. With datetime the values are compared as datetimes.
+func (c *Collection) RangeCandidates(key string, from, to float64, datetime bool) map[string]bool {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	for _, index := range c.Indexes {
+		if index.Precision == 0 && index.Key == key {
+			return index.rangeIDs(from, to, datetime, *c.Space)
+		}
+	}
+	return nil
+}
+
+// RestoreIndexes rebuilds the payload indexes of the Collection, the map holds the payload key of every index name
+func (c *Collection) RestoreIndexes(indexes map[string]string) error {
+	c.Mut.Lock()
+	defer c.Mut.Unlock()
+	for name, key := range indexes {
+		index, err := NewIndex(key, c.Space, c.Name)
+		if err != nil {
+			return fmt.Errorf("index %s: %w", name, err)
+		}
+		c.Indexes[name] = index
+	}
+	return nil
+}
+
+// indexConfigs returns the payload key of every index name - the caller holds the lock
+func (c *Collection) indexConfigs() map[string]string {
+	if len(c.Indexes) == 0 {
+		return nil
+	}
+	indexes := make(map[string]string, len(c.Indexes))
+	for name, index := range c.Indexes {
+		if index.Precision == 0 {
+			indexes[name] = index.Key
+		}
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+	return indexes
+}
+
+// geoIndexConfigs returns the payload key and the precision of every geo index - the caller holds the lock
+func (c *Collection) geoIndexConfigs() map[string]Utils.GeoIndexConfig {
+	indexes := make(map[string]Utils.GeoIndexConfig)
+	for name, index := range c.Indexes {
+		if index.Precision > 0 {
+			indexes[name] = Utils.GeoIndexConfig{Key: index.Key, Precision: index.Precision}
+		}
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+	return indexes
+}
+
 // CheckIndex Check if a specific Index exists
 func (c *Collection) CheckIndex(vector *Vector.Vector) error {
 	// First check if there is an Index
@@ -372,7 +1006,7 @@ func (c *Collection) CheckIndex(vector *Vector.Vector) error {
 	var result []string
 
 	// Get the Payload from the hdd
-	payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, c.Name)
+	payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,271 @@
+// Package Dataset reads the vector files of ANN benchmarks and NumPy arrays.
+//
+// The .fvecs, .bvecs and .ivecs files of the benchmarks (e.g. SIFT1M) hold one record per vector, a record is the
+// dimension as little endian int32 followed by the values - float32 in .fvecs, uint8 in .bvecs and int32 in .ivecs.
+// The .npy files are NumPy arrays of float32 or float64 values in C order, with one vector per row.
+package Dataset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxDimension is the largest dimension of a vector in a dataset, the buffer of a vector is allocated from the
+	// dimension in the file before anything else is read
+	MaxDimension = 1 << 16
+	// maxNpyHeader is the largest header of a .npy file
+	maxNpyHeader = 1 << 20
+)
+
+// Reader reads the vectors of a dataset one after the other
+type Reader interface {
+	// Dimension returns the dimension of the vectors
+	Dimension() int
+	// Next returns the next vector, io.EOF after the last one
+	Next() ([]float64, error)
+}
+
+// Formats are the formats of the vector files
+var Formats = []string{"fvecs", "bvecs", "npy"}
+
+// FormatOf returns the format of a file from its extension
+func FormatOf(path string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+}
+
+// Open opens a vector file, the format is taken from its extension. The returned Closer closes the file.
+func Open(path string) (Reader, io.Closer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := NewReader(file, FormatOf(path))
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return reader, file, nil
+}
+
+// NewReader returns a Reader for the vectors of the given format in r
+func NewReader(r io.Reader, format string) (Reader, error) {
+	br := bufio.NewReaderSize(r, 1<<20)
+	switch format {
+	case "fvecs":
+		return newVecsReader(br, 4)
+	case "bvecs":
+		return newVecsReader(br, 1)
+	case "npy":
+		return newNpyReader(br)
+	}
+	return nil, fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// vecsReader reads .fvecs and .bvecs files
+type vecsReader struct {
+	r         *bufio.Reader
+	dimension int
+	size      int // The size of a value in bytes - 4 for float32, 1 for uint8
+	buf       []byte
+	n         int
+}
+
+// newVecsReader returns a vecsReader, the dimension is taken from the first record
+func newVecsReader(r *bufio.Reader, size int) (*vecsReader, error) {
+	head, err := r.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("file has no vectors")
+	}
+	dimension := int(int32(binary.LittleEndian.Uint32(head)))
+	if dimension <= 0 || dimension > MaxDimension {
+		return nil, fmt.Errorf("invalid dimension %d", dimension)
+	}
+	return &vecsReader{r: r, dimension: dimension, size: size, buf: make([]byte, 4+dimension*size)}, nil
+}
+
+// Dimension returns the dimension of the vectors
+func (v *vecsReader) Dimension() int {
+	return v.dimension
+}
+
+// Next returns the next vector
+func (v *vecsReader) Next() ([]float64, error) {
+	_, err := io.ReadFull(v.r, v.buf)
+	if err == io.EOF {
+		return nil, io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("vector %d is incomplete", v.n)
+	} else if err != nil {
+		return nil, err
+	}
+	if d := int(int32(binary.LittleEndian.Uint32(v.buf))); d != v.dimension {
+		return nil, fmt.Errorf("vector %d has dimension %d, expected %d", v.n, d, v.dimension)
+	}
+	data := make([]float64, v.dimension)
+	for i := range data {
+		if v.size == 1 {
+			data[i] = float64(v.buf[4+i])
+		} else {
+			data[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(v.buf[4+i*4:])))
+		}
+	}
+	v.n++
+	return data, nil
+}
+
+// ReadIvecs reads all vectors of an .ivecs file, e.g. the ids of the true nearest neighbours of the queries
+func ReadIvecs(r io.Reader) ([][]int, error) {
+	br := bufio.NewReaderSize(r, 1<<20)
+	var vectors [][]int
+	head := make([]byte, 4)
+	for {
+		_, err := io.ReadFull(br, head)
+		if err == io.EOF {
+			return vectors, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("vector %d is incomplete", len(vectors))
+		}
+		dimension := int(int32(binary.LittleEndian.Uint32(head)))
+		if dimension < 0 || dimension > MaxDimension {
+			return nil, fmt.Errorf("vector %d has invalid dimension %d", len(vectors), dimension)
+		}
+		buf := make([]byte, dimension*4)
+		if _, err = io.ReadFull(br, buf); err != nil {
+			return nil, fmt.Errorf("vector %d is incomplete", len(vectors))
+		}
+		vector := make([]int, dimension)
+		for i := range vector {
+			vector[i] = int(int32(binary.LittleEndian.Uint32(buf[i*4:])))
+		}
+		vectors = append(vectors, vector)
+	}
+}
+
+// npyReader reads .npy files
+type npyReader struct {
+	r         *bufio.Reader
+	dimension int
+	rows      int
+	size      int // The size of a value in bytes - 4 for float32, 8 for float64
+	buf       []byte
+	n         int
+}
+
+var (
+	npyDescr   = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+)
+
+// newNpyReader parses the header of a .npy file and returns a npyReader for its rows
+func newNpyReader(r *bufio.Reader) (*npyReader, error) {
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic[:6]) != "\x93NUMPY" {
+		return nil, fmt.Errorf("file is not a .npy file")
+	}
+	// Version 1 has a 2 byte header length, the later versions 4 bytes
+	var length int
+	if magic[6] == 1 {
+		buf := make([]byte, 2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		length = int(binary.LittleEndian.Uint16(buf))
+	} else {
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		length = int(binary.LittleEndian.Uint32(buf))
+		if length > maxNpyHeader {
+			return nil, fmt.Errorf("npy header has %d bytes, expected at most %d", length, maxNpyHeader)
+		}
+	}
+	header := make([]byte, length)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	n := &npyReader{r: r}
+	descr := npyDescr.FindSubmatch(header)
+	if descr == nil {
+		return nil, fmt.Errorf("npy header has no descr")
+	}
+	switch string(descr[1]) {
+	case "<f4":
+		n.size = 4
+	case "<f8":
+		n.size = 8
+	default:
+		return nil, fmt.Errorf("npy dtype %s is not supported, expected <f4 or <f8", descr[1])
+	}
+	if fortran := npyFortran.FindSubmatch(header); fortran == nil || string(fortran[1]) != "False" {
+		return nil, fmt.Errorf("npy array has to be in C order")
+	}
+	shape := npyShape.FindSubmatch(header)
+	if shape == nil {
+		return nil, fmt.Errorf("npy header has no shape")
+	}
+	var dims []int
+	for _, s := range strings.Split(string(shape[1]), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		d, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("npy shape: %w", err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("npy shape has the negative size %d", d)
+		}
+		dims = append(dims, d)
+	}
+	// A one dimensional array is a single vector
+	switch len(dims) {
+	case 1:
+		n.rows, n.dimension = 1, dims[0]
+	case 2:
+		n.rows, n.dimension = dims[0], dims[1]
+	default:
+		return nil, fmt.Errorf("npy array has %d dimensions, expected 1 or 2", len(dims))
+	}
+	if n.dimension <= 0 || n.dimension > MaxDimension {
+		return nil, fmt.Errorf("invalid dimension %d", n.dimension)
+	}
+	n.buf = make([]byte, n.dimension*n.size)
+	return n, nil
+}
+
+// Dimension returns the dimension of the vectors
+func (n *npyReader) Dimension() int {
+	return n.dimension
+}
+
+// Next returns the next row
+func (n *npyReader) Next() ([]float64, error) {
+	if n.n == n.rows {
+		return nil, io.EOF
+	}
+	if _, err := io.ReadFull(n.r, n.buf); err != nil {
+		return nil, fmt.Errorf("row %d is incomplete", n.n)
+	}
+	data := make([]float64, n.dimension)
+	for i := range data {
+		if n.size == 4 {
+			data[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(n.buf[i*4:])))
+		} else {
+			data[i] = math.Float64frombits(binary.LittleEndian.Uint64(n.buf[i*8:]))
+		}
+	}
+	n.n++
+	return data, nil
+}
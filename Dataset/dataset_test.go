@@ -0,0 +1,132 @@
+package Dataset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// vecs returns the records of a .fvecs, .bvecs or .ivecs file, the values are written with the type of value
+func vecs(value interface{}, vectors ...[]float64) []byte {
+	var buf bytes.Buffer
+	for _, vector := range vectors {
+		binary.Write(&buf, binary.LittleEndian, int32(len(vector)))
+		for _, v := range vector {
+			switch value.(type) {
+			case float32:
+				binary.Write(&buf, binary.LittleEndian, float32(v))
+			case uint8:
+				buf.WriteByte(uint8(v))
+			case int32:
+				binary.Write(&buf, binary.LittleEndian, int32(v))
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// npy returns a version 1 .npy file with the given header fields and data
+func npy(descr string, fortran string, shape string, data interface{}) []byte {
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': %s, 'shape': (%s), }", descr, fortran, shape)
+	// The header is padded to 64 bytes with spaces and a newline like NumPy does it
+	header += strings.Repeat(" ", 63-(10+len(header))%64) + "\n"
+	buf := bytes.NewBufferString("\x93NUMPY\x01\x00")
+	binary.Write(buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	binary.Write(buf, binary.LittleEndian, data)
+	return buf.Bytes()
+}
+
+// readAll returns the dimension and all vectors of a Reader
+func readAll(t *testing.T, data []byte, format string) (int, [][]float64) {
+	t.Helper()
+	reader, err := NewReader(bytes.NewReader(data), format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors [][]float64
+	for {
+		vector, err := reader.Next()
+		if err == io.EOF {
+			return reader.Dimension(), vectors
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		vectors = append(vectors, vector)
+	}
+}
+
+func TestReaders(t *testing.T) {
+	want := [][]float64{{1, 2.5, 3}, {4, 5, 6}}
+	for _, test := range []struct {
+		format string
+		data   []byte
+		want   [][]float64
+	}{
+		{"fvecs", vecs(float32(0), want...), want},
+		{"bvecs", vecs(uint8(0), []float64{1, 2, 255}), [][]float64{{1, 2, 255}}},
+		{"npy", npy("<f4", "False", "2, 3", []float32{1, 2.5, 3, 4, 5, 6}), want},
+		{"npy", npy("<f8", "False", "2, 3", []float64{1, 2.5, 3, 4, 5, 6}), want},
+		{"npy", npy("<f8", "False", "3,", []float64{1, 2.5, 3}), want[:1]},
+	} {
+		dimension, vectors := readAll(t, test.data, test.format)
+		if dimension != 3 || !reflect.DeepEqual(vectors, test.want) {
+			t.Errorf("%s reads %d %v, want %v", test.format, dimension, vectors, test.want)
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		format string
+		data   []byte
+	}{
+		{"format", "hdf5", vecs(float32(0), []float64{1})},
+		{"empty", "fvecs", nil},
+		{"fortran", "npy", npy("<f4", "True", "1, 1", []float32{1})},
+		{"dtype", "npy", npy("<i4", "False", "1, 1", []int32{1})},
+		{"magic", "npy", []byte("NUMPY")},
+		{"negative rows", "npy", npy("<f4", "False", "-1, 1", []float32{1})},
+		{"negative dimension", "npy", npy("<f4", "False", "1, -1", []float32{1})},
+		{"npy dimension", "npy", npy("<f4", "False", "1, 100000000", []float32{1})},
+		{"fvecs dimension", "fvecs", []byte{0, 0, 0, 0x7f}},
+		{"npy header", "npy", []byte("\x93NUMPY\x02\x00\xff\xff\xff\x7f")},
+	} {
+		if _, err := NewReader(bytes.NewReader(test.data), test.format); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+
+	// Records of another dimension and incomplete records fail when they are read
+	for name, data := range map[string][]byte{
+		"dimension":  vecs(float32(0), []float64{1, 2}, []float64{1}),
+		"incomplete": vecs(float32(0), []float64{1, 2}, []float64{1, 2})[:20],
+	} {
+		reader, err := NewReader(bytes.NewReader(data), "fvecs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader.Next()
+		if _, err = reader.Next(); err == nil || err == io.EOF {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+}
+
+func TestReadIvecs(t *testing.T) {
+	truth, err := ReadIvecs(bytes.NewReader(vecs(int32(0), []float64{3, 1}, []float64{0, 2})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(truth, [][]int{{3, 1}, {0, 2}}) {
+		t.Errorf("ivecs reads %v", truth)
+	}
+	if _, err = ReadIvecs(bytes.NewReader([]byte{0, 0, 0, 0x7f})); err == nil {
+		t.Error("a record with a huge dimension was read")
+	}
+}
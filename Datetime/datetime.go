@@ -0,0 +1,72 @@
+// Package Datetime reads the datetime payload fields and the datetime literals of the filters. A datetime payload value
+// is an RFC 3339 string or a Unix epoch in seconds, a filter literal can also be relative to the time of the query:
+//
+//	"2024-05-01T12:00:00Z", 1714564800, "now", "now-7d", "now+12h"
+//
+// The units of a relative literal are s, m, h, d and w.
+package Datetime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// units are the durations of the units of a relative literal
+var units = map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour}
+
+// Parse returns a payload value as Unix seconds, it returns false if the value is no RFC 3339 string or number
+func Parse(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return seconds(t), true
+		}
+	}
+	return 0, false
+}
+
+// ParseLiteral returns a filter literal as Unix seconds, relative literals are resolved against now
+func ParseLiteral(value interface{}, now time.Time) (float64, error) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "now") {
+		if t, ok := Parse(value); ok {
+			return t, nil
+		}
+		return 0, fmt.Errorf("invalid datetime %v, use RFC 3339, Unix seconds or now-7d", value)
+	}
+	offset := strings.TrimSpace(s[3:])
+	if offset == "" {
+		return seconds(now), nil
+	}
+	if len(offset) < 3 || (offset[0] != '-' && offset[0] != '+') {
+		return 0, fmt.Errorf("invalid relative datetime %s", s)
+	}
+	unit, ok := units[offset[len(offset)-1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid unit in relative datetime %s, use s, m, h, d or w", s)
+	}
+	n, err := strconv.ParseFloat(offset[1:len(offset)-1], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid relative datetime %s", s)
+	}
+	if offset[0] == '-' {
+		n = -n
+	}
+	return seconds(now) + n*unit.Seconds(), nil
+}
+
+// seconds returns the time as Unix seconds with fractions
+func seconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
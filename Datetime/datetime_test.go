@@ -0,0 +1,52 @@
+package Datetime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		value interface{}
+		want  float64
+		ok    bool
+	}{
+		{"2024-05-01T12:00:00Z", 1714564800, true},
+		{"2024-05-01T14:00:00+02:00", 1714564800, true},
+		{"2024-05-01T12:00:00.5Z", 1714564800.5, true},
+		{1714564800.0, 1714564800, true},
+		{1714564800, 1714564800, true},
+		{"2024-05-01", 0, false},
+		{"now", 0, false},
+		{true, 0, false},
+	} {
+		if got, ok := Parse(test.value); got != test.want || ok != test.ok {
+			t.Errorf("Parse(%v) = %v, %v", test.value, got, ok)
+		}
+	}
+}
+
+func TestParseLiteral(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		value interface{}
+		want  float64
+	}{
+		{"now", 1714564800},
+		{"now-7d", 1714564800 - 7*86400},
+		{"now+12h", 1714564800 + 12*3600},
+		{"now-1.5w", 1714564800 - 1.5*7*86400},
+		{"now-10s", 1714564790},
+		{"2024-05-01T12:00:00Z", 1714564800},
+		{1714564800.0, 1714564800},
+	} {
+		if got, err := ParseLiteral(test.value, now); err != nil || got != test.want {
+			t.Errorf("ParseLiteral(%v) = %v, %v - want %v", test.value, got, err, test.want)
+		}
+	}
+	for _, value := range []interface{}{"now-7", "now - 30m", "now-7y", "now7d", "now--7d", "now-d", "yesterday", nil} {
+		if got, err := ParseLiteral(value, now); err == nil {
+			t.Errorf("ParseLiteral(%v) = %v", value, got)
+		}
+	}
+}
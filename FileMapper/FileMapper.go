@@ -2,12 +2,12 @@ package FileMapper
 
 import (
 	"VreeDB/ArgsParser"
+	"VreeDB/Format"
 	"VreeDB/Logger"
 	"bytes"
 	"encoding/binary"
 	"encoding/gob"
-	"encoding/json"
-	"io"
+	"fmt"
 	"math"
 	"os"
 	"sync"
@@ -18,6 +18,12 @@ type SaveVector struct {
 	VectorID     string
 	DataStart    int64
 	PayloadStart int64
+	SparseStart  map[string]int64   `json:",omitempty"`
+	VectorStart  map[string]int64   `json:",omitempty"`
+	MultiStart   map[string][]int64 `json:",omitempty"`
+	BinaryStart  map[string]int64   `json:",omitempty"`
+	RescoreStart map[string]int64   `json:",omitempty"`
+	ExpiresAt    int64              `json:",omitempty"`
 }
 
 type FileMapper struct {
@@ -27,6 +33,8 @@ type FileMapper struct {
 	Mut             map[string]*sync.RWMutex
 	MappedData      map[string][]byte
 	Mapped          map[string]bool
+	segments        map[string]*activeSegment
+	keysMut         sync.RWMutex // Guards the keys of the collections, their files are guarded by Mut
 }
 
 // the filemapper is a singleton
@@ -42,67 +50,76 @@ func init() {
 	Mapper.Mut = make(map[string]*sync.RWMutex)
 	Mapper.MappedData = make(map[string][]byte)
 	Mapper.Mapped = make(map[string]bool)
+	Mapper.segments = make(map[string]*activeSegment)
 }
 
-func (f *FileMapper) Start(collections []string) {
+// Start adds the given collections with their vector dimensions to the FileMapper
+func (f *FileMapper) Start(collections map[string]int) error {
 	// Loop over all Collections
-	for _, name := range collections {
-		// check if data.bin file exists
-		_, err := os.Stat(*ArgsParser.Ap.FileStore + name + ".bin")
+	for name, dimension := range collections {
+		err := f.AddCollection(name, dimension)
 		if err != nil {
-			// if not create it
-			file, err := os.Create(*ArgsParser.Ap.FileStore + name + ".bin")
-			if err != nil {
-				Logger.Log.Log("Error creating file: " + err.Error())
-				panic(err)
-			}
-			file.Close()
+			return err
 		}
-		Mapper.FileName[name] = *ArgsParser.Ap.FileStore + name + ".bin"
-		Mapper.Mut[name] = &sync.RWMutex{}
-		Mapper.CollectionNames = append(Mapper.CollectionNames, name)
-		Mapper.MapFile(name)
 	}
+	return nil
 }
 
-// WriteVector will write data to the file
-func (f *FileMapper) WriteVector(arr []float64, collection string) (int64, int, error) {
-	// Lock the file for writing
-	f.Mut[collection].Lock()
-	defer f.Mut[collection].Unlock()
-	// Unmap the file from memory
-	f.Unmap(collection)
-	// open the file again for writing und append
-	file, err := os.OpenFile(f.FileName[collection], os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		Logger.Log.Log("Error opening file: " + err.Error())
-		// Here we panic because we can't continue without the file
-		panic(err)
+// mutOf returns the mutex of the collection file, it fails if the collection was deleted or renamed
+func (f *FileMapper) mutOf(collection string) (*sync.RWMutex, error) {
+	f.keysMut.RLock()
+	defer f.keysMut.RUnlock()
+	mut, ok := f.Mut[collection]
+	if !ok {
+		return nil, fmt.Errorf("collection file %s does not exist", collection)
 	}
-	f.File[collection] = file
-	defer f.File[collection].Close()
+	return mut, nil
+}
 
-	// Get the Start position
-	start, err := f.File[collection].Seek(0, io.SeekEnd)
+// lock locks the collection file for writing. A collection that is deleted or renamed while the lock is awaited
+// fails, its file is gone or belongs to another key now.
+func (f *FileMapper) lock(collection string) (*sync.RWMutex, error) {
+	mut, err := f.mutOf(collection)
 	if err != nil {
-		Logger.Log.Log("Error seeking to end of file: " + err.Error())
-		// Here we panic because we can't continue without the start position
-		panic(err)
+		return nil, err
 	}
+	mut.Lock()
+	if current, err := f.mutOf(collection); err != nil || current != mut {
+		mut.Unlock()
+		return nil, fmt.Errorf("collection file %s does not exist", collection)
+	}
+	return mut, nil
+}
 
-	// Array in die Datei schreiben
-	for _, value := range arr {
-		err := binary.Write(f.File[collection], binary.LittleEndian, value)
-		if err != nil {
-			panic(err)
-		}
+// rlock locks the collection file for reading, like lock it fails if the collection was deleted or renamed
+func (f *FileMapper) rlock(collection string) (*sync.RWMutex, error) {
+	mut, err := f.mutOf(collection)
+	if err != nil {
+		return nil, err
 	}
+	mut.RLock()
+	if current, err := f.mutOf(collection); err != nil || current != mut {
+		mut.RUnlock()
+		return nil, fmt.Errorf("collection file %s does not exist", collection)
+	}
+	return mut, nil
+}
 
-	// Close the file and reopen it
-	err = f.File[collection].Close()
+// WriteVector will write data to the file
+func (f *FileMapper) WriteVector(arr []float64, collection string) (int64, int, error) {
+	// Lock the file for writing
+	mut, err := f.lock(collection)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer mut.Unlock()
 
-	// Map the file again
-	f.MapFile(collection)
+	// Encode the array little endian
+	buf := make([]byte, len(arr)*8)
+	for i, value := range arr {
+		binary.LittleEndian.PutUint64(buf[i*8:i*8+8], math.Float64bits(value))
+	}
+	start, err := f.appendBytes(buf, collection)
 
 	// Return the start position and the length of the array
 	return start, len(arr), err
@@ -110,105 +127,157 @@ func (f *FileMapper) WriteVector(arr []float64, collection string) (int64, int,
 
 // ReadVector will read data from the file
 func (f *FileMapper) ReadVector(start int64, length int, collection string) *[]float64 {
-	// Lock the file for reading
-	f.Mut[collection].RLock()
-	defer f.Mut[collection].RUnlock()
-	// if not mapped we map it
-	if !f.Mapped[collection] {
-		f.MapFile(collection)
-	}
 	// Create the array
 	arr := make([]float64, length)
-	// Check if the slice is not empty
-	if len(f.MappedData[collection]) > 0 {
-		// Read the data from the file
-		for i := 0; i < length; i++ {
-			arr[i] = math.Float64frombits(binary.LittleEndian.Uint64(f.MappedData[collection][start+int64(i)*8 : start+int64(i)*8+8]))
-		}
+	// Lock the file for reading
+	mut, err := f.rlock(collection)
+	if err != nil {
+		Logger.Log.Log("Error reading vector: " + err.Error())
+		return &arr
+	}
+	defer mut.RUnlock()
+	// Get the bytes of the vector from the sealed segments or the active segment
+	data, err := f.bytesAt(start, length*8, collection)
+	if err != nil {
+		Logger.Log.Log("Error reading vector: " + err.Error())
+		return &arr
+	}
+	// Read the data from the file
+	for i := 0; i < length; i++ {
+		arr[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8 : i*8+8]))
 	}
 	return &arr
 }
 
-// WritePayload will write the payload to the file
-func (f *FileMapper) WritePayload(payload *map[string]interface{}, collection string) (int64, error) {
+// WriteSparseVector will write a sparse vector to the file, the layout is the number of entries (uint32)
+// followed by the index (uint32) / value (float64) pairs
+func (f *FileMapper) WriteSparseVector(indices []int, values []float64, collection string) (int64, error) {
 	// Lock the file for writing
-	f.Mut[collection].Lock()
-	defer f.Mut[collection].Unlock()
-
-	// Unmap the file from memory
-	f.Unmap(collection)
-
-	// Map in einen Byte-Slice serialisieren
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-
-	// Register types
-	gob.Register(map[string]interface{}{})
-	gob.Register([]interface{}{})
+	mut, err := f.lock(collection)
+	if err != nil {
+		return 0, err
+	}
+	defer mut.Unlock()
+
+	// Encode the sparse vector
+	buf := make([]byte, 4+len(indices)*12)
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(indices)))
+	for i := range indices {
+		binary.LittleEndian.PutUint32(buf[4+i*12:8+i*12], uint32(indices[i]))
+		binary.LittleEndian.PutUint64(buf[8+i*12:16+i*12], math.Float64bits(values[i]))
+	}
+	return f.appendBytes(buf, collection)
+}
 
-	// Encode the payload
-	err := enc.Encode(payload)
+// WriteBinaryVector will write the uint64 words of a bit packed binary vector to the file
+func (f *FileMapper) WriteBinaryVector(words []uint64, collection string) (int64, error) {
+	// Lock the file for writing
+	mut, err := f.lock(collection)
 	if err != nil {
-		Logger.Log.Log("Error encoding payload: " + err.Error())
 		return 0, err
 	}
-	encodedBytes := buf.Bytes()
+	defer mut.Unlock()
 
-	// open the file for writing und append
-	file, err := os.OpenFile(f.FileName[collection], os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// Encode the words
+	buf := make([]byte, len(words)*8)
+	for i, word := range words {
+		binary.LittleEndian.PutUint64(buf[i*8:i*8+8], word)
+	}
+	return f.appendBytes(buf, collection)
+}
+
+// ReadBinaryVector will read the uint64 words of a bit packed binary vector from the file
+func (f *FileMapper) ReadBinaryVector(start int64, words int, collection string) ([]uint64, error) {
+	// Lock the file for reading
+	mut, err := f.rlock(collection)
 	if err != nil {
-		// Here we panic because we can't continue without the file
-		panic(err)
+		return nil, err
 	}
-	f.File[collection] = file
-	defer f.File[collection].Close()
+	defer mut.RUnlock()
 
-	// Sicherstellen, dass die Datei groß genug ist
-	fileInfo, err := f.File[collection].Stat()
+	data, err := f.bytesAt(start, words*8, collection)
 	if err != nil {
-		Logger.Log.Log("Error getting file info: " + err.Error())
-		return 0, err
+		return nil, err
+	}
+	arr := make([]uint64, words)
+	for i := range arr {
+		arr[i] = binary.LittleEndian.Uint64(data[i*8 : i*8+8])
 	}
+	return arr, nil
+}
 
-	// Get the actual File size
-	fileSize := fileInfo.Size()
+// ReadSparseVector will read a sparse vector from the file
+func (f *FileMapper) ReadSparseVector(start int64, collection string) ([]int, []float64, error) {
+	// Lock the file for reading
+	mut, err := f.rlock(collection)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer mut.RUnlock()
 
-	// We need to get the required size
-	requiredSize := fileSize + int64(len(encodedBytes))
-	if requiredSize >= fileSize {
-		err = file.Truncate(requiredSize)
-		if err != nil {
-			Logger.Log.Log("Error truncating file: " + err.Error())
-			return 0, err
-		}
+	header, err := f.bytesAt(start, 4, collection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sparse vector start %d is out of range", start)
+	}
+	// Read the number of entries
+	n := int64(binary.LittleEndian.Uint32(header))
+	data, err := f.bytesAt(start+4, int(n*12), collection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sparse vector at %d exceeds the file", start)
 	}
+	indices := make([]int, n)
+	values := make([]float64, n)
+	for i := int64(0); i < n; i++ {
+		pos := i * 12
+		indices[i] = int(binary.LittleEndian.Uint32(data[pos : pos+4]))
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[pos+4 : pos+12]))
+	}
+	return indices, values, nil
+}
 
-	// Zur letzten Position in der Datei springen
-	offset, err := file.Seek(0, io.SeekEnd)
+// WritePayload will write the payload to the file
+func (f *FileMapper) WritePayload(payload *map[string]interface{}, collection string) (int64, error) {
+	// Lock the file for writing
+	mut, err := f.lock(collection)
 	if err != nil {
-		Logger.Log.Log("Error seeking to end of file: " + err.Error())
 		return 0, err
 	}
+	defer mut.Unlock()
+
+	// Map in einen Byte-Slice serialisieren
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+
+	// Register types
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
 
-	// Serialisierte Daten in die Datei schreiben
-	_, err = file.Write(encodedBytes)
+	// Encode the payload
+	err = enc.Encode(payload)
 	if err != nil {
-		Logger.Log.Log("Error writing to file: " + err.Error())
+		Logger.Log.Log("Error encoding payload: " + err.Error())
 		return 0, err
 	}
-	// Map the file again
-	f.MapFile(collection)
+
 	// Return the offset
-	return offset, nil
+	return f.appendBytes(buf.Bytes(), collection)
 }
 
 // ReadPayload will read the payload from the file
 func (f *FileMapper) ReadPayload(offset int64, collection string) (*map[string]interface{}, error) {
 	// Lock the file for reading
-	f.Mut[collection].RLock()
-	defer f.Mut[collection].RUnlock()
+	mut, err := f.rlock(collection)
+	if err != nil {
+		Logger.Log.Log("Error reading payload: " + err.Error())
+		return nil, err
+	}
+	defer mut.RUnlock()
 	// Bytes-Slice ab der gegebenen Position erstellen
-	data := f.MappedData[collection][offset:]
+	r, err := f.readerAt(offset, collection)
+	if err != nil {
+		Logger.Log.Log("Error reading payload: " + err.Error())
+		return nil, err
+	}
 
 	// Gob register types
 	gob.Register(map[string]interface{}{})
@@ -216,9 +285,8 @@ func (f *FileMapper) ReadPayload(offset int64, collection string) (*map[string]i
 
 	// Daten deserialisieren
 	var m map[string]interface{}
-	buf := bytes.NewBuffer(data)
-	dec := gob.NewDecoder(buf)
-	err := dec.Decode(&m)
+	dec := gob.NewDecoder(r)
+	err = dec.Decode(&m)
 	if err != nil {
 		Logger.Log.Log("Error decoding payload: " + err.Error())
 		return nil, err
@@ -228,30 +296,15 @@ func (f *FileMapper) ReadPayload(offset int64, collection string) (*map[string]i
 
 // MapFile will map the file to memory
 func (f *FileMapper) MapFile(collection string) {
-	// Open the file
-	file, err := os.Open(f.FileName[collection])
-	f.File[collection] = file
-	// get the file info
-	fileInfo, err := f.File[collection].Stat()
+	file, mappedData, err := mapFile(f.FileName[collection])
 	if err != nil {
-		Logger.Log.Log("Error getting file info: " + err.Error())
-		// We panic here because we can't continue without the file info
+		Logger.Log.Log("Error mapping file: " + err.Error())
+		// We panic here because we can't continue without the mapped data
 		panic(err)
 	}
-
-	// if the file is not empty we map it to memory
-	if fileInfo.Size() != 0 {
-		mappedData, err := syscall.Mmap(int(f.File[collection].Fd()), 0, int(fileInfo.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
-		if err != nil {
-			Logger.Log.Log("Error mapping file: " + err.Error())
-			// We panic here because we can't continue without the mapped data
-			panic(err)
-		}
-		f.MappedData[collection] = mappedData
-		f.Mapped[collection] = true
-	} else {
-		f.MappedData[collection] = nil
-	}
+	f.File[collection] = file
+	f.MappedData[collection] = mappedData
+	f.Mapped[collection] = mappedData != nil
 }
 
 // Unmap will unmap the file from memory
@@ -265,40 +318,93 @@ func (f *FileMapper) Unmap(collection string) {
 			panic(err)
 		}
 		f.Mapped[collection] = false
-		// Close the file
-		err = f.File[collection].Close()
+		f.MappedData[collection] = nil
+	}
+	// Close the file - empty files are opened but not mapped
+	if f.File[collection] != nil {
+		err := f.File[collection].Close()
 		if err != nil {
 			// We panic here because we can't continue without the file
 			panic(err)
 		}
+		f.File[collection] = nil
 	}
 }
 
-// AddCollection adds a collection to the FileMapper
-func (f *FileMapper) AddCollection(collection string) {
-	// Check if data.cin file exists
-	_, err := os.Stat(*ArgsParser.Ap.FileStore + collection + ".bin")
+// AddCollection adds a collection to the FileMapper, a new data file is created with a format header. The header of
+// an existing data file has to match the current format version and the vector dimension.
+func (f *FileMapper) AddCollection(collection string, dimension int) error {
+	err := checkDataFile(*ArgsParser.Ap.FileStore+collection+".bin", dimension)
 	if err != nil {
-		// if not create it
-		file, err := os.Create(*ArgsParser.Ap.FileStore + collection + ".bin")
-		if err != nil {
-			panic(err)
-		}
-		file.Close()
+		return err
 	}
+	f.keysMut.Lock()
+	defer f.keysMut.Unlock()
 	f.FileName[collection] = *ArgsParser.Ap.FileStore + collection + ".bin"
 	f.Mut[collection] = &sync.RWMutex{}
 	f.CollectionNames = append(f.CollectionNames, collection)
 	f.MapFile(collection)
+	f.openSegment(collection, dimension)
+	return nil
+}
+
+// checkDataFile creates the data file with its header if it does not exist, otherwise it checks the header
+func checkDataFile(path string, dimension int) error {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 {
+		// if not create it
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = file.Write(Format.NewHeader(Format.KindData, dimension).Encode())
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	header, err := Format.ReadHeader(file)
+	if err != nil {
+		return fmt.Errorf("data file %s: %w", path, err)
+	}
+	if err = header.Check(Format.KindData, dimension); err != nil {
+		return fmt.Errorf("data file %s: %w", path, err)
+	}
+	return nil
 }
 
 // DelCollection deletes a collection from the FileMapper
 func (f *FileMapper) DelCollection(collection string) {
-	// Unmap the file from memory
+	// Delete the data and the meta file
+	f.DelSegment(collection)
+	// Remove the collection.json if exists
+	_, err := os.Stat(*ArgsParser.Ap.FileStore + collection + ".json")
+	if err == nil {
+		err = os.Remove(*ArgsParser.Ap.FileStore + collection + ".json")
+		if err != nil {
+			Logger.Log.Log("Error deleting collection config file: " + err.Error())
+		}
+	}
+}
+
+// DelSegment deletes the data and the meta file of a collection segment from the FileMapper
+func (f *FileMapper) DelSegment(collection string) {
+	// Wait for the running reads and writes, the following ones fail - a segment that was never added only has files
+	if mut, err := f.lock(collection); err == nil {
+		defer mut.Unlock()
+	}
+	f.keysMut.Lock()
+	defer f.keysMut.Unlock()
+
+	// Unmap the file from memory and close the active segment
 	f.Unmap(collection)
-	// Delete the file
-	err := os.Remove(f.FileName[collection])
-	if err != nil {
+	f.closeSegment(collection)
+	// Delete the file - it does not exist anymore if the segment was merged
+	err := os.Remove(*ArgsParser.Ap.FileStore + collection + ".bin")
+	if err != nil && !os.IsNotExist(err) {
 		// We panic here because we can't continue without the file
 		panic(err)
 	}
@@ -310,141 +416,189 @@ func (f *FileMapper) DelCollection(collection string) {
 			Logger.Log.Log("Error deleting meta file: " + err.Error())
 		}
 	}
-	// Remove the collection.json if exists
-	_, err = os.Stat(*ArgsParser.Ap.FileStore + collection + ".json")
-	if err == nil {
-		err = os.Remove(*ArgsParser.Ap.FileStore + collection + ".json")
+	// Remove the collection from the CollectionNames
+	for i, col := range f.CollectionNames {
+		if col == collection {
+			f.CollectionNames = append(f.CollectionNames[:i], f.CollectionNames[i+1:]...)
+			break
+		}
+	}
+	delete(f.FileName, collection)
+	delete(f.MappedData, collection)
+	delete(f.Mapped, collection)
+	delete(f.File, collection)
+	delete(f.Mut, collection)
+}
+
+// RenameSegment renames the data and the meta file of a collection segment. The open file, its mapping and the active
+// segment stay valid, only the key of the segment changes.
+func (f *FileMapper) RenameSegment(collection string, name string) error {
+	// Reads and writes of the old key that wait for the lock fail afterwards
+	mut, err := f.lock(collection)
+	if err != nil {
+		return fmt.Errorf("segment %s does not exist", collection)
+	}
+	defer mut.Unlock()
+	f.keysMut.Lock()
+	defer f.keysMut.Unlock()
+	if _, ok := f.FileName[name]; ok {
+		return fmt.Errorf("segment %s allready exists", name)
+	}
+	path := *ArgsParser.Ap.FileStore
+	if _, err := os.Stat(path + name + ".bin"); err == nil {
+		return fmt.Errorf("data file %s allready exists", path+name+".bin")
+	}
+
+	err = os.Rename(path+collection+".bin", path+name+".bin")
+	if err != nil {
+		return err
+	}
+	// A segment without vectors has no meta file
+	if _, err = os.Stat(path + collection + "_meta.bin"); err == nil {
+		err = os.Rename(path+collection+"_meta.bin", path+name+"_meta.bin")
 		if err != nil {
-			Logger.Log.Log("Error deleting collection config file: " + err.Error())
+			os.Rename(path+name+".bin", path+collection+".bin")
+			return err
 		}
 	}
-	// Remove the collection from the CollectionNames
+
+	// Move the segment to its new key
+	f.FileName[name] = path + name + ".bin"
+	f.File[name] = f.File[collection]
+	f.Mut[name] = f.Mut[collection]
+	f.MappedData[name] = f.MappedData[collection]
+	f.Mapped[name] = f.Mapped[collection]
+	if s, ok := f.segments[collection]; ok {
+		f.segments[name] = s
+	}
 	for i, col := range f.CollectionNames {
 		if col == collection {
-			f.CollectionNames = append(f.CollectionNames[:i], f.CollectionNames[i+1:]...)
+			f.CollectionNames[i] = name
+			break
 		}
 	}
+	delete(f.FileName, collection)
+	delete(f.File, collection)
+	delete(f.Mut, collection)
+	delete(f.MappedData, collection)
+	delete(f.Mapped, collection)
+	delete(f.segments, collection)
+	return nil
+}
+
+// SaveVectorWriter will write the SaveVector (vector.ID, vector.DataStart, vector.PayloadStart ...) as a record to the
+// meta file, a new meta file starts with its header
+func (w *FileMapper) SaveVectorWriter(sv SaveVector, collection string) error {
+	return w.SaveVectorsWriter([]SaveVector{sv}, collection)
 }
 
-// SaveVectorWriter will write the vector.ID, vector.DataStart, vector.PayloadStart to the file system
-func (w *FileMapper) SaveVectorWriter(id string, datastart, payloadstart int64, collection string) error {
+// SaveVectorsWriter will write the SaveVectors of a batch as records to the meta file with a single write, the
+// buffered data of the records is written to the data file before
+func (w *FileMapper) SaveVectorsWriter(svs []SaveVector, collection string) error {
 	// Lock the Wal
-	w.Mut[collection].Lock()
-	defer w.Mut[collection].Unlock()
+	mut, err := w.lock(collection)
+	if err != nil {
+		return err
+	}
+	defer mut.Unlock()
+	// The data of the records has to be in the data file before the records are in the meta file
+	s, err := w.segmentOf(collection)
+	if err != nil {
+		return err
+	}
+	if err = s.flush(); err != nil {
+		return err
+	}
 
 	// Open the file "collection"_meta.bin
 	file, err := os.OpenFile(*ArgsParser.Ap.FileStore+collection+"_meta.bin", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		Logger.Log.Log("Error opening meta.json file: " + err.Error())
+		Logger.Log.Log("Error opening meta file: " + err.Error())
 		return err
 	}
 	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
 
-	// Create the SaveVector
-	sv := SaveVector{VectorID: id, DataStart: datastart, PayloadStart: payloadstart}
-
-	// use json to encode the SaveVector
-	encoder := json.NewEncoder(file)
-	err = encoder.Encode(sv)
+	// The records are written with a single write, so a crash can only leave an incomplete record at the end
+	var buf []byte
+	if info.Size() == 0 {
+		buf = Format.NewHeader(Format.KindMeta, s.dimension).Encode()
+	}
+	for _, sv := range svs {
+		buf = append(buf, Format.EncodeRecord(encodeSaveVector(sv))...)
+	}
+	_, err = file.Write(buf)
 	if err != nil {
-		Logger.Log.Log("Error encoding SaveVector: " + err.Error())
+		Logger.Log.Log("Error writing SaveVector: " + err.Error())
 		return err
 	}
 	return nil
 }
 
-// SaveVectorRead will read the vector.ID, vector.DataStart, vector.PayloadStart from the file system and returns a map of vectors
+// SaveVectorRead will read the vector.ID, vector.DataStart, vector.PayloadStart from the file system and returns a map
+// of vectors - the last record of a vector wins. An incomplete record at the end of the file is cut off.
 func (w *FileMapper) SaveVectorRead(collection string) (*map[string]SaveVector, error) {
 	// Lock the Wal - we use a write lock because here will be no memory mapped file
-	w.Mut[collection].Lock()
-	defer w.Mut[collection].Unlock()
+	mut, err := w.lock(collection)
+	if err != nil {
+		return nil, err
+	}
+	defer mut.Unlock()
+	s, err := w.segmentOf(collection)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create the map
-	vectors := make(map[string]SaveVector)
-	// Open the file "collection"_meta.bin if existing
-	_, err := os.Stat(*ArgsParser.Ap.FileStore + collection + "_meta.bin")
-	if err == nil {
-		file, err := os.Open(*ArgsParser.Ap.FileStore + collection + "_meta.bin")
+	path := *ArgsParser.Ap.FileStore + collection + "_meta.bin"
+	records, size, err := ReadMetaFile(path, s.dimension)
+	if err != nil {
+		Logger.Log.Log("Error reading SaveVector: " + err.Error())
+		return nil, err
+	}
+	// Cut off an incomplete record, otherwise the next record would be appended behind it
+	if info, err := os.Stat(path); err == nil && info.Size() > size {
+		Logger.Log.Log(fmt.Sprintf("Cutting off %d bytes of an incomplete record in %s", info.Size()-size, path))
+		err = os.Truncate(path, size)
 		if err != nil {
-			Logger.Log.Log("Error opening meta.json file: " + err.Error())
 			return nil, err
 		}
-		defer file.Close()
+	}
 
-		// Use json to decode the SaveVector
-		decoder := json.NewDecoder(file)
-		for {
-			var sv SaveVector
-			if err := decoder.Decode(&sv); err == io.EOF {
-				break
-			} else if err != nil {
-				Logger.Log.Log("Error decoding SaveVector: " + err.Error())
-				return nil, err
-			}
-			vectors[sv.VectorID] = sv
-		}
+	// Create the map
+	vectors := make(map[string]SaveVector)
+	for _, sv := range records {
+		vectors[sv.VectorID] = sv
 	}
 	return &vectors, nil
 }
 
-// SaveVectorDelete will delete the vector.ID, vector.DataStart, vector.PayloadStart from the file system // TODO: This is shit
+// SaveVectorDelete will delete all records of the vector from the meta file
 func (w *FileMapper) SaveVectorDelete(id string, collection string) error {
-	// Lock the mutex for reading
-	w.Mut[collection].Lock()
-	defer w.Mut[collection].Unlock()
-
-	// Open the file "collection"_meta.bin
-	file, err := os.Open(*ArgsParser.Ap.FileStore + collection + "_meta.bin")
+	// Lock the Wal
+	mut, err := w.lock(collection)
 	if err != nil {
-		Logger.Log.Log("Error opening meta.json file: " + err.Error())
 		return err
 	}
-
-	// Decode all JSON objects into a slice of SaveVector
-	var vectors []SaveVector
-	decoder := json.NewDecoder(file)
-	for {
-		var sv SaveVector
-		if err := decoder.Decode(&sv); err == io.EOF {
-			break
-		} else if err != nil {
-			Logger.Log.Log("Error decoding SaveVector: " + err.Error())
-			return err
-		}
-		vectors = append(vectors, sv)
-	}
-
-	file.Close()
-	w.Mut[collection].Unlock()
-
-	// Iterate over the slice and if the VectorID matches the given ID, remove that element from the slice
-	for i, vector := range vectors {
-		if vector.VectorID == id {
-			vectors = append(vectors[:i], vectors[i+1:]...)
-			break
-		}
+	defer mut.Unlock()
+	s, err := w.segmentOf(collection)
+	if err != nil {
+		return err
 	}
 
-	// Lock the mutex for writing
-	w.Mut[collection].Lock()
-
-	// Open the file in write mode, this will clear the file content
-	file, err = os.OpenFile(*ArgsParser.Ap.FileStore+collection+"_meta.bin", os.O_CREATE|os.O_WRONLY, 0644)
+	path := *ArgsParser.Ap.FileStore + collection + "_meta.bin"
+	records, _, err := ReadMetaFile(path, s.dimension)
 	if err != nil {
-		Logger.Log.Log("Error opening meta.json file: " + err.Error())
+		Logger.Log.Log("Error reading SaveVector: " + err.Error())
 		return err
 	}
-	defer file.Close()
-
-	// Encode the modified slice of SaveVector back into the file
-	encoder := json.NewEncoder(file)
-	for _, vector := range vectors {
-		err = encoder.Encode(vector)
-		if err != nil {
-			Logger.Log.Log("Error encoding SaveVector: " + err.Error())
-			return err
+	vectors := make([]SaveVector, 0, len(records))
+	for _, sv := range records {
+		if sv.VectorID != id {
+			vectors = append(vectors, sv)
 		}
 	}
-
-	return nil
+	return WriteMetaFile(path, s.dimension, vectors)
 }
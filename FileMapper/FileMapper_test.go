@@ -0,0 +1,272 @@
+package FileMapper
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/Format"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// newTestCollection adds a collection with the given dimension to the Mapper, it is deleted after the test
+func newTestCollection(tb testing.TB, name string, dimension int) {
+	tb.Helper()
+	if err := os.MkdirAll(*ArgsParser.Ap.FileStore, 0755); err != nil {
+		tb.Fatal(err)
+	}
+	if err := Mapper.AddCollection(name, dimension); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		Mapper.DelCollection(name)
+	})
+}
+
+func TestAppendedDataIsReadAcrossSeals(t *testing.T) {
+	newTestCollection(t, "fmseals", 16)
+
+	// Enough vectors and payloads to seal the first active segments
+	vector := make([]float64, 16)
+	type written struct {
+		vector, payload int64
+	}
+	positions := make([]written, 0)
+	for i := 0; i < 20000; i++ {
+		vector[0] = float64(i)
+		start, _, err := Mapper.WriteVector(vector, "fmseals")
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload := map[string]interface{}{"i": float64(i)}
+		offset, err := Mapper.WritePayload(&payload, "fmseals")
+		if err != nil {
+			t.Fatal(err)
+		}
+		positions = append(positions, written{vector: start, payload: offset})
+	}
+	if Mapper.segments["fmseals"].sealed == 0 {
+		t.Fatal("no segment was sealed")
+	}
+
+	check := func() {
+		t.Helper()
+		for i, p := range positions {
+			if got := (*Mapper.ReadVector(p.vector, 16, "fmseals"))[0]; got != float64(i) {
+				t.Fatalf("vector %d reads %v", i, got)
+			}
+			payload, err := Mapper.ReadPayload(p.payload, "fmseals")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (*payload)["i"] != float64(i) {
+				t.Fatalf("payload %d reads %v", i, *payload)
+			}
+		}
+	}
+	check()
+	Mapper.Seal("fmseals")
+	if s := Mapper.segments["fmseals"]; s.size != 0 {
+		t.Fatalf("%d bytes are not sealed", s.size)
+	}
+	check()
+}
+
+func TestSparseAndBinaryVectorsRoundTrip(t *testing.T) {
+	newTestCollection(t, "fmsparse", 2)
+
+	start, err := Mapper.WriteSparseVector([]int{3, 9}, []float64{0.5, 2}, "fmsparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	indices, values, err := Mapper.ReadSparseVector(start, "fmsparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(indices, []int{3, 9}) || !reflect.DeepEqual(values, []float64{0.5, 2}) {
+		t.Fatalf("sparse vector reads %v %v", indices, values)
+	}
+
+	start, err = Mapper.WriteBinaryVector([]uint64{1, 1 << 63}, "fmsparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	words, err := Mapper.ReadBinaryVector(start, 2, "fmsparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(words, []uint64{1, 1 << 63}) {
+		t.Fatalf("binary vector reads %v", words)
+	}
+}
+
+func TestReadsOutOfRangeFail(t *testing.T) {
+	newTestCollection(t, "fmrange", 2)
+	start, _, err := Mapper.WriteVector([]float64{1, 2}, "fmrange")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Mapper.ReadBinaryVector(start, 3, "fmrange"); err == nil {
+		t.Error("a read beyond the end of the file did not fail")
+	}
+	if _, err = Mapper.ReadPayload(start+1<<20, "fmrange"); err == nil {
+		t.Error("a payload beyond the end of the file did not fail")
+	}
+	if _, _, err = Mapper.ReadSparseVector(-1, "fmrange"); err == nil {
+		t.Error("a negative position did not fail")
+	}
+}
+
+func BenchmarkWriteVector(b *testing.B) {
+	newTestCollection(b, "fmbenchvector", 128)
+	vector := make([]float64, 128)
+	b.SetBytes(128 * 8)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Mapper.WriteVector(vector, "fmbenchvector"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWritePayload(b *testing.B) {
+	newTestCollection(b, "fmbenchpayload", 2)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		payload := map[string]interface{}{"id": fmt.Sprint(i), "n": float64(i), "tags": []interface{}{"a", "b"}}
+		if _, err := Mapper.WritePayload(&payload, "fmbenchpayload"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRemovedKeysFail(t *testing.T) {
+	newTestCollection(t, "fmremoved", 2)
+	start, _, err := Mapper.WriteVector([]float64{1, 2}, "fmremoved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Mapper.RenameSegment("fmremoved", "fmmoved"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Mapper.DelCollection("fmmoved")
+	})
+
+	if _, _, err = Mapper.WriteVector([]float64{3, 4}, "fmremoved"); err == nil {
+		t.Error("a write to the renamed key did not fail")
+	}
+	if err = Mapper.SaveVectorWriter(SaveVector{VectorID: "a", DataStart: start}, "fmremoved"); err == nil {
+		t.Error("a record of the renamed key did not fail")
+	}
+	if got := *Mapper.ReadVector(start, 2, "fmmoved"); got[1] != 2 {
+		t.Errorf("the new key reads %v", got)
+	}
+
+	Mapper.DelSegment("fmmoved")
+	if _, err = Mapper.ReadBinaryVector(start, 1, "fmmoved"); err == nil {
+		t.Error("a read of the deleted key did not fail")
+	}
+	Mapper.Seal("fmmoved")
+}
+
+func TestRenameWhileWriting(t *testing.T) {
+	newTestCollection(t, "fmrenaming", 2)
+	t.Cleanup(func() {
+		Mapper.DelCollection("fmrenamed")
+	})
+
+	done := make(chan int)
+	go func() {
+		written := 0
+		for i := 0; i < 10000; i++ {
+			if _, _, err := Mapper.WriteVector([]float64{1, 2}, "fmrenaming"); err != nil {
+				break
+			}
+			written++
+		}
+		done <- written
+	}()
+	if err := Mapper.RenameSegment("fmrenaming", "fmrenamed"); err != nil {
+		t.Fatal(err)
+	}
+	written := <-done
+	if s := Mapper.segments["fmrenamed"]; s.sealed+s.size != int64(len(Format.NewHeader(Format.KindData, 2).Encode())+written*16) {
+		t.Errorf("%d vectors were written, the file has %d bytes", written, s.sealed+s.size)
+	}
+}
+
+func TestAppendsAreBufferedUntilARecordIsWritten(t *testing.T) {
+	newTestCollection(t, "fmbuffered", 2)
+	path := *ArgsParser.Ap.FileStore + "fmbuffered.bin"
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := info.Size()
+
+	start, _, err := Mapper.WriteVector([]float64{1, 2}, "fmbuffered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := map[string]interface{}{"a": "b"}
+	offset, err := Mapper.WritePayload(&payload, "fmbuffered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ = os.Stat(path); info.Size() != header {
+		t.Errorf("the file has %d bytes before the record, expected %d", info.Size(), header)
+	}
+	// Buffered bytes are read from the buffer
+	if got := *Mapper.ReadVector(start, 2, "fmbuffered"); got[1] != 2 {
+		t.Errorf("the buffered vector reads %v", got)
+	}
+	if got, err := Mapper.ReadPayload(offset, "fmbuffered"); err != nil || (*got)["a"] != "b" {
+		t.Errorf("the buffered payload reads %v, %v", got, err)
+	}
+
+	// The record is written behind its data
+	err = Mapper.SaveVectorWriter(SaveVector{VectorID: "a", DataStart: start, PayloadStart: offset}, "fmbuffered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ = os.Stat(path); info.Size() <= offset {
+		t.Errorf("the file has %d bytes after the record, the payload starts at %d", info.Size(), offset)
+	}
+	if got, err := Mapper.ReadPayload(offset, "fmbuffered"); err != nil || (*got)["a"] != "b" {
+		t.Errorf("the written payload reads %v, %v", got, err)
+	}
+}
+
+func TestReadWhileCollectionsAreAdded(t *testing.T) {
+	newTestCollection(t, "fmreading", 2)
+	start, _, err := Mapper.WriteVector([]float64{1, 2}, "fmreading")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			name := "fmadded" + fmt.Sprint(i)
+			if err := Mapper.AddCollection(name, 2); err != nil {
+				t.Error(err)
+				return
+			}
+			Mapper.WriteVector([]float64{3, 4}, name)
+			Mapper.Seal(name)
+			Mapper.DelCollection(name)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if got := *Mapper.ReadVector(start, 2, "fmreading"); got[1] != 2 {
+			t.Fatalf("the vector reads %v", got)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package FileMapper
+
+import (
+	"VreeDB/Format"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// encodeSaveVector encodes a SaveVector as the body of a meta record. Strings are a uvarint length followed by the
+// bytes, positions are varints, maps are a uvarint count followed by their entries sorted by name:
+//
+//	id, data start, payload start, expires at, sparse starts, vector starts, binary starts, rescore starts,
+//	multi starts (name, uvarint count, starts)
+func encodeSaveVector(sv SaveVector) []byte {
+	buf := make([]byte, 0, 64)
+	buf = appendString(buf, sv.VectorID)
+	buf = binary.AppendVarint(buf, sv.DataStart)
+	buf = binary.AppendVarint(buf, sv.PayloadStart)
+	buf = binary.AppendVarint(buf, sv.ExpiresAt)
+	for _, m := range []map[string]int64{sv.SparseStart, sv.VectorStart, sv.BinaryStart, sv.RescoreStart} {
+		buf = binary.AppendUvarint(buf, uint64(len(m)))
+		for _, name := range sortedKeys(m) {
+			buf = appendString(buf, name)
+			buf = binary.AppendVarint(buf, m[name])
+		}
+	}
+	names := make([]string, 0, len(sv.MultiStart))
+	for name := range sv.MultiStart {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf = binary.AppendUvarint(buf, uint64(len(names)))
+	for _, name := range names {
+		buf = appendString(buf, name)
+		buf = binary.AppendUvarint(buf, uint64(len(sv.MultiStart[name])))
+		for _, start := range sv.MultiStart[name] {
+			buf = binary.AppendVarint(buf, start)
+		}
+	}
+	return buf
+}
+
+// DecodeSaveVector decodes the body of a meta record
+func DecodeSaveVector(body []byte) (SaveVector, error) {
+	var sv SaveVector
+	r := bytes.NewReader(body)
+	var err error
+	if sv.VectorID, err = readString(r); err != nil {
+		return sv, err
+	}
+	for _, v := range []*int64{&sv.DataStart, &sv.PayloadStart, &sv.ExpiresAt} {
+		if *v, err = binary.ReadVarint(r); err != nil {
+			return sv, err
+		}
+	}
+	for _, m := range []*map[string]int64{&sv.SparseStart, &sv.VectorStart, &sv.BinaryStart, &sv.RescoreStart} {
+		if *m, err = readStartMap(r); err != nil {
+			return sv, err
+		}
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return sv, err
+	}
+	if n > 0 {
+		sv.MultiStart = make(map[string][]int64)
+	}
+	for i := uint64(0); i < n; i++ {
+		name, err := readString(r)
+		if err != nil {
+			return sv, err
+		}
+		count, err := binary.ReadUvarint(r)
+		if err != nil {
+			return sv, err
+		}
+		if count > uint64(r.Len()) {
+			return sv, fmt.Errorf("multi vector %s has %d starts, the record is too short", name, count)
+		}
+		starts := make([]int64, count)
+		for j := range starts {
+			if starts[j], err = binary.ReadVarint(r); err != nil {
+				return sv, err
+			}
+		}
+		sv.MultiStart[name] = starts
+	}
+	if r.Len() != 0 {
+		return sv, fmt.Errorf("record has %d trailing bytes", r.Len())
+	}
+	return sv, nil
+}
+
+// appendString appends a uvarint length and the bytes of s
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// readString reads a string written by appendString
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(r.Len()) {
+		return "", fmt.Errorf("string of length %d exceeds the record", n)
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	return string(buf), err
+}
+
+// readStartMap reads a map of names to positions, an empty map is returned as nil
+func readStartMap(r *bytes.Reader) (map[string]int64, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	m := make(map[string]int64)
+	for i := uint64(0); i < n; i++ {
+		name, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		if m[name], err = binary.ReadVarint(r); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// sortedKeys returns the keys of the map sorted, so the same SaveVector always gives the same record
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Shift moves all positions of the SaveVector by offset, deleted vectors keep their negative DataStart
+func (sv *SaveVector) Shift(offset int64) {
+	if sv.DataStart >= 0 {
+		sv.DataStart += offset
+	}
+	sv.PayloadStart += offset
+	for _, m := range []map[string]int64{sv.SparseStart, sv.VectorStart, sv.BinaryStart, sv.RescoreStart} {
+		for name := range m {
+			m[name] += offset
+		}
+	}
+	for _, starts := range sv.MultiStart {
+		for i := range starts {
+			starts[i] += offset
+		}
+	}
+}
+
+// ReadMetaFile reads all SaveVectors of a meta file in the order they were written. A record that was only partly
+// written (e.g. after a crash) ends the file, the returned size is the length of the file up to the last complete
+// record. Missing files have no SaveVectors.
+func ReadMetaFile(path string, dimension int) ([]SaveVector, int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err != nil || info.Size() == 0 {
+		return nil, 0, err
+	}
+
+	header, err := Format.ReadHeader(file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("meta file %s: %w", path, err)
+	}
+	if err = header.Check(Format.KindMeta, dimension); err != nil {
+		return nil, 0, fmt.Errorf("meta file %s: %w", path, err)
+	}
+
+	var vectors []SaveVector
+	size := int64(Format.HeaderSize)
+	r := bufio.NewReader(file)
+	for {
+		body, n, err := Format.ReadRecord(r)
+		if err == io.EOF || err == Format.ErrTornRecord {
+			return vectors, size, nil
+		} else if err != nil {
+			return vectors, size, fmt.Errorf("meta file %s at %d: %w", path, size, err)
+		}
+		sv, err := DecodeSaveVector(body)
+		if err != nil {
+			return vectors, size, fmt.Errorf("meta file %s at %d: %w", path, size, err)
+		}
+		vectors = append(vectors, sv)
+		size += n
+	}
+}
+
+// WriteMetaFile writes a new meta file with the given SaveVectors, the file is written next to the old one and
+// renamed over it
+func WriteMetaFile(path string, dimension int, vectors []SaveVector) error {
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	buf := Format.NewHeader(Format.KindMeta, dimension).Encode()
+	for _, sv := range vectors {
+		buf = append(buf, Format.EncodeRecord(encodeSaveVector(sv))...)
+	}
+	_, err = file.Write(buf)
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
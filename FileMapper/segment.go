@@ -0,0 +1,230 @@
+package FileMapper
+
+import (
+	"VreeDB/Logger"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+const (
+	// minSegmentSize is the capacity of the first active segment of a collection file
+	minSegmentSize = 1 << 20
+	// maxSegmentSize is the capacity the active segments grow to
+	maxSegmentSize = 1 << 28
+	// appendBufferSize is the number of appended bytes that are buffered before they are written to the file
+	appendBufferSize = 64 << 10
+)
+
+// activeSegment is the writable end of a collection file. The file is memory mapped up to sealed, everything that is
+// appended after it goes through a buffer: the buffered bytes are written to the file in one write when the buffer
+// is full, before a meta record is written and when the segment is sealed. Appended bytes are read back from the
+// buffer or with pread, so they can be read without mapping the file again. The capacity is not allocated in the
+// file, it is the number of appended bytes after which the segment is sealed: the file is mapped again up to its end
+// and the next active segment gets twice the capacity.
+type activeSegment struct {
+	file      *os.File
+	mapped    []byte // The mapped sealed segments - replaced under the lock of the collection file
+	dimension int
+	sealed    int64
+	size      int64 // The bytes appended after sealed
+	capacity  int64
+	written   int64  // The appended bytes that are in the file, the others are pending
+	pending   []byte // The appended bytes that are not written yet
+	err       error  // A failed write - the positions of the pending bytes are lost, nothing is appended anymore
+}
+
+// openSegment opens the active segment of the collection file - the file has to be mapped already and the caller has
+// to hold keysMut
+func (f *FileMapper) openSegment(collection string, dimension int) {
+	file, err := os.OpenFile(f.FileName[collection], os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		// Here we panic because we can't continue without the file
+		panic(err)
+	}
+	f.segments[collection] = &activeSegment{file: file, mapped: f.MappedData[collection], dimension: dimension,
+		sealed: int64(len(f.MappedData[collection])), capacity: minSegmentSize}
+}
+
+// segmentOf returns the active segment of the collection file, the caller has to hold the lock of the collection file
+func (f *FileMapper) segmentOf(collection string) (*activeSegment, error) {
+	f.keysMut.RLock()
+	defer f.keysMut.RUnlock()
+	s, ok := f.segments[collection]
+	if !ok {
+		return nil, fmt.Errorf("collection file %s does not exist", collection)
+	}
+	return s, nil
+}
+
+// closeSegment closes the active segment of the collection file - the caller has to hold keysMut
+func (f *FileMapper) closeSegment(collection string) {
+	if s, ok := f.segments[collection]; ok {
+		s.file.Close()
+		delete(f.segments, collection)
+	}
+}
+
+// flush writes the pending bytes of the active segment to the file - the caller has to hold the lock
+func (s *activeSegment) flush() error {
+	if s.err != nil || len(s.pending) == 0 {
+		return s.err
+	}
+	n, err := s.file.Write(s.pending)
+	s.written += int64(n)
+	if err != nil {
+		Logger.Log.Log("Error writing to file: " + err.Error())
+		s.err = err
+		return err
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
+
+// sealSegment maps the collection file up to its end and starts a new active segment - the caller has to hold the lock
+func (f *FileMapper) sealSegment(collection string, s *activeSegment) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	file, mapped, err := mapFile(f.FileName[collection])
+	if err != nil {
+		return err
+	}
+	// Swap the mapping, nobody reads the old one while the lock is held
+	f.keysMut.Lock()
+	oldFile, oldMapped := f.File[collection], f.MappedData[collection]
+	f.File[collection], f.MappedData[collection], f.Mapped[collection] = file, mapped, mapped != nil
+	f.keysMut.Unlock()
+	if oldMapped != nil {
+		syscall.Munmap(oldMapped)
+	}
+	if oldFile != nil {
+		oldFile.Close()
+	}
+	s.mapped = mapped
+	// The next segment grows geometrically
+	s.capacity *= 2
+	if s.capacity > maxSegmentSize {
+		s.capacity = maxSegmentSize
+	}
+	s.sealed = int64(len(mapped))
+	s.size, s.written = 0, 0
+	return nil
+}
+
+// Seal maps the whole collection file - used when nothing will be appended to the file anymore
+func (f *FileMapper) Seal(collection string) {
+	mut, err := f.lock(collection)
+	if err == nil {
+		defer mut.Unlock()
+		var s *activeSegment
+		if s, err = f.segmentOf(collection); err == nil && s.size > 0 {
+			err = f.sealSegment(collection, s)
+		}
+	}
+	if err != nil {
+		Logger.Log.Log("Error sealing segment: " + err.Error())
+	}
+}
+
+// Flush writes the buffered bytes of the collection file to the file
+func (f *FileMapper) Flush(collection string) error {
+	mut, err := f.lock(collection)
+	if err != nil {
+		return err
+	}
+	defer mut.Unlock()
+	s, err := f.segmentOf(collection)
+	if err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// appendBytes will append the bytes to the file and return their start position - the caller has to hold the lock
+func (f *FileMapper) appendBytes(buf []byte, collection string) (int64, error) {
+	s, err := f.segmentOf(collection)
+	if err != nil {
+		return 0, err
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	// Seal the active segment if the bytes do not fit into it anymore
+	if s.size > 0 && s.size+int64(len(buf)) > s.capacity {
+		if err = f.sealSegment(collection, s); err != nil {
+			return 0, err
+		}
+	}
+	start := s.sealed + s.size
+	s.pending = append(s.pending, buf...)
+	s.size += int64(len(buf))
+	if len(s.pending) >= appendBufferSize {
+		return start, s.flush()
+	}
+	return start, nil
+}
+
+// bytesAt returns n bytes of the collection file starting at start, from the mapped sealed segments, the file or the
+// buffer of the active segment - the caller has to hold the read lock
+func (f *FileMapper) bytesAt(start int64, n int, collection string) ([]byte, error) {
+	s, err := f.segmentOf(collection)
+	if err != nil {
+		return nil, err
+	}
+	end := start + int64(n)
+	written := s.sealed + s.written
+	switch {
+	case start < 0 || n < 0 || end > s.sealed+s.size:
+		return nil, fmt.Errorf("%d bytes at %d exceed the file", n, start)
+	case end <= s.sealed:
+		return s.mapped[start:end], nil
+	case start >= s.sealed && end <= written:
+		data := make([]byte, n)
+		_, err := s.file.ReadAt(data, start)
+		return data, err
+	case start >= written:
+		return s.pending[start-written : end-written], nil
+	}
+	// Nothing is appended across the end of the sealed segments or of the written bytes
+	return nil, fmt.Errorf("%d bytes at %d cross the end of the sealed segments", n, start)
+}
+
+// readerAt returns a reader of the collection file from start up to the end of the sealed segments or the active
+// segment - the caller has to hold the read lock
+func (f *FileMapper) readerAt(start int64, collection string) (io.Reader, error) {
+	s, err := f.segmentOf(collection)
+	if err != nil {
+		return nil, err
+	}
+	written := s.sealed + s.written
+	switch {
+	case start >= 0 && start < s.sealed:
+		return bytes.NewReader(s.mapped[start:s.sealed]), nil
+	case start >= s.sealed && start < written:
+		return io.MultiReader(io.NewSectionReader(s.file, start, written-start), bytes.NewReader(s.pending)), nil
+	case start >= written && start < s.sealed+s.size:
+		return bytes.NewReader(s.pending[start-written:]), nil
+	}
+	return nil, fmt.Errorf("position %d is out of range", start)
+}
+
+// mapFile opens the file and maps it to memory, an empty file is opened but not mapped
+func mapFile(path string) (*os.File, []byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return file, nil, err
+	}
+	mapped, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, mapped, nil
+}
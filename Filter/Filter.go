@@ -1,19 +1,29 @@
 package Filter
 
 import (
+	"VreeDB/Datetime"
 	"VreeDB/FileMapper"
+	"VreeDB/Geo"
 	"VreeDB/Logger"
 	"VreeDB/Vector"
 	"fmt"
+	"math"
+	"time"
 	"unsafe"
 )
 
 type Operator string
 
 type Filter struct {
-	Field string      `json:"field"`
-	Op    Operator    `json:"operator"`
-	Value interface{} `json:"value"`
+	Field    string          `json:"field"`
+	Op       Operator        `json:"operator"`
+	Value    interface{}     `json:"value"`
+	Datetime bool            `json:"datetime,omitempty"` // The field is compared as a datetime
+	shape    Geo.Shape       // The parsed value of a geo filter
+	from, to float64         // The parsed bounds of a datetime or between filter, to only for between
+	parsed   bool            // The value is parsed
+	ids      map[string]bool // The only IDs that can pass the filter, nil if there is no index for it
+	idsOnly  bool            // Only the IDs are checked, the filter has no field
 }
 
 // Operators
@@ -30,19 +40,239 @@ const (
 	LessThan Operator = "lt"
 	// LessThanOrEqual operator
 	LessThanOrEqual Operator = "le"
+	// GeoRadius operator - the geo point is within a radius in meters of a center
+	GeoRadius Operator = "geo_radius"
+	// GeoBoundingBox operator - the geo point is within a box
+	GeoBoundingBox Operator = "geo_bounding_box"
+	// GeoPolygon operator - the geo point is within a polygon
+	GeoPolygon Operator = "geo_polygon"
+	// Between operator - the number or datetime is within [from, to], both included
+	Between Operator = "between"
 )
 
 // IsValid checks if the operator is valid
 func (o Operator) IsValid() error {
 	switch o {
-	case Equal, NotEqual, GreaterThan, GreaterThanOrEqual, LessThan, LessThanOrEqual:
+	case Equal, NotEqual, GreaterThan, GreaterThanOrEqual, LessThan, LessThanOrEqual, GeoRadius, GeoBoundingBox,
+		GeoPolygon, Between:
 		return nil
 	}
 	return fmt.Errorf("Invalid operator: %s", o)
 }
 
+// IsGeo returns true if the operator is a geo operator
+func (o Operator) IsGeo() bool {
+	return o == GeoRadius || o == GeoBoundingBox || o == GeoPolygon
+}
+
+// Check checks the operator and the value of the filter, the Filter is not changed
+func (f *Filter) Check() error {
+	_, err := f.parsedFilter()
+	return err
+}
+
+// Prepare parses the value of a geo, a between or a datetime filter and keeps the parsed value in the Filter. Relative
+// datetimes like now-7d are resolved when the filter is prepared.
+func (f *Filter) Prepare() error {
+	parsed, err := f.parsedFilter()
+	if err == nil {
+		*f = *parsed
+	}
+	return err
+}
+
+// parsedFilter returns the Filter if it is prepared, otherwise a copy with the parsed value
+func (f *Filter) parsedFilter() (*Filter, error) {
+	if err := f.Op.IsValid(); err != nil {
+		return nil, err
+	}
+	if f.parsed {
+		return f, nil
+	}
+	parsed := *f
+	if err := parsed.parse(); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// parse parses the value of a geo, a between or a datetime filter into the Filter. Strings are only compared as
+// datetimes if the filter is a datetime filter.
+func (f *Filter) parse() error {
+	var err error
+	switch f.Op {
+	case GeoRadius:
+		f.shape, err = Geo.ParseRadius(f.Value)
+	case GeoBoundingBox:
+		f.shape, err = Geo.ParseBox(f.Value)
+	case GeoPolygon:
+		f.shape, err = Geo.ParsePolygon(f.Value)
+	case Between:
+		err = f.parseBetween()
+	default:
+		if f.Datetime {
+			f.from, err = Datetime.ParseLiteral(f.Value, time.Now())
+		}
+	}
+	f.parsed = err == nil
+	return err
+}
+
+// parseBetween parses the [from, to] value of a between filter, the bounds are numbers or the literals of a datetime
+// filter
+func (f *Filter) parseBetween() error {
+	bounds, ok := f.Value.([]interface{})
+	if !ok || len(bounds) != 2 {
+		return fmt.Errorf("between needs a value [from, to]")
+	}
+	for _, bound := range bounds {
+		if _, isNumber := bound.(float64); !isNumber && !f.Datetime {
+			return fmt.Errorf("between needs numbers, set datetime to compare datetimes")
+		}
+	}
+	var err error
+	if f.Datetime {
+		now := time.Now()
+		if f.from, err = Datetime.ParseLiteral(bounds[0], now); err != nil {
+			return err
+		}
+		if f.to, err = Datetime.ParseLiteral(bounds[1], now); err != nil {
+			return err
+		}
+	} else {
+		f.from, f.to = bounds[0].(float64), bounds[1].(float64)
+	}
+	if f.from > f.to {
+		return fmt.Errorf("between needs from <= to")
+	}
+	return nil
+}
+
+// UseDatetime compares the field as a datetime, e.g. because the schema declares it - numbers are Unix seconds
+func (f *Filter) UseDatetime() error {
+	if f.Op.IsGeo() || f.Datetime {
+		return nil
+	}
+	f.Datetime = true
+	f.parsed = false
+	return f.Prepare()
+}
+
+// IsDatetime returns true if the field is compared as a datetime
+func (f *Filter) IsDatetime() bool {
+	return f.Datetime
+}
+
+// Range returns the smallest and the largest value that can pass a datetime or a between filter, it returns false
+// for the other filters
+func (f *Filter) Range() (float64, float64, bool) {
+	f, err := f.parsedFilter()
+	if err != nil || !f.Datetime && f.Op != Between {
+		return 0, 0, false
+	}
+	switch f.Op {
+	case Equal:
+		return f.from, f.from, true
+	case GreaterThan, GreaterThanOrEqual:
+		return f.from, math.Inf(1), true
+	case LessThan, LessThanOrEqual:
+		return math.Inf(-1), f.from, true
+	case Between:
+		return f.from, f.to, true
+	}
+	return 0, 0, false
+}
+
+// Shape returns the parsed shape of a geo filter, nil if the filter is no valid geo filter
+func (f *Filter) Shape() Geo.Shape {
+	f, err := f.parsedFilter()
+	if err != nil || !f.Op.IsGeo() {
+		return nil
+	}
+	return f.shape
+}
+
+// Restrict limits the filter to the given IDs, e.g. the points a geo index found within the bounds of the shape or
+// the points of the values of a payload index within a datetime range. The other points are rejected without
+// reading their payload.
+func (f *Filter) Restrict(ids map[string]bool) {
+	f.ids = ids
+}
+
+// Restricted returns the IDs the filter is limited to, nil if it is not restricted
+func (f *Filter) Restricted() map[string]bool {
+	return f.ids
+}
+
+// validateGeo checks if the geo point of the payload field is within the shape of the filter
+func (f *Filter) validateGeo(vector *Vector.Vector) (bool, error) {
+	shape := f.Shape()
+	if shape == nil {
+		return false, nil
+	}
+	payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
+	if err != nil {
+		return false, err
+	}
+	p, ok := Geo.ParsePoint((*payload)[f.Field])
+	return ok && shape.Contains(p), nil
+}
+
+// validateRange compares the payload field with the parsed bounds of a datetime or a between filter
+func (f *Filter) validateRange(vector *Vector.Vector) (bool, error) {
+	payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
+	if err != nil {
+		return false, err
+	}
+	// Datetimes are RFC 3339 strings or Unix seconds, a between of numbers only takes numbers
+	value, ok := Datetime.Parse((*payload)[f.Field])
+	if _, isString := (*payload)[f.Field].(string); !ok || isString && !f.Datetime {
+		return false, nil
+	}
+	switch f.Op {
+	case Equal:
+		return value == f.from, nil
+	case NotEqual:
+		return value != f.from, nil
+	case GreaterThan:
+		return value > f.from, nil
+	case GreaterThanOrEqual:
+		return value >= f.from, nil
+	case LessThan:
+		return value < f.from, nil
+	case LessThanOrEqual:
+		return value <= f.from, nil
+	case Between:
+		return value >= f.from && value <= f.to, nil
+	}
+	return false, nil
+}
+
+// IDs returns a Filter that only the vectors with the given IDs pass, e.g. the points that passed the filters of a
+// search before. The vectors of the vector fields pass it by the ID of their point.
+func IDs(ids map[string]bool) Filter {
+	return Filter{ids: ids, idsOnly: true}
+}
+
 // ValidateFilter will validate the filters on a given Vector
 func (f *Filter) ValidateFilter(vector *Vector.Vector) (bool, error) {
+	if f.ids != nil && !f.ids[vector.Id] {
+		return false, nil
+	}
+	if f.idsOnly {
+		return true, nil
+	}
+	if f.Op.IsGeo() {
+		return f.validateGeo(vector)
+	}
+	// An unprepared filter is parsed for every vector
+	f, err := f.parsedFilter()
+	if err != nil {
+		return false, nil
+	}
+	if f.Datetime || f.Op == Between {
+		return f.validateRange(vector)
+	}
 	// Load the Payload from the hdd
 	payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
 	if err != nil {
@@ -0,0 +1,109 @@
+package Filter
+
+import (
+	"VreeDB/Geo"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCheckParsesGeoFilters(t *testing.T) {
+	center := map[string]interface{}{"lat": 52.52, "lon": 13.405}
+	for _, test := range []struct {
+		filter Filter
+		ok     bool
+	}{
+		{Filter{Field: "place", Op: GeoRadius, Value: map[string]interface{}{"center": center, "radius": 5000.0}},
+			true},
+		{Filter{Field: "place", Op: GeoRadius, Value: map[string]interface{}{"center": center}}, false},
+		{Filter{Field: "place", Op: GeoBoundingBox, Value: map[string]interface{}{"top_left": center,
+			"bottom_right": map[string]interface{}{"lat": 52.0, "lon": 14.0}}}, true},
+		{Filter{Field: "place", Op: GeoBoundingBox, Value: "berlin"}, false},
+		{Filter{Field: "place", Op: GeoPolygon, Value: map[string]interface{}{"points": []interface{}{center}}},
+			false},
+		{Filter{Field: "place", Op: "geo_circle", Value: center}, false},
+	} {
+		err := test.filter.Check()
+		if (err == nil) != test.ok {
+			t.Errorf("%s %v: %v", test.filter.Op, test.filter.Value, err)
+			continue
+		}
+		if shape := test.filter.Shape(); (shape != nil) != test.ok {
+			t.Errorf("%s has the shape %v", test.filter.Op, shape)
+		}
+	}
+
+	// The parsed shape is kept
+	f := Filter{Field: "place", Op: GeoRadius, Value: map[string]interface{}{"center": center, "radius": 5000.0}}
+	if !f.Op.IsGeo() || f.Shape() == nil || !f.Shape().Contains(Geo.Point{Lat: 52.52, Lon: 13.41}) {
+		t.Errorf("the radius filter is %+v", f)
+	}
+	if f := (Filter{Field: "x", Op: Equal, Value: "a"}); f.Op.IsGeo() || f.Shape() != nil {
+		t.Error("an eq filter has a shape")
+	}
+}
+
+func TestCheckParsesDatetimesAndRanges(t *testing.T) {
+	inf := math.Inf(1)
+	for _, test := range []struct {
+		filter   Filter
+		datetime bool
+		from, to float64
+		ok       bool
+	}{
+		{Filter{Op: GreaterThan, Value: "2024-05-01T12:00:00Z", Datetime: true}, true, 1714564800, inf, true},
+		{Filter{Op: LessThanOrEqual, Value: "2024-05-01T12:00:00Z", Datetime: true}, true, -inf, 1714564800, true},
+		{Filter{Op: Equal, Value: "2024-05-01T12:00:00Z", Datetime: true}, true, 1714564800, 1714564800, true},
+		{Filter{Op: Between, Value: []interface{}{1.0, 5.0}}, false, 1, 5, true},
+		{Filter{Op: Between, Value: []interface{}{"2024-05-01T12:00:00Z", 1714564900.0}, Datetime: true}, true,
+			1714564800, 1714564900, true},
+		{Filter{Op: GreaterThan, Value: 5.0}, false, 0, 0, false},
+		{Filter{Op: GreaterThan, Value: "abc"}, false, 0, 0, false},
+		// Without the datetime flag strings are compared as they are
+		{Filter{Op: GreaterThan, Value: "2024-05-01T12:00:00Z"}, false, 0, 0, false},
+		{Filter{Op: Equal, Value: "now-7d"}, false, 0, 0, false},
+	} {
+		if err := test.filter.Check(); err != nil {
+			t.Errorf("%s %v: %v", test.filter.Op, test.filter.Value, err)
+			continue
+		}
+		from, to, ok := test.filter.Range()
+		if test.filter.IsDatetime() != test.datetime || ok != test.ok || from != test.from || to != test.to {
+			t.Errorf("%s %v: datetime %v and range %v..%v, %v", test.filter.Op, test.filter.Value,
+				test.filter.IsDatetime(), from, to, ok)
+		}
+	}
+
+	// Check does not change the filter, a relative literal is resolved when the filter is prepared
+	f := Filter{Op: GreaterThan, Value: "now-7d", Datetime: true}
+	if err := f.Check(); err != nil || f.parsed {
+		t.Fatalf("Check = %v and changed the filter to %+v", err, f)
+	}
+	if err := f.Prepare(); err != nil || !f.parsed {
+		t.Fatalf("Prepare = %v and kept %+v", err, f)
+	}
+	if from, _, _ := f.Range(); math.Abs(from-float64(time.Now().Add(-7*24*time.Hour).Unix())) > 5 {
+		t.Errorf("now-7d is %v", from)
+	}
+
+	for _, between := range []Filter{
+		{Op: Between, Value: []interface{}{5.0, 1.0}},
+		{Op: Between, Value: []interface{}{1.0}},
+		{Op: Between, Value: "now-7d", Datetime: true},
+		{Op: Between, Value: []interface{}{1.0, "x"}, Datetime: true},
+		{Op: Between, Value: []interface{}{"now-7d", "now"}},
+	} {
+		if err := between.Check(); err == nil {
+			t.Errorf("between %v was parsed", between.Value)
+		}
+	}
+
+	// A field the schema declares as datetime compares numbers as Unix seconds
+	f = Filter{Op: LessThan, Value: 1714564800.0}
+	if err := f.UseDatetime(); err != nil || !f.IsDatetime() {
+		t.Errorf("UseDatetime = %v", err)
+	}
+	if _, to, ok := f.Range(); !ok || to != 1714564800 {
+		t.Errorf("the range ends at %v", to)
+	}
+}
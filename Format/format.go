@@ -0,0 +1,172 @@
+// Package Format describes the on-disk format of the collection files.
+//
+// Every data file (<segment>.bin) and every meta file (<segment>_meta.bin) starts with a header of HeaderSize bytes:
+//
+//	offset  size  field
+//	0       4     magic "VRDB"
+//	4       2     format version (uint16, little endian)
+//	6       1     kind - 1 data file, 2 meta file
+//	7       1     dtype of the vectors - 1 float64 little endian
+//	8       4     vector dimension of the collection (uint32, little endian)
+//	12      16    reserved, zero
+//	28      4     CRC-32 (IEEE) of the bytes 0-27
+//
+// The data file holds the vectors, sparse vectors, bit packed binary vectors and gob encoded payloads directly after
+// the header, all offsets in the meta file are absolute positions in the data file. The meta file holds one record
+// per SaveVector, a record is framed as
+//
+//	offset  size  field
+//	0       4     length of the body (uint32, little endian)
+//	4       4     CRC-32 (IEEE) of the body
+//	8       n     body
+//
+// The collection config (<collection>.json) carries the FormatVersion of its files. Version 1 is the legacy layout
+// without headers and with newline delimited JSON meta files, it is migrated on boot.
+package Format
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	// Version is the format version written by this build
+	Version = 2
+	// LegacyVersion is the version of files without a header
+	LegacyVersion = 1
+	// HeaderSize is the size of the file header in bytes
+	HeaderSize = 32
+	// KindData marks a data file
+	KindData byte = 1
+	// KindMeta marks a meta file
+	KindMeta byte = 2
+	// DTypeFloat64 marks vectors of little endian float64 values
+	DTypeFloat64 byte = 1
+	// recordHeaderSize is the size of the length and the checksum in front of a record
+	recordHeaderSize = 8
+	// maxRecordSize is the largest record body that is accepted, larger lengths are treated as corruption
+	maxRecordSize = 1 << 30
+)
+
+// magic is the magic number every file starts with
+var magic = [4]byte{'V', 'R', 'D', 'B'}
+
+var (
+	// ErrNoHeader is returned for files that do not start with the magic number, e.g. files of the legacy layout
+	ErrNoHeader = errors.New("file has no format header")
+	// ErrChecksum is returned if a header or a record does not match its checksum
+	ErrChecksum = errors.New("checksum mismatch")
+	// ErrNewerVersion is returned for files that were written by a newer version of VreeDB
+	ErrNewerVersion = errors.New("file was written by a newer format version")
+	// ErrTornRecord is returned for a record that was only partly written, e.g. after a crash
+	ErrTornRecord = errors.New("record is incomplete")
+)
+
+// Header is the header of a data or a meta file
+type Header struct {
+	Version   uint16
+	Kind      byte
+	DType     byte
+	Dimension uint32
+}
+
+// NewHeader returns the Header of the current version for a file of the given kind
+func NewHeader(kind byte, dimension int) Header {
+	return Header{Version: Version, Kind: kind, DType: DTypeFloat64, Dimension: uint32(dimension)}
+}
+
+// Encode returns the HeaderSize bytes of the Header
+func (h Header) Encode() []byte {
+	buf := make([]byte, HeaderSize)
+	copy(buf[0:4], magic[:])
+	binary.LittleEndian.PutUint16(buf[4:6], h.Version)
+	buf[6] = h.Kind
+	buf[7] = h.DType
+	binary.LittleEndian.PutUint32(buf[8:12], h.Dimension)
+	binary.LittleEndian.PutUint32(buf[28:32], crc32.ChecksumIEEE(buf[:28]))
+	return buf
+}
+
+// DecodeHeader reads a Header from the first HeaderSize bytes of data
+func DecodeHeader(data []byte) (Header, error) {
+	if len(data) < HeaderSize || string(data[0:4]) != string(magic[:]) {
+		return Header{}, ErrNoHeader
+	}
+	if binary.LittleEndian.Uint32(data[28:32]) != crc32.ChecksumIEEE(data[:28]) {
+		return Header{}, fmt.Errorf("header: %w", ErrChecksum)
+	}
+	h := Header{Version: binary.LittleEndian.Uint16(data[4:6]), Kind: data[6], DType: data[7],
+		Dimension: binary.LittleEndian.Uint32(data[8:12])}
+	if h.Version > Version {
+		return h, fmt.Errorf("version %d, this build reads up to %d: %w", h.Version, Version, ErrNewerVersion)
+	}
+	return h, nil
+}
+
+// ReadHeader reads and decodes the Header at the start of r
+func ReadHeader(r io.Reader) (Header, error) {
+	buf := make([]byte, HeaderSize)
+	_, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return Header{}, ErrNoHeader
+	} else if err != nil {
+		return Header{}, err
+	}
+	return DecodeHeader(buf)
+}
+
+// Check checks if the Header belongs to a file of the given kind and dimension
+func (h Header) Check(kind byte, dimension int) error {
+	if h.Kind != kind {
+		return fmt.Errorf("file is of kind %d, expected %d", h.Kind, kind)
+	}
+	if h.DType != DTypeFloat64 {
+		return fmt.Errorf("file has dtype %d, expected %d", h.DType, DTypeFloat64)
+	}
+	if int(h.Dimension) != dimension {
+		return fmt.Errorf("file has dimension %d, expected %d", h.Dimension, dimension)
+	}
+	return nil
+}
+
+// EncodeRecord frames a record body with its length and checksum
+func EncodeRecord(body []byte) []byte {
+	buf := make([]byte, recordHeaderSize+len(body))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(body))
+	copy(buf[recordHeaderSize:], body)
+	return buf
+}
+
+// ReadRecord reads the body of the next record from r. It returns io.EOF at the end of r, ErrTornRecord if r ends
+// within the record and ErrChecksum if the body does not match its checksum. The returned size is the number of
+// bytes the record takes in the file.
+func ReadRecord(r io.Reader) ([]byte, int64, error) {
+	head := make([]byte, recordHeaderSize)
+	n, err := io.ReadFull(r, head)
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return nil, int64(n), ErrTornRecord
+	} else if err != nil {
+		return nil, 0, err
+	}
+	length := binary.LittleEndian.Uint32(head[0:4])
+	if length > maxRecordSize {
+		return nil, recordHeaderSize, fmt.Errorf("record length %d: %w", length, ErrChecksum)
+	}
+	body := make([]byte, length)
+	n, err = io.ReadFull(r, body)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, recordHeaderSize + int64(n), ErrTornRecord
+	} else if err != nil {
+		return nil, 0, err
+	}
+	if binary.LittleEndian.Uint32(head[4:8]) != crc32.ChecksumIEEE(body) {
+		return nil, recordHeaderSize + int64(length), fmt.Errorf("record: %w", ErrChecksum)
+	}
+	return body, recordHeaderSize + int64(length), nil
+}
@@ -0,0 +1,83 @@
+package Format
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	data := NewHeader(KindMeta, 128).Encode()
+	if len(data) != HeaderSize || string(data[:4]) != "VRDB" {
+		t.Fatalf("header %x", data)
+	}
+	h, err := ReadHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h != NewHeader(KindMeta, 128) {
+		t.Errorf("decoded header %+v", h)
+	}
+	if err = h.Check(KindMeta, 128); err != nil {
+		t.Error(err)
+	}
+	if err = h.Check(KindData, 128); err == nil {
+		t.Error("a meta file passes as data file")
+	}
+	if err = h.Check(KindMeta, 64); err == nil {
+		t.Error("a header of another dimension passes")
+	}
+}
+
+func TestDecodeHeaderErrors(t *testing.T) {
+	corrupt := NewHeader(KindData, 2).Encode()
+	corrupt[9] ^= 1
+	newer := NewHeader(KindData, 2)
+	newer.Version = Version + 1
+
+	for _, test := range []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"legacy", []byte{0, 0, 0, 0, 1, 2}, ErrNoHeader},
+		{"empty", nil, ErrNoHeader},
+		{"checksum", corrupt, ErrChecksum},
+		{"newer", newer.Encode(), ErrNewerVersion},
+	} {
+		if _, err := ReadHeader(bytes.NewReader(test.data)); !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestRecords(t *testing.T) {
+	var file bytes.Buffer
+	file.Write(EncodeRecord([]byte("first")))
+	file.Write(EncodeRecord(nil))
+	file.Write(EncodeRecord([]byte("torn"))[:10])
+
+	r := bytes.NewReader(file.Bytes())
+	for _, want := range []string{"first", ""} {
+		body, size, err := ReadRecord(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != want || size != int64(8+len(want)) {
+			t.Errorf("record %q of %d bytes, want %q", body, size, want)
+		}
+	}
+	if _, size, err := ReadRecord(r); err != ErrTornRecord || size != 10 {
+		t.Errorf("the torn record gives %v after %d bytes", err, size)
+	}
+	if _, _, err := ReadRecord(r); err != io.EOF {
+		t.Errorf("the end gives %v", err)
+	}
+
+	record := EncodeRecord([]byte("body"))
+	record[9] ^= 1
+	if _, size, err := ReadRecord(bytes.NewReader(record)); !errors.Is(err, ErrChecksum) || size != 12 {
+		t.Errorf("a corrupt body gives %v after %d bytes", err, size)
+	}
+}
@@ -0,0 +1,335 @@
+// Package Formula parses and evaluates the score formulas of a search. A formula is an arithmetic expression over
+// numbers, variables and functions, e.g.
+//
+//	-distance + 0.1 * log(popularity)
+//	score * exp(-(now - created) / 86400)
+//	-distance * decay_exp(now - published, 7 * 86400)
+//
+// The operators are + - * / and ^ (power) with the usual precedence, ^ binds right. The functions are log (natural),
+// log10, exp, sqrt, abs, pow, min and max. The decay functions decay_exp, decay_gauss and decay_linear take an offset,
+// e.g. the age of a datetime field in seconds, and a scale: they are 1 at offset 0 and 0.5 at the scale, linear decay
+// reaches 0 at twice the scale. The variables are resolved when the formula is evaluated.
+package Formula
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Formula is a parsed expression
+type Formula struct {
+	Source string
+	root   node
+}
+
+// node is a node of the syntax tree
+type node interface {
+	eval(vars func(string) (float64, bool)) (float64, error)
+}
+
+type number float64
+
+type variable string
+
+type unary struct {
+	op      byte
+	operand node
+}
+
+type binary struct {
+	op          byte
+	left, right node
+}
+
+type call struct {
+	name string
+	args []node
+}
+
+// functions are the functions of a formula and their number of arguments
+var functions = map[string]int{"log": 1, "log10": 1, "exp": 1, "sqrt": 1, "abs": 1, "pow": 2, "min": 2, "max": 2,
+	"decay_exp": 2, "decay_gauss": 2, "decay_linear": 2}
+
+// Parse parses a formula
+func Parse(source string) (*Formula, error) {
+	p := &parser{}
+	err := p.tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return &Formula{Source: source, root: root}, nil
+}
+
+// Eval evaluates the formula, vars returns the value of a variable and false if it is unknown
+func (f *Formula) Eval(vars func(string) (float64, bool)) (float64, error) {
+	return f.root.eval(vars)
+}
+
+func (n number) eval(func(string) (float64, bool)) (float64, error) {
+	return float64(n), nil
+}
+
+func (n variable) eval(vars func(string) (float64, bool)) (float64, error) {
+	value, ok := vars(string(n))
+	if !ok {
+		return 0, fmt.Errorf("unknown variable %s", string(n))
+	}
+	return value, nil
+}
+
+func (n unary) eval(vars func(string) (float64, bool)) (float64, error) {
+	value, err := n.operand.eval(vars)
+	if n.op == '-' {
+		value = -value
+	}
+	return value, err
+}
+
+func (n binary) eval(vars func(string) (float64, bool)) (float64, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	case '/':
+		return left / right, nil
+	default:
+		return math.Pow(left, right), nil
+	}
+}
+
+func (n call) eval(vars func(string) (float64, bool)) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = value
+	}
+	switch n.name {
+	case "log":
+		return math.Log(args[0]), nil
+	case "log10":
+		return math.Log10(args[0]), nil
+	case "exp":
+		return math.Exp(args[0]), nil
+	case "sqrt":
+		return math.Sqrt(args[0]), nil
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "pow":
+		return math.Pow(args[0], args[1]), nil
+	case "min":
+		return math.Min(args[0], args[1]), nil
+	case "decay_exp":
+		return math.Pow(0.5, math.Abs(args[0])/args[1]), nil
+	case "decay_gauss":
+		return math.Exp(-math.Ln2 * (args[0] / args[1]) * (args[0] / args[1])), nil
+	case "decay_linear":
+		return math.Max(0, 1-0.5*math.Abs(args[0])/args[1]), nil
+	default:
+		return math.Max(args[0], args[1]), nil
+	}
+}
+
+// parser is a recursive descent parser over the tokens of a formula
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+// tokenize splits the source into numbers, names and operators
+func (p *parser) tokenize(source string) error {
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			// An exponent like 1e-3
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			p.tokens = append(p.tokens, string(runes[start:i]))
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' ||
+				runes[i] == '.') {
+				i++
+			}
+			p.tokens = append(p.tokens, string(runes[start:i]))
+		case strings.ContainsRune("+-*/^(),", r):
+			p.tokens = append(p.tokens, string(r))
+			i++
+		default:
+			return fmt.Errorf("unexpected %q", r)
+		}
+	}
+	if len(p.tokens) == 0 {
+		return fmt.Errorf("empty formula")
+	}
+	return nil
+}
+
+// peek returns the current token or an empty string at the end
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// expr parses a sum
+func (p *parser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.peek()[0]
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// term parses a product
+func (p *parser) term() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" {
+		op := p.peek()[0]
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// unary parses a sign
+func (p *parser) unary() (node, error) {
+	if p.peek() == "-" || p.peek() == "+" {
+		op := p.peek()[0]
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op: op, operand: operand}, nil
+	}
+	return p.power()
+}
+
+// power parses a power, the exponent binds right
+func (p *parser) power() (node, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() == "^" {
+		p.pos++
+		exponent, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return binary{op: '^', left: base, right: exponent}, nil
+	}
+	return base, nil
+}
+
+// primary parses a number, a variable, a function call or a parenthesised expression
+func (p *parser) primary() (node, error) {
+	token := p.peek()
+	if token == "" {
+		return nil, fmt.Errorf("unexpected end of formula")
+	}
+	p.pos++
+	switch {
+	case token == "(":
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return n, nil
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", token)
+		}
+		return number(value), nil
+	case unicode.IsLetter(rune(token[0])) || token[0] == '_' || token[0] >= 0x80:
+		if p.peek() != "(" {
+			return variable(token), nil
+		}
+		p.pos++
+		arity, ok := functions[token]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %s", token)
+		}
+		var args []node
+		for p.peek() != ")" {
+			if len(args) > 0 {
+				if p.peek() != "," {
+					return nil, fmt.Errorf("missing , in %s", token)
+				}
+				p.pos++
+			}
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		p.pos++
+		if len(args) != arity {
+			return nil, fmt.Errorf("%s takes %d arguments, got %d", token, arity, len(args))
+		}
+		return call{name: token, args: args}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", token)
+}
@@ -0,0 +1,94 @@
+package Formula
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	vars := func(name string) (float64, bool) {
+		value, ok := map[string]float64{"distance": 2, "popularity": math.E, "a.b": 3}[name]
+		return value, ok
+	}
+	for source, want := range map[string]float64{
+		"1 + 2 * 3":                         7,
+		"(1 + 2) * 3":                       9,
+		"2 ^ 3 ^ 2":                         512,
+		"-2 ^ 2":                            -4,
+		"--distance":                        2,
+		"8 / 4 / 2":                         1,
+		"1e-3 * 1000":                       1,
+		"-distance + 0.1 * log(popularity)": -1.9,
+		"a.b * max(1, min(distance, 5))":    6,
+		"pow(distance, 3) - sqrt(16)":       4,
+		"abs(-3) + log10(100) + exp(0)":     6,
+	} {
+		f, err := Parse(source)
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+		if got, err := f.Eval(vars); err != nil || math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s = %v, %v - want %v", source, got, err, want)
+		}
+	}
+
+	f, err := Parse("distance + missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Eval(vars); err == nil {
+		t.Error("an unknown variable was evaluated")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, source := range []string{
+		"",
+		"  ",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		"1 # 2",
+		"1..2",
+		"unknown(1)",
+		"log(1, 2)",
+		"pow(1)",
+		"max(1 2)",
+		")",
+	} {
+		if _, err := Parse(source); err == nil {
+			t.Errorf("%q was parsed", source)
+		}
+	}
+}
+
+func TestDecay(t *testing.T) {
+	for _, test := range []struct {
+		source string
+		want   float64
+	}{
+		{"decay_exp(0, 10)", 1},
+		{"decay_exp(10, 10)", 0.5},
+		{"decay_exp(-20, 10)", 0.25},
+		{"decay_gauss(0, 10)", 1},
+		{"decay_gauss(10, 10)", 0.5},
+		{"decay_gauss(20, 10)", 0.0625},
+		{"decay_linear(10, 10)", 0.5},
+		{"decay_linear(-5, 10)", 0.75},
+		{"decay_linear(30, 10)", 0},
+		{"-distance * decay_exp(now - published, 7 * 86400)", -1},
+	} {
+		f, err := Parse(test.source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.Eval(func(name string) (float64, bool) {
+			value, ok := map[string]float64{"distance": 2, "now": 8 * 86400, "published": 86400}[name]
+			return value, ok
+		})
+		if err != nil || math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s = %v, %v - want %v", test.source, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,505 @@
+// Package Fsck checks the files of a collection against its CollectionConfig and repairs the meta files
+package Fsck
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/FileMapper"
+	"VreeDB/Format"
+	"VreeDB/Utils"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// maxProblems is the number of problems that are listed per segment, the ProblemCount holds all of them
+const maxProblems = 100
+
+// The kinds of problems
+const (
+	ProblemHeader        = "header"
+	ProblemFormat        = "format_version"
+	ProblemRecord        = "meta_record"
+	ProblemTornRecord    = "torn_record"
+	ProblemOutOfRange    = "out_of_range"
+	ProblemUndecodable   = "undecodable"
+	ProblemDuplicateID   = "duplicate_id"
+	ProblemUnknownField  = "unknown_field"
+	ProblemMissingFile   = "missing_file"
+	ProblemOrphanedFile  = "orphaned_file"
+	ProblemRepairFailure = "repair_failed"
+)
+
+// Report is the result of the check of a collection
+type Report struct {
+	Collection    string           `json:"collection"`
+	FormatVersion int              `json:"format_version"`
+	Ok            bool             `json:"ok"`
+	Repaired      bool             `json:"repaired"`
+	Segments      []*SegmentReport `json:"segments"`
+	Problems      []Problem        `json:"problems,omitempty"`
+	Removed       []string         `json:"removed,omitempty"` // IDs without a valid entry left after the repair
+}
+
+// SegmentReport is the result of the check of the files of one segment
+type SegmentReport struct {
+	Key             string    `json:"key"`
+	DataSize        int64     `json:"data_size"`
+	Records         int       `json:"records"`
+	Vectors         int       `json:"vectors"`
+	Deleted         int       `json:"deleted"`
+	OrphanedBytes   int64     `json:"orphaned_bytes"` // bytes of the data file no vector refers to, e.g. of deleted vectors
+	OrphanedRegions int       `json:"orphaned_regions"`
+	TornBytes       int64     `json:"torn_bytes"`
+	ProblemCount    int       `json:"problem_count"`
+	Problems        []Problem `json:"problems,omitempty"`
+	Repaired        bool      `json:"repaired"`
+}
+
+// Problem is a single finding of the check
+type Problem struct {
+	Kind     string `json:"kind"`
+	VectorID string `json:"vector_id,omitempty"`
+	Offset   int64  `json:"offset,omitempty"`
+	Message  string `json:"message"`
+}
+
+// segment holds the state of the check of one segment
+type segment struct {
+	report *SegmentReport
+	data   []byte
+	// The valid live entries of the segment and the index of their record
+	live map[string]FileMapper.SaveVector
+	pos  map[string]int
+	// The entries that have to be dropped from the meta file
+	invalid map[string]bool
+	dirty   bool
+	// The meta file cannot be read at all, it is not rewritten
+	broken bool
+}
+
+// region is a range of the data file that belongs to a vector
+type region struct {
+	start int64
+	end   int64
+}
+
+// Check checks the files of all segments of a collection. Every meta entry has to point to decodable data inside the
+// data file, IDs may only be live in one segment and the headers have to match the config. With repair the meta files
+// of the segments with problems are rewritten with their valid entries only - the data files are left untouched.
+func Check(config Utils.CollectionConfig, repair bool) *Report {
+	report := &Report{Collection: config.Name, FormatVersion: config.FormatVersion}
+	if config.FormatVersion != Format.Version {
+		report.Problems = append(report.Problems, Problem{Kind: ProblemFormat,
+			Message: fmt.Sprintf("format version %d, this build checks version %d", config.FormatVersion, Format.Version)})
+		return report
+	}
+
+	// Collections from before the segments have only one segment - the files of the collection itself
+	keys := config.Segments
+	if len(keys) == 0 {
+		keys = []string{config.Name}
+	}
+	segments := make([]*segment, len(keys))
+	for i, key := range keys {
+		segments[i] = checkSegment(key, config)
+		report.Segments = append(report.Segments, segments[i].report)
+	}
+
+	// An ID may only be live in one segment - on boot the later segment wins, so the earlier entries are dropped
+	owner := make(map[string]int)
+	for i, s := range segments {
+		for _, id := range s.ids() {
+			if j, ok := owner[id]; ok {
+				segments[j].problem(Problem{Kind: ProblemDuplicateID, VectorID: id,
+					Message: "vector is also live in segment " + s.report.Key})
+				segments[j].drop(id)
+			}
+			owner[id] = i
+		}
+	}
+
+	// Files of segments that are not part of the collection
+	report.Problems = append(report.Problems, orphanedFiles(config.Name, keys)...)
+
+	if repair {
+		report.repair(segments, owner, config.VectorDimension)
+	}
+	report.Ok = len(report.Problems) == 0
+	for _, s := range segments {
+		if s.report.ProblemCount > 0 {
+			report.Ok = false
+		}
+	}
+	return report
+}
+
+// repair rewrites the meta files of all segments with problems
+func (r *Report) repair(segments []*segment, owner map[string]int, dimension int) {
+	removed := make(map[string]bool)
+	for _, s := range segments {
+		if !s.dirty {
+			continue
+		}
+		if s.broken {
+			s.problem(Problem{Kind: ProblemRepairFailure, Message: "the meta file cannot be read, it is left untouched"})
+			continue
+		}
+		ids := s.ids()
+		vectors := make([]FileMapper.SaveVector, 0, len(ids))
+		for _, id := range ids {
+			vectors = append(vectors, s.live[id])
+		}
+		err := FileMapper.WriteMetaFile(*ArgsParser.Ap.FileStore+s.report.Key+"_meta.bin", dimension, vectors)
+		if err != nil {
+			s.problem(Problem{Kind: ProblemRepairFailure, Message: err.Error()})
+			continue
+		}
+		s.report.Repaired = true
+		r.Repaired = true
+		for id := range s.invalid {
+			if _, ok := owner[id]; !ok {
+				removed[id] = true
+			}
+		}
+	}
+	for id := range removed {
+		r.Removed = append(r.Removed, id)
+	}
+	sort.Strings(r.Removed)
+}
+
+// checkSegment reads the data and the meta file of a segment and checks all entries
+func checkSegment(key string, config Utils.CollectionConfig) *segment {
+	s := &segment{report: &SegmentReport{Key: key}, live: make(map[string]FileMapper.SaveVector),
+		pos: make(map[string]int), invalid: make(map[string]bool)}
+
+	// The data file
+	data, err := os.ReadFile(*ArgsParser.Ap.FileStore + key + ".bin")
+	if err != nil {
+		s.problem(Problem{Kind: ProblemMissingFile, Message: err.Error()})
+		return s
+	}
+	s.data = data
+	s.report.DataSize = int64(len(data))
+	header, err := Format.DecodeHeader(data)
+	if err == nil {
+		err = header.Check(Format.KindData, config.VectorDimension)
+	}
+	if err != nil {
+		s.problem(Problem{Kind: ProblemHeader, Message: "data file: " + err.Error()})
+	}
+
+	// The meta file - the last record of an ID wins
+	records, err := s.readMeta(key, config.VectorDimension)
+	if err != nil {
+		s.problem(Problem{Kind: ProblemMissingFile, Message: err.Error()})
+		return s
+	}
+	for i, sv := range records {
+		if sv.DataStart < 0 {
+			delete(s.live, sv.VectorID)
+			s.report.Deleted++
+			continue
+		}
+		if _, ok := s.live[sv.VectorID]; ok {
+			// Insert refuses existing IDs, a second live entry without a delete in between is a duplicate
+			s.problem(Problem{Kind: ProblemDuplicateID, VectorID: sv.VectorID,
+				Message: "vector has a second entry without a delete in between"})
+			s.dirty = true
+		}
+		s.live[sv.VectorID] = sv
+		s.pos[sv.VectorID] = i
+	}
+
+	// Check the entries and collect the regions of the data file they use
+	var regions []region
+	for _, id := range s.ids() {
+		used, err := s.checkEntry(s.live[id], config)
+		if err != nil {
+			s.drop(id)
+			s.invalid[id] = true
+			continue
+		}
+		regions = append(regions, used...)
+	}
+	s.report.Vectors = len(s.live)
+	s.orphaned(regions)
+	return s
+}
+
+// readMeta reads the records of the meta file, records behind a broken record cannot be read
+func (s *segment) readMeta(key string, dimension int) ([]FileMapper.SaveVector, error) {
+	path := *ArgsParser.Ap.FileStore + key + "_meta.bin"
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// A segment without vectors has no meta file
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return nil, err
+	}
+
+	header, err := Format.ReadHeader(file)
+	if err == nil {
+		err = header.Check(Format.KindMeta, dimension)
+	}
+	if err != nil {
+		s.problem(Problem{Kind: ProblemHeader, Message: "meta file: " + err.Error()})
+		s.dirty = true
+		if errors.Is(err, Format.ErrNoHeader) || errors.Is(err, Format.ErrNewerVersion) {
+			s.broken = true
+			return nil, nil
+		}
+	}
+
+	var records []FileMapper.SaveVector
+	offset := int64(Format.HeaderSize)
+	r := bufio.NewReader(file)
+	for {
+		body, n, err := Format.ReadRecord(r)
+		if err == io.EOF {
+			return records, nil
+		} else if err == Format.ErrTornRecord {
+			s.report.TornBytes = info.Size() - offset
+			s.problem(Problem{Kind: ProblemTornRecord, Offset: offset,
+				Message: fmt.Sprintf("%d bytes of an incomplete record", s.report.TornBytes)})
+			s.dirty = true
+			return records, nil
+		} else if err != nil {
+			s.problem(Problem{Kind: ProblemRecord, Offset: offset,
+				Message: fmt.Sprintf("%s, %d bytes behind it cannot be read", err.Error(), info.Size()-offset)})
+			s.dirty = true
+			return records, nil
+		}
+		sv, err := FileMapper.DecodeSaveVector(body)
+		if err != nil {
+			s.problem(Problem{Kind: ProblemRecord, Offset: offset, Message: err.Error()})
+			s.dirty = true
+		} else {
+			records = append(records, sv)
+		}
+		s.report.Records++
+		offset += n
+	}
+}
+
+// checkEntry checks that all parts of a vector are inside the data file and can be decoded, it returns the regions
+// of the data file the vector uses
+func (s *segment) checkEntry(sv FileMapper.SaveVector, config Utils.CollectionConfig) ([]region, error) {
+	var regions []region
+	fail := func(kind string, offset int64, format string, args ...interface{}) ([]region, error) {
+		err := fmt.Errorf(format, args...)
+		s.problem(Problem{Kind: kind, VectorID: sv.VectorID, Offset: offset, Message: err.Error()})
+		return nil, err
+	}
+	vector := func(name string, start int64, dimension int) error {
+		if !s.inRange(start, int64(dimension)*8) {
+			_, err := fail(ProblemOutOfRange, start, "%s of %d values exceeds the data file", name, dimension)
+			return err
+		}
+		regions = append(regions, region{start: start, end: start + int64(dimension)*8})
+		return nil
+	}
+
+	if err := vector("vector", sv.DataStart, config.VectorDimension); err != nil {
+		return nil, err
+	}
+
+	// The payload is a gob stream, its length is what the decoder consumed
+	if !s.inRange(sv.PayloadStart, 1) {
+		return fail(ProblemOutOfRange, sv.PayloadStart, "payload exceeds the data file")
+	}
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	reader := bytes.NewReader(s.data[sv.PayloadStart:])
+	var payload map[string]interface{}
+	if err := gob.NewDecoder(reader).Decode(&payload); err != nil {
+		return fail(ProblemUndecodable, sv.PayloadStart, "payload: %s", err.Error())
+	}
+	regions = append(regions, region{start: sv.PayloadStart, end: int64(len(s.data)) - int64(reader.Len())})
+
+	for name, start := range sv.SparseStart {
+		if !s.inRange(start, 4) {
+			return fail(ProblemOutOfRange, start, "sparse vector %s exceeds the data file", name)
+		}
+		n := int64(binary.LittleEndian.Uint32(s.data[start : start+4]))
+		if !s.inRange(start, 4+n*12) {
+			return fail(ProblemUndecodable, start, "sparse vector %s of %d entries exceeds the data file", name, n)
+		}
+		regions = append(regions, region{start: start, end: start + 4 + n*12})
+	}
+	for name, start := range sv.VectorStart {
+		field, ok := config.VectorFields[name]
+		if !ok {
+			s.problem(Problem{Kind: ProblemUnknownField, VectorID: sv.VectorID, Message: "vector field " + name})
+			continue
+		}
+		if err := vector("vector "+name, start, field.VectorDimension); err != nil {
+			return nil, err
+		}
+	}
+	for name, starts := range sv.MultiStart {
+		field, ok := config.MultiFields[name]
+		if !ok {
+			s.problem(Problem{Kind: ProblemUnknownField, VectorID: sv.VectorID, Message: "multi vector field " + name})
+			continue
+		}
+		for _, start := range starts {
+			if err := vector("multi vector "+name, start, field.VectorDimension); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for name, start := range sv.BinaryStart {
+		field, ok := config.BinaryFields[name]
+		if !ok {
+			s.problem(Problem{Kind: ProblemUnknownField, VectorID: sv.VectorID, Message: "binary vector field " + name})
+			continue
+		}
+		words := int64(field.VectorDimension+63) / 64
+		if !s.inRange(start, words*8) {
+			return fail(ProblemOutOfRange, start, "binary vector %s exceeds the data file", name)
+		}
+		regions = append(regions, region{start: start, end: start + words*8})
+		if rescoreStart, ok := sv.RescoreStart[name]; ok {
+			if err := vector("rescore vector "+name, rescoreStart, field.VectorDimension); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return regions, nil
+}
+
+// inRange returns true if n bytes at start are inside the data area of the data file
+func (s *segment) inRange(start int64, n int64) bool {
+	return start >= Format.HeaderSize && n >= 0 && start+n <= int64(len(s.data))
+}
+
+// orphaned counts the bytes of the data file that are not used by any valid entry
+func (s *segment) orphaned(regions []region) {
+	if len(s.data) <= Format.HeaderSize {
+		return
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].start < regions[j].start
+	})
+	pos := int64(Format.HeaderSize)
+	for _, r := range regions {
+		if r.start > pos {
+			s.report.OrphanedBytes += r.start - pos
+			s.report.OrphanedRegions++
+		}
+		if r.end > pos {
+			pos = r.end
+		}
+	}
+	if size := int64(len(s.data)); size > pos {
+		s.report.OrphanedBytes += size - pos
+		s.report.OrphanedRegions++
+	}
+}
+
+// problem adds a problem to the report of the segment
+func (s *segment) problem(p Problem) {
+	s.report.ProblemCount++
+	if len(s.report.Problems) < maxProblems {
+		s.report.Problems = append(s.report.Problems, p)
+	}
+}
+
+// ids returns the IDs of the live entries in the order of their records
+func (s *segment) ids() []string {
+	ids := make([]string, 0, len(s.live))
+	for id := range s.live {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return s.pos[ids[i]] < s.pos[ids[j]]
+	})
+	return ids
+}
+
+// drop removes an ID from the live entries, the meta file has to be rewritten
+func (s *segment) drop(id string) {
+	delete(s.live, id)
+	s.dirty = true
+	s.report.Vectors = len(s.live)
+}
+
+// orphanedFiles returns a problem for every segment file of the collection that is not one of its segments
+func orphanedFiles(name string, keys []string) []Problem {
+	entries, err := os.ReadDir(*ArgsParser.Ap.FileStore)
+	if err != nil {
+		return []Problem{{Kind: ProblemMissingFile, Message: err.Error()}}
+	}
+	known := make(map[string]bool)
+	for _, key := range keys {
+		known[key+".bin"] = true
+		known[key+"_meta.bin"] = true
+	}
+	var problems []Problem
+	for _, entry := range entries {
+		file := entry.Name()
+		if known[file] || !isSegmentFile(name, file) {
+			continue
+		}
+		problems = append(problems, Problem{Kind: ProblemOrphanedFile, Message: file + " is not a segment of the collection"})
+	}
+	return problems
+}
+
+// isSegmentFile returns true if the file is a data or a meta file of a segment of the collection
+func isSegmentFile(name string, file string) bool {
+	if !strings.HasPrefix(file, name+"_seg") {
+		return false
+	}
+	n := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(file, name+"_seg"), ".bin"), "_meta")
+	if n == "" || !strings.HasSuffix(file, ".bin") {
+		return false
+	}
+	for _, r := range n {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Run checks all collections of the file store, it is used by the -fsck mode before the collections are restored
+func Run(repair bool) ([]*Report, error) {
+	entries, err := os.ReadDir(*ArgsParser.Ap.FileStore)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]*Report, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(*ArgsParser.Ap.FileStore + entry.Name())
+		if err != nil {
+			return reports, err
+		}
+		config := Utils.CollectionConfig{}
+		err = json.Unmarshal(data, &config)
+		if err != nil {
+			reports = append(reports, &Report{Collection: strings.TrimSuffix(entry.Name(), ".json"),
+				Problems: []Problem{{Kind: ProblemHeader, Message: "config: " + err.Error()}}})
+			continue
+		}
+		reports = append(reports, Check(config, repair))
+	}
+	return reports, nil
+}
@@ -0,0 +1,140 @@
+package Fsck
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/FileMapper"
+	"VreeDB/Format"
+	"VreeDB/Utils"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// writeTestSegment writes the data file of a segment with one vector and its payload followed by 8 bytes no entry
+// refers to, and a meta file of the given entries. The vector starts at Format.HeaderSize, its payload 16 bytes later.
+func writeTestSegment(t *testing.T, key string, entries []FileMapper.SaveVector) {
+	t.Helper()
+	store := *ArgsParser.Ap.FileStore
+	if err := os.MkdirAll(store, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.NewBuffer(Format.NewHeader(Format.KindData, 2).Encode())
+	binary.Write(data, binary.LittleEndian, []float64{1, 2})
+	payload := map[string]interface{}{"n": 1.0}
+	if err := gob.NewEncoder(data).Encode(&payload); err != nil {
+		t.Fatal(err)
+	}
+	data.Write(bytes.Repeat([]byte{0xff}, 8))
+	if err := os.WriteFile(store+key+".bin", data.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := FileMapper.WriteMetaFile(store+key+"_meta.bin", 2, entries); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Remove(store + key + ".bin")
+		os.Remove(store + key + "_meta.bin")
+	})
+}
+
+// problemKinds returns the kinds of the problems of a segment
+func problemKinds(s *SegmentReport) map[string]bool {
+	kinds := make(map[string]bool)
+	for _, p := range s.Problems {
+		kinds[p.Kind] = true
+	}
+	return kinds
+}
+
+func TestCheckFindsAndRepairsBrokenEntries(t *testing.T) {
+	const vector, payload = Format.HeaderSize, Format.HeaderSize + 16
+	writeTestSegment(t, "fsck_seg0", []FileMapper.SaveVector{
+		{VectorID: "a", DataStart: vector, PayloadStart: payload},
+		{VectorID: "b", DataStart: 1000, PayloadStart: payload},
+		{VectorID: "d", DataStart: vector, PayloadStart: payload},
+		{VectorID: "d", DataStart: -1},
+		{VectorID: "e", DataStart: vector, PayloadStart: payload},
+	})
+	size := 0
+	if data, err := os.ReadFile(*ArgsParser.Ap.FileStore + "fsck_seg0.bin"); err == nil {
+		size = len(data)
+	}
+	writeTestSegment(t, "fsck_seg1", []FileMapper.SaveVector{
+		{VectorID: "a", DataStart: vector, PayloadStart: payload},
+		// The payload of c starts in the bytes no entry refers to
+		{VectorID: "c", DataStart: vector, PayloadStart: int64(size - 8)},
+	})
+	// A crash left half a record behind
+	meta, err := os.OpenFile(*ArgsParser.Ap.FileStore+"fsck_seg0_meta.bin", os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.Write(Format.EncodeRecord([]byte("torn"))[:6])
+	meta.Close()
+	// A segment file that is not part of the collection
+	orphan := *ArgsParser.Ap.FileStore + "fsck_seg7.bin"
+	os.WriteFile(orphan, nil, 0644)
+	defer os.Remove(orphan)
+
+	config := Utils.CollectionConfig{Name: "fsck", VectorDimension: 2, FormatVersion: Format.Version,
+		Segments: []string{"fsck_seg0", "fsck_seg1"}}
+	before, _ := os.ReadFile(*ArgsParser.Ap.FileStore + "fsck_seg0_meta.bin")
+	report := Check(config, false)
+	if report.Ok || report.Repaired {
+		t.Fatalf("the report is ok %v, repaired %v", report.Ok, report.Repaired)
+	}
+	seg0, seg1 := report.Segments[0], report.Segments[1]
+	want := map[string]bool{ProblemOutOfRange: true, ProblemTornRecord: true, ProblemDuplicateID: true}
+	if !reflect.DeepEqual(problemKinds(seg0), want) {
+		t.Errorf("segment 0 has the problems %v", seg0.Problems)
+	}
+	if !reflect.DeepEqual(problemKinds(seg1), map[string]bool{ProblemUndecodable: true}) {
+		t.Errorf("segment 1 has the problems %v", seg1.Problems)
+	}
+	if seg0.Records != 5 || seg0.Deleted != 1 || seg0.Vectors != 1 || seg0.TornBytes != 6 {
+		t.Errorf("segment 0 has %d records, %d deleted, %d vectors and %d torn bytes", seg0.Records, seg0.Deleted,
+			seg0.Vectors, seg0.TornBytes)
+	}
+	if seg0.OrphanedBytes != 8 || seg0.OrphanedRegions != 1 {
+		t.Errorf("segment 0 has %d orphaned bytes in %d regions", seg0.OrphanedBytes, seg0.OrphanedRegions)
+	}
+	if len(report.Problems) != 1 || report.Problems[0].Kind != ProblemOrphanedFile {
+		t.Errorf("the collection has the problems %v", report.Problems)
+	}
+	if after, _ := os.ReadFile(*ArgsParser.Ap.FileStore + "fsck_seg0_meta.bin"); !bytes.Equal(before, after) {
+		t.Fatal("the check without repair changed the meta file")
+	}
+
+	report = Check(config, true)
+	if !report.Repaired || !reflect.DeepEqual(report.Removed, []string{"b", "c"}) {
+		t.Fatalf("repaired %v, removed %v", report.Repaired, report.Removed)
+	}
+	for key, want := range map[string][]string{"fsck_seg0": {"e"}, "fsck_seg1": {"a"}} {
+		vectors, _, err := FileMapper.ReadMetaFile(*ArgsParser.Ap.FileStore+key+"_meta.bin", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, len(vectors))
+		for i, sv := range vectors {
+			ids[i] = sv.VectorID
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("the repaired meta file of %s holds %v, want %v", key, ids, want)
+		}
+	}
+
+	os.Remove(orphan)
+	if report = Check(config, false); !report.Ok {
+		t.Errorf("the repaired collection has problems: %+v %+v", report.Segments[0], report.Segments[1])
+	}
+}
+
+func TestCheckRefusesOtherFormatVersions(t *testing.T) {
+	report := Check(Utils.CollectionConfig{Name: "fsckold", VectorDimension: 2}, true)
+	if report.Ok || len(report.Problems) != 1 || report.Problems[0].Kind != ProblemFormat || report.Repaired {
+		t.Errorf("a legacy collection gives %+v", report)
+	}
+}
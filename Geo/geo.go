@@ -0,0 +1,211 @@
+// Package Geo holds the geo points of the payloads, the shapes of the geo filters and the geohashes of the geo
+// indexes. A geo point is a payload object with a latitude and a longitude in degrees:
+//
+//	{"location": {"lat": 52.52, "lon": 13.40}}
+package Geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// earthRadius is the mean radius of the earth in meters
+const earthRadius = 6371008.8
+
+// Point is a position on the earth in degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Box is a latitude and longitude range, a box that crosses the antimeridian has MinLon > MaxLon
+type Box struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+}
+
+// ParsePoint reads a geo point from a payload or filter value, it returns false if the value is no valid geo point
+func ParsePoint(value interface{}) (Point, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return Point{}, false
+	}
+	lat, ok := number(m["lat"])
+	if !ok || lat < -90 || lat > 90 {
+		return Point{}, false
+	}
+	lon, ok := number(m["lon"])
+	if !ok || lon < -180 || lon > 180 {
+		return Point{}, false
+	}
+	return Point{Lat: lat, Lon: lon}, true
+}
+
+// Distance returns the great circle distance of two points in meters
+func Distance(a, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Shape is the area of a geo filter
+type Shape interface {
+	Contains(p Point) bool
+	Bounds() Box
+}
+
+// Radius is the area within Meters of the Center
+type Radius struct {
+	Center Point
+	Meters float64
+}
+
+// Contains returns true if the point is within the radius
+func (r *Radius) Contains(p Point) bool {
+	return Distance(r.Center, p) <= r.Meters
+}
+
+// Bounds returns the box around the circle, a circle over a pole spans all longitudes
+func (r *Radius) Bounds() Box {
+	dLat := r.Meters / earthRadius * 180 / math.Pi
+	box := Box{MinLat: r.Center.Lat - dLat, MaxLat: r.Center.Lat + dLat, MinLon: -180, MaxLon: 180}
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		box.MinLat, box.MaxLat = math.Max(box.MinLat, -90), math.Min(box.MaxLat, 90)
+		return box
+	}
+	dLon := math.Asin(math.Min(1, math.Sin(r.Meters/earthRadius)/math.Cos(r.Center.Lat*math.Pi/180))) * 180 / math.Pi
+	if dLon >= 180 {
+		return box
+	}
+	box.MinLon, box.MaxLon = wrap(r.Center.Lon-dLon), wrap(r.Center.Lon+dLon)
+	return box
+}
+
+// Contains returns true if the point is within the box
+func (b *Box) Contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+	}
+	return p.Lon >= b.MinLon || p.Lon <= b.MaxLon
+}
+
+// Bounds returns the box itself
+func (b *Box) Bounds() Box {
+	return *b
+}
+
+// Polygon is the area within a closed ring of points, edges are straight lines in degrees
+type Polygon struct {
+	Points []Point
+}
+
+// Contains returns true if the point is within the polygon - a ray cast that counts the crossed edges
+func (g *Polygon) Contains(p Point) bool {
+	inside := false
+	for i, j := 0, len(g.Points)-1; i < len(g.Points); j, i = i, i+1 {
+		a, b := g.Points[i], g.Points[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) && p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Bounds returns the box around the points of the polygon
+func (g *Polygon) Bounds() Box {
+	box := Box{MinLat: 90, MinLon: 180, MaxLat: -90, MaxLon: -180}
+	for _, p := range g.Points {
+		box.MinLat, box.MaxLat = math.Min(box.MinLat, p.Lat), math.Max(box.MaxLat, p.Lat)
+		box.MinLon, box.MaxLon = math.Min(box.MinLon, p.Lon), math.Max(box.MaxLon, p.Lon)
+	}
+	return box
+}
+
+// ParseRadius reads a radius filter value: {"center": {"lat": .., "lon": ..}, "radius": meters}
+func ParseRadius(value interface{}) (*Radius, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("geo_radius needs a center and a radius")
+	}
+	center, ok := ParsePoint(m["center"])
+	if !ok {
+		return nil, fmt.Errorf("geo_radius needs a center with lat and lon")
+	}
+	meters, ok := number(m["radius"])
+	if !ok || meters < 0 {
+		return nil, fmt.Errorf("geo_radius needs a radius in meters")
+	}
+	return &Radius{Center: center, Meters: meters}, nil
+}
+
+// ParseBox reads a bounding box filter value: {"top_left": {"lat": .., "lon": ..}, "bottom_right": {...}}. A box whose
+// left longitude is east of its right one crosses the antimeridian.
+func ParseBox(value interface{}) (*Box, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("geo_bounding_box needs a top_left and a bottom_right point")
+	}
+	topLeft, ok := ParsePoint(m["top_left"])
+	if !ok {
+		return nil, fmt.Errorf("geo_bounding_box needs a top_left point with lat and lon")
+	}
+	bottomRight, ok := ParsePoint(m["bottom_right"])
+	if !ok {
+		return nil, fmt.Errorf("geo_bounding_box needs a bottom_right point with lat and lon")
+	}
+	if topLeft.Lat < bottomRight.Lat {
+		return nil, fmt.Errorf("geo_bounding_box top_left is south of bottom_right")
+	}
+	return &Box{MinLat: bottomRight.Lat, MinLon: topLeft.Lon, MaxLat: topLeft.Lat, MaxLon: bottomRight.Lon}, nil
+}
+
+// ParsePolygon reads a polygon filter value: {"points": [{"lat": .., "lon": ..}, ...]} with at least three points
+func ParsePolygon(value interface{}) (*Polygon, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("geo_polygon needs points")
+	}
+	items, ok := m["points"].([]interface{})
+	if !ok || len(items) < 3 {
+		return nil, fmt.Errorf("geo_polygon needs at least three points")
+	}
+	polygon := &Polygon{Points: make([]Point, len(items))}
+	for i, item := range items {
+		p, ok := ParsePoint(item)
+		if !ok {
+			return nil, fmt.Errorf("geo_polygon point %d needs lat and lon", i)
+		}
+		polygon.Points[i] = p
+	}
+	return polygon, nil
+}
+
+// wrap moves a longitude into -180..180
+func wrap(lon float64) float64 {
+	switch {
+	case lon < -180:
+		return lon + 360
+	case lon > 180:
+		return lon - 360
+	}
+	return lon
+}
+
+// number returns the value as float64 if it is a number
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
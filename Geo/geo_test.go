@@ -0,0 +1,175 @@
+package Geo
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParsePoint(t *testing.T) {
+	for _, test := range []struct {
+		value interface{}
+		ok    bool
+	}{
+		{map[string]interface{}{"lat": 52.52, "lon": 13.4}, true},
+		{map[string]interface{}{"lat": -90.0, "lon": 180}, true},
+		{map[string]interface{}{"lat": 91.0, "lon": 0.0}, false},
+		{map[string]interface{}{"lat": 0.0, "lon": -181.0}, false},
+		{map[string]interface{}{"lat": "52", "lon": 13.4}, false},
+		{map[string]interface{}{"lat": 52.52}, false},
+		{[]interface{}{52.52, 13.4}, false},
+	} {
+		if _, ok := ParsePoint(test.value); ok != test.ok {
+			t.Errorf("ParsePoint(%v) = %v", test.value, ok)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	berlin, paris := Point{Lat: 52.5200, Lon: 13.4050}, Point{Lat: 48.8566, Lon: 2.3522}
+	if d := Distance(berlin, paris); math.Abs(d-877500) > 2000 {
+		t.Errorf("Berlin to Paris is %v m", d)
+	}
+	if d := Distance(berlin, berlin); d != 0 {
+		t.Errorf("Berlin to Berlin is %v m", d)
+	}
+	// Across the antimeridian
+	if d := Distance(Point{Lon: 179.5}, Point{Lon: -179.5}); math.Abs(d-111195) > 100 {
+		t.Errorf("one degree at the equator is %v m", d)
+	}
+}
+
+func TestShapes(t *testing.T) {
+	radius := &Radius{Center: Point{Lat: 52.52, Lon: 13.405}, Meters: 5000}
+	box := &Box{MinLat: -10, MinLon: 170, MaxLat: 10, MaxLon: -170}
+	polygon := &Polygon{Points: []Point{{0, 0}, {0, 10}, {10, 10}, {10, 0}}}
+	for _, test := range []struct {
+		shape Shape
+		point Point
+		want  bool
+	}{
+		{radius, Point{Lat: 52.53, Lon: 13.41}, true},
+		{radius, Point{Lat: 52.60, Lon: 13.405}, false},
+		{box, Point{Lat: 0, Lon: 175}, true},
+		{box, Point{Lat: 0, Lon: -175}, true},
+		{box, Point{Lat: 0, Lon: 0}, false},
+		{box, Point{Lat: 11, Lon: 180}, false},
+		{polygon, Point{Lat: 5, Lon: 5}, true},
+		{polygon, Point{Lat: 5, Lon: 11}, false},
+		{polygon, Point{Lat: -1, Lon: 5}, false},
+	} {
+		if got := test.shape.Contains(test.point); got != test.want {
+			t.Errorf("%+v contains %+v: %v", test.shape, test.point, got)
+		}
+		// Every point of a shape is within its bounds
+		if bounds := test.shape.Bounds(); test.want && !bounds.Contains(test.point) {
+			t.Errorf("the bounds %+v of %+v miss %+v", bounds, test.shape, test.point)
+		}
+	}
+
+	// A radius near the antimeridian wraps its bounds, a radius over a pole spans all longitudes
+	if b := (&Radius{Center: Point{Lon: 179.99}, Meters: 5000}).Bounds(); b.MinLon < b.MaxLon {
+		t.Errorf("the bounds %+v do not cross the antimeridian", b)
+	}
+	if b := (&Radius{Center: Point{Lat: 89.99}, Meters: 5000}).Bounds(); b.MinLon != -180 || b.MaxLon != 180 ||
+		b.MaxLat != 90 {
+		t.Errorf("the bounds %+v do not cover the pole", b)
+	}
+}
+
+func TestParseShapes(t *testing.T) {
+	p := func(lat, lon float64) map[string]interface{} {
+		return map[string]interface{}{"lat": lat, "lon": lon}
+	}
+	if r, err := ParseRadius(map[string]interface{}{"center": p(1, 2), "radius": 300.0}); err != nil ||
+		*r != (Radius{Center: Point{1, 2}, Meters: 300}) {
+		t.Errorf("ParseRadius = %+v, %v", r, err)
+	}
+	if b, err := ParseBox(map[string]interface{}{"top_left": p(10, 170), "bottom_right": p(-10, -170)}); err != nil ||
+		*b != (Box{MinLat: -10, MinLon: 170, MaxLat: 10, MaxLon: -170}) {
+		t.Errorf("ParseBox = %+v, %v", b, err)
+	}
+	if g, err := ParsePolygon(map[string]interface{}{"points": []interface{}{p(0, 0), p(0, 1), p(1, 1)}}); err != nil ||
+		len(g.Points) != 3 {
+		t.Errorf("ParsePolygon = %+v, %v", g, err)
+	}
+
+	for name, err := range map[string]error{
+		"radius without center": func() error {
+			_, err := ParseRadius(map[string]interface{}{"radius": 300.0})
+			return err
+		}(),
+		"negative radius": func() error {
+			_, err := ParseRadius(map[string]interface{}{"center": p(1, 2), "radius": -1.0})
+			return err
+		}(),
+		"box upside down": func() error {
+			_, err := ParseBox(map[string]interface{}{"top_left": p(-10, 0), "bottom_right": p(10, 10)})
+			return err
+		}(),
+		"box without corner": func() error {
+			_, err := ParseBox(map[string]interface{}{"top_left": p(10, 0)})
+			return err
+		}(),
+		"polygon of two points": func() error {
+			_, err := ParsePolygon(map[string]interface{}{"points": []interface{}{p(0, 0), p(1, 1)}})
+			return err
+		}(),
+		"polygon with a text point": func() error {
+			_, err := ParsePolygon(map[string]interface{}{"points": []interface{}{p(0, 0), p(1, 1), "x"}})
+			return err
+		}(),
+	} {
+		if err == nil {
+			t.Errorf("the %s was parsed", name)
+		}
+	}
+}
+
+func TestGeohash(t *testing.T) {
+	p := Point{Lat: 57.64911, Lon: 10.40744}
+	if got := Geohash(p, 11); got != "u4pruydqqvj" {
+		t.Errorf("Geohash = %s", got)
+	}
+	if got := Geohash(p, 3); got != "u4p" {
+		t.Errorf("Geohash with 3 characters = %s", got)
+	}
+}
+
+func TestCover(t *testing.T) {
+	box := Box{MinLat: 57.6, MinLon: 10.3, MaxLat: 57.7, MaxLon: 10.5}
+	cells, precision := Cover(box, 5, 100)
+	if precision != 5 || len(cells) == 0 {
+		t.Fatalf("%d cells with precision %d", len(cells), precision)
+	}
+	for _, p := range []Point{{57.6, 10.3}, {57.64911, 10.40744}, {57.7, 10.5}} {
+		hash := Geohash(p, precision)
+		found := false
+		for _, cell := range cells {
+			found = found || cell == hash
+		}
+		if !found {
+			t.Errorf("the cell %s of %+v is not covered", hash, p)
+		}
+	}
+
+	// Too many cells lower the precision
+	if cells, precision := Cover(box, 8, 10); precision >= 8 || len(cells) > 10 {
+		t.Errorf("%d cells with precision %d", len(cells), precision)
+	}
+	if cells, precision := Cover(Box{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180}, 3, 10); precision != 0 ||
+		cells != nil {
+		t.Errorf("the world is covered by %d cells with precision %d", len(cells), precision)
+	}
+
+	// A box over the antimeridian covers both edges
+	cells, _ = Cover(Box{MinLat: 0, MinLon: 179, MaxLat: 1, MaxLon: -179}, 2, 100)
+	edges := map[string]bool{}
+	for _, cell := range cells {
+		edges[cell] = true
+	}
+	if !edges[Geohash(Point{0.5, 179.5}, 2)] || !edges[Geohash(Point{0.5, -179.5}, 2)] ||
+		edges[Geohash(Point{0.5, 0}, 2)] {
+		t.Errorf("the box over the antimeridian is covered by %s", strings.Join(cells, " "))
+	}
+}
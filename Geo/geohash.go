@@ -0,0 +1,82 @@
+package Geo
+
+import "math"
+
+const (
+	// base32 is the alphabet of the geohashes
+	base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+	// MaxPrecision is the longest geohash, 12 characters are a few centimeters
+	MaxPrecision = 12
+)
+
+// Geohash returns the geohash of the point with precision characters
+func Geohash(p Point, precision int) string {
+	latBits, lonBits := cellBits(precision)
+	return encode(cellIndex(p.Lat, -90, 180, latBits), cellIndex(p.Lon, -180, 360, lonBits), precision)
+}
+
+// Cover returns the geohashes with the given precision of all cells that touch the box. If the box needs more than
+// maxCells cells the precision is lowered until it fits, the returned precision is 0 if even one character does not.
+func Cover(box Box, precision int, maxCells int) ([]string, int) {
+	for ; precision > 0; precision-- {
+		latBits, lonBits := cellBits(precision)
+		minLat, maxLat := cellIndex(box.MinLat, -90, 180, latBits), cellIndex(box.MaxLat, -90, 180, latBits)
+		minLon, maxLon := cellIndex(box.MinLon, -180, 360, lonBits), cellIndex(box.MaxLon, -180, 360, lonBits)
+		// A box over the antimeridian wraps around to the first column
+		columns := maxLon - minLon + 1
+		if box.MinLon > box.MaxLon {
+			columns = (uint64(1)<<lonBits - minLon) + maxLon + 1
+		}
+		if (maxLat-minLat+1)*columns > uint64(maxCells) {
+			continue
+		}
+		cells := make([]string, 0, (maxLat-minLat+1)*columns)
+		for lat := minLat; lat <= maxLat; lat++ {
+			for i := uint64(0); i < columns; i++ {
+				lon := (minLon + i) % (uint64(1) << lonBits)
+				cells = append(cells, encode(lat, lon, precision))
+			}
+		}
+		return cells, precision
+	}
+	return nil, 0
+}
+
+// cellBits returns the latitude and the longitude bits of a geohash, the bits alternate starting with the longitude
+func cellBits(precision int) (uint, uint) {
+	bits := uint(precision) * 5
+	return bits / 2, bits - bits/2
+}
+
+// cellIndex returns the cell of a coordinate on an axis that starts at min and spans span degrees
+func cellIndex(value, min, span float64, bits uint) uint64 {
+	cells := uint64(1) << bits
+	index := uint64(math.Max(0, math.Floor((value-min)/span*float64(cells))))
+	if index >= cells {
+		index = cells - 1
+	}
+	return index
+}
+
+// encode interleaves the cell indexes to a geohash, the highest bit first
+func encode(lat, lon uint64, precision int) string {
+	latBits, lonBits := cellBits(precision)
+	hash := make([]byte, precision)
+	var char byte
+	for i, bits := uint(0), latBits+lonBits; i < bits; i++ {
+		var bit uint64
+		if i%2 == 0 {
+			lonBits--
+			bit = lon >> lonBits & 1
+		} else {
+			latBits--
+			bit = lat >> latBits & 1
+		}
+		char = char<<1 | byte(bit)
+		if i%5 == 4 {
+			hash[i/5] = base32[char]
+			char = 0
+		}
+	}
+	return string(hash)
+}
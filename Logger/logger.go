@@ -3,6 +3,7 @@ package Logger
 import (
 	"VreeDB/ArgsParser"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -11,6 +12,7 @@ type Logger struct {
 	Logfile *os.File
 	In      chan string
 	Quit    chan bool
+	open    sync.Once
 }
 
 // Log is a singleton
@@ -18,13 +20,18 @@ var Log *Logger
 
 // init initializes the Logger - Log is singleton
 func init() {
-	// open the Log file for write access
+	Log = &Logger{In: make(chan string, 100), Quit: make(chan bool)}
+}
+
+// openLogfile opens the Log file at the -loglocation for write access, it is opened by the first message after the
+// command line was parsed
+func (l *Logger) openLogfile() {
 	f, err := os.OpenFile(*ArgsParser.Ap.Loglocation, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	// Panic if there is an error - logfile is critical
 	if err != nil {
 		panic(err)
 	}
-	Log = &Logger{Logfile: f, In: make(chan string, 100), Quit: make(chan bool)}
+	l.Logfile = f
 }
 
 // Start will start the LoggerService
@@ -48,6 +55,7 @@ func (l *Logger) Log(s string) {
 	// write the current date and the time to the log file + the string
 	date := time.Now()
 	s = date.Format("2006-01-02T15:04:05Z07:00") + " " + s + "\n"
+	l.open.Do(l.openLogfile)
 	_, err := l.Logfile.WriteString(s)
 	// panic if there is an error - logfile is critical
 	if err != nil {
@@ -0,0 +1,234 @@
+// Package Schema describes the payload fields of a collection. A schema declares the type of a field, whether it is
+// required, the values it may take and the element type of arrays - a subset of JSON schema:
+//
+//	{"fields": {"label": {"type": "integer", "required": true},
+//	            "color": {"type": "string", "enum": ["red", "green"]},
+//	            "tags":  {"type": "array", "items": "string"},
+//	            "place": {"type": "geo"},
+//	            "published": {"type": "datetime"}}}
+//
+// Fields that are not declared are not checked. Payloads are checked on insert, filters use the schema to convert
+// their literals to the declared types.
+package Schema
+
+import (
+	"VreeDB/Datetime"
+	"VreeDB/Geo"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Types of the payload fields
+const (
+	String   = "string"
+	Number   = "number"
+	Integer  = "integer"
+	Boolean  = "boolean"
+	Object   = "object"
+	Array    = "array"
+	GeoPoint = "geo"      // An object with lat and lon in degrees
+	DateTime = "datetime" // An RFC 3339 string or Unix seconds
+)
+
+// Schema is the declaration of the payload fields of a collection, the Version counts the changes of the schema
+type Schema struct {
+	Version int              `json:"version"`
+	Fields  map[string]Field `json:"fields"`
+}
+
+// Field is the declaration of a payload field
+type Field struct {
+	Type     string        `json:"type"`
+	Required bool          `json:"required,omitempty"`
+	Enum     []interface{} `json:"enum,omitempty"`  // Optional - the values the field may take
+	Items    string        `json:"items,omitempty"` // The type of the elements of an array
+}
+
+// Check checks that the schema only declares known types and that the enums match their types
+func (s *Schema) Check() error {
+	for _, name := range s.fieldNames() {
+		f := s.Fields[name]
+		if !validType(f.Type) {
+			return fmt.Errorf("field %s: unknown type %q", name, f.Type)
+		}
+		if f.Type == Array {
+			if !validType(f.Items) || f.Items == Array {
+				return fmt.Errorf("field %s: unknown items type %q", name, f.Items)
+			}
+		} else if f.Items != "" {
+			return fmt.Errorf("field %s: items is only allowed for arrays", name)
+		}
+		for _, value := range f.Enum {
+			if f.Type == Array || f.Type == Object || f.Type == GeoPoint || f.Type == DateTime {
+				return fmt.Errorf("field %s: enum is not allowed for %s", name, f.Type)
+			}
+			if !hasType(value, f.Type) {
+				return fmt.Errorf("field %s: enum value %v is not of type %s", name, value, f.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks a payload against the schema
+func (s *Schema) Validate(payload map[string]interface{}) error {
+	if s == nil {
+		return nil
+	}
+	for _, name := range s.fieldNames() {
+		f := s.Fields[name]
+		value, ok := payload[name]
+		if !ok || value == nil {
+			if f.Required {
+				return fmt.Errorf("payload field %s is required", name)
+			}
+			continue
+		}
+		if !hasType(value, f.Type) {
+			return fmt.Errorf("payload field %s must be of type %s", name, f.Type)
+		}
+		if f.Type == Array {
+			items, _ := value.([]interface{})
+			for i, item := range items {
+				if !hasType(item, f.Items) {
+					return fmt.Errorf("payload field %s: element %d must be of type %s", name, i, f.Items)
+				}
+			}
+		}
+		if len(f.Enum) > 0 && !inEnum(value, f.Enum) {
+			return fmt.Errorf("payload field %s: %v is not one of %v", name, value, f.Enum)
+		}
+	}
+	return nil
+}
+
+// Coerce converts the literal of a filter to the type the schema declares for the field, e.g. "5" to 5 for a number
+// or true to "true" for a string. Literals that can not be converted and undeclared fields are returned as they are.
+func (s *Schema) Coerce(field string, value interface{}) interface{} {
+	if s == nil {
+		return value
+	}
+	f, ok := s.Fields[field]
+	if !ok {
+		return value
+	}
+	switch f.Type {
+	case Number, Integer:
+		switch v := value.(type) {
+		case string:
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				return n
+			}
+		case int:
+			return float64(v)
+		case int64:
+			return float64(v)
+		case float32:
+			return float64(v)
+		}
+	case String:
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		}
+	case Boolean:
+		if v, ok := value.(string); ok {
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b
+			}
+		}
+	}
+	return value
+}
+
+// Type returns the declared type of a field, an empty string if the field is not declared
+func (s *Schema) Type(field string) string {
+	if s == nil {
+		return ""
+	}
+	return s.Fields[field].Type
+}
+
+// fieldNames returns the names of the declared fields sorted, so the first error is always the same
+func (s *Schema) fieldNames() []string {
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validType returns true if the type is a known type
+func validType(typ string) bool {
+	switch typ {
+	case String, Number, Integer, Boolean, Object, Array, GeoPoint, DateTime:
+		return true
+	}
+	return false
+}
+
+// hasType returns true if the value is of the type - numbers are float64 after json, ints are set by the server
+func hasType(value interface{}, typ string) bool {
+	switch typ {
+	case String:
+		_, ok := value.(string)
+		return ok
+	case Number:
+		_, ok := number(value)
+		return ok
+	case Integer:
+		n, ok := number(value)
+		return ok && n == math.Trunc(n)
+	case Boolean:
+		_, ok := value.(bool)
+		return ok
+	case Object:
+		_, ok := value.(map[string]interface{})
+		return ok
+	case Array:
+		_, ok := value.([]interface{})
+		return ok
+	case GeoPoint:
+		_, ok := Geo.ParsePoint(value)
+		return ok
+	case DateTime:
+		_, ok := Datetime.Parse(value)
+		return ok
+	}
+	return false
+}
+
+// number returns the value as float64 if it is a number
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// inEnum returns true if the value is one of the enum values, numbers are compared by their value
+func inEnum(value interface{}, enum []interface{}) bool {
+	n, isNumber := number(value)
+	for _, e := range enum {
+		if isNumber {
+			if m, ok := number(e); ok && m == n {
+				return true
+			}
+		} else if e == value {
+			return true
+		}
+	}
+	return false
+}
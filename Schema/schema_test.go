@@ -0,0 +1,125 @@
+package Schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	for name, test := range map[string]struct {
+		field Field
+		ok    bool
+	}{
+		"string":              {Field{Type: String}, true},
+		"unknown type":        {Field{Type: "text"}, false},
+		"array of integers":   {Field{Type: Array, Items: Integer}, true},
+		"array without items": {Field{Type: Array}, false},
+		"array of arrays":     {Field{Type: Array, Items: Array}, false},
+		"items of a string":   {Field{Type: String, Items: String}, false},
+		"enum of strings":     {Field{Type: String, Enum: []interface{}{"red", "green"}}, true},
+		"enum of other type":  {Field{Type: String, Enum: []interface{}{"red", 1.0}}, false},
+		"enum of an object":   {Field{Type: Object, Enum: []interface{}{map[string]interface{}{}}}, false},
+	} {
+		s := &Schema{Fields: map[string]Field{"f": test.field}}
+		if err := s.Check(); (err == nil) != test.ok {
+			t.Errorf("%s: Check() = %v", name, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := &Schema{Fields: map[string]Field{
+		"label": {Type: Integer, Required: true},
+		"color": {Type: String, Enum: []interface{}{"red", "green"}},
+		"size":  {Type: Number, Enum: []interface{}{1.0, 2.5}},
+		"tags":  {Type: Array, Items: String},
+		"meta":  {Type: Object},
+		"flag":  {Type: Boolean},
+	}}
+	for name, test := range map[string]struct {
+		payload map[string]interface{}
+		ok      bool
+	}{
+		"all fields": {map[string]interface{}{"label": 1.0, "color": "red", "size": 2.5, "tags": []interface{}{"a"},
+			"meta": map[string]interface{}{"a": 1.0}, "flag": true}, true},
+		"required only":      {map[string]interface{}{"label": 3}, true},
+		"undeclared field":   {map[string]interface{}{"label": 3, "other": "x"}, true},
+		"required missing":   {map[string]interface{}{"color": "red"}, false},
+		"required null":      {map[string]interface{}{"label": nil}, false},
+		"fraction":           {map[string]interface{}{"label": 1.5}, false},
+		"string label":       {map[string]interface{}{"label": "1"}, false},
+		"value not in enum":  {map[string]interface{}{"label": 1.0, "color": "blue"}, false},
+		"number in enum":     {map[string]interface{}{"label": 1.0, "size": 1}, true},
+		"element of array":   {map[string]interface{}{"label": 1.0, "tags": []interface{}{"a", 2.0}}, false},
+		"array of no array":  {map[string]interface{}{"label": 1.0, "tags": "a"}, false},
+		"string of a bool":   {map[string]interface{}{"label": 1.0, "flag": "true"}, false},
+		"array of an object": {map[string]interface{}{"label": 1.0, "meta": []interface{}{}}, false},
+	} {
+		if err := s.Validate(test.payload); (err == nil) != test.ok {
+			t.Errorf("%s: Validate() = %v", name, err)
+		}
+	}
+
+	// Without a schema every payload is valid
+	var none *Schema
+	if err := none.Validate(map[string]interface{}{"label": "x"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	s := &Schema{Fields: map[string]Field{
+		"label": {Type: Integer},
+		"name":  {Type: String},
+		"flag":  {Type: Boolean},
+	}}
+	for _, test := range []struct {
+		field string
+		value interface{}
+		want  interface{}
+	}{
+		{"label", "5", 5.0},
+		{"label", 5, 5.0},
+		{"label", "five", "five"},
+		{"name", 5.0, "5"},
+		{"name", true, "true"},
+		{"flag", "true", true},
+		{"flag", "yes", "yes"},
+		{"other", "5", "5"},
+	} {
+		if got := s.Coerce(test.field, test.value); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Coerce(%s, %#v) = %#v, want %#v", test.field, test.value, got, test.want)
+		}
+	}
+	var none *Schema
+	if got := none.Coerce("label", "5"); got != "5" {
+		t.Errorf("Coerce without a schema = %#v", got)
+	}
+}
+
+func TestValidateDatetimes(t *testing.T) {
+	s := &Schema{Fields: map[string]Field{"published": {Type: DateTime}, "dates": {Type: Array, Items: DateTime}}}
+	if err := s.Check(); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		value interface{}
+		ok    bool
+	}{
+		{"2024-05-01T12:00:00Z", true},
+		{1714564800.0, true},
+		{"2024-05-01", false},
+		{"now", false},
+		{true, false},
+	} {
+		if err := s.Validate(map[string]interface{}{"published": test.value}); (err == nil) != test.ok {
+			t.Errorf("the datetime %v: %v", test.value, err)
+		}
+	}
+	if err := s.Validate(map[string]interface{}{"dates": []interface{}{"2024-05-01T12:00:00Z", 0.0}}); err != nil {
+		t.Error(err)
+	}
+	if err := (&Schema{Fields: map[string]Field{"d": {Type: DateTime, Enum: []interface{}{0.0}}}}).Check(); err == nil {
+		t.Error("an enum of datetimes was accepted")
+	}
+}
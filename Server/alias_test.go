@@ -0,0 +1,67 @@
+package Server
+
+import (
+	"VreeDB/ApiKeyHandler"
+	"VreeDB/Vdb"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestAliasRoutesResolveTheCollection(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	newTestCollection(t, "routealias1", 2)
+	newTestCollection(t, "routealias2", 3)
+	t.Cleanup(func() {
+		if Vdb.DB.IsAlias("routealias") {
+			Vdb.DB.DeleteAlias("routealias")
+		}
+	})
+	admin := newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Admin})
+
+	w := serve(mux, http.MethodPost, "/createalias", `{"alias":"routealias"}`, "X-API-Key", admin)
+	if w.Code != http.StatusBadRequest || w.Body.String() != "Variables Missing" {
+		t.Errorf("an alias without collection: %d %s", w.Code, w.Body.String())
+	}
+	w = serve(mux, http.MethodPost, "/createalias", `{"alias":"routealias","collection_name":"routealias1"}`,
+		"X-API-Key", admin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	dimension := func() int {
+		t.Helper()
+		w := serve(mux, http.MethodGet, "/collectioninfo", `{"collection_name":"routealias"}`, "X-API-Key", admin)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body.String())
+		}
+		info := struct {
+			Dimension int `json:"dimension"`
+		}{}
+		if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+			t.Fatal(err)
+		}
+		return info.Dimension
+	}
+	if got := dimension(); got != 2 {
+		t.Errorf("the alias resolves to a collection with %d dimensions", got)
+	}
+
+	w = serve(mux, http.MethodPost, "/switchalias", `{"alias":"routealias","collection_name":"routealias2"}`,
+		"X-API-Key", admin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	if got := dimension(); got != 3 {
+		t.Errorf("the switched alias resolves to a collection with %d dimensions", got)
+	}
+
+	w = serve(mux, http.MethodDelete, "/deletealias", `{"alias":"routealias"}`, "X-API-Key", admin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	w = serve(mux, http.MethodGet, "/collectioninfo", `{"collection_name":"routealias"}`, "X-API-Key", admin)
+	if w.Code == http.StatusOK {
+		t.Error("the deleted alias still resolves")
+	}
+}
@@ -0,0 +1,209 @@
+package Server
+
+import (
+	"VreeDB/ApiKeyHandler"
+	"VreeDB/Logger"
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// deprecatedApiKey logs once that an ApiKey was sent in the body of a request
+var deprecatedApiKey sync.Once
+
+// headerApiKey returns the ApiKey of the Authorization: Bearer or the X-API-Key header of a request
+func headerApiKey(req *http.Request) string {
+	if auth := req.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return strings.TrimSpace(req.Header.Get("X-API-Key"))
+}
+
+// apiKeyOf returns the ApiKey of a request, the header wins over the deprecated api_key of the body
+func apiKeyOf(req *http.Request, bodyKey string) string {
+	if key := headerApiKey(req); key != "" {
+		return key
+	}
+	if bodyKey != "" {
+		deprecatedApiKey.Do(func() {
+			Logger.Log.Log("The api_key in the body of a request is deprecated, use the Authorization: Bearer or the " +
+				"X-API-Key header")
+		})
+	}
+	return bodyKey
+}
+
+// The access a route needs besides the roles of the ApiKeys
+const (
+	publicRoute  = "public"  // Pages of the web interface that check the session themselves
+	sessionRoute = "session" // Routes of the web interface that need a valid session
+)
+
+// routeRoles is the role an ApiKey needs for a route, a route that is missing here needs the admin role
+var routeRoles = map[string]string{
+	"Login": publicRoute, "Logout": publicRoute, "Index": publicRoute, "ShowApiKey": publicRoute,
+	"NeuralNetBuilder": publicRoute, "GetTrainPhase": sessionRoute, "GetAccessData": sessionRoute,
+	"ListCollections": ApiKeyHandler.Reader, "IngestStatus": ApiKeyHandler.Reader, "Export": ApiKeyHandler.Reader,
+	"ImportStatus": ApiKeyHandler.Reader, "Search": ApiKeyHandler.Reader, "Classify": ApiKeyHandler.Reader,
+	"DeleteApiKey": ApiKeyHandler.Reader, "ListAliases": ApiKeyHandler.Reader, "CollectionInfo": ApiKeyHandler.Reader,
+	"Facets": ApiKeyHandler.Reader, "Count": ApiKeyHandler.Reader, "DeleteStatus": ApiKeyHandler.Reader,
+	"AddPoint": ApiKeyHandler.Writer, "AddPointBatch": ApiKeyHandler.Writer, "Import": ApiKeyHandler.Writer,
+	"DeletePoint": ApiKeyHandler.Writer, "DeleteByFilter": ApiKeyHandler.Writer,
+	"Delete": ApiKeyHandler.Admin, "CreateCollection": ApiKeyHandler.Admin, "LoadDataset": ApiKeyHandler.Admin,
+	"DatasetStatus": ApiKeyHandler.Admin, "Fsck": ApiKeyHandler.Admin, "CreateSnapshot": ApiKeyHandler.Admin,
+	"ListSnapshots": ApiKeyHandler.Admin, "RestoreSnapshot": ApiKeyHandler.Admin, "DeleteSnapshot": ApiKeyHandler.Admin,
+	"TrainClassifier": ApiKeyHandler.Admin, "DeleteClassifier": ApiKeyHandler.Admin,
+	"CreateApiKey": ApiKeyHandler.Admin, "ListApiKeys": ApiKeyHandler.Admin, "RevokeApiKey": ApiKeyHandler.Admin,
+	"CreateIndex": ApiKeyHandler.Admin, "CreateAlias": ApiKeyHandler.Admin, "SwitchAlias": ApiKeyHandler.Admin,
+	"DeleteAlias": ApiKeyHandler.Admin, "RenameCollection": ApiKeyHandler.Admin,
+	"CloneCollection": ApiKeyHandler.Admin, "SetSchema": ApiKeyHandler.Admin, "SetTenant": ApiKeyHandler.Admin,
+	"ListTenants": ApiKeyHandler.Admin, "DeleteTenant": ApiKeyHandler.Admin,
+}
+
+// maxHeaderLine is the longest JSON header line of a stream, it is read before the request is authenticated
+const maxHeaderLine = 64 << 10
+
+// requestAuth is the authentication of a request in its context. A request without ApiKey in its header and with a
+// body is authenticated by the route, it decodes its limited body and passes the deprecated api_key of it to
+// requestKey.
+type requestAuth struct {
+	key     *ApiKeyHandler.ApiKey
+	pending bool   // The route authenticates the request
+	role    string // The role the route needs
+	session bool   // The request has a valid session
+}
+
+// apiKeyContext is the key of the requestAuth of a request in its context
+type apiKeyContext struct{}
+
+// authenticate wraps a route, it finds the ApiKey of a request in its header or its session and checks that the key
+// has the role of the route. Requests without a valid key or session are Unauthorized and keys without the role
+// Forbidden before they reach the route. A request with a body may carry the deprecated api_key in it, the route
+// checks it with requestKey after it decoded the body. The route gets the ApiKey with requestKey, a session of the web
+// interface and a database without ApiKeys have no ApiKey.
+func (r *Routes) authenticate(name string, next http.HandlerFunc) http.HandlerFunc {
+	role, ok := routeRoles[name]
+	if !ok {
+		role = ApiKeyHandler.Admin
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch role {
+		case publicRoute:
+			next(w, req)
+			return
+		case sessionRoute:
+			if !r.validateCookie(req) {
+				unauthorized(w)
+				return
+			}
+			next(w, req)
+			return
+		}
+
+		auth := &requestAuth{role: role}
+		switch {
+		case headerApiKey(req) != "":
+			key, ok := r.ApiKeyHandler.Lookup(headerApiKey(req))
+			if !ok {
+				unauthorized(w)
+				return
+			}
+			if !key.Can(role) {
+				forbidden(w)
+				return
+			}
+			auth.key = key
+		case req.Body != nil && req.Body != http.NoBody && req.ContentLength != 0:
+			// The body is only read by the route, with its own limit
+			auth.pending, auth.session = true, r.validateCookie(req)
+		case !r.validateCookie(req):
+			unauthorized(w)
+			return
+		}
+		next(w, req.WithContext(context.WithValue(req.Context(), apiKeyContext{}, auth)))
+	}
+}
+
+// requestKey returns the ApiKey the request was authenticated with. A request that is not authenticated yet is
+// authenticated with the deprecated api_key of its body, without it a valid session authenticates it. Requests
+// without a valid key or session are answered with Unauthorized, keys without the role of the route with Forbidden.
+func (r *Routes) requestKey(w http.ResponseWriter, req *http.Request, bodyKey string) (*ApiKeyHandler.ApiKey, bool) {
+	auth, ok := req.Context().Value(apiKeyContext{}).(*requestAuth)
+	if !ok {
+		return nil, true
+	}
+	if !auth.pending {
+		return auth.key, true
+	}
+	if bodyKey == "" && auth.session {
+		return nil, true
+	}
+	key, ok := r.ApiKeyHandler.Lookup(apiKeyOf(req, bodyKey))
+	if !ok {
+		unauthorized(w)
+		return nil, false
+	}
+	if !key.Can(auth.role) {
+		forbidden(w)
+		return nil, false
+	}
+	return key, true
+}
+
+// readHeaderLine reads the JSON header line of a stream, a line longer than maxHeaderLine is an error
+func readHeaderLine(body *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := body.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxHeaderLine {
+			return nil, fmt.Errorf("the header line exceeds %d bytes", maxHeaderLine)
+		}
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// tenantOf returns the tenant of an ApiKey, keys without a tenant see all Collections
+func tenantOf(key *ApiKeyHandler.ApiKey) string {
+	if key == nil {
+		return ""
+	}
+	return key.Tenant
+}
+
+// allowed checks that the ApiKey may use the Collection and that its tenant has at least the access to the Collection,
+// otherwise it sends Forbidden. The role of the ApiKey is checked before the request reaches the route.
+func (r *Routes) allowed(w http.ResponseWriter, key *ApiKeyHandler.ApiKey, collectionName string, access int) bool {
+	if key.CanUse(collectionName) && r.DB.TenantAccess(tenantOf(key), collectionName) >= access {
+		return true
+	}
+	forbidden(w)
+	return false
+}
+
+// allowedGlobal checks that the ApiKey is an admin key without tenant and collections, otherwise it sends Forbidden.
+// The routes that act on the whole database are only available to those keys.
+func (r *Routes) allowedGlobal(w http.ResponseWriter, key *ApiKeyHandler.ApiKey) bool {
+	if key.IsGlobal() {
+		return true
+	}
+	forbidden(w)
+	return false
+}
+
+// unauthorized sends Unauthorized to the client
+func unauthorized(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte("Unauthorized"))
+}
+
+// forbidden sends Forbidden to the client
+func forbidden(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte("Forbidden"))
+}
@@ -0,0 +1,150 @@
+package Server
+
+import (
+	"VreeDB/ApiKeyHandler"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRolesOfTheRoutes(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	newTestCollection(t, "rbac", 2)
+	admin := newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Admin})
+	writer := newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Writer})
+	reader := newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Reader})
+
+	if w := serve(mux, http.MethodPut, "/addpoint", `{"collection_name":"rbac","id":"r","vector":[1,2]}`,
+		"X-API-Key", reader); w.Code != http.StatusForbidden {
+		t.Errorf("a reader added a point: %d", w.Code)
+	}
+	if w := serve(mux, http.MethodPut, "/addpoint", `{"collection_name":"rbac","id":"w","vector":[1,2]}`,
+		"X-API-Key", writer); w.Code != http.StatusOK {
+		t.Errorf("a writer can not add a point: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(mux, http.MethodGet, "/collectioninfo", `{"collection_name":"rbac"}`,
+		"X-API-Key", reader); w.Code != http.StatusOK {
+		t.Errorf("a reader can not read the collection: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(mux, http.MethodGet, "/listapikeys", `{}`, "X-API-Key", writer); w.Code != http.StatusForbidden {
+		t.Errorf("a writer listed the ApiKeys: %d", w.Code)
+	}
+	w := serve(mux, http.MethodGet, "/listapikeys", `{}`, "X-API-Key", admin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("an admin can not list the ApiKeys: %d %s", w.Code, w.Body.String())
+	}
+	list := ApiKeyList{}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.ApiKeys) != 3 {
+		t.Errorf("%d ApiKeys are listed", len(list.ApiKeys))
+	}
+}
+
+func TestScopedKeyOnlyUsesItsCollections(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	newTestCollection(t, "scopeda", 2)
+	newTestCollection(t, "scopedb", 2)
+	newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Admin})
+	scoped := newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Writer, Collections: []string{"scopeda"}})
+
+	if w := serve(mux, http.MethodGet, "/collectioninfo", `{"collection_name":"scopeda"}`,
+		"X-API-Key", scoped); w.Code != http.StatusOK {
+		t.Errorf("the key can not use its collection: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(mux, http.MethodGet, "/collectioninfo", `{"collection_name":"scopedb"}`,
+		"X-API-Key", scoped); w.Code != http.StatusForbidden {
+		t.Errorf("the key uses another collection: %d", w.Code)
+	}
+}
+
+func TestDeleteApiKey(t *testing.T) {
+	_, mux := newTestRoutes(t)
+
+	// Without ApiKeys every request is allowed, but there is no key to delete
+	if w := serve(mux, http.MethodDelete, "/deleteapikey", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("deleting no key answered %d %s", w.Code, w.Body.String())
+	}
+
+	newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Admin})
+	reader := newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Reader})
+	if w := serve(mux, http.MethodDelete, "/deleteapikey", `{}`, "X-API-Key", reader); w.Code != http.StatusOK {
+		t.Fatalf("a key can not delete itself: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(mux, http.MethodDelete, "/deleteapikey", `{}`, "X-API-Key", reader); w.Code != http.StatusUnauthorized {
+		t.Errorf("a deleted key is still valid: %d", w.Code)
+	}
+}
+
+func TestEveryRouteHasARole(t *testing.T) {
+	routes := reflect.TypeOf(&Routes{})
+	for i := 0; i < routes.NumMethod(); i++ {
+		name := routes.Method(i).Name
+		if _, ok := routeRoles[name]; !ok {
+			t.Errorf("route %s has no role", name)
+		}
+	}
+}
+
+func TestRequestsWithoutApiKeyAreUnauthorized(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	newTestCollection(t, "unauth", 2)
+	newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Admin})
+
+	for _, body := range []string{"", `{"collection_name":"unauth"}`, `{"api_key":"wrong"}`} {
+		if w := serve(mux, http.MethodGet, "/collectioninfo", body); w.Code != http.StatusUnauthorized ||
+			w.Body.String() != "Unauthorized" {
+			t.Errorf("the body %q is answered with %d %s", body, w.Code, w.Body.String())
+		}
+	}
+	// The route decodes the body before it checks its api_key
+	if w := serve(mux, http.MethodGet, "/collectioninfo", "{"); w.Code != http.StatusInternalServerError {
+		t.Errorf("invalid JSON is answered with %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(mux, http.MethodGet, "/collectioninfo", `{"collection_name":"unauth"}`,
+		"Authorization", "Bearer wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("an invalid header is answered with %d", w.Code)
+	}
+	if w := serve(mux, http.MethodPost, "/gettrainphase", `{}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("a request without session is answered with %d", w.Code)
+	}
+	// The pages of the web interface check the session themselves
+	if w := serve(mux, http.MethodPost, "/logout", ""); w.Code != http.StatusSeeOther {
+		t.Errorf("the logout is answered with %d", w.Code)
+	}
+}
+
+func TestBodyApiKeyReachesTheRoute(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	newTestCollection(t, "bodykey", 2)
+	newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Admin})
+	reader := newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Reader})
+
+	// The route checks the key after it decoded its body
+	w := serve(mux, http.MethodGet, "/collectioninfo", `{"api_key":"`+reader+`","collection_name":"bodykey"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	if w = serve(mux, http.MethodDelete, "/delete", `{"api_key":"`+reader+`","name":"bodykey"}`); w.Code !=
+		http.StatusForbidden || w.Body.String() != "Forbidden" {
+		t.Errorf("a reader deleted the collection: %d %s", w.Code, w.Body.String())
+	}
+	// The header wins over the body
+	if w = serve(mux, http.MethodGet, "/collectioninfo", `{"api_key":"`+reader+`","collection_name":"bodykey"}`,
+		"X-API-Key", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("an invalid header is answered with %d", w.Code)
+	}
+
+	// A body is only read up to the limit of the route before the key is checked
+	large := `{"api_key":"` + reader + `","collection_name":"` + strings.Repeat("x", 10000) + `"}`
+	if w = serve(mux, http.MethodGet, "/collectioninfo", large); w.Code != http.StatusInternalServerError {
+		t.Errorf("a body over the limit is answered with %d %s", w.Code, w.Body.String())
+	}
+	header := `{"api_key":"` + reader + `","collection_name":"` + strings.Repeat("x", maxHeaderLine) + `"}`
+	if w = serve(mux, http.MethodPost, "/import", header+"\n"); w.Code != http.StatusInternalServerError {
+		t.Errorf("a header line over the limit is answered with %d %s", w.Code, w.Body.String())
+	}
+}
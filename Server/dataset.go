@@ -0,0 +1,108 @@
+package Server
+
+import (
+	"VreeDB/Dataset"
+	"VreeDB/Utils"
+	"bufio"
+	"io"
+	"os"
+	"time"
+)
+
+// datasetJobRetention is the time a finished DatasetJob can be polled
+const datasetJobRetention = time.Hour
+
+// startDatasetJob starts a DatasetJob that loads the vectors of the reader into the Collection of the DatasetLoader.
+// The file of the reader is closed afterwards, an uploaded file is removed.
+func (r *Routes) startDatasetJob(dl *DatasetLoader, reader Dataset.Reader, file *os.File, upload bool) *DatasetJob {
+	job := &DatasetJob{Id: Utils.Utils.CreateUUID(), CollectionName: dl.CollectionName, Status: "running",
+		Dimension: reader.Dimension(), done: make(chan struct{})}
+	r.datasetMut.Lock()
+	// Forget the jobs that are finished for a while
+	for id, j := range r.DatasetJobs {
+		if j.isFinishedBefore(time.Now().Add(-datasetJobRetention)) {
+			delete(r.DatasetJobs, id)
+		}
+	}
+	r.DatasetJobs[job.Id] = job
+	r.datasetMut.Unlock()
+
+	go func() {
+		defer closeDataset(file, upload)
+		n, err := r.DB.LoadDataset(dl.CollectionName, dl.DistanceFunction, reader, !dl.RandomIds, dl.Limit,
+			func(n int) {
+				r.AData <- "ADD"
+				job.setPoints(n)
+			})
+		job.finish(n, err)
+	}()
+	return job
+}
+
+// getDatasetJob returns the DatasetJob with the given id
+func (r *Routes) getDatasetJob(id string) (*DatasetJob, bool) {
+	r.datasetMut.Lock()
+	defer r.datasetMut.Unlock()
+	job, ok := r.DatasetJobs[id]
+	return job, ok
+}
+
+// spoolDataset writes an uploaded dataset to a temporary file, so it can be loaded after the request
+func spoolDataset(body *bufio.Reader) (*os.File, error) {
+	file, err := os.CreateTemp("", "vreedb-dataset-")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(file, body)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		closeDataset(file, true)
+		return nil, err
+	}
+	return file, nil
+}
+
+// closeDataset closes the file of a dataset, an uploaded file is removed
+func closeDataset(file *os.File, upload bool) {
+	file.Close()
+	if upload {
+		os.Remove(file.Name())
+	}
+}
+
+// setPoints sets the number of loaded points of the DatasetJob
+func (j *DatasetJob) setPoints(n int) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	j.Points = n
+}
+
+// finish records the result of the DatasetJob and wakes up everyone who waits for it
+func (j *DatasetJob) finish(points int, err error) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	j.Status = "done"
+	j.Points = points
+	if err != nil {
+		j.Error = err.Error()
+	}
+	j.finished = time.Now()
+	close(j.done)
+}
+
+// isFinishedBefore returns true if the DatasetJob finished before the given time
+func (j *DatasetJob) isFinishedBefore(t time.Time) bool {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	return j.Status == "done" && j.finished.Before(t)
+}
+
+// snapshot returns a copy of the DatasetJob that can be encoded while the job is running
+func (j *DatasetJob) snapshot() *DatasetJob {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	return &DatasetJob{Id: j.Id, CollectionName: j.CollectionName, Status: j.Status, Dimension: j.Dimension,
+		Points: j.Points, Error: j.Error}
+}
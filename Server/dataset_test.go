@@ -0,0 +1,72 @@
+package Server
+
+import (
+	"VreeDB/Vdb"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fvecs returns an .fvecs file of the vectors
+func fvecs(vectors ...[]float32) string {
+	var buf bytes.Buffer
+	for _, vector := range vectors {
+		binary.Write(&buf, binary.LittleEndian, int32(len(vector)))
+		binary.Write(&buf, binary.LittleEndian, vector)
+	}
+	return buf.String()
+}
+
+func TestLoadDatasetRunsAsAJob(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	t.Cleanup(func() {
+		if _, ok := Vdb.DB.GetCollection("datasetjob"); ok {
+			Vdb.DB.DeleteCollection("datasetjob")
+		}
+	})
+
+	// The upload is loaded in the background
+	body := `{"collection_name":"datasetjob","format":"fvecs","distance_function":"euclid"}` + "\n" +
+		fvecs([]float32{1, 2}, []float32{3, 4}, []float32{5, 6})
+	w := serve(mux, http.MethodPost, "/loaddataset", body)
+	job := DatasetJob{}
+	if w.Code != http.StatusAccepted || json.NewDecoder(w.Body).Decode(&job) != nil || job.Id == "" {
+		t.Fatalf("load %d: %s", w.Code, w.Body.String())
+	}
+	if job.Dimension != 2 {
+		t.Errorf("the job reports the dimension %d", job.Dimension)
+	}
+	for deadline := time.Now().Add(10 * time.Second); job.Status != "done"; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the job did not finish")
+		}
+		w = serve(mux, http.MethodGet, "/datasetstatus", `{"job_id":"`+job.Id+`"}`)
+		if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&job) != nil {
+			t.Fatalf("status %d: %s", w.Code, w.Body.String())
+		}
+	}
+	if job.Points != 3 || job.Error != "" {
+		t.Errorf("the job loaded %d points: %s", job.Points, job.Error)
+	}
+	if c, ok := Vdb.DB.GetCollection("datasetjob"); !ok || len(*c.Space) != 3 {
+		t.Error("the points are not in the collection")
+	}
+
+	// A waiting client gets the error of the load
+	body = `{"collection_name":"datasetjob","format":"fvecs","wait":true}` + "\n" + fvecs([]float32{1, 2, 3})
+	w = serve(mux, http.MethodPost, "/loaddataset", body)
+	if w.Code != http.StatusBadRequest || json.NewDecoder(w.Body).Decode(&job) != nil || job.Error == "" {
+		t.Errorf("a dataset of another dimension: %d %s", w.Code, w.Body.String())
+	}
+	w = serve(mux, http.MethodPost, "/loaddataset", `{"collection_name":"datasetjob","file":"missing.fvecs"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("a missing file: %d %s", w.Code, w.Body.String())
+	}
+	w = serve(mux, http.MethodGet, "/datasetstatus", `{"job_id":"unknown"}`)
+	if w.Code == http.StatusOK {
+		t.Errorf("an unknown job was found: %s", w.Body.String())
+	}
+}
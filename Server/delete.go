@@ -0,0 +1,75 @@
+package Server
+
+import (
+	"VreeDB/Filter"
+	"VreeDB/Utils"
+	"time"
+)
+
+const (
+	// deleteJobRetention is the time a finished DeleteJob can be polled
+	deleteJobRetention = time.Hour
+	// deleteJobThreshold is the number of points from which a delete by filter runs in the background
+	deleteJobThreshold = 10000
+)
+
+// startDeleteJob starts a DeleteJob that deletes the points of the Collection that pass the filter
+func (r *Routes) startDeleteJob(collectionName string, filter *[]Filter.Filter, tenant string) *DeleteJob {
+	job := &DeleteJob{Id: Utils.Utils.CreateUUID(), CollectionName: collectionName, Status: "running", tenant: tenant,
+		done: make(chan struct{})}
+	r.deleteMut.Lock()
+	// Forget the jobs that are finished for a while
+	for id, j := range r.DeleteJobs {
+		if j.isFinishedBefore(time.Now().Add(-deleteJobRetention)) {
+			delete(r.DeleteJobs, id)
+		}
+	}
+	r.DeleteJobs[job.Id] = job
+	r.deleteMut.Unlock()
+
+	go func() {
+		matched, deleted, err := r.DB.DeleteByFilter(collectionName, filter)
+		job.finish(matched, deleted, err)
+	}()
+	return job
+}
+
+// getDeleteJob returns the DeleteJob with the given id, a tenant only gets its own jobs
+func (r *Routes) getDeleteJob(id string, tenant string) (*DeleteJob, bool) {
+	r.deleteMut.Lock()
+	defer r.deleteMut.Unlock()
+	job, ok := r.DeleteJobs[id]
+	if ok && tenant != "" && job.tenant != tenant {
+		return nil, false
+	}
+	return job, ok
+}
+
+// finish records the result of the DeleteJob and wakes up everyone who waits for it
+func (j *DeleteJob) finish(matched int, deleted int, err error) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	j.Status = "done"
+	j.Matched = matched
+	j.Deleted = deleted
+	if err != nil {
+		j.Error = err.Error()
+	}
+	j.finished = time.Now()
+	close(j.done)
+}
+
+// isFinishedBefore returns true if the DeleteJob finished before the given time
+func (j *DeleteJob) isFinishedBefore(t time.Time) bool {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	return j.Status == "done" && j.finished.Before(t)
+}
+
+// snapshot returns a copy of the DeleteJob that can be encoded while the job is running
+func (j *DeleteJob) snapshot() *DeleteJob {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	return &DeleteJob{Id: j.Id, CollectionName: j.CollectionName, Status: j.Status, Matched: j.Matched,
+		Deleted: j.Deleted, Error: j.Error}
+}
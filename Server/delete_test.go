@@ -0,0 +1,60 @@
+package Server
+
+import (
+	"VreeDB/ApiKeyHandler"
+	"VreeDB/Vdb"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestDeleteByFilterReportsTheJob(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	newTestCollection(t, "deletejob", 2)
+	for i := 0; i < 10; i++ {
+		p := &Vdb.PointItem{Id: fmt.Sprint(i), Vector: []float64{float64(i), 1},
+			Payload: map[string]interface{}{"source": fmt.Sprint(i % 2)}}
+		if _, err := Vdb.DB.AddPoint("deletejob", p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writer := newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Writer})
+	filter := `"filter":[{"field":"source","operator":"eq","value":"1"}]`
+
+	w := serve(mux, http.MethodGet, "/count", `{"collection_name":"deletejob",`+filter+`}`, "X-API-Key", writer)
+	count := CountResult{}
+	if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&count) != nil || count.Count != 5 {
+		t.Fatalf("count %d: %d %s", count.Count, w.Code, w.Body.String())
+	}
+
+	w = serve(mux, http.MethodDelete, "/deletebyfilter", `{"collection_name":"deletejob"}`, "X-API-Key", writer)
+	if w.Code != http.StatusBadRequest || w.Body.String() != "Variables Missing" {
+		t.Errorf("a delete without filter: %d %s", w.Code, w.Body.String())
+	}
+
+	// A small collection is deleted right away
+	w = serve(mux, http.MethodDelete, "/deletebyfilter", `{"collection_name":"deletejob",`+filter+`}`,
+		"X-API-Key", writer)
+	job := DeleteJob{}
+	if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&job) != nil {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	if job.Status != "done" || job.Matched != 5 || job.Deleted != 5 || job.Id == "" {
+		t.Errorf("the job is %s with %d matched and %d deleted", job.Status, job.Matched, job.Deleted)
+	}
+
+	// The job can be polled by its id
+	w = serve(mux, http.MethodGet, "/deletestatus", `{"job_id":"`+job.Id+`"}`, "X-API-Key", writer)
+	polled := DeleteJob{}
+	if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&polled) != nil || polled.Deleted != 5 {
+		t.Errorf("status %d: %s", w.Code, w.Body.String())
+	}
+	w = serve(mux, http.MethodGet, "/deletestatus", `{"job_id":"unknown"}`, "X-API-Key", writer)
+	if w.Code == http.StatusOK {
+		t.Errorf("an unknown job was found: %s", w.Body.String())
+	}
+	if n := len(*Vdb.DB.Collections["deletejob"].Space); n != 5 {
+		t.Errorf("%d points are left", n)
+	}
+}
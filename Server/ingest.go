@@ -0,0 +1,141 @@
+package Server
+
+import (
+	"VreeDB/Utils"
+	"time"
+)
+
+const (
+	// ingestJobRetention is the time a finished IngestJob can be polled
+	ingestJobRetention = time.Hour
+	// ingestBatchSize is the number of points of an IngestJob that are inserted as one batch
+	ingestBatchSize = 256
+)
+
+// newIngestJob creates a new IngestJob for the points of the PointBatch
+func newIngestJob(pb *PointBatch, tenant string) *IngestJob {
+	return &IngestJob{Id: Utils.Utils.CreateUUID(), CollectionName: pb.CollectionName, Status: "queued",
+		Total: len(pb.Points), Results: make([]PointResult, len(pb.Points)), points: pb.Points, tenant: tenant,
+		done: make(chan struct{})}
+}
+
+// enqueueIngestJob adds the IngestJob to the ingest queue, it returns false if the queue is full
+func (r *Routes) enqueueIngestJob(job *IngestJob) bool {
+	r.ingestMut.Lock()
+	defer r.ingestMut.Unlock()
+	select {
+	case r.IngestQueue <- job:
+	default:
+		return false
+	}
+	// Forget the jobs that are finished for a while
+	for id, j := range r.IngestJobs {
+		if j.isFinishedBefore(time.Now().Add(-ingestJobRetention)) {
+			delete(r.IngestJobs, id)
+		}
+	}
+	r.IngestJobs[job.Id] = job
+	return true
+}
+
+// getIngestJob returns the IngestJob with the given id, a tenant only gets its own jobs
+func (r *Routes) getIngestJob(id string, tenant string) (*IngestJob, bool) {
+	r.ingestMut.Lock()
+	defer r.ingestMut.Unlock()
+	job, ok := r.IngestJobs[id]
+	if ok && tenant != "" && job.tenant != tenant {
+		return nil, false
+	}
+	return job, ok
+}
+
+// ingestWorker inserts the IngestJobs of the ingest queue
+func (r *Routes) ingestWorker() {
+	for job := range r.IngestQueue {
+		r.processIngestJob(job)
+	}
+}
+
+// processIngestJob inserts all points of the IngestJob into the Collection. Every point gets its own result, a failing
+// point does not stop the others.
+func (r *Routes) processIngestJob(job *IngestJob) {
+	defer job.finish()
+	job.setStatus("running")
+
+	if _, ok := r.DB.GetCollection(job.CollectionName); !ok {
+		for i := range job.points {
+			job.setResult(i, job.points[i].Id, "Collection does not exist")
+		}
+		return
+	}
+
+	// Create and insert the vectors in batches, the records of a batch are written at once
+	for start := 0; start < len(job.points); start += ingestBatchSize {
+		end := start + ingestBatchSize
+		if end > len(job.points) {
+			end = len(job.points)
+		}
+		vectors, errs := r.DB.AddPoints(job.CollectionName, job.points[start:end])
+		for j, v := range vectors {
+			i := start + j
+			r.AData <- "ADD"
+			switch {
+			case v == nil:
+				job.setResult(i, job.points[i].Id, errs[j].Error())
+			case errs[j] != nil:
+				job.setResult(i, v.Id, errs[j].Error())
+			default:
+				job.setResult(i, v.Id, "")
+			}
+		}
+	}
+}
+
+// setStatus sets the status of the IngestJob
+func (j *IngestJob) setStatus(status string) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	j.Status = status
+}
+
+// setResult sets the result of the point at index i, an empty error marks the point as added
+func (j *IngestJob) setResult(i int, id string, err string) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	j.Processed++
+	if err != "" {
+		j.Failed++
+		j.Results[i] = PointResult{Id: id, Status: "error", Error: err}
+		return
+	}
+	j.Results[i] = PointResult{Id: id, Status: "ok"}
+}
+
+// finish marks the IngestJob as done and wakes up everyone who waits for it
+func (j *IngestJob) finish() {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	j.Status = "done"
+	j.finished = time.Now()
+	j.points = nil
+	close(j.done)
+}
+
+// isFinishedBefore returns true if the IngestJob finished before the given time
+func (j *IngestJob) isFinishedBefore(t time.Time) bool {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	return j.Status == "done" && j.finished.Before(t)
+}
+
+// snapshot returns a copy of the IngestJob that can be encoded while the job is running
+func (j *IngestJob) snapshot(withResults bool) *IngestJob {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	s := &IngestJob{Id: j.Id, CollectionName: j.CollectionName, Status: j.Status, Total: j.Total,
+		Processed: j.Processed, Failed: j.Failed}
+	if withResults {
+		s.Results = append([]PointResult(nil), j.Results...)
+	}
+	return s
+}
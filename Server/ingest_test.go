@@ -0,0 +1,103 @@
+package Server
+
+import (
+	"VreeDB/Vdb"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAddPointBatchWaitReportsEveryPoint(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	newTestCollection(t, "ingestwait", 2)
+
+	w := serve(mux, http.MethodPut, "/addpointbatch", `{"collection_name":"ingestwait","wait":true,"points":[
+		{"id":"a","vector":[1,2]},{"id":"b","vector":[1,2,3]},{"id":"c","vector":[3,4]}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	job := IngestJob{}
+	if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != "done" || job.Total != 3 || job.Processed != 3 || job.Failed != 1 {
+		t.Fatalf("unexpected job %s %d %d %d", job.Status, job.Total, job.Processed, job.Failed)
+	}
+	want := []string{"ok", "error", "ok"}
+	for i, result := range job.Results {
+		if result.Status != want[i] {
+			t.Errorf("point %d has status %s, want %s", i, result.Status, want[i])
+		}
+	}
+	if job.Results[1].Error == "" {
+		t.Error("the failed point has no error")
+	}
+	if n := len(*Vdb.DB.Collections["ingestwait"].Space); n != 2 {
+		t.Errorf("collection has %d points, want 2", n)
+	}
+}
+
+func TestAddPointBatchJobCanBePolled(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	newTestCollection(t, "ingestpoll", 2)
+
+	w := serve(mux, http.MethodPut, "/addpointbatch", `{"collection_name":"ingestpoll","points":[{"vector":[1,2]}]}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	job := IngestJob{}
+	json.NewDecoder(w.Body).Decode(&job)
+	if job.Id == "" {
+		t.Fatal("no job id")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w = serve(mux, http.MethodGet, "/ingeststatus", `{"job_id":"`+job.Id+`"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body.String())
+		}
+		state := IngestJob{}
+		json.NewDecoder(w.Body).Decode(&state)
+		if state.Status == "done" {
+			if state.Failed != 0 || len(state.Results) != 1 || state.Results[0].Status != "ok" {
+				t.Fatalf("unexpected job %s %d %v", state.Status, state.Failed, state.Results)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job is still %s", state.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestIngestQueueIsBounded(t *testing.T) {
+	r := &Routes{IngestQueue: make(chan *IngestJob, 1), IngestJobs: make(map[string]*IngestJob),
+		ingestMut: &sync.Mutex{}}
+	first := newIngestJob(&PointBatch{CollectionName: "full"}, "")
+	if !r.enqueueIngestJob(first) {
+		t.Fatal("the first job was not queued")
+	}
+	if r.enqueueIngestJob(newIngestJob(&PointBatch{CollectionName: "full"}, "")) {
+		t.Fatal("a job was queued into a full queue")
+	}
+	if _, ok := r.getIngestJob(first.Id, ""); !ok {
+		t.Fatal("the queued job can not be polled")
+	}
+}
+
+func TestIngestJobOfTenantIsHidden(t *testing.T) {
+	r := &Routes{IngestQueue: make(chan *IngestJob, 1), IngestJobs: make(map[string]*IngestJob),
+		ingestMut: &sync.Mutex{}}
+	job := newIngestJob(&PointBatch{CollectionName: "shared"}, "acme")
+	r.enqueueIngestJob(job)
+	if _, ok := r.getIngestJob(job.Id, "other"); ok {
+		t.Fatal("another tenant sees the job")
+	}
+	if _, ok := r.getIngestJob(job.Id, "acme"); !ok {
+		t.Fatal("the tenant does not see its job")
+	}
+}
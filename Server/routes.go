@@ -3,16 +3,25 @@ package Server
 import (
 	"VreeDB/AccessDataHUB"
 	"VreeDB/ApiKeyHandler"
+	"VreeDB/ArgsParser"
+	"VreeDB/Dataset"
+	"VreeDB/Formula"
+	"VreeDB/Fsck"
 	"VreeDB/Logger"
 	"VreeDB/Utils"
 	"VreeDB/Vdb"
 	"VreeDB/Vector"
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,15 +30,29 @@ var RouteProvider *Routes
 
 // NewRoutes returns a new Routes struct
 func NewRoutes(db *Vdb.Vdb) *Routes {
-	return &Routes{templates: template.Must(template.ParseGlob("templates/*.gohtml")), DB: db,
-		ApiKeyHandler: ApiKeyHandler.ApiHandler, SessionKeys: make(map[string]time.Time), AData: AccessDataHUB.AccessList.ReadChan}
+	return newRoutes(db, template.Must(template.ParseGlob("templates/*.gohtml")))
+}
+
+// newRoutes returns the Routes with the given templates of the web interface and starts the ingest workers
+func newRoutes(db *Vdb.Vdb, templates *template.Template) *Routes {
+	r := &Routes{templates: templates, DB: db,
+		ApiKeyHandler: ApiKeyHandler.ApiHandler, SessionKeys: make(map[string]time.Time), AData: AccessDataHUB.AccessList.ReadChan,
+		IngestQueue: make(chan *IngestJob, *ArgsParser.Ap.IngestQueue), IngestJobs: make(map[string]*IngestJob),
+		ingestMut: &sync.Mutex{}, ImportJobs: make(map[string]*ImportJob), importMut: &sync.Mutex{},
+		DeleteJobs: make(map[string]*DeleteJob), deleteMut: &sync.Mutex{}, DatasetJobs: make(map[string]*DatasetJob),
+		datasetMut: &sync.Mutex{}}
+	// Start the workers of the ingest queue
+	for i := 0; i < *ArgsParser.Ap.IngestWorkers; i++ {
+		go r.ingestWorker()
+	}
+	return r
 }
 
 // ValidateCookie validates cookies
 func (r *Routes) validateCookie(req *http.Request) bool {
 
 	// If empty - all access is granted
-	if len(ApiKeyHandler.ApiHandler.ApiKeyHashes) == 0 {
+	if ApiKeyHandler.ApiHandler.CheckIfEmpty() {
 		return true
 	}
 
@@ -102,8 +125,8 @@ func (r *Routes) Login(w http.ResponseWriter, req *http.Request) {
 			w.Write([]byte("Error parsing form"))
 			return
 		}
-		// Check if the ApiKey is valid
-		if r.ApiKeyHandler.CheckApiKey(req.FormValue("password")) {
+		// Check if the ApiKey is valid - the web interface can do everything, only the global admin keys can log in
+		if key, ok := r.ApiKeyHandler.Lookup(req.FormValue("password")); ok && key.IsGlobal() {
 			r.createCookie(w)
 			http.Redirect(w, req, "/", http.StatusSeeOther)
 			return
@@ -133,7 +156,7 @@ func (r *Routes) Logout(w http.ResponseWriter, req *http.Request) {
 func (r *Routes) Index(w http.ResponseWriter, req *http.Request) {
 	if req.Method == "GET" && req.URL.Path == "/" {
 		// Check if there are ApiKeys in the system
-		if len(r.ApiKeyHandler.ApiKeyHashes) == 0 || r.validateCookie(req) {
+		if r.ApiKeyHandler.CheckIfEmpty() || r.validateCookie(req) {
 			err := r.renderTemplate("index", w, NewData())
 			if err != nil {
 				panic(err.Error())
@@ -168,33 +191,35 @@ func (r *Routes) Delete(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// Check if the ApiKey is valid
-		if r.validateCookie(req) || r.ApiKeyHandler.CheckApiKey(dc.ApiKey) { // added cookiecheck - because button ui
-			// Delete all Classifiers of the Collection
-			r.DB.Collections[dc.Name].DeleteAllClassifiers()
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, dc.ApiKey)
+		if !ok {
+			return
+		}
+		if !r.allowed(w, key, dc.Name, Vdb.FullAccess) {
+			return
+		}
+		c, _ := r.DB.GetCollection(dc.Name)
 
-			// Call the function in the Vdb
-			err = r.DB.DeleteCollection(dc.Name)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
-			// Send the success or error message to the client
-			w.WriteHeader(http.StatusOK)
-			status := struct {
-				Status  string `json:"status"`
-				Message string `json:"message"`
-			}{
-				Status:  "success",
-				Message: "Collection deleted",
-			}
-			json.NewEncoder(w).Encode(status)
+		// Call the function in the Vdb
+		err = r.DB.DeleteCollection(dc.Name)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
 			return
 		}
-		// Send the unauthorized message to the client
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Delete all Classifiers of the Collection - only after the Vdb accepted the delete
+		c.DeleteAllClassifiers()
+		// Send the success or error message to the client
+		w.WriteHeader(http.StatusOK)
+		status := struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		}{
+			Status:  "success",
+			Message: "Collection deleted",
+		}
+		json.NewEncoder(w).Encode(status)
 		return
 	}
 }
@@ -233,53 +258,89 @@ func (r *Routes) CreateCollection(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(cc.ApiKey) || r.validateCookie(req) {
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, cc.ApiKey)
+		if !ok {
+			return
+		}
+		// A key of restricted collections only creates the ones it may use
+		if !key.CanUse(cc.Name) {
+			forbidden(w)
+			return
+		}
 
-			// Check if name is empty
-			if cc.Name == "" || cc.Dimensions == 0 {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Variables Missing"))
+		// The Collections of the keys of a tenant belong to the tenant, only keys without tenant share Collections
+		if tenant := tenantOf(key); tenant != "" {
+			if cc.TenantField != "" || (cc.Tenant != "" && cc.Tenant != tenant) {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte("Forbidden"))
 				return
 			}
+			cc.Tenant = tenant
+		}
 
-			// Check if Collection exists
-			if _, ok := r.DB.Collections[cc.Name]; ok {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Collection with name " + cc.Name + " allready exists"))
-				return
-			}
+		// Check if name is empty
+		if cc.Name == "" || (cc.Dimensions == 0 && len(cc.VectorFields) == 0 && len(cc.MultiFields) == 0 &&
+			len(cc.BinaryFields) == 0) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
 
-			// There is a wait bool - if true the function will wait for the collection to be created
-			if cc.Wait {
-				// Choose distance function from Distancefunction string
-				if strings.ToLower(cc.DistanceFunction) != "euclid" {
-					cc.DistanceFunction = "cosine"
-				}
-				err = r.DB.AddCollection(cc.Name, cc.Dimensions, cc.DistanceFunction)
-				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					w.Write([]byte(err.Error()))
-					return
-				}
-				// Send the success or error message to the client
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte("Collection created"))
-				return
-			} else {
-				// Create the Collection
-				go r.DB.AddCollection(cc.Name, cc.Dimensions, cc.DistanceFunction)
-				// Send the success or error message to the client
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte("Collection created"))
+		// The config of the new Collection
+		config := cc.Config()
+		err = config.Validate()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Check if Collection exists
+		if _, ok := r.DB.GetCollection(cc.Name); ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection with name " + cc.Name + " allready exists"))
+			return
+		}
+
+		// Check if the tenant exists
+		if cc.Tenant != "" && !r.DB.TenantExists(cc.Tenant) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Tenant with name " + cc.Tenant + " does not exist"))
+			return
+		}
+
+		// Check if an alias has the name
+		if r.DB.IsAlias(cc.Name) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Alias with name " + cc.Name + " allready exists"))
+			return
+		}
+
+		// There is a wait bool - if true the function will wait for the collection to be created
+		if cc.Wait {
+			// Choose distance function from Distancefunction string
+			if strings.ToLower(cc.DistanceFunction) != "euclid" {
+				config.DistanceFuncName = "cosine"
+			}
+			err = r.DB.AddCollectionFromConfig(config)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(err.Error()))
 				return
 			}
+			// Send the success or error message to the client
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Collection created"))
+			return
+		} else {
+			// Create the Collection
+			go r.DB.AddCollectionFromConfig(config)
+			// Send the success or error message to the client
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Collection created"))
+			return
 		}
-
-		// Not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
-		return
 	}
 
 	// Notice the user that the route is not found under given information
@@ -294,23 +355,34 @@ func (r *Routes) ListCollections(w http.ResponseWriter, req *http.Request) {
 	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/listcollections" {
 		// Create CollectionList type
 		cl := &CollectionList{}
+		// The body with the ApiKey is optional
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		err := json.NewDecoder(req.Body).Decode(cl)
+		if err != nil && err != io.EOF {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(cl.ApiKey) || r.validateCookie(req) {
-			// Get the Collections
-			collections := r.DB.ListCollections()
-			cl.Collections = collections
-
-			// Send the collections to the client
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(cl)
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, cl.ApiKey)
+		if !ok {
 			return
 		}
+		// Get the Collections - a tenant only sees its own and the shared ones, a key only the ones it may use
+		collections := make([]string, 0)
+		for _, c := range r.DB.ListCollections(tenantOf(key)) {
+			if key.CanUse(c) {
+				collections = append(collections, c)
+			}
+		}
+		cl.Collections = collections
+		cl.ApiKey = ""
 
-		// Not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Send the collections to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(cl)
 		return
 	}
 	// Notice the user that the route is not found under given information
@@ -341,43 +413,60 @@ func (r *Routes) AddPoint(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(p.ApiKey) || r.validateCookie(req) {
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, p.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		p.CollectionName = r.DB.ResolveAlias(p.CollectionName)
+		if !r.allowed(w, key, p.CollectionName, Vdb.PartitionAccess) {
+			return
+		}
 
-			// Checks if the CollectionName and the Vector are set
-			if p.CollectionName == "" || p.Vector == nil {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Missing required fields"))
-				return
-			}
+		// Checks if the CollectionName and the Vector (or named / multi / binary vectors) are set
+		if p.CollectionName == "" || (p.Vector == nil && len(p.Vectors) == 0 && len(p.MultiVectors) == 0 &&
+			len(p.BinaryVectors) == 0) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Missing required fields"))
+			return
+		}
 
-			// Check if Collection exists
-			if _, ok := r.DB.Collections[p.CollectionName]; !ok {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Collection does not exist"))
-				return
-			}
+		// Check if Collection exists
+		if _, ok := r.DB.GetCollection(p.CollectionName); !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
 
-			// Add the point to the Collection
-			v := Vector.NewVector(p.Id, p.Vector, &p.Payload, p.CollectionName)
-			err = r.DB.Collections[p.CollectionName].Insert(v)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
+		// Put the point of a tenant into its partition of a shared Collection
+		item := &Vdb.PointItem{Id: p.Id, Vector: p.Vector, Payload: p.Payload, SparseVectors: p.SparseVectors,
+			Vectors: p.Vectors, MultiVectors: p.MultiVectors, BinaryVectors: p.BinaryVectors,
+			TTLSeconds: p.TTLSeconds, ExpiresAt: p.ExpiresAt}
+		err = r.DB.PartitionPoint(tenantOf(key), p.CollectionName, item)
+		if err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(err.Error()))
+			return
+		}
 
-			// Send the success or error message to the client
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("Point added"))
+		// Create the vector with all its fields and add it to the Collection - without a vector the point was
+		// not valid
+		v, err := r.DB.AddPoint(p.CollectionName, item)
+		if v == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		} else if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
 			return
 		}
 
-		// Not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Send the success or error message to the client
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Point added"))
 		return
-
 	}
 
 	// Notice the user that the route is not found under given information
@@ -386,9 +475,12 @@ func (r *Routes) AddPoint(w http.ResponseWriter, req *http.Request) {
 	return
 }
 
-// AddPointBatch adds a batch of points to a Collection
+// AddPointBatch adds a batch of points to a Collection. The batch is queued for the ingest workers, with wait the
+// result of every point is returned, otherwise a job id that can be polled by IngestStatus
 func (r *Routes) AddPointBatch(w http.ResponseWriter, req *http.Request) {
 	if req.Method == http.MethodPut && strings.ToLower(req.URL.String()) == "/addpointbatch" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 100000000)
 		// Parse the form
 		err := req.ParseForm()
 		if err != nil {
@@ -406,47 +498,62 @@ func (r *Routes) AddPointBatch(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(pb.ApiKey) || r.validateCookie(req) {
-			// Name, Vector are required
-			if pb.CollectionName == "" || pb.Points == nil {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Missing required fields"))
-				return
-			}
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, pb.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		pb.CollectionName = r.DB.ResolveAlias(pb.CollectionName)
+		if !r.allowed(w, key, pb.CollectionName, Vdb.PartitionAccess) {
+			return
+		}
 
-			// Check if Collection exists
-			if _, ok := r.DB.Collections[pb.CollectionName]; !ok {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Collection does not exist"))
+		// Name, Vector are required
+		if pb.CollectionName == "" || len(pb.Points) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Missing required fields"))
+			return
+		}
+
+		// Check if Collection exists
+		if _, ok := r.DB.GetCollection(pb.CollectionName); !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		// Put the points of a tenant into its partition of a shared Collection
+		for i := range pb.Points {
+			err = r.DB.PartitionPoint(tenantOf(key), pb.CollectionName, &pb.Points[i])
+			if err != nil {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(fmt.Sprintf("Point %d: %s", i, err.Error())))
 				return
 			}
+		}
 
-			// Add the points to the Collection
-			go func() {
-				for _, p := range pb.Points {
-					r.AData <- "ADD"
-					d := p.Payload // This is no longer necessary from GO >= 1.22
-					v := Vector.NewVector(p.Id, p.Vector, &d, pb.CollectionName)
-					err = r.DB.Collections[pb.CollectionName].Insert(v)
-					if err != nil {
-						Logger.Log.Log("Error in BulkAdd: " + err.Error())
-						return
-					}
-				}
-			}()
+		// Queue the points - if the queue is full the client has to try again later
+		job := newIngestJob(&pb, tenantOf(key))
+		if !r.enqueueIngestJob(job) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("Ingest queue is full"))
+			return
+		}
 
-			// Send the success or error message to the client
+		// Wait for the job and send the result of every point
+		w.Header().Set("Content-Type", "application/json")
+		if pb.Wait {
+			<-job.done
 			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("Points bulk added"))
+			json.NewEncoder(w).Encode(job.snapshot(true))
 			return
 		}
 
-		// Not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Send the job id to the client
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job.snapshot(false))
 		return
-
 	}
 
 	// Notice the user that the route is not found under given information
@@ -455,10 +562,9 @@ func (r *Routes) AddPointBatch(w http.ResponseWriter, req *http.Request) {
 	return
 }
 
-// DeletePoint deletes a point from a Collection
-func (r *Routes) DeletePoint(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "DELETE"
-	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/deletepoint" {
+// IngestStatus returns the state of a queued point batch and the result of every point
+func (r *Routes) IngestStatus(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/ingeststatus" {
 		// Limit the size of the request
 		req.Body = http.MaxBytesReader(w, req.Body, 5000)
 		// Parse the form
@@ -468,42 +574,33 @@ func (r *Routes) DeletePoint(w http.ResponseWriter, req *http.Request) {
 			w.Write([]byte("Error parsing form"))
 			return
 		}
-		// load the request into the DeletePoint via json decode
-		dp := &DeletePoint{}
-		err = json.NewDecoder(req.Body).Decode(dp)
+
+		// load the request into the IngestStatus via json decode
+		is := IngestStatus{}
+		err = json.NewDecoder(req.Body).Decode(&is)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("Error decoding json"))
 			return
 		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(dp.ApiKey) || r.validateCookie(req) {
-
-			// Check if Collection exists
-			if _, ok := r.DB.Collections[dp.CollectionName]; !ok {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Collection does not exist"))
-				return
-			}
-
-			// Delete the point from the Collection
-			err = r.DB.Collections[dp.CollectionName].Delete(dp.Id)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, is.ApiKey)
+		if !ok {
+			return
+		}
+		// Check if the job exists
+		job, ok := r.getIngestJob(is.JobId, tenantOf(key))
+		if !ok || !key.CanUse(job.CollectionName) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Job does not exist"))
+			return
 		}
 
-		// not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
-		return
-
-		// Send the success or error message to the client
+		// Send the state of the job to the client
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Point deleted"))
+		json.NewEncoder(w).Encode(job.snapshot(true))
 		return
 	}
 
@@ -513,10 +610,12 @@ func (r *Routes) DeletePoint(w http.ResponseWriter, req *http.Request) {
 	return
 }
 
-// Search searches for the nearest neighbours of the given target vector
-func (r *Routes) Search(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "SEARCH"
-	if req.Method == http.MethodGet {
+// Export streams the points of a collection as JSON lines, one point with all its fields per line
+func (r *Routes) Export(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/export" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
 		// Parse the form
 		err := req.ParseForm()
 		if err != nil {
@@ -524,72 +623,50 @@ func (r *Routes) Search(w http.ResponseWriter, req *http.Request) {
 			w.Write([]byte("Error parsing form"))
 			return
 		}
-		// load the request into the Point via json decode
-		p := &Point{}
-		err = json.NewDecoder(req.Body).Decode(p)
+
+		// load the request into the ExportRequest via json decode
+		er := ExportRequest{}
+		err = json.NewDecoder(req.Body).Decode(&er)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("Error decoding json"))
 			return
 		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(p.ApiKey) || r.validateCookie(req) {
-
-			// Check if possible Filter is valid
-			if err := p.ValidateFilter(); err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte(err.Error()))
-				return
-			}
-
-			// Name, Vector are required
-			if p.CollectionName == "" || p.Vector == nil {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Missing required fields"))
-				return
-			}
-
-			// Check if Collection exists
-			if _, ok := r.DB.Collections[p.CollectionName]; !ok {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Collection does not exist"))
-				return
-			}
-
-			// Search for the nearest neighbours
-			var queue *Utils.HeapControl
-			if p.Depth == 0 {
-				queue = Utils.NewHeapControl(3)
-			} else {
-				queue = Utils.NewHeapControl(p.Depth)
-			}
-
-			// Set the resultset
-			var results []*Utils.ResultSet
-
-			// Check if Index is set
-			switch p.Index {
-			case nil:
-				results = r.DB.Search(p.CollectionName, Vector.NewVector(p.Id, p.Vector, &p.Payload, ""), queue,
-					p.MaxDistancePercent, p.Filter)
-			default:
-				results = r.DB.IndexSearch(p.CollectionName, Vector.NewVector(p.Id, p.Vector, &p.Payload, ""),
-					queue, p.MaxDistancePercent, p.Filter, p.Index.IndexName, p.Index.IndexValue)
-			}
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, er.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		er.CollectionName = r.DB.ResolveAlias(er.CollectionName)
+		if !r.allowed(w, key, er.CollectionName, Vdb.PartitionAccess) {
+			return
+		}
 
-			// Send the results to the client
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(results)
+		// Check if the collection exists
+		if _, ok := r.DB.GetCollection(er.CollectionName); !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+		// Check the filter before the stream starts
+		if err := r.DB.CheckFilter(er.CollectionName, er.Filter); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
 			return
 		}
 
-		// not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Stream the points - an error after the start can only end the stream
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		n, err := r.DB.Export(er.CollectionName, r.DB.TenantFilter(tenantOf(key), er.CollectionName, er.Filter), w)
+		if err != nil {
+			Logger.Log.Log("Error exporting collection " + er.CollectionName + ": " + err.Error())
+			return
+		}
+		Logger.Log.Log(fmt.Sprintf("Exported %d points of collection %s", n, er.CollectionName))
 		return
-
 	}
 
 	// Notice the user that the route is not found under given information
@@ -598,10 +675,13 @@ func (r *Routes) Search(w http.ResponseWriter, req *http.Request) {
 	return
 }
 
-// TrainClassifier trains a classifier
-func (r *Routes) TrainClassifier(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "SYSTEMEVENT"
-	if req.Method == http.MethodPut && strings.ToLower(req.URL.String()) == "/trainclassifier" {
+// Import inserts points that are send as JSON lines after an ImportHeader line. Large imports are send in chunks,
+// a chunk that was interrupted is resumed from the lines of the import that ImportStatus reports.
+func (r *Routes) Import(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/import" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 100000000)
 		// Parse the form
 		err := req.ParseForm()
 		if err != nil {
@@ -609,72 +689,61 @@ func (r *Routes) TrainClassifier(w http.ResponseWriter, req *http.Request) {
 			w.Write([]byte("Error parsing form"))
 			return
 		}
-		// load the request into the TrainClassifier via json decode
-		tc := &Classifier{}
-		err = json.NewDecoder(req.Body).Decode(tc)
+
+		// load the first line into the ImportHeader via json decode
+		body := bufio.NewReader(req.Body)
+		line, err := readHeaderLine(body)
+		ih := ImportHeader{}
+		if err == nil || err == io.EOF {
+			err = json.Unmarshal(line, &ih)
+		}
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("Error decoding json"))
 			return
 		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(tc.ApiKey) || r.validateCookie(req) {
-
-			// Check if Collection exists
-			if _, ok := r.DB.Collections[tc.CollectionName]; !ok {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Collection does not exist"))
-				return
-			}
-
-			// Check if Collection is ClassifierReady
-			if !r.DB.Collections[tc.CollectionName].ClassifierReady {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Collection is not ready for classification"))
-				return
-			}
-
-			// Check if Type exists
-			if tc.Type == "" {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Type is missing"))
-				return
-			}
-
-			// Create the classifier in the collection
-			err = r.DB.Collections[tc.CollectionName].AddClassifier(tc.ClassifierName, tc.Type, tc.Loss, tc.Architecture)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
-
-			// if tc.c is not set - set it
-			if tc.C == 0 {
-				tc.C = 0.000001
-			}
-
-			// Train the classifier non blocking
-			go func() {
-				err := r.DB.Collections[tc.CollectionName].TrainClassifier(tc.ClassifierName, tc.Degree, tc.C,
-					tc.Epochs, tc.Batchsize)
-				if err != nil {
-					Logger.Log.Log(err.Error())
-				}
-			}()
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, ih.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		ih.CollectionName = r.DB.ResolveAlias(ih.CollectionName)
+		if !r.allowed(w, key, ih.CollectionName, Vdb.PartitionAccess) {
+			return
+		}
 
-			// Send the success or error message to the client
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("Classifier created and training started"))
+		// Check if the collection exists
+		if _, ok := r.DB.GetCollection(ih.CollectionName); !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
 			return
 		}
 
-		// not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
-		return
+		// Find the import - lines that were imported by an interrupted chunk are skipped
+		job, skip, status, err := r.startImportChunk(&ih, tenantOf(key))
+		if err != nil {
+			w.WriteHeader(status)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		lines, err := r.DB.Import(ih.CollectionName, tenantOf(key), body, skip, func(line int, id string, err error) {
+			r.AData <- "ADD"
+			job.report(ih.Offset, line, id, err)
+		})
+		job.finishChunk(ih.Offset, lines)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
 
+		// Send the state of the import to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(job.snapshot())
+		return
 	}
 
 	// Notice the user that the route is not found under given information
@@ -683,13 +752,11 @@ func (r *Routes) TrainClassifier(w http.ResponseWriter, req *http.Request) {
 	return
 }
 
-// DeleteClassifier will delete a classifier
-func (r *Routes) DeleteClassifier(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "TRANSACTION"
-	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/deleteclassifier" {
+// ImportStatus returns the progress of an import and the errors of its lines
+func (r *Routes) ImportStatus(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/importstatus" {
 		// Limit the size of the request
 		req.Body = http.MaxBytesReader(w, req.Body, 5000)
-
 		// Parse the form
 		err := req.ParseForm()
 		if err != nil {
@@ -698,111 +765,151 @@ func (r *Routes) DeleteClassifier(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// load the request into the DeleteClassifier via json decode
-		dc := &DeleteClassifier{}
-		err = json.NewDecoder(req.Body).Decode(dc)
+		// load the request into the ImportStatus via json decode
+		is := ImportStatus{}
+		err = json.NewDecoder(req.Body).Decode(&is)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("Error decoding json"))
 			return
 		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(dc.ApiKey) || r.validateCookie(req) {
-
-			// Check if Collection exists
-			if _, ok := r.DB.Collections[dc.CollectionName]; !ok {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Collection does not exist"))
-				return
-			}
-
-			// Delete the classifier from the collection
-			r.DB.Collections[dc.CollectionName].DeleteClassifier(dc.ClassifierName)
-
-			// Log the deletion
-			Logger.Log.Log("Classifier " + dc.ClassifierName + " in Collection " + dc.CollectionName + " deleted")
-
-			// Send the success or error message to the client
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("Classifier deleted"))
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, is.ApiKey)
+		if !ok {
+			return
+		}
+		// Check if the import exists
+		job, ok := r.getImportJob(is.ImportId, tenantOf(key))
+		if !ok || !key.CanUse(job.CollectionName) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Import does not exist"))
 			return
 		}
 
-		// Not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Send the state of the import to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(job.snapshot())
 		return
-
 	}
+
 	// Notice the user that the route is not found under given information
 	w.WriteHeader(http.StatusNotFound)
 	w.Write([]byte("Not Found"))
 	return
 }
 
-// GetTrainPhase will return the training phase of a classifier
-func (r *Routes) GetTrainPhase(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "SYSTEMEVENT"
-	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/gettrainphase" {
-		// load the request into the TrainPhase via json decode
-		tp := &ShowTrainProgress{}
-		err := json.NewDecoder(req.Body).Decode(tp)
+// LoadDataset loads the vectors of a benchmark dataset (.fvecs, .bvecs or .npy) into a collection, it is created with
+// the dimension of the dataset if it does not exist. The file is read from the dataset directory or uploaded after the
+// DatasetLoader line. The vectors are loaded by a DatasetJob in the background unless the client waits for it.
+func (r *Routes) LoadDataset(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/loaddataset" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 1<<34)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the first line into the DatasetLoader via json decode
+		body := bufio.NewReader(req.Body)
+		line, err := readHeaderLine(body)
+		dl := DatasetLoader{}
+		if err == nil || err == io.EOF {
+			err = json.Unmarshal(line, &dl)
+		}
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("Error decoding json"))
 			return
 		}
 
-		// Check if Auth is valid
-		if r.validateCookie(req) {
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, dl.ApiKey)
+		if !ok {
+			return
+		}
+		// Only the global admin keys work on the files of the server
+		if !r.allowedGlobal(w, key) {
+			return
+		}
+		// Resolve an alias to its collection
+		dl.CollectionName = r.DB.ResolveAlias(dl.CollectionName)
 
-			// Check if Collection exists
-			if _, ok := r.DB.Collections[tp.CollectionName]; !ok {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Collection does not exist"))
-				return
-			}
+		// Check if the collection name is set
+		if dl.CollectionName == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Missing required fields"))
+			return
+		}
 
-			// Check if Classifier exists
-			if _, ok := r.DB.Collections[tp.CollectionName].Classifiers[tp.ClassifierName]; !ok {
+		// Open the file of the dataset directory or keep the upload in a temporary file
+		var file *os.File
+		upload := dl.File == ""
+		if upload {
+			file, err = spoolDataset(body)
+		} else {
+			if strings.Contains(dl.File, "..") || strings.HasPrefix(dl.File, "/") {
 				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Classifier does not exist"))
+				w.Write([]byte("File has to be in the dataset directory"))
 				return
 			}
+			file, err = os.Open(*ArgsParser.Ap.DatasetDir + dl.File)
+			if dl.Format == "" {
+				dl.Format = Dataset.FormatOf(dl.File)
+			}
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		reader, err := Dataset.NewReader(file, strings.ToLower(dl.Format))
+		if err != nil {
+			closeDataset(file, upload)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
 
-			// Get the training phase
-			phase, err := r.DB.Collections[tp.CollectionName].GetClassifierTrainingPhase(tp.ClassifierName)
-
-			// Check if there was an error
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
+		// Insert the vectors in the background
+		job := r.startDatasetJob(&dl, reader, file, upload)
+		w.Header().Set("Content-Type", "application/json")
+		if dl.Wait {
+			<-job.done
+			result := job.snapshot()
+			if result.Error != "" {
+				w.WriteHeader(http.StatusBadRequest)
+			} else {
+				w.WriteHeader(http.StatusOK)
 			}
-			// Send the training phase to the client
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(*phase)
+			json.NewEncoder(w).Encode(result)
 			return
 		}
 
-		// Not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Send the job id to the client
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job.snapshot())
 		return
-
 	}
+
 	// Notice the user that the route is not found under given information
 	w.WriteHeader(http.StatusNotFound)
 	w.Write([]byte("Not Found"))
 	return
 }
 
-// Classify will classify a vector
-func (r *Routes) Classify(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "CLASSIFY"
-	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/classify" {
+// DatasetStatus returns the state of a DatasetJob
+func (r *Routes) DatasetStatus(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/datasetstatus" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
 		// Parse the form
 		err := req.ParseForm()
 		if err != nil {
@@ -811,86 +918,47 @@ func (r *Routes) Classify(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// load the request into the Classify via json decode
-		c := &Classify{}
-		err = json.NewDecoder(req.Body).Decode(c)
+		// load the request into the DatasetStatus via json decode
+		ds := DatasetStatus{}
+		err = json.NewDecoder(req.Body).Decode(&ds)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("Error decoding json"))
 			return
 		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(c.ApiKey) || r.validateCookie(req) {
-
-			// Check if Collection exists
-			if _, ok := r.DB.Collections[c.CollectionName]; !ok {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Collection does not exist"))
-				return
-			}
-
-			// Check if Classifier exists
-			if _, ok := r.DB.Collections[c.CollectionName].Classifiers[c.ClassifierName]; !ok {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Classifier does not exist"))
-				return
-			}
-
-			// Check if the vector is of the right dimension
-			if len(c.Vector) != r.DB.Collections[c.CollectionName].VectorDimension {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Vector has wrong dimension it should be " + fmt.Sprint(r.DB.Collections[c.CollectionName].VectorDimension) + " but is " + fmt.Sprint(len(c.Vector)) + " long"))
-				return
-			}
-
-			// Classify the vector
-			class := r.DB.Collections[c.CollectionName].Classifiers[c.ClassifierName].Predict(c.Vector)
-
-			// Send the class to the client
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-
-			// Type switch - a classifier can have various returns
-			switch class.(type) {
-			case int:
-				json.NewEncoder(w).Encode(struct {
-					Class int `json:"class"`
-				}{
-					Class: class.(int),
-				})
-			case []float64:
-				json.NewEncoder(w).Encode(struct {
-					Class []float64 `json:"class"`
-				}{
-					Class: class.([]float64),
-				})
-			case float64:
-				json.NewEncoder(w).Encode(struct {
-					Class float64 `json:"class"`
-				}{
-					Class: class.(float64),
-				})
-			}
+		// Only the global admin keys load datasets
+		key, ok := r.requestKey(w, req, ds.ApiKey)
+		if !ok || !r.allowedGlobal(w, key) {
+			return
+		}
+		// Check if the job exists
+		job, ok := r.getDatasetJob(ds.JobId)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Job does not exist"))
 			return
 		}
 
-		// Not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Send the state of the job to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(job.snapshot())
 		return
-
 	}
+
 	// Notice the user that the route is not found under given information
 	w.WriteHeader(http.StatusNotFound)
 	w.Write([]byte("Not Found"))
 	return
 }
 
-// CreateApiKey creates a new ApiKey
-func (r *Routes) CreateApiKey(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "SYSTEMEVENT"
-	if req.Method == http.MethodPut && strings.ToLower(req.URL.String()) == "/createapikey" {
+// Fsck checks the files of a collection or of all collections and returns the reports
+func (r *Routes) Fsck(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/fsck" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
 		// Parse the form
 		err := req.ParseForm()
 		if err != nil {
@@ -899,53 +967,66 @@ func (r *Routes) CreateApiKey(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// load the request into the ApiKeyCreator via json decode
-		ac := &ApiKeyCreator{}
-		err = json.NewDecoder(req.Body).Decode(ac)
+		// load the request into the FsckRequest via json decode
+		fr := FsckRequest{}
+		err = json.NewDecoder(req.Body).Decode(&fr)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("Error decoding json"))
 			return
 		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(ac.ApiKey) || r.validateCookie(req) {
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, fr.ApiKey)
+		if !ok {
+			return
+		}
+		// Only the global admin keys work on the files of the server
+		if !r.allowedGlobal(w, key) {
+			return
+		}
+		// Resolve an alias to its collection
+		fr.CollectionName = r.DB.ResolveAlias(fr.CollectionName)
 
-			// Create the ApiKey
-			key, err := r.ApiKeyHandler.CreateApiKey()
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
+		names := r.DB.ListCollections("")
+		if fr.CollectionName != "" {
+			// Check if the collection exists
+			if _, ok := r.DB.GetCollection(fr.CollectionName); !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("Collection does not exist"))
 				return
 			}
+			names = []string{fr.CollectionName}
+		}
+		sort.Strings(names)
 
-			// Return the apikey to the client
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(ApiKeyCreator{
-				ApiKey: key,
-			})
-			return
+		// Check the collections one after the other
+		reports := make([]*Fsck.Report, 0, len(names))
+		for _, name := range names {
+			if c, ok := r.DB.GetCollection(name); ok {
+				reports = append(reports, c.Check(fr.Repair))
+			}
 		}
 
-		// not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Send the reports to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(reports)
 		return
 	}
+
 	// Notice the user that the route is not found under given information
 	w.WriteHeader(http.StatusNotFound)
 	w.Write([]byte("Not Found"))
 	return
 }
 
-// DeleteApiKey deletes an ApiKey
-func (r *Routes) DeleteApiKey(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "SYSTEMEVENT"
-	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/deleteapikey" {
+// CreateSnapshot writes a point-in-time snapshot of a collection into the snapshot directory
+func (r *Routes) CreateSnapshot(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/createsnapshot" {
 		// Limit the size of the request
 		req.Body = http.MaxBytesReader(w, req.Body, 5000)
-
 		// Parse the form
 		err := req.ParseForm()
 		if err != nil {
@@ -954,45 +1035,61 @@ func (r *Routes) DeleteApiKey(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// load the request into the DeleteApiKey via json decode
-		da := &ApiKeyCreator{}
-		err = json.NewDecoder(req.Body).Decode(da)
+		// load the request into the SnapshotRequest via json decode
+		sr := SnapshotRequest{}
+		err = json.NewDecoder(req.Body).Decode(&sr)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("Error decoding json"))
 			return
 		}
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(da.ApiKey) || r.validateCookie(req) {
-			// Delete the ApiKey
-			err = r.ApiKeyHandler.DeleteApiKey(da.ApiKey)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, sr.ApiKey)
+		if !ok {
+			return
+		}
+		// Only the global admin keys work on the snapshots, they hold the points of all tenants
+		if !r.allowedGlobal(w, key) {
+			return
+		}
+		// Resolve an alias to its collection
+		sr.CollectionName = r.DB.ResolveAlias(sr.CollectionName)
 
-			// Send the success or error message to the client
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("ApiKey deleted"))
+		// Check if the collection exists
+		if _, ok := r.DB.GetCollection(sr.CollectionName); !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		// Write the snapshot - writes to the collection are only paused while its files are opened
+		manifest, err := r.DB.CreateSnapshot(sr.CollectionName)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
 			return
 		}
-		// Not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+
+		// Send the manifest to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(manifest)
 		return
 	}
+
 	// Notice the user that the route is not found under given information
 	w.WriteHeader(http.StatusNotFound)
 	w.Write([]byte("Not Found"))
 	return
 }
 
-// CreateIndex will create an index
-func (r *Routes) CreateIndex(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "SYSTEMEVENT"
-	if req.Method == http.MethodPut && strings.ToLower(req.URL.String()) == "/createindex" {
+// ListSnapshots lists the snapshots, optionally only those of one collection
+func (r *Routes) ListSnapshots(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/listsnapshots" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
 		// Parse the form
 		err := req.ParseForm()
 		if err != nil {
@@ -1001,129 +1098,2042 @@ func (r *Routes) CreateIndex(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// load the request into the IndexCreator via json decode
-		ic := &IndexCreator{}
-		err = json.NewDecoder(req.Body).Decode(ic)
-		if err != nil {
+		// load the request into the SnapshotRequest via json decode - the body is optional
+		sr := SnapshotRequest{}
+		err = json.NewDecoder(req.Body).Decode(&sr)
+		if err != nil && err != io.EOF {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("Error decoding json"))
 			return
 		}
 
-		// Check if all field of the IndexCreator are set
-		if ic.ApiKey == "" || ic.CollectionName == "" || ic.IndexName == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Missing required fields"))
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, sr.ApiKey)
+		if !ok {
 			return
 		}
+		// Only the global admin keys work on the snapshots, they hold the points of all tenants
+		if !r.allowedGlobal(w, key) {
+			return
+		}
+		// Resolve an alias to its collection
+		sr.CollectionName = r.DB.ResolveAlias(sr.CollectionName)
 
-		// Check if Auth is valid
-		if r.ApiKeyHandler.CheckApiKey(ic.ApiKey) || r.validateCookie(req) {
-			// Create the Index
-			err = r.DB.Collections[ic.CollectionName].CreateIndex(ic.IndexName, ic.IndexName)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
-
-			// Send the success or error message to the client
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("Index created"))
+		manifests, err := r.DB.ListSnapshots(sr.CollectionName)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
 			return
 		}
 
-		// not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Send the manifests to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(manifests)
 		return
 	}
+
 	// Notice the user that the route is not found under given information
 	w.WriteHeader(http.StatusNotFound)
 	w.Write([]byte("Not Found"))
 	return
-
 }
 
-// GetAccessData will return the AccessData
-func (r *Routes) GetAccessData(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "SYSTEMEVENT"
-	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/getaccessdata" {
-		// Check if Auth is valid
-		if r.validateCookie(req) {
-			// Get the data
-			accessList := AccessDataHUB.AccessList.GetData()
-			// Send the AccessData to the client
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(accessList)
+// RestoreSnapshot restores a snapshot into its collection or into a new one
+func (r *Routes) RestoreSnapshot(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/restoresnapshot" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the SnapshotRequest via json decode
+		sr := SnapshotRequest{}
+		err = json.NewDecoder(req.Body).Decode(&sr)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, sr.ApiKey)
+		if !ok {
+			return
+		}
+		// Only the global admin keys work on the snapshots, they hold the points of all tenants
+		if !r.allowedGlobal(w, key) {
+			return
+		}
+		// Check if the snapshot name is set
+		if sr.SnapshotName == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+
+		// Restore the snapshot - into the original collection if no target is given
+		manifest, err := r.DB.RestoreSnapshot(sr.SnapshotName, sr.TargetName)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
 			return
 		}
 
-		// Not authorized
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized"))
+		// Send the manifest to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(manifest)
 		return
 	}
+
 	// Notice the user that the route is not found under given information
 	w.WriteHeader(http.StatusNotFound)
 	w.Write([]byte("Not Found"))
 	return
 }
 
-// showapikey will show the apikey
-func (r *Routes) ShowApiKey(w http.ResponseWriter, req *http.Request) {
-	r.AData <- "SYSTEMEVENT"
-	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/showapikey" {
-		// This will only work if there is no APIKEY
-		if len(ApiKeyHandler.ApiHandler.ApiKeyHashes) == 0 {
-			// Create the APIKEY
-			key, err := r.ApiKeyHandler.CreateApiKey()
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
-			// Create the data for the template to show the apikey
-			data := struct {
-				Data string
-			}{
-				Data: key,
+// DeleteSnapshot deletes a snapshot
+func (r *Routes) DeleteSnapshot(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/deletesnapshot" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the SnapshotRequest via json decode
+		sr := SnapshotRequest{}
+		err = json.NewDecoder(req.Body).Decode(&sr)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, sr.ApiKey)
+		if !ok {
+			return
+		}
+		// Only the global admin keys work on the snapshots, they hold the points of all tenants
+		if !r.allowedGlobal(w, key) {
+			return
+		}
+		// Check if the snapshot name is set
+		if sr.SnapshotName == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+
+		err = r.DB.DeleteSnapshot(sr.SnapshotName)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Snapshot deleted"))
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// DeletePoint deletes a point from a Collection
+func (r *Routes) DeletePoint(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "DELETE"
+	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/deletepoint" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+		// load the request into the DeletePoint via json decode
+		dp := &DeletePoint{}
+		err = json.NewDecoder(req.Body).Decode(dp)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, dp.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		dp.CollectionName = r.DB.ResolveAlias(dp.CollectionName)
+		if !r.allowed(w, key, dp.CollectionName, Vdb.PartitionAccess) {
+			return
+		}
+
+		// Check if Collection exists
+		collection, ok := r.DB.GetCollection(dp.CollectionName)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		// A tenant can only delete the points of its partition of a shared Collection
+		err = r.DB.CheckPartition(tenantOf(key), dp.CollectionName, dp.Id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Delete the point from the Collection
+		err = collection.Delete(dp.Id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Send the success or error message to the client
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Point deleted"))
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// Search searches for the nearest neighbours of the given target vector
+func (r *Routes) Search(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SEARCH"
+	if req.Method == http.MethodGet {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 10000000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+		// load the request into the Point via json decode
+		p := &Point{}
+		err = json.NewDecoder(req.Body).Decode(p)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, p.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		p.CollectionName = r.DB.ResolveAlias(p.CollectionName)
+		if !r.allowed(w, key, p.CollectionName, Vdb.PartitionAccess) {
+			return
+		}
+
+		// Check if possible Filter is valid
+		if err := p.ValidateFilter(r.DB); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Check the order and the score formula
+		formula, err := p.parseRanking()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Name and Vector, named vectors, multi vectors, binary vectors or SparseVectors are required - a query
+		// without a vector needs an order
+		noVector := p.Vector == nil && len(p.Vectors) == 0 && len(p.MultiVectors) == 0 &&
+			len(p.BinaryVectors) == 0 && len(p.SparseVectors) == 0
+		if p.CollectionName == "" || (noVector && p.OrderBy == nil) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Missing required fields"))
+			return
+		}
+
+		// Check if Collection exists
+		collection, ok := r.DB.GetCollection(p.CollectionName)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+		// A tenant only finds the points of its partition of a shared Collection
+		p.Filter = r.DB.TenantFilter(tenantOf(key), p.CollectionName, p.Filter)
+
+		// A query without a vector returns the points that pass the filter in the order
+		if noVector {
+			results, err := r.DB.Query(p.CollectionName, p.Filter, p.OrderBy, p.depth())
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(err.Error()))
+				return
 			}
-			//  Show the template
-			err = r.renderTemplate("showapikey", w, data)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(results)
+			return
+		}
+
+		// Sparse, named, multi and binary vectors are searched together with the dense vector in a hybrid search
+		if len(p.SparseVectors) > 0 || len(p.Vectors) > 0 || len(p.MultiVectors) > 0 || len(p.BinaryVectors) > 0 {
+			r.hybridSearch(w, p, formula)
+			return
+		}
+
+		// Check if the named vector field exists
+		if _, ok := collection.VectorFields[p.VectorName]; p.VectorName != "" && !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Vector field does not exist"))
+			return
+		}
+
+		// Search for the nearest neighbours - a score formula reranks more candidates
+		queue := Utils.NewHeapControl(p.candidates())
+
+		// Set the resultset
+		var results []*Utils.ResultSet
+
+		// Check if Index is set
+		switch {
+		case p.VectorName != "":
+			results = r.DB.FieldSearch(p.CollectionName, p.VectorName, Vector.NewVector(p.Id, p.Vector, &p.Payload, ""),
+				queue, p.MaxDistancePercent, p.Filter)
+		case p.Index == nil:
+			results = r.DB.Search(p.CollectionName, Vector.NewVector(p.Id, p.Vector, &p.Payload, ""), queue,
+				p.MaxDistancePercent, p.Filter)
+		default:
+			results = r.DB.IndexSearch(p.CollectionName, Vector.NewVector(p.Id, p.Vector, &p.Payload, ""),
+				queue, p.MaxDistancePercent, p.Filter, p.Index.IndexName, p.Index.IndexValue)
+		}
+		results = p.rank(formula, results)
+
+		// Send the results to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(results)
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// hybridSearch searches the dense vector, the named vectors, the multi vectors, the binary vectors and the sparse vectors
+// of the Point together
+func (r *Routes) hybridSearch(w http.ResponseWriter, p *Point, formula *Formula.Formula) {
+	collection, ok := r.DB.GetCollection(p.CollectionName)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Collection does not exist"))
+		return
+	}
+	// Check if the sparse and vector fields exist
+	err := collection.CheckSparseFields(p.SparseVectors)
+	if err == nil {
+		err = collection.CheckVectorFields(p.Vectors)
+	}
+	if err == nil {
+		err = collection.CheckMultiFields(p.MultiVectors)
+	}
+	if err == nil {
+		err = collection.CheckBinaryFields(p.BinaryVectors)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	// Create the target
+	target := Vector.NewVector(p.Id, p.Vector, &p.Payload, "")
+	for name, sparse := range p.SparseVectors {
+		err = target.SetSparse(name, sparse)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+	for name, data := range p.Vectors {
+		err = target.SetNamed(name, data)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+	for name, data := range p.MultiVectors {
+		err = target.SetMulti(name, data)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+	for name, data := range p.BinaryVectors {
+		err = target.SetBinary(name, data, Utils.Utils.PackBits(data), false)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+
+	// The depth defaults to 3, a score formula reranks more candidates
+	results := r.DB.HybridSearch(p.CollectionName, target, p.candidates(), p.Weights, p.Filter)
+	results = p.rank(formula, results)
+
+	// Send the results to the client
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+// TrainClassifier trains a classifier
+func (r *Routes) TrainClassifier(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodPut && strings.ToLower(req.URL.String()) == "/trainclassifier" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 100000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+		// load the request into the TrainClassifier via json decode
+		tc := &Classifier{}
+		err = json.NewDecoder(req.Body).Decode(tc)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, tc.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		tc.CollectionName = r.DB.ResolveAlias(tc.CollectionName)
+		if !r.allowed(w, key, tc.CollectionName, Vdb.FullAccess) {
+			return
+		}
+
+		// Check if Collection exists
+		collection, ok := r.DB.GetCollection(tc.CollectionName)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		// Check if Collection is ClassifierReady
+		if !collection.ClassifierReady {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection is not ready for classification"))
+			return
+		}
+
+		// Check if Type exists
+		if tc.Type == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Type is missing"))
+			return
+		}
+
+		// Create the classifier in the collection
+		err = collection.AddClassifier(tc.ClassifierName, tc.Type, tc.Loss, tc.Architecture)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// if tc.c is not set - set it
+		if tc.C == 0 {
+			tc.C = 0.000001
+		}
+
+		// Train the classifier non blocking
+		go func() {
+			err := collection.TrainClassifier(tc.ClassifierName, tc.Degree, tc.C, tc.Epochs, tc.Batchsize)
 			if err != nil {
 				Logger.Log.Log(err.Error())
 			}
+		}()
+
+		// Send the success or error message to the client
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Classifier created and training started"))
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// DeleteClassifier will delete a classifier
+func (r *Routes) DeleteClassifier(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/deleteclassifier" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the DeleteClassifier via json decode
+		dc := &DeleteClassifier{}
+		err = json.NewDecoder(req.Body).Decode(dc)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, dc.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		dc.CollectionName = r.DB.ResolveAlias(dc.CollectionName)
+		if !r.allowed(w, key, dc.CollectionName, Vdb.FullAccess) {
+			return
+		}
+
+		// Check if Collection exists
+		collection, ok := r.DB.GetCollection(dc.CollectionName)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		// Delete the classifier from the collection
+		collection.DeleteClassifier(dc.ClassifierName)
+
+		// Log the deletion
+		Logger.Log.Log("Classifier " + dc.ClassifierName + " in Collection " + dc.CollectionName + " deleted")
+
+		// Send the success or error message to the client
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Classifier deleted"))
+		return
+	}
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// GetTrainPhase will return the training phase of a classifier
+func (r *Routes) GetTrainPhase(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/gettrainphase" {
+		// load the request into the TrainPhase via json decode
+		tp := &ShowTrainProgress{}
+		err := json.NewDecoder(req.Body).Decode(tp)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Resolve an alias to its collection
+		tp.CollectionName = r.DB.ResolveAlias(tp.CollectionName)
+
+		// Check if Collection exists
+		collection, ok := r.DB.GetCollection(tp.CollectionName)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		// Check if Classifier exists
+		if _, ok := collection.Classifiers[tp.ClassifierName]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Classifier does not exist"))
+			return
+		}
+
+		// Get the training phase
+		phase, err := collection.GetClassifierTrainingPhase(tp.ClassifierName)
+
+		// Check if there was an error
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		// Send the training phase to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(*phase)
+		return
+	}
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// Classify will classify a vector
+func (r *Routes) Classify(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "CLASSIFY"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/classify" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 10000000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the Classify via json decode
+		c := &Classify{}
+		err = json.NewDecoder(req.Body).Decode(c)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, c.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		c.CollectionName = r.DB.ResolveAlias(c.CollectionName)
+		if !r.allowed(w, key, c.CollectionName, Vdb.FullAccess) {
+			return
+		}
+
+		// Check if Collection exists
+		collection, ok := r.DB.GetCollection(c.CollectionName)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		// Check if Classifier exists
+		if _, ok := collection.Classifiers[c.ClassifierName]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Classifier does not exist"))
+			return
+		}
+
+		// Check if the vector is of the right dimension
+		if len(c.Vector) != collection.VectorDimension {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Vector has wrong dimension it should be " + fmt.Sprint(collection.VectorDimension) + " but is " + fmt.Sprint(len(c.Vector)) + " long"))
+			return
+		}
+
+		// Classify the vector
+		class := collection.Classifiers[c.ClassifierName].Predict(c.Vector)
+
+		// Send the class to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		// Type switch - a classifier can have various returns
+		switch class.(type) {
+		case int:
+			json.NewEncoder(w).Encode(struct {
+				Class int `json:"class"`
+			}{
+				Class: class.(int),
+			})
+		case []float64:
+			json.NewEncoder(w).Encode(struct {
+				Class []float64 `json:"class"`
+			}{
+				Class: class.([]float64),
+			})
+		case float64:
+			json.NewEncoder(w).Encode(struct {
+				Class float64 `json:"class"`
+			}{
+				Class: class.(float64),
+			})
+		}
+		return
+	}
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// CreateApiKey creates a new ApiKey
+func (r *Routes) CreateApiKey(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodPut && strings.ToLower(req.URL.String()) == "/createapikey" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the ApiKeyCreator via json decode
+		ac := &ApiKeyCreator{}
+		err = json.NewDecoder(req.Body).Decode(ac)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		caller, ok := r.requestKey(w, req, ac.ApiKey)
+		if !ok {
+			return
+		}
+		// A key can not grant more than it has
+		if ac.Role == "" {
+			ac.Role = ApiKeyHandler.Admin
+		}
+		if !ApiKeyHandler.ValidRole(ac.Role) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Unknown role " + ac.Role))
+			return
+		}
+		if caller != nil && len(caller.Collections) > 0 {
+			if len(ac.Collections) == 0 {
+				ac.Collections = caller.Collections
+			}
+			for _, c := range ac.Collections {
+				if !caller.CanUse(c) {
+					forbidden(w)
+					return
+				}
+			}
+		}
+
+		// The keys of a tenant can only create keys of the tenant
+		if tenant := tenantOf(caller); tenant != "" {
+			if ac.Tenant != "" && ac.Tenant != tenant {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte("Forbidden"))
+				return
+			}
+			ac.Tenant = tenant
+		}
+		if ac.Tenant != "" && !r.DB.TenantExists(ac.Tenant) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Tenant with name " + ac.Tenant + " does not exist"))
+			return
+		}
+
+		// Check the role and the expiry
+		options := ApiKeyHandler.ApiKey{Name: ac.Name, Role: ac.Role, Collections: ac.Collections, Tenant: ac.Tenant,
+			Expires: ac.Expires}
+		err = options.Validate()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Create the ApiKey
+		key, created, err := r.ApiKeyHandler.CreateApiKey(options)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Return the apikey to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(ApiKeyCreator{
+			ApiKey:      key,
+			Id:          created.Id,
+			Name:        created.Name,
+			Role:        created.Role,
+			Collections: created.Collections,
+			Tenant:      created.Tenant,
+			Expires:     created.Expires,
+		})
+		return
+	}
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// DeleteApiKey deletes an ApiKey
+func (r *Routes) DeleteApiKey(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/deleteapikey" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the DeleteApiKey via json decode
+		da := &ApiKeyCreator{}
+		err = json.NewDecoder(req.Body).Decode(da)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Every key can delete itself, other keys are revoked by their id - a session of the web interface has no ApiKey
+		apiKey := apiKeyOf(req, da.ApiKey)
+		if apiKey == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+
+		// Delete the ApiKey
+		err = r.ApiKeyHandler.DeleteApiKey(apiKey)
+		if errors.Is(err, ApiKeyHandler.ErrApiKeyNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(err.Error()))
+			return
+		} else if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Send the success or error message to the client
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ApiKey deleted"))
+		return
+	}
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// ListApiKeys lists the ApiKeys with their metadata but without the keys, the admins of a tenant only get the keys of
+// the tenant
+func (r *Routes) ListApiKeys(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/listapikeys" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the ApiKeyRequest via json decode - the body is optional
+		ar := ApiKeyRequest{}
+		err = json.NewDecoder(req.Body).Decode(&ar)
+		if err != nil && err != io.EOF {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, ar.ApiKey)
+		if !ok {
+			return
+		}
+		// Only the admins of all collections manage the keys
+		if key != nil && len(key.Collections) > 0 {
+			forbidden(w)
+			return
+		}
+
+		// Send the keys to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(ApiKeyList{ApiKeys: r.ApiKeyHandler.ListApiKeys(tenantOf(key))})
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// RevokeApiKey deletes the ApiKey with the given id, the admins of a tenant can only revoke the keys of the tenant
+func (r *Routes) RevokeApiKey(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/revokeapikey" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the ApiKeyRequest via json decode
+		ar := ApiKeyRequest{}
+		err = json.NewDecoder(req.Body).Decode(&ar)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, ar.ApiKey)
+		if !ok {
+			return
+		}
+		// Only the admins of all collections manage the keys
+		if key != nil && len(key.Collections) > 0 {
+			forbidden(w)
+			return
+		}
+
+		// Check if the variables are set
+		if ar.Id == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+
+		err = r.ApiKeyHandler.RevokeApiKey(ar.Id, tenantOf(key))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ApiKey revoked"))
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// CreateIndex will create an index
+func (r *Routes) CreateIndex(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodPut && strings.ToLower(req.URL.String()) == "/createindex" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the IndexCreator via json decode
+		ic := &IndexCreator{}
+		err = json.NewDecoder(req.Body).Decode(ic)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Check if all field of the IndexCreator are set
+		if ic.ApiKey == "" || ic.CollectionName == "" || ic.IndexName == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Missing required fields"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, ic.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		ic.CollectionName = r.DB.ResolveAlias(ic.CollectionName)
+		if !r.allowed(w, key, ic.CollectionName, Vdb.FullAccess) {
+			return
+		}
+
+		// Check if Collection exists
+		collection, ok := r.DB.GetCollection(ic.CollectionName)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		// Create the Index
+		switch strings.ToLower(ic.Type) {
+		case "":
+			err = collection.CreateIndex(ic.IndexName, ic.IndexName)
+		case "geo":
+			if ic.Precision == 0 {
+				ic.Precision = 6
+			}
+			err = collection.CreateGeoIndex(ic.IndexName, ic.IndexName, ic.Precision)
+		default:
+			err = fmt.Errorf("unknown index type %s", ic.Type)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Send the success or error message to the client
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Index created"))
+		return
+	}
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+
+}
+
+// GetAccessData will return the AccessData
+func (r *Routes) GetAccessData(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/getaccessdata" {
+		// Get the data
+		accessList := AccessDataHUB.AccessList.GetData()
+		// Send the AccessData to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(accessList)
+		return
+	}
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// showapikey will show the apikey
+func (r *Routes) ShowApiKey(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/showapikey" {
+		// This will only work if there is no APIKEY
+		if ApiKeyHandler.ApiHandler.CheckIfEmpty() {
+			// Create the APIKEY
+			key, _, err := r.ApiKeyHandler.CreateApiKey(ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Admin})
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(err.Error()))
+				return
+			}
+			// Create the data for the template to show the apikey
+			data := struct {
+				Data string
+			}{
+				Data: key,
+			}
+			//  Show the template
+			err = r.renderTemplate("showapikey", w, data)
+			if err != nil {
+				Logger.Log.Log(err.Error())
+			}
+			return
+		} else {
+			// Redirect to login
+			http.Redirect(w, req, "/login", http.StatusSeeOther)
+			return
+		}
+	}
+}
+
+// NeuralNetBuilder will show the NeuralNetBuilder to enable the user to build a neural net
+func (r *Routes) NeuralNetBuilder(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/neuralnetbuilder" {
+		// Create the data for the template to show the apikey
+		neuralNetData := "Neural Net Builder"
+		data := struct {
+			Data string
+		}{
+			Data: neuralNetData,
+		}
+		//  Show the template
+		err := r.renderTemplate("neuralnetbuilder", w, data)
+		if err != nil {
+			Logger.Log.Log(err.Error())
+		}
+		return
+	} else {
+		// Redirect to login
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+}
+
+// CreateAlias creates an alias that can be used instead of the name of a collection
+func (r *Routes) CreateAlias(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/createalias" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the AliasRequest via json decode
+		ar := AliasRequest{}
+		err = json.NewDecoder(req.Body).Decode(&ar)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, ar.ApiKey)
+		if !ok {
+			return
+		}
+		// Check if the variables are set
+		if ar.Alias == "" || ar.CollectionName == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+		if !r.allowed(w, key, ar.CollectionName, Vdb.FullAccess) {
+			return
+		}
+
+		err = r.DB.CreateAlias(ar.Alias, ar.CollectionName)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Alias created"))
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// SwitchAlias points an alias to another collection, the switch is atomic for all requests
+func (r *Routes) SwitchAlias(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/switchalias" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the AliasRequest via json decode
+		ar := AliasRequest{}
+		err = json.NewDecoder(req.Body).Decode(&ar)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, ar.ApiKey)
+		if !ok {
+			return
+		}
+		// Check if the variables are set
+		if ar.Alias == "" || ar.CollectionName == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+		// A tenant needs both the old and the new Collection of the alias
+		if !r.allowed(w, key, r.DB.ResolveAlias(ar.Alias), Vdb.FullAccess) ||
+			!r.allowed(w, key, ar.CollectionName, Vdb.FullAccess) {
+			return
+		}
+
+		err = r.DB.SwitchAlias(ar.Alias, ar.CollectionName)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Alias switched"))
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// DeleteAlias deletes an alias, the collection stays
+func (r *Routes) DeleteAlias(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/deletealias" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the AliasRequest via json decode
+		ar := AliasRequest{}
+		err = json.NewDecoder(req.Body).Decode(&ar)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, ar.ApiKey)
+		if !ok {
+			return
+		}
+		// Check if the variables are set
+		if ar.Alias == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+		if !r.allowed(w, key, r.DB.ResolveAlias(ar.Alias), Vdb.FullAccess) {
+			return
+		}
+
+		err = r.DB.DeleteAlias(ar.Alias)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Alias deleted"))
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// ListAliases lists all aliases and their collections
+func (r *Routes) ListAliases(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/listaliases" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the AliasRequest via json decode - the body is optional
+		ar := AliasRequest{}
+		err = json.NewDecoder(req.Body).Decode(&ar)
+		if err != nil && err != io.EOF {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, ar.ApiKey)
+		if !ok {
+			return
+		}
+		// Send the aliases to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		aliases := make([]Vdb.Alias, 0)
+		for _, a := range r.DB.ListAliases(tenantOf(key)) {
+			if key.CanUse(a.Collection) {
+				aliases = append(aliases, a)
+			}
+		}
+		json.NewEncoder(w).Encode(aliases)
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// RenameCollection renames a collection, its aliases follow it
+func (r *Routes) RenameCollection(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/renamecollection" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the RenameRequest via json decode
+		rr := RenameRequest{}
+		err = json.NewDecoder(req.Body).Decode(&rr)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, rr.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		rr.CollectionName = r.DB.ResolveAlias(rr.CollectionName)
+		if !r.allowed(w, key, rr.CollectionName, Vdb.FullAccess) {
+			return
+		}
+
+		// Check if the variables are set
+		if rr.CollectionName == "" || rr.NewName == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+
+		err = r.DB.RenameCollection(rr.CollectionName, rr.NewName)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// The ApiKeys of the collection follow it
+		err = r.ApiKeyHandler.RenameCollection(rr.CollectionName, rr.NewName)
+		if err != nil {
+			Logger.Log.Log("Error renaming the collection of the ApiKeys: " + err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Collection renamed"))
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// CloneCollection copies the points of a collection into a new collection, optionally filtered and with another
+// distance function or other payload indexes
+func (r *Routes) CloneCollection(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/clonecollection" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 100000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the CloneRequest via json decode
+		cr := CloneRequest{}
+		err = json.NewDecoder(req.Body).Decode(&cr)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, cr.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		cr.CollectionName = r.DB.ResolveAlias(cr.CollectionName)
+		if !r.allowed(w, key, cr.CollectionName, Vdb.FullAccess) {
+			return
+		}
+
+		// Check if the variables are set
+		if cr.CollectionName == "" || cr.TargetName == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+
+		// A key of restricted collections can only clone into collections it may use
+		if !key.CanUse(cr.TargetName) {
+			forbidden(w)
+			return
+		}
+
+		n, err := r.DB.CloneCollection(cr.CollectionName, cr.TargetName, cr.DistanceFunction, cr.Filter, cr.Indexes)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Send the result to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(CollectionCloned{CollectionName: cr.TargetName, Points: n})
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// CollectionInfo returns the statistics of a collection
+func (r *Routes) CollectionInfo(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/collectioninfo" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the CollectionInfoRequest via json decode
+		cr := CollectionInfoRequest{}
+		err = json.NewDecoder(req.Body).Decode(&cr)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, cr.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		cr.CollectionName = r.DB.ResolveAlias(cr.CollectionName)
+		if !r.allowed(w, key, cr.CollectionName, Vdb.FullAccess) {
+			return
+		}
+
+		// Check if the collection exists
+		collection, ok := r.DB.GetCollection(cr.CollectionName)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		info, err := collection.Info()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Send the statistics to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(info)
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// SetSchema declares the payload fields of a collection, the payloads of new points are checked against it
+func (r *Routes) SetSchema(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/setschema" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 100000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the SchemaRequest via json decode
+		sr := SchemaRequest{}
+		err = json.NewDecoder(req.Body).Decode(&sr)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, sr.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		sr.CollectionName = r.DB.ResolveAlias(sr.CollectionName)
+		if !r.allowed(w, key, sr.CollectionName, Vdb.FullAccess) {
+			return
+		}
+
+		// Check if the variables are set
+		if sr.CollectionName == "" || sr.Schema == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+		// Check if the collection exists
+		collection, ok := r.DB.GetCollection(sr.CollectionName)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		err = collection.SetSchema(sr.Schema)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Send the schema with its version to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(sr.Schema)
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// Facets returns the value counts, the numeric stats and the histograms of payload fields over the points of a
+// collection, the points that pass a filter or the nearest neighbours of a vector
+func (r *Routes) Facets(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SEARCH"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/facets" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 100000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the FacetRequest via json decode
+		fr := FacetRequest{}
+		err = json.NewDecoder(req.Body).Decode(&fr)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, fr.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		fr.CollectionName = r.DB.ResolveAlias(fr.CollectionName)
+		if !r.allowed(w, key, fr.CollectionName, Vdb.PartitionAccess) {
+			return
+		}
+
+		// Check if the variables are set
+		if fr.CollectionName == "" || len(fr.Fields) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
 			return
-		} else {
-			// Redirect to login
-			http.Redirect(w, req, "/login", http.StatusSeeOther)
+		}
+
+		// A tenant only counts the points of its partition of a shared Collection
+		filter := r.DB.TenantFilter(tenantOf(key), fr.CollectionName, fr.Filter)
+		facets, err := r.DB.Facets(fr.CollectionName, Vdb.FacetQuery{Fields: fr.Fields, Filter: filter,
+			Target: fr.Vector, Depth: fr.Depth, Buckets: fr.Buckets, Limit: fr.Limit})
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
 			return
 		}
+
+		// Send the facets to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(facets)
+		return
 	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
 }
 
-// NeuralNetBuilder will show the NeuralNetBuilder to enable the user to build a neural net
-func (r *Routes) NeuralNetBuilder(w http.ResponseWriter, req *http.Request) {
+// Count returns the number of points of a collection that pass a filter
+func (r *Routes) Count(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SEARCH"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/count" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 100000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the CountRequest via json decode
+		cr := CountRequest{}
+		err = json.NewDecoder(req.Body).Decode(&cr)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, cr.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		cr.CollectionName = r.DB.ResolveAlias(cr.CollectionName)
+		if !r.allowed(w, key, cr.CollectionName, Vdb.PartitionAccess) {
+			return
+		}
+
+		// Check if the variables are set
+		if cr.CollectionName == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+
+		count, err := r.DB.Count(cr.CollectionName, r.DB.TenantFilter(tenantOf(key), cr.CollectionName, cr.Filter))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Send the count to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(CountResult{CollectionName: cr.CollectionName, Count: count})
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// DeleteByFilter deletes all points of a collection that pass a filter. On large collections the delete runs as a
+// DeleteJob in the background unless the client waits for it.
+func (r *Routes) DeleteByFilter(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/deletebyfilter" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 100000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the CountRequest via json decode
+		cr := CountRequest{}
+		err = json.NewDecoder(req.Body).Decode(&cr)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, cr.ApiKey)
+		if !ok {
+			return
+		}
+		// Resolve an alias to its collection
+		cr.CollectionName = r.DB.ResolveAlias(cr.CollectionName)
+		if !r.allowed(w, key, cr.CollectionName, Vdb.PartitionAccess) {
+			return
+		}
+
+		// Check if the variables are set - deleting all points needs a delete of the collection
+		if cr.CollectionName == "" || cr.Filter == nil || len(*cr.Filter) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+
+		// Check if Collection exists
+		c, ok := r.DB.GetCollection(cr.CollectionName)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Collection does not exist"))
+			return
+		}
+
+		// Check if the filter is valid
+		if err := r.DB.CheckFilter(cr.CollectionName, cr.Filter); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		// Small collections are deleted right away, large ones in the background
+		c.Mut.RLock()
+		size := len(*c.Space)
+		c.Mut.RUnlock()
+		// A tenant only deletes the points of its partition of a shared Collection
+		filter := r.DB.TenantFilter(tenantOf(key), cr.CollectionName, cr.Filter)
+		job := r.startDeleteJob(cr.CollectionName, filter, tenantOf(key))
+		w.Header().Set("Content-Type", "application/json")
+		if cr.Wait || size < deleteJobThreshold {
+			<-job.done
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(job.snapshot())
+			return
+		}
+
+		// Send the job id to the client
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job.snapshot())
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// DeleteStatus returns the state of a DeleteJob
+func (r *Routes) DeleteStatus(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "TRANSACTION"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/deletestatus" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the DeleteStatus via json decode
+		ds := DeleteStatus{}
+		err = json.NewDecoder(req.Body).Decode(&ds)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, ds.ApiKey)
+		if !ok {
+			return
+		}
+		// Check if the job exists
+		job, ok := r.getDeleteJob(ds.JobId, tenantOf(key))
+		if !ok || !key.CanUse(job.CollectionName) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Job does not exist"))
+			return
+		}
+
+		// Send the state of the job to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(job.snapshot())
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// SetTenant creates a tenant or sets its quotas
+func (r *Routes) SetTenant(w http.ResponseWriter, req *http.Request) {
 	r.AData <- "SYSTEMEVENT"
-	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/neuralnetbuilder" {
-		// Create the data for the template to show the apikey
-		neuralNetData := "Neural Net Builder"
-		data := struct {
-			Data string
-		}{
-			Data: neuralNetData,
+	if req.Method == http.MethodPost && strings.ToLower(req.URL.String()) == "/settenant" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
 		}
-		//  Show the template
-		err := r.renderTemplate("neuralnetbuilder", w, data)
+
+		// load the request into the TenantRequest via json decode
+		tr := TenantRequest{}
+		err = json.NewDecoder(req.Body).Decode(&tr)
 		if err != nil {
-			Logger.Log.Log(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
 		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, tr.ApiKey)
+		if !ok {
+			return
+		}
+		// Only the global admin keys manage the tenants
+		if !r.allowedGlobal(w, key) {
+			return
+		}
+
+		// Check if the variables are set
+		if tr.Name == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+
+		err = r.DB.SetTenant(Vdb.Tenant{Name: tr.Name, MaxPoints: tr.MaxPoints, MaxBytes: tr.MaxBytes})
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Tenant set"))
 		return
-	} else {
-		// Redirect to login
-		http.Redirect(w, req, "/login", http.StatusSeeOther)
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// ListTenants lists the tenants with their quotas and usage, the keys of a tenant only get their own tenant
+func (r *Routes) ListTenants(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodGet && strings.ToLower(req.URL.String()) == "/listtenants" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the TenantRequest via json decode - the body is optional
+		tr := TenantRequest{}
+		err = json.NewDecoder(req.Body).Decode(&tr)
+		if err != nil && err != io.EOF {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, tr.ApiKey)
+		if !ok {
+			return
+		}
+		// The admins of a tenant only see their own tenant
+		name := tr.Name
+		if tenant := tenantOf(key); tenant != "" {
+			name = tenant
+		}
+
+		// Send the tenants to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(TenantList{Tenants: r.DB.ListTenants(name)})
+		return
+	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
+}
+
+// DeleteTenant deletes a tenant that owns no Collections and has no ApiKeys
+func (r *Routes) DeleteTenant(w http.ResponseWriter, req *http.Request) {
+	r.AData <- "SYSTEMEVENT"
+	if req.Method == http.MethodDelete && strings.ToLower(req.URL.String()) == "/deletetenant" {
+		// Limit the size of the request
+		req.Body = http.MaxBytesReader(w, req.Body, 5000)
+		// Parse the form
+		err := req.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error parsing form"))
+			return
+		}
+
+		// load the request into the TenantRequest via json decode
+		tr := TenantRequest{}
+		err = json.NewDecoder(req.Body).Decode(&tr)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Error decoding json"))
+			return
+		}
+
+		// Get the ApiKey of the request, the deprecated api_key of the body is checked here
+		key, ok := r.requestKey(w, req, tr.ApiKey)
+		if !ok {
+			return
+		}
+		// Only the global admin keys manage the tenants
+		if !r.allowedGlobal(w, key) {
+			return
+		}
+
+		// Check if the variables are set
+		if tr.Name == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Variables Missing"))
+			return
+		}
+
+		// The keys of the tenant have to be deleted first, they would see the Collections of a new tenant of the name
+		if r.ApiKeyHandler.HasTenant(tr.Name) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Tenant " + tr.Name + " still has ApiKeys"))
+			return
+		}
+
+		err = r.DB.DeleteTenant(tr.Name)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Tenant deleted"))
 		return
 	}
+
+	// Notice the user that the route is not found under given information
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("Not Found"))
+	return
 }
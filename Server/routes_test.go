@@ -0,0 +1,105 @@
+package Server
+
+import (
+	"VreeDB/ApiKeyHandler"
+	"VreeDB/ArgsParser"
+	"VreeDB/Vdb"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// newTestRoutes returns the Routes without the templates of the web interface and a mux with all of them. The
+// database has no ApiKeys, so every request is allowed.
+func newTestRoutes(t *testing.T) (*Routes, *http.ServeMux) {
+	t.Helper()
+	if err := os.MkdirAll(*ArgsParser.Ap.FileStore, 0755); err != nil {
+		t.Fatal(err)
+	}
+	clearApiKeys(t)
+	r := newRoutes(Vdb.DB, nil)
+	mux := http.NewServeMux()
+	registerRoutes(mux, r)
+	return r, mux
+}
+
+// clearApiKeys removes all ApiKeys for the test and restores them afterwards
+func clearApiKeys(t *testing.T) {
+	t.Helper()
+	h := ApiKeyHandler.ApiHandler
+	h.Mut.Lock()
+	keys := h.ApiKeys
+	h.ApiKeys = make(map[string]*ApiKeyHandler.ApiKey)
+	h.Mut.Unlock()
+	t.Cleanup(func() {
+		h.Mut.Lock()
+		h.ApiKeys = keys
+		h.Mut.Unlock()
+	})
+}
+
+// newTestCollection creates a euclid Collection with the given dimension that is deleted after the test
+func newTestCollection(t *testing.T, name string, dimension int) {
+	t.Helper()
+	if err := Vdb.DB.AddCollection(name, dimension, "euclid"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, ok := Vdb.DB.Collections[name]; ok {
+			Vdb.DB.DeleteCollection(name)
+		}
+	})
+}
+
+// serve sends a JSON request through the mux, the header is given as name and value pairs
+func serve(mux http.Handler, method string, path string, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+// newTestApiKey creates an ApiKey for the test, the ApiKeys have to be cleared before
+func newTestApiKey(t *testing.T, options ApiKeyHandler.ApiKey) string {
+	t.Helper()
+	key, _, err := ApiKeyHandler.ApiHandler.CreateApiKey(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestRenameCollectionKeepsScopedKeys(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	newTestCollection(t, "scopedold", 2)
+	t.Cleanup(func() {
+		if _, ok := Vdb.DB.Collections["scopednew"]; ok {
+			Vdb.DB.DeleteCollection("scopednew")
+		}
+	})
+	newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Admin})
+	scoped := newTestApiKey(t, ApiKeyHandler.ApiKey{Role: ApiKeyHandler.Admin, Collections: []string{"scopedold"}})
+
+	w := serve(mux, http.MethodPost, "/renamecollection", `{"collection_name":"scopedold","new_name":"scopednew"}`,
+		"X-API-Key", scoped)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	w = serve(mux, http.MethodGet, "/collectioninfo", `{"collection_name":"scopednew"}`, "X-API-Key", scoped)
+	if w.Code != http.StatusOK {
+		t.Errorf("the key lost the renamed collection: %d %s", w.Code, w.Body.String())
+	}
+
+	// A new collection under the old name is not part of the key
+	newTestCollection(t, "scopedold", 2)
+	w = serve(mux, http.MethodGet, "/collectioninfo", `{"collection_name":"scopedold"}`, "X-API-Key", scoped)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("the key uses the new collection: %d %s", w.Code, w.Body.String())
+	}
+}
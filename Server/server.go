@@ -43,6 +43,24 @@ func NewServer(ip string, port int, certfile string, keyfile string, secure bool
 
 	// Start  the bootup
 	server.DB.Collections = Boot.NewBootUp().Boot()
+	err := server.DB.LoadAliases()
+	if err != nil {
+		Logger.Log.Log("Error loading aliases: " + err.Error())
+	}
+	err = server.DB.LoadTenants()
+	if err != nil {
+		Logger.Log.Log("Error loading tenants: " + err.Error())
+	}
+
+	// Start the reaper that deletes expired points
+	if *ArgsParser.Ap.ReapInterval > 0 {
+		server.DB.StartReaper(time.Duration(*ArgsParser.Ap.ReapInterval) * time.Second)
+	}
+
+	// Start the merger that merges small sealed segments
+	if *ArgsParser.Ap.MergeInterval > 0 {
+		server.DB.StartMerger(time.Duration(*ArgsParser.Ap.MergeInterval) * time.Second)
+	}
 
 	// Add the routes
 	server.addRoutes(mux)
@@ -52,21 +70,27 @@ func NewServer(ip string, port int, certfile string, keyfile string, secure bool
 // addRoutes adds all routes to the server
 func (s *Server) addRoutes(mux *http.ServeMux) {
 	// Get all the Routes out of the Routeprovider
-	routes := NewRoutes(s.DB)
+	registerRoutes(mux, NewRoutes(s.DB))
+	fileServer := http.FileServer(http.Dir("./static"))
+	mux.Handle("/static/", http.StripPrefix("/static/", static(fileServer)))
+}
+
+// registerRoutes adds every method of the Routes as a route, the path is the lower case name of the method
+func registerRoutes(mux *http.ServeMux, routes *Routes) {
 	v := reflect.ValueOf(routes)
 	for i := 0; i < v.NumMethod(); i++ {
 		// get the Name of the Route
 		name := v.Type().Method(i).Name
 		// Get the Route
 		route := v.MethodByName(name).Interface().(func(http.ResponseWriter, *http.Request))
+		// Every route checks the ApiKey and its role first
+		route = routes.authenticate(name, route)
 		if name == "Index" {
 			mux.HandleFunc("/", route)
 			continue
 		}
 		mux.HandleFunc("/"+strings.ToLower(name), route)
 	}
-	fileServer := http.FileServer(http.Dir("./static"))
-	mux.Handle("/static/", http.StripPrefix("/static/", static(fileServer)))
 }
 
 // Start starts the server
@@ -0,0 +1,110 @@
+package Server
+
+import (
+	"VreeDB/Utils"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// importJobRetention is the time an idle ImportJob can be continued and polled
+	importJobRetention = time.Hour
+	// maxImportErrors is the number of line errors an ImportJob keeps, the later ones are only counted
+	maxImportErrors = 1000
+)
+
+// startImportChunk returns the ImportJob a chunk belongs to and the number of lines at the start of the chunk that
+// were imported before. A chunk without an import id starts a new ImportJob, a tenant can only continue its own ones.
+// On an error the http status is returned.
+func (r *Routes) startImportChunk(h *ImportHeader, tenant string) (*ImportJob, int, int, error) {
+	r.importMut.Lock()
+	defer r.importMut.Unlock()
+	// Forget the jobs that are idle for a while
+	for id, j := range r.ImportJobs {
+		if j.isIdleBefore(time.Now().Add(-importJobRetention)) {
+			delete(r.ImportJobs, id)
+		}
+	}
+
+	if h.ImportId == "" {
+		if h.Offset != 0 {
+			return nil, 0, http.StatusBadRequest, fmt.Errorf("a new import starts at offset 0")
+		}
+		job := &ImportJob{Id: Utils.Utils.CreateUUID(), CollectionName: h.CollectionName, Status: "running",
+			tenant: tenant, updated: time.Now()}
+		r.ImportJobs[job.Id] = job
+		return job, 0, http.StatusOK, nil
+	}
+
+	job, ok := r.ImportJobs[h.ImportId]
+	if !ok || (tenant != "" && job.tenant != tenant) {
+		return nil, 0, http.StatusBadRequest, fmt.Errorf("Import does not exist")
+	}
+	job.mut.Lock()
+	defer job.mut.Unlock()
+	if job.CollectionName != h.CollectionName {
+		return nil, 0, http.StatusBadRequest, fmt.Errorf("Import belongs to collection %s", job.CollectionName)
+	}
+	if job.Status == "running" {
+		return nil, 0, http.StatusConflict, fmt.Errorf("Import is running")
+	}
+	// Lines that were imported before are skipped, a gap would lose lines
+	if h.Offset > job.Lines {
+		return nil, 0, http.StatusConflict, fmt.Errorf("Import continues at offset %d", job.Lines)
+	}
+	job.Status = "running"
+	return job, job.Lines - h.Offset, http.StatusOK, nil
+}
+
+// getImportJob returns the ImportJob with the given id, a tenant only gets its own imports
+func (r *Routes) getImportJob(id string, tenant string) (*ImportJob, bool) {
+	r.importMut.Lock()
+	defer r.importMut.Unlock()
+	job, ok := r.ImportJobs[id]
+	if ok && tenant != "" && job.tenant != tenant {
+		return nil, false
+	}
+	return job, ok
+}
+
+// report records the result of a line of the chunk that starts at offset
+func (j *ImportJob) report(offset int, line int, id string, err error) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	j.Lines = offset + line
+	if err == nil {
+		j.Imported++
+		return
+	}
+	j.Failed++
+	if len(j.Errors) < maxImportErrors {
+		j.Errors = append(j.Errors, ImportError{Line: offset + line, Id: id, Error: err.Error()})
+	}
+}
+
+// finishChunk marks the chunk that starts at offset and has the given number of lines as imported
+func (j *ImportJob) finishChunk(offset int, lines int) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	if offset+lines > j.Lines {
+		j.Lines = offset + lines
+	}
+	j.Status = "idle"
+	j.updated = time.Now()
+}
+
+// isIdleBefore returns true if the ImportJob is idle since before the given time
+func (j *ImportJob) isIdleBefore(t time.Time) bool {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	return j.Status == "idle" && j.updated.Before(t)
+}
+
+// snapshot returns a copy of the ImportJob that can be encoded while a chunk is imported
+func (j *ImportJob) snapshot() *ImportJob {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	return &ImportJob{Id: j.Id, CollectionName: j.CollectionName, Status: j.Status, Lines: j.Lines,
+		Imported: j.Imported, Failed: j.Failed, Errors: append([]ImportError(nil), j.Errors...)}
+}
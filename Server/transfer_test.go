@@ -0,0 +1,60 @@
+package Server
+
+import (
+	"VreeDB/Vdb"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestImportInChunks(t *testing.T) {
+	_, mux := newTestRoutes(t)
+	newTestCollection(t, "chunked", 2)
+
+	w := serve(mux, http.MethodPost, "/import", `{"collection_name":"chunked"}
+{"id":"a","vector":[1,2]}
+{"id":"b","vector":[1]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	job := ImportJob{}
+	json.NewDecoder(w.Body).Decode(&job)
+	if job.Status != "idle" || job.Lines != 2 || job.Imported != 1 || job.Failed != 1 || len(job.Errors) != 1 ||
+		job.Errors[0].Line != 2 {
+		t.Fatalf("unexpected job %s %d %d %d %v", job.Status, job.Lines, job.Imported, job.Failed, job.Errors)
+	}
+
+	// A chunk may only continue where the import stopped
+	if w = serve(mux, http.MethodPost, "/import", `{"collection_name":"chunked","import_id":"`+job.Id+`","offset":3}
+{"id":"x","vector":[1,2]}`); w.Code != http.StatusConflict {
+		t.Errorf("a chunk behind a gap is answered with %d", w.Code)
+	}
+	// The repeated line 2 is skipped
+	w = serve(mux, http.MethodPost, "/import", `{"collection_name":"chunked","import_id":"`+job.Id+`","offset":1}
+{"id":"b","vector":[1]}
+{"id":"c","vector":[3,4]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(mux, http.MethodGet, "/importstatus", `{"import_id":"`+job.Id+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	state := ImportJob{}
+	json.NewDecoder(w.Body).Decode(&state)
+	if state.Lines != 3 || state.Imported != 2 || state.Failed != 1 {
+		t.Errorf("unexpected state %d %d %d", state.Lines, state.Imported, state.Failed)
+	}
+	if n := len(*Vdb.DB.Collections["chunked"].Space); n != 2 {
+		t.Errorf("collection has %d points, want 2", n)
+	}
+
+	// The export gives the points back as lines
+	w = serve(mux, http.MethodGet, "/export", `{"collection_name":"chunked"}`)
+	if lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n"); w.Code != http.StatusOK || len(lines) != 2 ||
+		!strings.HasPrefix(lines[0], `{"id":"a","vector":[1,2]`) {
+		t.Errorf("export %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -3,21 +3,44 @@ package Server
 import (
 	"VreeDB/ApiKeyHandler"
 	"VreeDB/Filter"
+	"VreeDB/Formula"
 	"VreeDB/NN"
+	"VreeDB/Schema"
 	"VreeDB/Utils"
 	"VreeDB/Vdb"
 	"VreeDB/Vector"
 	"html/template"
+	"sync"
 	"time"
 )
 
 // CollectionCreator is the struct that creates a Collection in the VDB, when send by REST
 type CollectionCreator struct {
-	ApiKey           string `json:"api_key"` // Must not be present in the request
-	Name             string `json:"name"`
+	ApiKey           string                        `json:"api_key"` // Must not be present in the request
+	Name             string                        `json:"name"`
+	DistanceFunction string                        `json:"distance_function"`
+	Dimensions       int                           `json:"dimensions"`
+	Wait             bool                          `json:"wait"`
+	SparseFields     []string                      `json:"sparse_fields"`       // Optional - names of the sparse vector fields
+	VectorFields     map[string]VectorFieldCreator `json:"vector_fields"`       // Optional - named vector fields
+	MultiFields      map[string]VectorFieldCreator `json:"multi_vector_fields"` // Optional - fields holding a list of vectors per point
+	BinaryFields     map[string]BinaryFieldCreator `json:"binary_fields"`       // Optional - binary quantised vector fields
+	DefaultTTL       int64                         `json:"default_ttl"`         // Optional - seconds until new points expire
+	Tenant           string                        `json:"tenant"`              // Optional - the owner, set for the keys of a tenant
+	TenantField      string                        `json:"tenant_field"`        // Optional - the payload key of the tenants of a shared Collection
+}
+
+// VectorFieldCreator describes a named vector field of a Collection, when send by REST
+type VectorFieldCreator struct {
 	DistanceFunction string `json:"distance_function"`
 	Dimensions       int    `json:"dimensions"`
-	Wait             bool   `json:"wait"`
+}
+
+// BinaryFieldCreator describes a binary vector field of a Collection, when send by REST
+type BinaryFieldCreator struct {
+	DistanceFunction string `json:"distance_function"` // Only used with rescore
+	Dimensions       int    `json:"dimensions"`
+	Rescore          bool   `json:"rescore"` // Optional - keep the float vectors to rescore the hamming candidates
 }
 
 // Used to delete a Collection, when send by REST
@@ -39,29 +62,286 @@ type IndexName struct {
 
 // Point is the struct that adds a point to a Collection, when send by REST
 type Point struct {
-	Id                 string                 `json:"id"` // Must not be present in the request
-	ApiKey             string                 `json:"api_key"`
-	CollectionName     string                 `json:"collection_name"`
-	Vector             []float64              `json:"vector"`
-	Payload            map[string]interface{} `json:"payload"`              // Optional
-	Depth              int                    `json:"depth"`                // Must not be present in the request default 3
-	Wait               bool                   `json:"wait"`                 // Must not be present in the request default false
-	MaxDistancePercent float64                `json:"max_distance_percent"` // Must not be present in the request default 0.0 (no limit)
-	Index              *IndexName             `json:"index"`                // Must not be present in the request default ""
-	Filter             *[]Filter.Filter       `json:"filter"`               // Must not be present in the request default nil
+	Id                 string                          `json:"id"` // Must not be present in the request
+	ApiKey             string                          `json:"api_key"`
+	CollectionName     string                          `json:"collection_name"`
+	Vector             []float64                       `json:"vector"`
+	Payload            map[string]interface{}          `json:"payload"`              // Optional
+	Depth              int                             `json:"depth"`                // Must not be present in the request default 3
+	Wait               bool                            `json:"wait"`                 // Must not be present in the request default false
+	MaxDistancePercent float64                         `json:"max_distance_percent"` // Must not be present in the request default 0.0 (no limit)
+	Index              *IndexName                      `json:"index"`                // Must not be present in the request default ""
+	Filter             *[]Filter.Filter                `json:"filter"`               // Must not be present in the request default nil
+	SparseVectors      map[string]*Vector.SparseVector `json:"sparse_vectors"`       // Optional - sparse vectors by field name
+	Weights            map[string]float64              `json:"weights"`              // Optional - field weights of a hybrid search, "dense" weights the vector
+	Vectors            map[string][]float64            `json:"vectors"`              // Optional - named vectors by field name
+	VectorName         string                          `json:"vector_name"`          // Optional - search the Vector in this named vector field
+	MultiVectors       map[string][][]float64          `json:"multi_vectors"`        // Optional - lists of vectors by multi vector field name
+	BinaryVectors      map[string][]float64            `json:"binary_vectors"`       // Optional - vectors of the binary fields, values > 0 are 1 bits
+	TTLSeconds         int64                           `json:"ttl_seconds"`          // Optional - seconds until the point expires
+	ExpiresAt          int64                           `json:"expires_at"`           // Optional - unix time in seconds when the point expires
+	OrderBy            *Vdb.OrderBy                    `json:"order_by"`             // Optional - sort the results by a payload field, without a vector only the filter is applied
+	ScoreFormula       string                          `json:"score_formula"`        // Optional - rerank the candidates by a formula, e.g. "-distance + 0.1 * log(popularity)"
+	Candidates         int                             `json:"candidates"`           // Optional - candidates a score formula reranks, default 4 * depth
 }
 
-type PointItem struct {
-	Id      string                 `json:"id"` // Must not be present in the request
-	Vector  []float64              `json:"vector"`
-	Payload map[string]interface{} `json:"payload"` // Optional
+// depth returns the number of results of a search, the default is 3
+func (p *Point) depth() int {
+	if p.Depth <= 0 {
+		return 3
+	}
+	return p.Depth
+}
+
+// candidates returns the number of results a search collects, a score formula reranks more candidates than it returns
+func (p *Point) candidates() int {
+	if p.ScoreFormula == "" {
+		return p.depth()
+	}
+	if p.Candidates > p.depth() {
+		return p.Candidates
+	}
+	return 4 * p.depth()
+}
+
+// parseRanking checks the order of the Point and parses its score formula, nil if it has none
+func (p *Point) parseRanking() (*Formula.Formula, error) {
+	if p.OrderBy != nil {
+		if err := p.OrderBy.Check(); err != nil {
+			return nil, err
+		}
+	}
+	if p.ScoreFormula == "" {
+		return nil, nil
+	}
+	return Formula.Parse(p.ScoreFormula)
+}
+
+// rank reranks the results of a search by the score formula and sorts them by the order of the Point
+func (p *Point) rank(formula *Formula.Formula, results []*Utils.ResultSet) []*Utils.ResultSet {
+	if formula != nil {
+		results = Vdb.Rerank(results, formula, p.depth())
+	}
+	if p.OrderBy != nil {
+		Vdb.Order(results, p.OrderBy)
+	}
+	return results
 }
 
 // PointBatch is the struct that adds a batch of points to a Collection, when send by REST
 type PointBatch struct {
-	ApiKey         string      `json:"api_key"`
-	CollectionName string      `json:"collection_name"`
-	Points         []PointItem `json:"points"`
+	ApiKey         string          `json:"api_key"`
+	CollectionName string          `json:"collection_name"`
+	Points         []Vdb.PointItem `json:"points"`
+	Wait           bool            `json:"wait"` // Optional - wait for the batch and get the result of every point
+}
+
+// IngestJob is a batch of points that waits in the ingest queue or is inserted by an ingest worker
+type IngestJob struct {
+	Id             string        `json:"job_id"`
+	CollectionName string        `json:"collection_name"`
+	Status         string        `json:"status"` // queued, running or done
+	Total          int           `json:"total"`
+	Processed      int           `json:"processed"`
+	Failed         int           `json:"failed"`
+	Results        []PointResult `json:"results,omitempty"`
+	points         []Vdb.PointItem
+	tenant         string // The tenant of the ApiKey that queued the job
+	done           chan struct{}
+	finished       time.Time
+	mut            sync.Mutex
+}
+
+// PointResult is the result of a single point of an IngestJob
+type PointResult struct {
+	Id     string `json:"id"`
+	Status string `json:"status"` // ok or error
+	Error  string `json:"error,omitempty"`
+}
+
+// IngestStatus is the struct that requests the state of an IngestJob, when send by REST
+type IngestStatus struct {
+	ApiKey string `json:"api_key"`
+	JobId  string `json:"job_id"`
+}
+
+// ExportRequest is the struct that exports the points of a collection as JSON lines, when send by REST
+type ExportRequest struct {
+	ApiKey         string           `json:"api_key"`
+	CollectionName string           `json:"collection_name"`
+	Filter         *[]Filter.Filter `json:"filter"` // Optional - only the points that pass the filter are exported
+}
+
+// ImportHeader is the first line of an import request, the points follow as one JSON object per line. An import can
+// be send in several chunks, every chunk after the first one names the import and the line it starts at.
+type ImportHeader struct {
+	ApiKey         string `json:"api_key"`
+	CollectionName string `json:"collection_name"`
+	ImportId       string `json:"import_id"` // Optional - continues the import with this id
+	Offset         int    `json:"offset"`    // Optional - the number of lines of the import before this chunk
+}
+
+// ImportJob is an import of points that is send in one or more chunks
+type ImportJob struct {
+	Id             string        `json:"import_id"`
+	CollectionName string        `json:"collection_name"`
+	Status         string        `json:"status"` // running while a chunk is imported, otherwise idle
+	Lines          int           `json:"lines"`  // The lines of the import that are read - the offset of the next chunk
+	Imported       int           `json:"imported"`
+	Failed         int           `json:"failed"`
+	Errors         []ImportError `json:"errors,omitempty"`
+	tenant         string        // The tenant of the ApiKey that started the import
+	updated        time.Time
+	mut            sync.Mutex
+}
+
+// ImportError is the error of a single line of an ImportJob
+type ImportError struct {
+	Line  int    `json:"line"`
+	Id    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportStatus is the struct that requests the state of an ImportJob, when send by REST
+type ImportStatus struct {
+	ApiKey   string `json:"api_key"`
+	ImportId string `json:"import_id"`
+}
+
+// DatasetLoader is the first line of a dataset load, the dataset is a file of the dataset directory or it is uploaded
+// as the rest of the request body
+type DatasetLoader struct {
+	ApiKey           string `json:"api_key"`
+	CollectionName   string `json:"collection_name"`
+	File             string `json:"file"`              // Optional - a file of the dataset directory, otherwise the body is uploaded
+	Format           string `json:"format"`            // Optional for files - fvecs, bvecs or npy, default is the extension
+	DistanceFunction string `json:"distance_function"` // Optional - of a new collection, cosine or euclid
+	RandomIds        bool   `json:"random_ids"`        // Optional - generate the ids, otherwise the row of a vector is its id
+	Limit            int    `json:"limit"`             // Optional - the number of vectors to load, 0 loads all
+	Wait             bool   `json:"wait"`              // Optional - wait for the load, otherwise the job can be polled
+}
+
+// DatasetJob is a dataset load, it runs in the background
+type DatasetJob struct {
+	Id             string `json:"job_id"`
+	CollectionName string `json:"collection_name"`
+	Status         string `json:"status"` // running or done
+	Dimension      int    `json:"dimension"`
+	Points         int    `json:"points"` // The points loaded so far
+	Error          string `json:"error,omitempty"`
+	done           chan struct{}
+	finished       time.Time
+	mut            sync.Mutex
+}
+
+// DatasetStatus is the struct that requests the state of a DatasetJob, when send by REST
+type DatasetStatus struct {
+	ApiKey string `json:"api_key"`
+	JobId  string `json:"job_id"`
+}
+
+// FsckRequest is the struct that starts the integrity check of a collection, when send by REST
+type FsckRequest struct {
+	ApiKey         string `json:"api_key"`
+	CollectionName string `json:"collection_name"` // Optional - all collections are checked if empty
+	Repair         bool   `json:"repair"`          // Optional - rewrite the broken meta files
+}
+
+// SnapshotRequest is the struct to create, list, restore and delete snapshots of collections, when send by REST
+type SnapshotRequest struct {
+	ApiKey         string `json:"api_key"`
+	CollectionName string `json:"collection_name"` // The collection of a new snapshot, optional filter of the list
+	SnapshotName   string `json:"snapshot_name"`   // The snapshot to restore or delete
+	TargetName     string `json:"target_name"`     // Optional - the collection to restore into, default is the original one
+}
+
+// CollectionInfoRequest is a struct that contains the information to get the statistics of a Collection
+type CollectionInfoRequest struct {
+	ApiKey         string `json:"api_key"`
+	CollectionName string `json:"collection_name"`
+}
+
+// SchemaRequest is a struct that contains the schema of the payload fields of a Collection
+type SchemaRequest struct {
+	ApiKey         string         `json:"api_key"`
+	CollectionName string         `json:"collection_name"`
+	Schema         *Schema.Schema `json:"schema"`
+}
+
+// FacetRequest is a struct that contains the fields to aggregate and the points to aggregate them over
+type FacetRequest struct {
+	ApiKey         string           `json:"api_key"`
+	CollectionName string           `json:"collection_name"`
+	Fields         []string         `json:"fields"`
+	Filter         *[]Filter.Filter `json:"filter"`  // Optional - only the points that pass the filter are aggregated
+	Vector         []float64        `json:"vector"`  // Optional - only the nearest neighbours of the vector are aggregated
+	Depth          int              `json:"depth"`   // Optional - number of nearest neighbours, default 3
+	Buckets        int              `json:"buckets"` // Optional - buckets of the numeric histograms, default 10
+	Limit          int              `json:"limit"`   // Optional - most frequent values per field, default 10
+}
+
+// CountRequest is a struct that contains the filter of the points to count or to delete
+type CountRequest struct {
+	ApiKey         string           `json:"api_key"`
+	CollectionName string           `json:"collection_name"`
+	Filter         *[]Filter.Filter `json:"filter"` // Optional for a count - without a filter all points are counted
+	Wait           bool             `json:"wait"`   // Optional - wait for the delete on a large collection
+}
+
+// CountResult is the number of points that pass a filter
+type CountResult struct {
+	CollectionName string `json:"collection_name"`
+	Count          int    `json:"count"`
+}
+
+// DeleteJob is a delete by filter, on large collections it runs in the background
+type DeleteJob struct {
+	Id             string `json:"job_id"`
+	CollectionName string `json:"collection_name"`
+	Status         string `json:"status"` // running or done
+	Matched        int    `json:"matched"`
+	Deleted        int    `json:"deleted"`
+	Error          string `json:"error,omitempty"`
+	tenant         string // The tenant of the ApiKey that started the job
+	done           chan struct{}
+	finished       time.Time
+	mut            sync.Mutex
+}
+
+// DeleteStatus is the struct that requests the state of a DeleteJob, when send by REST
+type DeleteStatus struct {
+	ApiKey string `json:"api_key"`
+	JobId  string `json:"job_id"`
+}
+
+// RenameRequest is a struct that contains the information to rename a Collection
+type RenameRequest struct {
+	ApiKey         string `json:"api_key"`
+	CollectionName string `json:"collection_name"`
+	NewName        string `json:"new_name"`
+}
+
+// CloneRequest is a struct that contains the information to clone a Collection
+type CloneRequest struct {
+	ApiKey           string            `json:"api_key"`
+	CollectionName   string            `json:"collection_name"`
+	TargetName       string            `json:"target_name"`
+	DistanceFunction string            `json:"distance_function"` // Optional - default is the one of the source
+	Filter           *[]Filter.Filter  `json:"filter"`            // Optional - only the points that pass the filter are cloned
+	Indexes          map[string]string `json:"indexes"`           // Optional - payload key of every index name, default are the indexes of the source
+}
+
+// CollectionCloned is the result of a clone
+type CollectionCloned struct {
+	CollectionName string `json:"collection_name"`
+	Points         int    `json:"points"`
+}
+
+// AliasRequest is a struct that contains the information of an alias request
+type AliasRequest struct {
+	ApiKey         string `json:"api_key"`
+	Alias          string `json:"alias"`
+	CollectionName string `json:"collection_name"` // The collection the alias points to
 }
 
 // Result is a struct that contains the result of a search
@@ -82,6 +362,15 @@ type Routes struct {
 	ApiKeyHandler *ApiKeyHandler.ApiKeyHandler
 	SessionKeys   map[string]time.Time
 	AData         chan string
+	IngestQueue   chan *IngestJob
+	IngestJobs    map[string]*IngestJob
+	ingestMut     *sync.Mutex
+	ImportJobs    map[string]*ImportJob
+	importMut     *sync.Mutex
+	DeleteJobs    map[string]*DeleteJob
+	deleteMut     *sync.Mutex
+	DatasetJobs   map[string]*DatasetJob
+	datasetMut    *sync.Mutex
 }
 
 // Collection will display Collection related stuff
@@ -146,9 +435,39 @@ type DeletePoint struct {
 	Id             string `json:"id"`
 }
 
-// ApiKeyCreator is the struct that will be used to create a new Api key
+// ApiKeyCreator is the struct that will be used to create a new Api key, the answer holds the new key and its id
 type ApiKeyCreator struct {
+	ApiKey      string     `json:"api_key"`
+	Id          string     `json:"id,omitempty"`
+	Name        string     `json:"name,omitempty"`        // Optional - a name to recognize the key
+	Role        string     `json:"role,omitempty"`        // Optional - reader, writer or admin, admin by default
+	Collections []string   `json:"collections,omitempty"` // Optional - the collections the key may use, all by default
+	Tenant      string     `json:"tenant,omitempty"`      // Optional - the tenant of the new key
+	Expires     *time.Time `json:"expires,omitempty"`     // Optional - the key is valid until then
+}
+
+// ApiKeyRequest is the struct that will be used to list or revoke ApiKeys, when send by REST
+type ApiKeyRequest struct {
 	ApiKey string `json:"api_key"`
+	Id     string `json:"id"` // The id of the key to revoke
+}
+
+// ApiKeyList is the list of the ApiKeys without the keys themselves, when send by REST
+type ApiKeyList struct {
+	ApiKeys []ApiKeyHandler.ApiKey `json:"api_keys"`
+}
+
+// TenantRequest is the struct that will be used to set, list or delete a tenant, when send by REST
+type TenantRequest struct {
+	ApiKey    string `json:"api_key"`
+	Name      string `json:"name"`
+	MaxPoints int    `json:"max_points"` // Optional - 0 is unlimited
+	MaxBytes  int64  `json:"max_bytes"`  // Optional - 0 is unlimited
+}
+
+// TenantList is the list of the tenants with their usage, when send by REST
+type TenantList struct {
+	Tenants []Vdb.TenantUsage `json:"tenants"`
 }
 
 // ShowTrainProgress shows the progress of the training of the neural network
@@ -161,32 +480,56 @@ type IndexCreator struct {
 	ApiKey         string `json:"api_key"`
 	CollectionName string `json:"collection_name"`
 	IndexName      string `json:"index_name"`
+	Type           string `json:"type"`      // Optional - geo for a geohash index of geo points
+	Precision      int    `json:"precision"` // Optional - geohash characters of a geo index, default 6
 }
 
-// ValidateFilter will validate the filters in Point
-func (p *Point) ValidateFilter() error {
-	if p.Filter != nil {
-		for _, filter := range *p.Filter {
-			if err := filter.Op.IsValid(); err != nil {
-				return err
-			}
+// Config creates the CollectionConfig of the Collection to create
+func (cc *CollectionCreator) Config() Utils.CollectionConfig {
+	config := Utils.CollectionConfig{Name: cc.Name, VectorDimension: cc.Dimensions, DistanceFuncName: cc.DistanceFunction,
+		SparseFields: cc.SparseFields, DefaultTTL: cc.DefaultTTL, Tenant: cc.Tenant, TenantField: cc.TenantField}
+	for name, field := range cc.VectorFields {
+		if config.VectorFields == nil {
+			config.VectorFields = make(map[string]Utils.VectorFieldConfig)
 		}
+		config.VectorFields[name] = Utils.VectorFieldConfig{VectorDimension: field.Dimensions,
+			DistanceFuncName: field.DistanceFunction}
 	}
-	return nil
+	for name, field := range cc.MultiFields {
+		if config.MultiFields == nil {
+			config.MultiFields = make(map[string]Utils.VectorFieldConfig)
+		}
+		config.MultiFields[name] = Utils.VectorFieldConfig{VectorDimension: field.Dimensions,
+			DistanceFuncName: field.DistanceFunction}
+	}
+	for name, field := range cc.BinaryFields {
+		if config.BinaryFields == nil {
+			config.BinaryFields = make(map[string]Utils.BinaryFieldConfig)
+		}
+		config.BinaryFields[name] = Utils.BinaryFieldConfig{VectorDimension: field.Dimensions, Rescore: field.Rescore,
+			DistanceFuncName: field.DistanceFunction}
+	}
+	return config
+}
+
+// ValidateFilter will validate the filters in Point the way its Collection compares them
+func (p *Point) ValidateFilter(db *Vdb.Vdb) error {
+	return db.CheckFilter(p.CollectionName, p.Filter)
 }
 
 // NewData creates new Data Structure for the web page
 func NewData() Data {
 	data := Data{}
 	// Add all the Collections
-	for _, collection := range Vdb.DB.Collections {
+	collections := Vdb.DB.GetCollections()
+	for _, collection := range collections {
 		data.Collections = append(data.Collections, Collection{Name: collection.Name, NodeCount: len(*collection.Space),
 			DistanceFunc: collection.DistanceFuncName, DiagonalLength: collection.DiagonalLength,
 			Classifier: collection.ClassifierToSlice(), ClassifierReady: collection.ClassifierReady})
 	}
 	data.Application = RuntimeData{RamUsage: Utils.Utils.GetMemoryUsage(), FreeRam: Utils.Utils.GetAvailableRAM(),
 		Uptime: 0, Percent: (Utils.Utils.GetMemoryUsage() / Utils.Utils.GetAvailableRAM()) * 100,
-		CollectionCount: len(Vdb.DB.Collections), ApiKeyExists: !ApiKeyHandler.ApiHandler.CheckIfEmpty()}
+		CollectionCount: len(collections), ApiKeyExists: !ApiKeyHandler.ApiHandler.CheckIfEmpty()}
 
 	return data
 }
@@ -117,7 +117,7 @@ func (mcs *MultiClassSVM) Train(data []*Vector.Vector, epochs int, C float64, de
 	mcs.Classifiers = make(map[int]*SVM)
 	classes := make(map[int]bool)
 	for _, point := range data {
-		m, err := FileMapper.Mapper.ReadPayload(point.PayloadStart, mcs.Collection)
+		m, err := FileMapper.Mapper.ReadPayload(point.PayloadStart, point.Collection)
 		if err != nil {
 			Logger.Log.Log("Error reading payload: " + err.Error())
 			return
@@ -80,6 +80,10 @@ func (hc *HeapControl) StartThreads() {
 func (hc *HeapControl) worker() {
 	defer hc.Wg.Done()
 	for item := range hc.In {
+		// Skip expired vectors, they are not yet removed by the reaper
+		if item.node.Vector.IsExpired() {
+			continue
+		}
 		// Validate the filters
 		if ok, err := hc.validateFilters(&item); !ok {
 			// If the filters are not valid log the possible error
@@ -100,8 +104,8 @@ func (hc *HeapControl) validateFilters(hcs *HeapChannelStruct) (bool, error) {
 		return true, nil
 	}
 	// Validate the filters
-	for _, f := range *hcs.Filter {
-		if ok, err := f.ValidateFilter(hcs.node.Vector); !ok {
+	for i := range *hcs.Filter {
+		if ok, err := (*hcs.Filter)[i].ValidateFilter(hcs.node.Vector); !ok {
 			return false, err
 		}
 	}
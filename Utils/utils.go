@@ -1,10 +1,12 @@
 package Utils
 
 import (
+	"VreeDB/Schema"
 	"VreeDB/Vector"
 	"crypto/rand"
 	"fmt"
 	"math"
+	"math/bits"
 	"runtime"
 	"sync"
 )
@@ -18,12 +20,98 @@ type CollectionConfig struct {
 	VectorDimension  int
 	DistanceFuncName string
 	DiagonalLength   float64
+	SparseFields     []string                     `json:",omitempty"`
+	VectorFields     map[string]VectorFieldConfig `json:",omitempty"`
+	MultiFields      map[string]VectorFieldConfig `json:",omitempty"`
+	BinaryFields     map[string]BinaryFieldConfig `json:",omitempty"`
+	DefaultTTL       int64                        `json:",omitempty"` // Seconds until new points expire, 0 never
+	Segments         []string                     `json:",omitempty"` // FileMapper keys of the segments, the last one is active
+	NextSegment      int                          `json:",omitempty"`
+	FormatVersion    int                          `json:",omitempty"` // Format version of the files, missing is the legacy layout
+	Indexes          map[string]string            `json:",omitempty"` // Payload key of every payload index
+	GeoIndexes       map[string]GeoIndexConfig    `json:",omitempty"` // Payload key and precision of every geo index
+	Schema           *Schema.Schema               `json:",omitempty"` // Declared payload fields, versioned by the schema
+	Tenant           string                       `json:",omitempty"` // The tenant that owns the Collection
+	TenantField      string                       `json:",omitempty"` // Payload key of the tenant of a shared Collection
+}
+
+// VectorFieldConfig is the configuration of a named vector field of a Collection
+type VectorFieldConfig struct {
+	VectorDimension  int
+	DistanceFuncName string
+}
+
+// GeoIndexConfig is the configuration of a geohash index of the geo points of a payload key
+type GeoIndexConfig struct {
+	Key       string
+	Precision int
+}
+
+// BinaryFieldConfig is the configuration of a binary vector field of a Collection, with Rescore the float vectors are
+// stored alongside the bits and compared with the distance function
+type BinaryFieldConfig struct {
+	VectorDimension  int
+	Rescore          bool
+	DistanceFuncName string
+}
+
+// Validate checks if the fields of the CollectionConfig are usable
+func (c *CollectionConfig) Validate() error {
+	if c.VectorDimension < 0 {
+		return fmt.Errorf("dimensions must not be negative")
+	}
+	if c.DefaultTTL < 0 {
+		return fmt.Errorf("default ttl must not be negative")
+	}
+	if c.Tenant != "" && c.TenantField != "" {
+		return fmt.Errorf("a collection of a tenant can not be shared")
+	}
+	if c.VectorDimension == 0 && len(c.VectorFields) == 0 && len(c.MultiFields) == 0 && len(c.BinaryFields) == 0 {
+		return fmt.Errorf("a collection needs dimensions or at least one vector field")
+	}
+	// All field names share the weights of a fused search, so they have to be unique
+	names := map[string]bool{"dense": true}
+	for _, name := range c.SparseFields {
+		if name == "" || names[name] {
+			return fmt.Errorf("field name %q is empty, reserved or used twice", name)
+		}
+		names[name] = true
+	}
+	for name, field := range c.VectorFields {
+		if name == "" || names[name] {
+			return fmt.Errorf("field name %q is empty, reserved or used twice", name)
+		}
+		if field.VectorDimension <= 0 {
+			return fmt.Errorf("vector field %s needs dimensions", name)
+		}
+		names[name] = true
+	}
+	for name, field := range c.MultiFields {
+		if name == "" || names[name] {
+			return fmt.Errorf("field name %q is empty, reserved or used twice", name)
+		}
+		if field.VectorDimension <= 0 {
+			return fmt.Errorf("multi vector field %s needs dimensions", name)
+		}
+		names[name] = true
+	}
+	for name, field := range c.BinaryFields {
+		if name == "" || names[name] {
+			return fmt.Errorf("field name %q is empty, reserved or used twice", name)
+		}
+		if field.VectorDimension <= 0 {
+			return fmt.Errorf("binary vector field %s needs dimensions", name)
+		}
+		names[name] = true
+	}
+	return nil
 }
 
 // ResultSet is the result of a search
 type ResultSet struct {
 	Payload  *map[string]interface{}
 	Distance float64
+	Score    float64 `json:",omitempty"` // Only set by hybrid searches - higher is better
 }
 
 // Utils is the main struct of the Utils
@@ -129,3 +217,34 @@ func (u *Util) CreateUUID() string {
 	}
 	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
+
+// PackBits quantises a vector to one bit per dimension (1 if the value is > 0) packed into uint64 words
+func (u *Util) PackBits(data []float64) []uint64 {
+	words := make([]uint64, (len(data)+63)/64)
+	for i, value := range data {
+		if value > 0 {
+			words[i/64] |= 1 << uint(i%64)
+		}
+	}
+	return words
+}
+
+// UnpackBits returns the first length bits of a bit packed vector as 1 and 0 values
+func (u *Util) UnpackBits(words []uint64, length int) []float64 {
+	data := make([]float64, length)
+	for i := range data {
+		if words[i/64]&(1<<uint(i%64)) != 0 {
+			data[i] = 1
+		}
+	}
+	return data
+}
+
+// HammingDistance returns the number of differing bits of two bit packed vectors
+func (u *Util) HammingDistance(a, b []uint64) int {
+	distance := 0
+	for i := range a {
+		distance += bits.OnesCount64(a[i] ^ b[i])
+	}
+	return distance
+}
@@ -0,0 +1,36 @@
+package Utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackBits(t *testing.T) {
+	data := make([]float64, 70)
+	data[0], data[3], data[64], data[69] = 1, 0.5, 2, 1
+	data[1] = -1
+	words := Utils.PackBits(data)
+	if !reflect.DeepEqual(words, []uint64{1 | 1<<3, 1 | 1<<5}) {
+		t.Fatalf("packed bits %b", words)
+	}
+	want := make([]float64, 70)
+	want[0], want[3], want[64], want[69] = 1, 1, 1, 1
+	if got := Utils.UnpackBits(words, 70); !reflect.DeepEqual(got, want) {
+		t.Errorf("unpacked bits %v", got)
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	for _, test := range []struct {
+		a, b []uint64
+		want int
+	}{
+		{[]uint64{0}, []uint64{0}, 0},
+		{[]uint64{0b1011}, []uint64{0b0110}, 3},
+		{[]uint64{1 << 63, 0}, []uint64{0, 1<<64 - 1}, 65},
+	} {
+		if got := Utils.HammingDistance(test.a, test.b); got != test.want {
+			t.Errorf("HammingDistance(%b, %b) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package Vdb
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/Logger"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// aliasFile is the file of the aliases in the file store
+const aliasFile = "__aliases"
+
+// Alias is an alias and the Collection it points to
+type Alias struct {
+	Alias      string `json:"alias"`
+	Collection string `json:"collection"`
+}
+
+// LoadAliases reads the aliases from the file store
+func (v *Vdb) LoadAliases() error {
+	data, err := os.ReadFile(*ArgsParser.Ap.FileStore + aliasFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	aliases := make(map[string]string)
+	err = json.Unmarshal(data, &aliases)
+	if err != nil {
+		return err
+	}
+	// Aliases of Collections that are gone are kept - the Collection can be restored
+	for alias, collection := range aliases {
+		if _, ok := v.GetCollection(collection); !ok {
+			Logger.Log.Log("Alias " + alias + " points to the missing Collection " + collection)
+		}
+	}
+	v.aliasMut.Lock()
+	v.Aliases = aliases
+	v.aliasMut.Unlock()
+	return nil
+}
+
+// ResolveAlias returns the Collection an alias points to, names that are no alias are returned as they are
+func (v *Vdb) ResolveAlias(name string) string {
+	v.aliasMut.RLock()
+	defer v.aliasMut.RUnlock()
+	if collection, ok := v.Aliases[name]; ok {
+		return collection
+	}
+	return name
+}
+
+// CreateAlias creates an alias for a Collection
+func (v *Vdb) CreateAlias(alias string, collection string) error {
+	// The Collections can not change while the alias is checked
+	v.collectionsMut.RLock()
+	defer v.collectionsMut.RUnlock()
+	v.aliasMut.Lock()
+	defer v.aliasMut.Unlock()
+	if _, ok := v.Aliases[alias]; ok {
+		return fmt.Errorf("Alias with name %s allready exists", alias)
+	}
+	if err := v.checkAlias(alias, collection); err != nil {
+		return err
+	}
+	v.Aliases[alias] = collection
+	if err := v.writeAliases(); err != nil {
+		delete(v.Aliases, alias)
+		return err
+	}
+	Logger.Log.Log("Alias " + alias + " for Collection " + collection + " created")
+	return nil
+}
+
+// SwitchAlias points an alias to another Collection, every request after the switch uses the new Collection
+func (v *Vdb) SwitchAlias(alias string, collection string) error {
+	// The Collections can not change while the alias is checked
+	v.collectionsMut.RLock()
+	defer v.collectionsMut.RUnlock()
+	v.aliasMut.Lock()
+	defer v.aliasMut.Unlock()
+	old, ok := v.Aliases[alias]
+	if !ok {
+		return fmt.Errorf("Alias with name %s does not exist", alias)
+	}
+	if err := v.checkAlias(alias, collection); err != nil {
+		return err
+	}
+	v.Aliases[alias] = collection
+	if err := v.writeAliases(); err != nil {
+		v.Aliases[alias] = old
+		return err
+	}
+	Logger.Log.Log("Alias " + alias + " switched from Collection " + old + " to " + collection)
+	return nil
+}
+
+// DeleteAlias deletes an alias, the Collection stays
+func (v *Vdb) DeleteAlias(alias string) error {
+	v.aliasMut.Lock()
+	defer v.aliasMut.Unlock()
+	collection, ok := v.Aliases[alias]
+	if !ok {
+		return fmt.Errorf("Alias with name %s does not exist", alias)
+	}
+	delete(v.Aliases, alias)
+	if err := v.writeAliases(); err != nil {
+		v.Aliases[alias] = collection
+		return err
+	}
+	Logger.Log.Log("Alias " + alias + " deleted")
+	return nil
+}
+
+// ListAliases returns all aliases sorted by their name, a tenant only gets the aliases of the Collections it sees
+func (v *Vdb) ListAliases(tenant string) []Alias {
+	v.aliasMut.RLock()
+	all := make([]Alias, 0, len(v.Aliases))
+	for alias, collection := range v.Aliases {
+		all = append(all, Alias{Alias: alias, Collection: collection})
+	}
+	v.aliasMut.RUnlock()
+	aliases := make([]Alias, 0, len(all))
+	for _, alias := range all {
+		if v.TenantAccess(tenant, alias.Collection) != NoAccess {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Alias < aliases[j].Alias
+	})
+	return aliases
+}
+
+// IsAlias returns true if the name is an alias
+func (v *Vdb) IsAlias(name string) bool {
+	v.aliasMut.RLock()
+	defer v.aliasMut.RUnlock()
+	_, ok := v.Aliases[name]
+	return ok
+}
+
+// aliasesOf returns the aliases that point to a Collection
+func (v *Vdb) aliasesOf(collection string) []string {
+	v.aliasMut.RLock()
+	defer v.aliasMut.RUnlock()
+	var aliases []string
+	for alias, target := range v.Aliases {
+		if target == collection {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// retargetAliases points the aliases of a renamed Collection to its new name
+func (v *Vdb) retargetAliases(collection string, name string) error {
+	v.aliasMut.Lock()
+	defer v.aliasMut.Unlock()
+	changed := false
+	for alias, target := range v.Aliases {
+		if target == collection {
+			v.Aliases[alias] = name
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return v.writeAliases()
+}
+
+// checkAlias checks if an alias can point to a Collection - the caller holds the locks of the Collections and the
+// aliases
+func (v *Vdb) checkAlias(alias string, collection string) error {
+	if alias == "" || collection == "" {
+		return fmt.Errorf("alias and collection must not be empty")
+	}
+	if _, ok := v.Collections[alias]; ok {
+		return fmt.Errorf("Collection with name %s allready exists", alias)
+	}
+	if _, ok := v.Collections[collection]; !ok {
+		return fmt.Errorf("Collection with name %s does not exist", collection)
+	}
+	return nil
+}
+
+// writeAliases writes the aliases to the file store, the file is written next to the old one and renamed over it -
+// the caller holds the lock
+func (v *Vdb) writeAliases() error {
+	data, err := json.Marshal(v.Aliases)
+	if err != nil {
+		return err
+	}
+	path := *ArgsParser.Ap.FileStore + aliasFile
+	err = os.WriteFile(path+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
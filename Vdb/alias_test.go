@@ -0,0 +1,73 @@
+package Vdb
+
+import (
+	"VreeDB/Utils"
+	"reflect"
+	"testing"
+)
+
+func TestAliasesAreSwitchedAndPersisted(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "aliasv1", VectorDimension: 2})
+	newTestCollection(t, Utils.CollectionConfig{Name: "aliasv2", VectorDimension: 2})
+	if err := DB.CreateAlias("aliasprod", "aliasv1"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if DB.IsAlias("aliasprod") {
+			DB.DeleteAlias("aliasprod")
+		}
+	})
+
+	for name, err := range map[string]error{
+		"the alias twice":             DB.CreateAlias("aliasprod", "aliasv2"),
+		"an alias of a collection":    DB.CreateAlias("aliasv2", "aliasv1"),
+		"an alias of nothing":         DB.CreateAlias("aliasnone", "aliasmissing"),
+		"a collection of the alias":   DB.AddCollection("aliasprod", 2, "euclid"),
+		"the delete of the target":    DB.DeleteCollection("aliasv1"),
+		"a switch of a missing alias": DB.SwitchAlias("aliasnone", "aliasv2"),
+	} {
+		if err == nil {
+			t.Errorf("%s did not fail", name)
+		}
+	}
+
+	if err := DB.SwitchAlias("aliasprod", "aliasv2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := DB.ResolveAlias("aliasprod"); got != "aliasv2" {
+		t.Errorf("the alias resolves to %s", got)
+	}
+	if got := DB.ResolveAlias("aliasv1"); got != "aliasv1" {
+		t.Errorf("a collection resolves to %s", got)
+	}
+
+	// The aliases are read back from the file store
+	DB.aliasMut.Lock()
+	DB.Aliases = make(map[string]string)
+	DB.aliasMut.Unlock()
+	if err := DB.LoadAliases(); err != nil {
+		t.Fatal(err)
+	}
+	if got := DB.ListAliases(""); !reflect.DeepEqual(got, []Alias{{Alias: "aliasprod", Collection: "aliasv2"}}) {
+		t.Errorf("the loaded aliases are %v", got)
+	}
+
+	// A renamed Collection keeps its aliases
+	if err := DB.RenameCollection("aliasv2", "aliasv3"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, ok := DB.Collections["aliasv3"]; ok {
+			DB.DeleteCollection("aliasv3")
+		}
+	})
+	if got := DB.ResolveAlias("aliasprod"); got != "aliasv3" {
+		t.Errorf("the alias of the renamed collection resolves to %s", got)
+	}
+	if err := DB.DeleteAlias("aliasprod"); err != nil {
+		t.Fatal(err)
+	}
+	if DB.IsAlias("aliasprod") {
+		t.Error("the alias was not deleted")
+	}
+}
@@ -0,0 +1,143 @@
+package Vdb
+
+import (
+	"VreeDB/Collection"
+	"VreeDB/Filter"
+	"VreeDB/Node"
+	"VreeDB/Vector"
+	"fmt"
+)
+
+// Count returns the number of points of a Collection that pass the filter. Without a filter no payload is read, an
+// eq condition on an indexed field only reads the payloads of the points of the index value.
+func (v *Vdb) Count(collectionName string, filter *[]Filter.Filter) (int, error) {
+	ids, err := v.Match(collectionName, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// DeleteByFilter deletes all points of a Collection that pass the filter, the KD-Trees are only rebuild once. It
+// returns the number of matched and deleted points - points that are deleted in between are only matched.
+func (v *Vdb) DeleteByFilter(collectionName string, filter *[]Filter.Filter) (int, int, error) {
+	if filter == nil || len(*filter) == 0 {
+		return 0, 0, fmt.Errorf("a filter is required, delete the collection to remove all points")
+	}
+	ids, err := v.Match(collectionName, filter)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(ids) == 0 {
+		return 0, 0, nil
+	}
+	// The Collection can be deleted while its points are matched
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return len(ids), 0, fmt.Errorf("Collection with name %s does not exist", collectionName)
+	}
+	deleted, err := c.DeleteBatch(ids)
+	return len(ids), len(deleted), err
+}
+
+// Match returns the IDs of all points of a Collection that pass the filter and are not expired
+func (v *Vdb) Match(collectionName string, filter *[]Filter.Filter) ([]string, error) {
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return nil, fmt.Errorf("Collection with name %s does not exist", collectionName)
+	}
+	if err := v.CheckFilter(collectionName, filter); err != nil {
+		return nil, err
+	}
+	v.prepareFilter(collectionName, filter)
+
+	candidates, exact := candidateIDs(c, filter)
+	if exact {
+		return candidates, nil
+	}
+	matched := make([]string, 0)
+	for start := 0; start < len(candidates); start += exportChunk {
+		end := start + exportChunk
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+		matched = append(matched, matchChunk(c, candidates[start:end], filter)...)
+	}
+	return matched, nil
+}
+
+// candidateIDs returns the IDs of the live points that can pass the filter. If an eq condition of the filter is on an
+// indexed field only the points of the index value are candidates, a geo filter limits them to the points its geo
+// index found. Otherwise all points are candidates. The candidates are exact if they need no check of the filter: no
+// filter or a single eq condition on a string of an indexed field.
+func candidateIDs(c *Collection.Collection, filter *[]Filter.Filter) ([]string, bool) {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	if filter != nil {
+		for _, f := range *filter {
+			if restricted := f.Restricted(); restricted != nil {
+				ids := make([]string, 0, len(restricted))
+				for id := range restricted {
+					ids = append(ids, id)
+				}
+				return ids, false
+			}
+		}
+		for _, f := range *filter {
+			if f.Op != Filter.Equal {
+				continue
+			}
+			// Indexes only hold strings and float64
+			switch f.Value.(type) {
+			case string, float64:
+			default:
+				continue
+			}
+			for _, index := range c.Indexes {
+				if index.Key != f.Field || index.Precision > 0 {
+					continue
+				}
+				index.Mut.RLock()
+				ids := indexedIDs(index.Entries[f.Value], *c.Space, make([]string, 0))
+				index.Mut.RUnlock()
+				_, isString := f.Value.(string)
+				return ids, isString && len(*filter) == 1
+			}
+		}
+	}
+	ids := make([]string, 0, len(*c.Space))
+	for id, vector := range *c.Space {
+		if !vector.IsExpired() {
+			ids = append(ids, id)
+		}
+	}
+	return ids, filter == nil || len(*filter) == 0
+}
+
+// indexedIDs appends the IDs of the live vectors of the KD-Tree of an index value
+func indexedIDs(n *Node.Node, space map[string]*Vector.Vector, ids []string) []string {
+	if n == nil {
+		return ids
+	}
+	ids = indexedIDs(n.Left, space, ids)
+	ids = indexedIDs(n.Right, space, ids)
+	if n.Vector != nil && space[n.Vector.Id] == n.Vector && !n.Vector.IsExpired() {
+		ids = append(ids, n.Vector.Id)
+	}
+	return ids
+}
+
+// matchChunk returns the IDs of a chunk of points that pass the filter, the Collection is read locked for the chunk
+func matchChunk(c *Collection.Collection, ids []string, filter *[]Filter.Filter) []string {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	matched := make([]string, 0)
+	for _, id := range ids {
+		vector, ok := (*c.Space)[id]
+		if !ok || vector.IsExpired() || !validateFilters(vector, filter) {
+			continue
+		}
+		matched = append(matched, id)
+	}
+	return matched
+}
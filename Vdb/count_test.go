@@ -0,0 +1,67 @@
+package Vdb
+
+import (
+	"VreeDB/Filter"
+	"VreeDB/Utils"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestCountAndDeleteByFilter(t *testing.T) {
+	c := newTestCollection(t, Utils.CollectionConfig{Name: "countfilter", VectorDimension: 2})
+	for i := 0; i < 30; i++ {
+		addTestPoint(t, "countfilter", PointItem{Id: fmt.Sprintf("%02d", i), Vector: []float64{float64(i), 1},
+			Payload: map[string]interface{}{"doc": fmt.Sprint(i % 3), "page": float64(i)}})
+	}
+	if err := c.CreateIndex("docs", "doc"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		filter *[]Filter.Filter
+		want   int
+	}{
+		{nil, 30},
+		{&[]Filter.Filter{}, 30},
+		{&[]Filter.Filter{{Field: "doc", Op: Filter.Equal, Value: "1"}}, 10},
+		{&[]Filter.Filter{{Field: "doc", Op: Filter.Equal, Value: "1"},
+			{Field: "page", Op: Filter.GreaterThan, Value: 20.0}}, 3},
+		{&[]Filter.Filter{{Field: "page", Op: Filter.LessThan, Value: 5.0}}, 5},
+		{&[]Filter.Filter{{Field: "doc", Op: Filter.Equal, Value: "7"}}, 0},
+	} {
+		if got, err := DB.Count("countfilter", test.filter); err != nil || got != test.want {
+			t.Errorf("the filter %v counts %d: %v, want %d", test.filter, got, err, test.want)
+		}
+	}
+	if _, err := DB.Count("countfilter", &[]Filter.Filter{{Field: "doc", Op: "~"}}); err == nil {
+		t.Error("an invalid filter was counted")
+	}
+
+	// Deleting all points needs a delete of the Collection
+	if _, _, err := DB.DeleteByFilter("countfilter", nil); err == nil {
+		t.Error("all points were deleted without a filter")
+	}
+	matched, deleted, err := DB.DeleteByFilter("countfilter", &[]Filter.Filter{{Field: "doc", Op: Filter.Equal,
+		Value: "1"}})
+	if err != nil || matched != 10 || deleted != 10 {
+		t.Fatalf("%d points matched and %d deleted: %v", matched, deleted, err)
+	}
+
+	// The delete is durable and the index is updated
+	c = reloadTestCollection(t, "countfilter")
+	if len(*c.Space) != 20 {
+		t.Errorf("%d points are left after the reload", len(*c.Space))
+	}
+	ids, err := DB.Match("countfilter", &[]Filter.Filter{{Field: "page", Op: Filter.LessThan, Value: 5.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids)
+	if fmt.Sprint(ids) != "[00 02 03]" {
+		t.Errorf("the points %v are left on the first pages", ids)
+	}
+	if got, _ := DB.Count("countfilter", &[]Filter.Filter{{Field: "doc", Op: Filter.Equal, Value: "1"}}); got != 0 {
+		t.Errorf("%d deleted points are counted", got)
+	}
+}
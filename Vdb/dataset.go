@@ -0,0 +1,141 @@
+package Vdb
+
+import (
+	"VreeDB/Dataset"
+	"VreeDB/Utils"
+	"VreeDB/Vector"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RowKey is the payload key that holds the row of a loaded vector in its dataset file, the ground truth of an
+// evaluation refers to the rows
+const RowKey = "row"
+
+// Evaluation is the result of an evaluation of the search against the true nearest neighbours
+type Evaluation struct {
+	Collection string  `json:"collection"`
+	Queries    int     `json:"queries"`
+	K          int     `json:"k"`
+	Recall     float64 `json:"recall"` // Mean share of the k true nearest neighbours in the k results
+	QPS        float64 `json:"qps"`
+	MeanMs     float64 `json:"mean_ms"`
+}
+
+// LoadDataset inserts the vectors of a dataset into a Collection, the Collection is created with the dimension of the
+// dataset if it does not exist. Every point gets the row of its vector as payload, with sequential ids the row is the
+// id too, otherwise the ids are generated. A limit > 0 stops after that many vectors. The report is called after
+// every inserted vector with their count. It returns the number of inserted vectors.
+func (v *Vdb) LoadDataset(collectionName string, distanceFunc string, reader Dataset.Reader, sequential bool,
+	limit int, report func(n int)) (int, error) {
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		// Cosine is the default distance function
+		distanceFunc = strings.ToLower(distanceFunc)
+		if distanceFunc != "euclid" {
+			distanceFunc = "cosine"
+		}
+		err := v.AddCollection(collectionName, reader.Dimension(), distanceFunc)
+		if err != nil {
+			return 0, err
+		}
+		c = v.collection(collectionName)
+	} else if c.VectorDimension != reader.Dimension() {
+		return 0, fmt.Errorf("Collection %s has dimension %d, the dataset %d", collectionName, c.VectorDimension,
+			reader.Dimension())
+	}
+
+	n := 0
+	for ; limit <= 0 || n < limit; n++ {
+		data, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return n, err
+		}
+		p := &PointItem{Vector: data, Payload: map[string]interface{}{RowKey: n}}
+		if sequential {
+			p.Id = strconv.Itoa(n)
+		}
+		_, err = v.AddPoint(collectionName, p)
+		if err != nil {
+			return n, fmt.Errorf("row %d: %w", n, err)
+		}
+		report(n + 1)
+	}
+	return n, nil
+}
+
+// Evaluate searches the k nearest neighbours of every query like /search does and compares them with the rows of the
+// true nearest neighbours, e.g. of an .ivecs ground truth file. A limit > 0 stops after that many queries.
+func (v *Vdb) Evaluate(collectionName string, queries Dataset.Reader, truth [][]int, k int, limit int) (*Evaluation, error) {
+	if _, ok := v.GetCollection(collectionName); !ok {
+		return nil, fmt.Errorf("Collection with name %s does not exist", collectionName)
+	}
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive")
+	}
+
+	evaluation := &Evaluation{Collection: collectionName, K: k}
+	var recall float64
+	var took time.Duration
+	for ; limit <= 0 || evaluation.Queries < limit; evaluation.Queries++ {
+		data, err := queries.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		i := evaluation.Queries
+		if i >= len(truth) {
+			return nil, fmt.Errorf("the ground truth has no neighbours for query %d", i)
+		}
+		if len(truth[i]) < k {
+			return nil, fmt.Errorf("the ground truth of query %d has %d neighbours, k is %d", i, len(truth[i]), k)
+		}
+
+		start := time.Now()
+		results := v.Search(collectionName, Vector.NewVector("", data, nil, ""), Utils.NewHeapControl(k), 0, nil)
+		took += time.Since(start)
+
+		// The rows of the true nearest neighbours that are found
+		want := make(map[int]bool, k)
+		for _, row := range truth[i][:k] {
+			want[row] = true
+		}
+		found := 0
+		for _, result := range results {
+			if result.Payload == nil {
+				continue
+			}
+			if row, ok := rowOf((*result.Payload)[RowKey]); ok && want[row] {
+				found++
+				delete(want, row)
+			}
+		}
+		recall += float64(found) / float64(k)
+	}
+	if evaluation.Queries == 0 {
+		return nil, fmt.Errorf("no queries")
+	}
+	evaluation.Recall = recall / float64(evaluation.Queries)
+	evaluation.MeanMs = float64(took.Microseconds()) / 1000 / float64(evaluation.Queries)
+	if took > 0 {
+		evaluation.QPS = float64(evaluation.Queries) / took.Seconds()
+	}
+	return evaluation, nil
+}
+
+// rowOf returns the row of a payload value, it is an int when it was loaded and a float64 after a json round trip
+func rowOf(value any) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
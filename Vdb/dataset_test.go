@@ -0,0 +1,82 @@
+package Vdb
+
+import (
+	"VreeDB/ArgsParser"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// sliceReader is a Dataset.Reader of the given vectors
+type sliceReader struct {
+	vectors [][]float64
+}
+
+func (s *sliceReader) Dimension() int {
+	return len(s.vectors[0])
+}
+
+func (s *sliceReader) Next() ([]float64, error) {
+	if len(s.vectors) == 0 {
+		return nil, io.EOF
+	}
+	vector := s.vectors[0]
+	s.vectors = s.vectors[1:]
+	return vector, nil
+}
+
+func TestLoadDatasetAndEvaluateRecall(t *testing.T) {
+	// The Collection is created by the load
+	if err := os.MkdirAll(*ArgsParser.Ap.FileStore, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, ok := DB.Collections["dataset"]; ok {
+			DB.DeleteCollection("dataset")
+		}
+	})
+	rows := make([][]float64, 50)
+	for i := range rows {
+		rows[i] = []float64{float64(i), float64(i % 7)}
+	}
+	reported := 0
+	n, err := DB.LoadDataset("dataset", "euclid", &sliceReader{rows}, true, 40, func(n int) {
+		reported = n
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := DB.Collections["dataset"]
+	if n != 40 || reported != 40 || len(*c.Space) != 40 || c.VectorDimension != 2 || c.DistanceFuncName != "euclid" {
+		t.Fatalf("loaded %d of %d points", n, len(*c.Space))
+	}
+	// Sequential ids are the rows
+	if _, ok := (*c.Space)[strconv.Itoa(39)]; !ok {
+		t.Error("row 39 is not the id of its point")
+	}
+	if _, err = DB.LoadDataset("dataset", "", &sliceReader{[][]float64{{1, 2, 3}}}, true, 0, func(int) {}); err == nil {
+		t.Error("a dataset of another dimension was loaded")
+	}
+
+	// Every row is its own nearest neighbour
+	truth := [][]int{{5}, {12}, {30}}
+	queries := [][]float64{rows[5], rows[12], rows[30]}
+	evaluation, err := DB.Evaluate("dataset", &sliceReader{queries}, truth, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evaluation.Queries != 3 || evaluation.Recall != 1 {
+		t.Errorf("%d queries have a recall of %v", evaluation.Queries, evaluation.Recall)
+	}
+	evaluation, err = DB.Evaluate("dataset", &sliceReader{queries}, [][]int{{5}, {0}, {0}}, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evaluation.Recall != 1.0/3 {
+		t.Errorf("a wrong ground truth has a recall of %v", evaluation.Recall)
+	}
+	if _, err = DB.Evaluate("dataset", &sliceReader{queries}, truth, 2, 0); err == nil {
+		t.Error("k is larger than the ground truth")
+	}
+}
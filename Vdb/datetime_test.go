@@ -0,0 +1,95 @@
+package Vdb
+
+import (
+	"VreeDB/Filter"
+	"VreeDB/Formula"
+	"VreeDB/Schema"
+	"VreeDB/Utils"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestDatetimeFiltersFindTheTimeRange(t *testing.T) {
+	c := newTestCollection(t, Utils.CollectionConfig{Name: "datetime", VectorDimension: 2})
+	now := time.Now()
+	day := 24 * time.Hour
+	for id, published := range map[string]interface{}{
+		"today":     now.Add(-time.Hour).Format(time.RFC3339),
+		"yesterday": float64(now.Add(-day).Unix()),
+		"lastweek":  now.Add(-10 * day).UTC().Format(time.RFC3339Nano),
+		"lastyear":  float64(now.Add(-365 * day).Unix()),
+		"undated":   "soon",
+	} {
+		addTestPoint(t, "datetime", PointItem{Id: id, Vector: []float64{1, 1},
+			Payload: map[string]interface{}{"published": published}})
+	}
+
+	match := func(want string, filter ...Filter.Filter) {
+		t.Helper()
+		ids, err := DB.Match("datetime", &filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(ids)
+		if got := fmt.Sprint(ids); got != want {
+			t.Errorf("%s %v found %s, want %s", filter[0].Op, filter[0].Value, got, want)
+		}
+	}
+	lastMonth := now.Add(-30 * day).Format(time.RFC3339)
+	check := func(datetime bool) {
+		t.Helper()
+		match("[today yesterday]", Filter.Filter{Field: "published", Op: Filter.GreaterThan, Value: "now-7d",
+			Datetime: datetime})
+		match("[lastweek lastyear]", Filter.Filter{Field: "published", Op: Filter.LessThanOrEqual, Value: "now-2d",
+			Datetime: datetime})
+		match("[lastweek]", Filter.Filter{Field: "published", Op: Filter.Between,
+			Value: []interface{}{lastMonth, "now-7d"}, Datetime: datetime})
+	}
+	check(true)
+	// Without the datetime flag the literal is compared as a string
+	match("[]", Filter.Filter{Field: "published", Op: Filter.Equal, Value: "now-7d"})
+
+	// A payload index finds the same points
+	if err := c.CreateIndex("published", "published"); err != nil {
+		t.Fatal(err)
+	}
+	check(true)
+
+	// A field the schema declares as datetime compares number literals as Unix seconds
+	err := c.SetSchema(&Schema.Schema{Fields: map[string]Schema.Field{"published": {Type: Schema.DateTime}}})
+	if err == nil {
+		t.Fatal("a schema the undated point does not match was set")
+	}
+	if err := c.Delete("undated"); err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetSchema(&Schema.Schema{Fields: map[string]Schema.Field{"published": {Type: Schema.DateTime}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	match("[lastweek lastyear]", Filter.Filter{Field: "published", Op: Filter.LessThan,
+		Value: float64(now.Add(-2 * day).Unix())})
+	check(false)
+}
+
+func TestRerankDecaysOldResults(t *testing.T) {
+	now := time.Now()
+	result := func(id string, distance float64, published interface{}) *Utils.ResultSet {
+		return &Utils.ResultSet{Distance: distance, Payload: &map[string]interface{}{"id": id, "published": published}}
+	}
+	formula, err := Formula.Parse("(1 - distance) * decay_exp(now - published, 86400)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The nearest result is old, the fresh one wins
+	ranked := Rerank([]*Utils.ResultSet{
+		result("old", 0.1, now.Add(-30*24*time.Hour).Format(time.RFC3339)),
+		result("fresh", 0.5, float64(now.Unix())),
+		result("undated", 0, "soon"),
+	}, formula, 0)
+	if got := resultIDs(ranked); fmt.Sprint(got) != "[fresh old undated]" {
+		t.Errorf("the results are ranked %v", got)
+	}
+}
@@ -0,0 +1,324 @@
+package Vdb
+
+import (
+	"VreeDB/Collection"
+	"VreeDB/FileMapper"
+	"VreeDB/Filter"
+	"VreeDB/Node"
+	"VreeDB/Utils"
+	"VreeDB/Vector"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Facets are the aggregations of the payload fields of the points that match a facet query
+type Facets struct {
+	Points int               `json:"points"`
+	Fields map[string]*Facet `json:"fields"`
+}
+
+// Facet is the aggregation of a payload field. Strings and booleans are counted by value, numbers get their stats
+// and a histogram. The elements of arrays are counted one by one.
+type Facet struct {
+	Count    int           `json:"count"`    // Points that have the field
+	Distinct int           `json:"distinct"` // Distinct values of the field
+	Values   []ValueCount  `json:"values"`   // The most frequent values
+	Numeric  *NumericFacet `json:"numeric,omitempty"`
+	Indexed  bool          `json:"indexed"` // The counts were taken from a payload index
+}
+
+// ValueCount is a value of a payload field and the number of points that have it
+type ValueCount struct {
+	Value interface{} `json:"value"`
+	Count int         `json:"count"`
+}
+
+// NumericFacet holds the stats and the histogram of the numeric values of a payload field
+type NumericFacet struct {
+	Count     int      `json:"count"`
+	Min       float64  `json:"min"`
+	Max       float64  `json:"max"`
+	Avg       float64  `json:"avg"`
+	Histogram []Bucket `json:"histogram"`
+}
+
+// Bucket is a range of a histogram, the last bucket includes its upper bound
+type Bucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// FacetQuery describes the points and the payload fields of a facet request. With a target only the depth nearest
+// neighbours are aggregated, otherwise all points that pass the filter.
+type FacetQuery struct {
+	Fields  []string
+	Filter  *[]Filter.Filter
+	Target  []float64
+	Depth   int
+	Buckets int // Buckets of the histograms
+	Limit   int // Values per field
+}
+
+// facetCounter collects the values of a payload field
+type facetCounter struct {
+	count   int
+	values  map[interface{}]int
+	numbers map[float64]int
+	indexed bool
+}
+
+// Facets aggregates the payload fields of the points of a Collection. Without a filter and a target the counts of a
+// field are taken from a payload index of the field, the other fields are read from the payloads.
+func (v *Vdb) Facets(collectionName string, q FacetQuery) (*Facets, error) {
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return nil, fmt.Errorf("Collection with name %s does not exist", collectionName)
+	}
+	if len(q.Fields) == 0 {
+		return nil, fmt.Errorf("no fields")
+	}
+	if err := v.CheckFilter(collectionName, q.Filter); err != nil {
+		return nil, err
+	}
+	if q.Target != nil && len(q.Target) != c.VectorDimension {
+		return nil, fmt.Errorf("Vector length is %d, expected %d", len(q.Target), c.VectorDimension)
+	}
+	v.prepareFilter(collectionName, q.Filter)
+
+	counters := make(map[string]*facetCounter, len(q.Fields))
+	for _, field := range q.Fields {
+		counters[field] = &facetCounter{values: make(map[interface{}]int), numbers: make(map[float64]int)}
+	}
+	count := func(payload map[string]interface{}) {
+		for field, counter := range counters {
+			if !counter.indexed {
+				counter.add(payload[field])
+			}
+		}
+	}
+
+	points := 0
+	switch {
+	case q.Target != nil:
+		// The payloads of the nearest neighbours
+		depth := q.Depth
+		if depth <= 0 {
+			depth = 3
+		}
+		results := v.Search(collectionName, Vector.NewVector("", q.Target, nil, ""), Utils.NewHeapControl(depth), 0,
+			q.Filter)
+		for _, result := range results {
+			if result != nil && result.Payload != nil {
+				count(*result.Payload)
+				points++
+			}
+		}
+	default:
+		// The payload indexes already know the values of their fields
+		if q.Filter == nil {
+			indexFacets(c, counters)
+		}
+		var err error
+		points, err = scanFacets(c, q.Filter, counters, count)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	facets := &Facets{Points: points, Fields: make(map[string]*Facet, len(counters))}
+	for field, counter := range counters {
+		facets.Fields[field] = counter.facet(q.Buckets, q.Limit)
+	}
+	return facets, nil
+}
+
+// indexFacets counts the values of the fields that have a payload index, only the points that are still in the
+// Collection and not expired are counted
+func indexFacets(c *Collection.Collection, counters map[string]*facetCounter) {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	for _, index := range c.Indexes {
+		counter, ok := counters[index.Key]
+		if !ok || counter.indexed || index.Precision > 0 {
+			continue
+		}
+		index.Mut.RLock()
+		for value, nodes := range index.Entries {
+			counter.addIndexed(value, countIndexed(nodes, *c.Space))
+		}
+		index.Mut.RUnlock()
+		counter.indexed = true
+	}
+}
+
+// countIndexed counts the live vectors of the KD-Tree of an index value
+func countIndexed(n *Node.Node, space map[string]*Vector.Vector) int {
+	if n == nil {
+		return 0
+	}
+	count := countIndexed(n.Left, space) + countIndexed(n.Right, space)
+	if n.Vector != nil && space[n.Vector.Id] == n.Vector && !n.Vector.IsExpired() {
+		count++
+	}
+	return count
+}
+
+// scanFacets reads the payloads of all points that pass the filter, the Collection is only read locked while a chunk
+// of points is read. It returns the number of points.
+func scanFacets(c *Collection.Collection, filter *[]Filter.Filter, counters map[string]*facetCounter,
+	count func(map[string]interface{})) (int, error) {
+	scan := false
+	for _, counter := range counters {
+		scan = scan || !counter.indexed
+	}
+
+	c.Mut.RLock()
+	ids := make([]string, 0, len(*c.Space))
+	for id := range *c.Space {
+		ids = append(ids, id)
+	}
+	c.Mut.RUnlock()
+
+	points := 0
+	for start := 0; start < len(ids); start += exportChunk {
+		end := start + exportChunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+		n, err := scanFacetChunk(c, ids[start:end], filter, scan, count)
+		if err != nil {
+			return points, err
+		}
+		points += n
+	}
+	return points, nil
+}
+
+// scanFacetChunk counts the payloads of a chunk of points, without scan the points are only counted
+func scanFacetChunk(c *Collection.Collection, ids []string, filter *[]Filter.Filter, scan bool,
+	count func(map[string]interface{})) (int, error) {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	points := 0
+	for _, id := range ids {
+		vector, ok := (*c.Space)[id]
+		if !ok || vector.IsExpired() || !validateFilters(vector, filter) {
+			continue
+		}
+		points++
+		if !scan {
+			continue
+		}
+		payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
+		if err != nil {
+			return points, fmt.Errorf("point %s: %w", id, err)
+		}
+		count(*payload)
+	}
+	return points, nil
+}
+
+// add counts a value of the field, arrays count their elements
+func (f *facetCounter) add(value interface{}) {
+	if value == nil {
+		return
+	}
+	f.count++
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			f.addValue(item)
+		}
+		return
+	}
+	f.addValue(value)
+}
+
+// addIndexed counts the n points of a value of a payload index, indexes only hold strings and float64
+func (f *facetCounter) addIndexed(value interface{}, n int) {
+	if n == 0 {
+		return
+	}
+	f.count += n
+	switch v := value.(type) {
+	case float64:
+		f.numbers[v] += n
+	case string:
+		f.values[v] += n
+	}
+}
+
+// addValue counts a single value, objects are only counted as present
+func (f *facetCounter) addValue(value interface{}) {
+	switch v := value.(type) {
+	case float64:
+		f.numbers[v]++
+	case int:
+		f.numbers[float64(v)]++
+	case string, bool:
+		f.values[v]++
+	}
+}
+
+// facet returns the Facet of the counted values with the limit most frequent values and a histogram of the numbers
+func (f *facetCounter) facet(buckets int, limit int) *Facet {
+	if buckets <= 0 {
+		buckets = 10
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	facet := &Facet{Count: f.count, Distinct: len(f.values) + len(f.numbers), Values: []ValueCount{},
+		Indexed: f.indexed}
+	for value, n := range f.values {
+		facet.Values = append(facet.Values, ValueCount{Value: value, Count: n})
+	}
+	for value, n := range f.numbers {
+		facet.Values = append(facet.Values, ValueCount{Value: value, Count: n})
+	}
+	sort.Slice(facet.Values, func(i, j int) bool {
+		if facet.Values[i].Count != facet.Values[j].Count {
+			return facet.Values[i].Count > facet.Values[j].Count
+		}
+		return fmt.Sprint(facet.Values[i].Value) < fmt.Sprint(facet.Values[j].Value)
+	})
+	if len(facet.Values) > limit {
+		facet.Values = facet.Values[:limit]
+	}
+
+	if len(f.numbers) == 0 {
+		return facet
+	}
+	numeric := &NumericFacet{Min: math.Inf(1), Max: math.Inf(-1)}
+	sum := 0.0
+	for value, n := range f.numbers {
+		numeric.Count += n
+		sum += value * float64(n)
+		numeric.Min = math.Min(numeric.Min, value)
+		numeric.Max = math.Max(numeric.Max, value)
+	}
+	numeric.Avg = sum / float64(numeric.Count)
+	// A single value gets a single bucket
+	if numeric.Min == numeric.Max {
+		buckets = 1
+	}
+	width := (numeric.Max - numeric.Min) / float64(buckets)
+	numeric.Histogram = make([]Bucket, buckets)
+	for i := range numeric.Histogram {
+		numeric.Histogram[i] = Bucket{From: numeric.Min + float64(i)*width, To: numeric.Min + float64(i+1)*width}
+	}
+	numeric.Histogram[buckets-1].To = numeric.Max
+	for value, n := range f.numbers {
+		i := buckets - 1
+		if width > 0 {
+			i = int((value - numeric.Min) / width)
+		}
+		if i >= buckets {
+			i = buckets - 1
+		}
+		numeric.Histogram[i].Count += n
+	}
+	facet.Numeric = numeric
+	return facet
+}
@@ -0,0 +1,86 @@
+package Vdb
+
+import (
+	"VreeDB/Filter"
+	"VreeDB/Utils"
+	"reflect"
+	"testing"
+)
+
+func TestFacetsCountThePayloadFields(t *testing.T) {
+	c := newTestCollection(t, Utils.CollectionConfig{Name: "facets", VectorDimension: 2})
+	for _, p := range []PointItem{
+		{Id: "a", Vector: []float64{0, 0}, Payload: map[string]interface{}{"color": "red", "size": 1.0,
+			"tags": []interface{}{"x", "y"}}},
+		{Id: "b", Vector: []float64{1, 1}, Payload: map[string]interface{}{"color": "red", "size": 2.0,
+			"tags": []interface{}{"x"}}},
+		{Id: "c", Vector: []float64{2, 2}, Payload: map[string]interface{}{"color": "blue", "size": 5.0}},
+		{Id: "d", Vector: []float64{3, 3}, Payload: map[string]interface{}{"color": "green"}},
+	} {
+		addTestPoint(t, "facets", p)
+	}
+	if err := c.CreateIndex("colors", "color"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("d"); err != nil {
+		t.Fatal(err)
+	}
+
+	facets, err := DB.Facets("facets", FacetQuery{Fields: []string{"color", "size", "tags"}, Buckets: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if facets.Points != 3 {
+		t.Errorf("%d points were aggregated", facets.Points)
+	}
+	// The deleted point is not counted by the index
+	color := facets.Fields["color"]
+	if !color.Indexed || color.Count != 3 || color.Distinct != 2 ||
+		!reflect.DeepEqual(color.Values, []ValueCount{{"red", 2}, {"blue", 1}}) {
+		t.Errorf("the colors are %+v", color)
+	}
+	// The elements of arrays are counted one by one
+	tags := facets.Fields["tags"]
+	if tags.Indexed || tags.Count != 2 || !reflect.DeepEqual(tags.Values, []ValueCount{{"x", 2}, {"y", 1}}) {
+		t.Errorf("the tags are %+v", tags)
+	}
+	size := facets.Fields["size"].Numeric
+	want := &NumericFacet{Count: 3, Min: 1, Max: 5, Avg: 8.0 / 3,
+		Histogram: []Bucket{{From: 1, To: 3, Count: 2}, {From: 3, To: 5, Count: 1}}}
+	if !reflect.DeepEqual(size, want) {
+		t.Errorf("the sizes are %+v", size)
+	}
+
+	// With a filter the payloads are read instead of the index
+	filter := []Filter.Filter{{Field: "color", Op: Filter.Equal, Value: "red"}}
+	facets, err = DB.Facets("facets", FacetQuery{Fields: []string{"color", "size"}, Filter: &filter, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	color = facets.Fields["color"]
+	if facets.Points != 2 || color.Indexed || !reflect.DeepEqual(color.Values, []ValueCount{{"red", 2}}) {
+		t.Errorf("%d points with the colors %+v", facets.Points, color)
+	}
+	if avg := facets.Fields["size"].Numeric.Avg; avg != 1.5 {
+		t.Errorf("the filtered sizes have the average %v", avg)
+	}
+
+	// The nearest neighbours of a target
+	facets, err = DB.Facets("facets", FacetQuery{Fields: []string{"color"}, Target: []float64{0, 0}, Depth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if facets.Points != 1 || !reflect.DeepEqual(facets.Fields["color"].Values, []ValueCount{{"red", 1}}) {
+		t.Errorf("the nearest neighbour has %+v", facets.Fields["color"])
+	}
+
+	for name, q := range map[string]FacetQuery{
+		"no fields":        {},
+		"wrong dimension":  {Fields: []string{"color"}, Target: []float64{0}},
+		"unknown operator": {Fields: []string{"color"}, Filter: &[]Filter.Filter{{Field: "color", Op: "~"}}},
+	} {
+		if _, err := DB.Facets("facets", q); err == nil {
+			t.Errorf("a query with %s was aggregated", name)
+		}
+	}
+}
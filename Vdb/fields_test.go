@@ -0,0 +1,177 @@
+package Vdb
+
+import (
+	"VreeDB/Utils"
+	"VreeDB/Vector"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNamedVectorFieldsAreSearchedByName(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "named", VectorFields: map[string]Utils.VectorFieldConfig{
+		"image": {VectorDimension: 2, DistanceFuncName: "euclid"},
+		"text":  {VectorDimension: 3, DistanceFuncName: "cosine"}}})
+	for i := 0; i < 10; i++ {
+		addTestPoint(t, "named", PointItem{Id: fmt.Sprint(i), Payload: map[string]interface{}{"n": float64(i)},
+			Vectors: map[string][]float64{"image": {float64(i), 0}, "text": {1, float64(10 - i), 0}}})
+	}
+	if _, err := DB.AddPoint("named", &PointItem{Id: "bad", Vectors: map[string][]float64{"image": {1, 2, 3}}}); err == nil {
+		t.Error("a vector of the wrong dimension was added")
+	}
+	if _, err := DB.AddPoint("named", &PointItem{Id: "bad", Vectors: map[string][]float64{"audio": {1}}}); err == nil {
+		t.Error("a vector of an unknown field was added")
+	}
+
+	search := func(field string, target []float64) float64 {
+		t.Helper()
+		results := DB.FieldSearch("named", field, Vector.NewVector("", target, nil, ""), Utils.NewHeapControl(1), 0,
+			nil)
+		if len(results) != 1 {
+			t.Fatalf("field %s found %d results", field, len(results))
+		}
+		return (*results[0].Payload)["n"].(float64)
+	}
+	if n := search("image", []float64{7.2, 0}); n != 7 {
+		t.Errorf("image finds point %v, want 7", n)
+	}
+	// The text field measures the angle with its own distance function
+	if n := search("text", []float64{1, 0, 0}); n != 9 {
+		t.Errorf("text finds point %v, want 9", n)
+	}
+
+	// The named vectors are read back from the files
+	c := reloadTestCollection(t, "named")
+	if len(c.VectorFields["image"].Space) != 10 || c.VectorFields["text"].DistanceFuncName != "cosine" {
+		t.Fatalf("the fields were not restored: %v", c.Config().VectorFields)
+	}
+	if n := search("image", []float64{2.9, 0}); n != 3 {
+		t.Errorf("the restored image field finds point %v, want 3", n)
+	}
+	points, err := readPointItems(c, []string{"4"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(points[0].Vectors, map[string][]float64{"image": {4, 0}, "text": {1, 6, 0}}) {
+		t.Errorf("point 4 reads %v", points[0].Vectors)
+	}
+}
+
+func TestFusedSearchWeighsTheFields(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "fused", VectorFields: map[string]Utils.VectorFieldConfig{
+		"image": {VectorDimension: 2, DistanceFuncName: "euclid"},
+		"text":  {VectorDimension: 2, DistanceFuncName: "euclid"}}})
+	// Point a is near in the image field, point b in the text field
+	addTestPoint(t, "fused", PointItem{Id: "a", Payload: map[string]interface{}{"id": "a"},
+		Vectors: map[string][]float64{"image": {0, 0}, "text": {10, 10}}})
+	addTestPoint(t, "fused", PointItem{Id: "b", Payload: map[string]interface{}{"id": "b"},
+		Vectors: map[string][]float64{"image": {10, 10}, "text": {0, 0}}})
+
+	target := Vector.NewVector("", nil, nil, "")
+	target.SetNamed("image", []float64{1, 1})
+	target.SetNamed("text", []float64{1, 1})
+	for _, test := range []struct {
+		weights map[string]float64
+		want    string
+	}{
+		{map[string]float64{"image": 2, "text": 1}, "a"},
+		{map[string]float64{"image": 1, "text": 2}, "b"},
+	} {
+		results := DB.HybridSearch("fused", target, 2, test.weights, nil)
+		if len(results) != 2 || (*results[0].Payload)["id"] != test.want {
+			t.Errorf("weights %v rank %v first, want %s", test.weights, results, test.want)
+		}
+	}
+}
+
+func TestMultiVectorsAreRankedByMaxSim(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "maxsim", MultiFields: map[string]Utils.VectorFieldConfig{
+		"tokens": {VectorDimension: 2, DistanceFuncName: "cosine"}}})
+	// Point a matches both query tokens a little, point b one of them very well
+	addTestPoint(t, "maxsim", PointItem{Id: "a", Payload: map[string]interface{}{"id": "a"},
+		MultiVectors: map[string][][]float64{"tokens": {{1, 0}, {0, 1}, {0.5, 0.5}}}})
+	addTestPoint(t, "maxsim", PointItem{Id: "b", Payload: map[string]interface{}{"id": "b"},
+		MultiVectors: map[string][][]float64{"tokens": {{3, 0}}}})
+	if _, err := DB.AddPoint("maxsim", &PointItem{Id: "c", MultiVectors: map[string][][]float64{"tokens": {}}}); err == nil {
+		t.Error("an empty multi vector was added")
+	}
+	if _, err := DB.AddPoint("maxsim", &PointItem{Id: "c",
+		MultiVectors: map[string][][]float64{"tokens": {{1, 0}, {1, 0, 0}}}}); err == nil {
+		t.Error("a token of the wrong dimension was added")
+	}
+
+	check := func() {
+		t.Helper()
+		target := Vector.NewVector("", nil, nil, "")
+		target.SetMulti("tokens", [][]float64{{1, 0}, {0, 1}})
+		results := DB.HybridSearch("maxsim", target, 2, nil, nil)
+		if len(results) != 2 {
+			t.Fatalf("got %d results, want 2", len(results))
+		}
+		// The sum over the query tokens of their best dot product: a 1 + 1, b 3 + 0
+		for i, want := range []struct {
+			id    string
+			score float64
+		}{{"b", 3}, {"a", 2}} {
+			if (*results[i].Payload)["id"] != want.id || results[i].Score != want.score {
+				t.Errorf("result %d is %v with score %v, want %s with %v", i, *results[i].Payload, results[i].Score,
+					want.id, want.score)
+			}
+		}
+	}
+	check()
+
+	c := reloadTestCollection(t, "maxsim")
+	if n := len(c.MultiFields["tokens"].Points["a"]); n != 3 {
+		t.Errorf("point a has %d tokens after the restore", n)
+	}
+	check()
+}
+
+func TestBinaryVectorsAreRankedByHammingDistance(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "binary", BinaryFields: map[string]Utils.BinaryFieldConfig{
+		"bits":    {VectorDimension: 4},
+		"rescore": {VectorDimension: 4, Rescore: true, DistanceFuncName: "euclid"}}})
+	// The bits of a are nearer to the target, the float vector of b is nearer to it
+	addTestPoint(t, "binary", PointItem{Id: "a", Payload: map[string]interface{}{"id": "a"},
+		BinaryVectors: map[string][]float64{"bits": {1, 1, 1, -1}, "rescore": {5, 5, 5, -1}}})
+	addTestPoint(t, "binary", PointItem{Id: "b", Payload: map[string]interface{}{"id": "b"},
+		BinaryVectors: map[string][]float64{"bits": {1, -1, -1, -1}, "rescore": {1, -1, 0, 0}}})
+	if _, err := DB.AddPoint("binary", &PointItem{Id: "c", BinaryVectors: map[string][]float64{"bits": {1}}}); err == nil {
+		t.Error("a binary vector of the wrong dimension was added")
+	}
+
+	search := func(field string) string {
+		t.Helper()
+		data := []float64{1, 1, 1, 0}
+		target := Vector.NewVector("", nil, nil, "")
+		target.SetBinary(field, data, Utils.Utils.PackBits(data), false)
+		results := DB.HybridSearch("binary", target, 2, nil, nil)
+		if len(results) != 2 {
+			t.Fatalf("field %s found %d results", field, len(results))
+		}
+		return (*results[0].Payload)["id"].(string)
+	}
+	check := func() {
+		t.Helper()
+		if id := search("bits"); id != "a" {
+			t.Errorf("the hamming distance ranks %s first", id)
+		}
+		if id := search("rescore"); id != "b" {
+			t.Errorf("the rescored field ranks %s first", id)
+		}
+	}
+	check()
+
+	// Only the packed words are held in memory
+	c := DB.Collections["binary"]
+	if point := c.BinaryFields["bits"].Points["a"]; !reflect.DeepEqual(point.Bits, []uint64{0b111}) {
+		t.Errorf("point a holds the bits %b", point.Bits)
+	}
+	if v := (*c.Space)["a"]; v.Binary["bits"] != nil {
+		t.Error("the binary vector of point a is held by the point")
+	}
+
+	reloadTestCollection(t, "binary")
+	check()
+}
@@ -0,0 +1,69 @@
+package Vdb
+
+import (
+	"VreeDB/Filter"
+	"VreeDB/Schema"
+	"VreeDB/Utils"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestGeoFiltersFindThePointsOfTheArea(t *testing.T) {
+	c := newTestCollection(t, Utils.CollectionConfig{Name: "geo", VectorDimension: 2})
+	err := c.SetSchema(&Schema.Schema{Fields: map[string]Schema.Field{"place": {Type: Schema.GeoPoint}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, place := range map[string][2]float64{
+		"mitte":   {52.5200, 13.4050},
+		"kreuz":   {52.4990, 13.4030},
+		"potsdam": {52.3906, 13.0645},
+		"paris":   {48.8566, 2.3522},
+	} {
+		addTestPoint(t, "geo", PointItem{Id: id, Vector: []float64{1, 1},
+			Payload: map[string]interface{}{"place": map[string]interface{}{"lat": place[0], "lon": place[1]}}})
+	}
+	addTestPoint(t, "geo", PointItem{Id: "nowhere", Vector: []float64{1, 1}})
+	if _, err := DB.AddPoint("geo", &PointItem{Vector: []float64{1, 1},
+		Payload: map[string]interface{}{"place": map[string]interface{}{"lat": 100.0, "lon": 0.0}}}); err == nil {
+		t.Error("a point with an invalid place was added")
+	}
+
+	point := func(lat, lon float64) map[string]interface{} {
+		return map[string]interface{}{"lat": lat, "lon": lon}
+	}
+	filters := map[string]Filter.Filter{
+		"[kreuz mitte]": {Field: "place", Op: Filter.GeoRadius,
+			Value: map[string]interface{}{"center": point(52.52, 13.405), "radius": 5000.0}},
+		"[kreuz mitte potsdam]": {Field: "place", Op: Filter.GeoBoundingBox,
+			Value: map[string]interface{}{"top_left": point(53, 13), "bottom_right": point(52, 14)}},
+		"[paris]": {Field: "place", Op: Filter.GeoPolygon,
+			Value: map[string]interface{}{"points": []interface{}{point(48, 2), point(49, 2), point(49, 3)}}},
+	}
+	match := func() {
+		t.Helper()
+		for want, f := range filters {
+			ids, err := DB.Match("geo", &[]Filter.Filter{f})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(ids)
+			if got := fmt.Sprint(ids); got != want {
+				t.Errorf("%s found %s, want %s", f.Op, got, want)
+			}
+		}
+	}
+	match()
+
+	// A geohash index finds the same points
+	if err := c.CreateGeoIndex("places", "place", 5); err != nil {
+		t.Fatal(err)
+	}
+	match()
+	c = reloadTestCollection(t, "geo")
+	if index, ok := c.Indexes["places"]; !ok || index.Precision != 5 {
+		t.Fatal("the geo index was not reloaded")
+	}
+	match()
+}
@@ -0,0 +1,274 @@
+package Vdb
+
+import (
+	"VreeDB/Collection"
+	"VreeDB/FileMapper"
+	"VreeDB/Filter"
+	"VreeDB/Logger"
+	"VreeDB/Node"
+	"VreeDB/Utils"
+	"VreeDB/Vector"
+	"sort"
+	"time"
+)
+
+// hybridOversample is the factor of candidates every field contributes to a hybrid search, compared to the requested depth
+const hybridOversample = 10
+
+// DenseWeight is the name of the weight that is used for the dense vector in a hybrid search
+const DenseWeight = "dense"
+
+// HybridSearch combines the dense vector search with the named vector fields, the multi vector fields and the sparse
+// fields and the binary fields of a collection. Every field adds its weighted score to a candidate: sparse fields add
+// their dot product, multi vector fields their MaxSim, the dense vector and the named vectors add their similarity (the
+// negative distance for euclid, 1 - distance for cosine), binary fields their hamming or rescored similarity. The
+// results are sorted by the combined score, highest first. The fields only collect the candidates that pass the
+// filter, so a filtered search finds depth points as long as enough points pass it.
+func (v *Vdb) HybridSearch(collectionName string, target *Vector.Vector, depth int, weights map[string]float64,
+	filter *[]Filter.Filter) []*Utils.ResultSet {
+	// Get the starting time
+	t := time.Now()
+
+	// The points that pass the filter, nil without a filter - the searches of the fields only see them
+	var matched map[string]bool
+	var only *[]Filter.Filter
+	if filter != nil && len(*filter) > 0 {
+		ids, err := v.Match(collectionName, filter)
+		if err != nil {
+			Logger.Log.Log("Error matching filters: " + err.Error())
+			return []*Utils.ResultSet{}
+		}
+		matched = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			matched[id] = true
+		}
+		only = &[]Filter.Filter{Filter.IDs(matched)}
+	}
+
+	c := v.collection(collectionName)
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+
+	// The candidates of the search and their combined score
+	candidates := make(map[string]float64)
+
+	// Collect the sparse scores - the inverted index already gives the exact dot product of every matching vector
+	sparseScores := make(map[string]map[string]float64)
+	for name, sparse := range target.Sparse {
+		sparseIndex, ok := c.SparseFields[name]
+		if !ok {
+			continue
+		}
+		scores := sparseIndex.Score(sparse)
+		if matched != nil {
+			for id := range scores {
+				if !matched[id] {
+					delete(scores, id)
+				}
+			}
+		}
+		sparseScores[name] = scores
+		for _, id := range topScores(scores, depth*hybridOversample) {
+			candidates[id] = 0
+		}
+	}
+
+	// The dense spaces of the search - the vector of the collection and the named vector fields
+	var spaces []hybridSpace
+	if target.Length > 0 && target.Length == c.VectorDimension && c.DiagonalLength != 0 {
+		spaces = append(spaces, hybridSpace{name: DenseWeight, target: target, vectors: *c.Space,
+			space: searchSpace{nodes: c.SegmentNodes(), distanceFunc: c.DistanceFunc, distanceFuncName: c.DistanceFuncName,
+				dimensionDiff: c.DimensionDiff, diagonalLength: c.DiagonalLength}})
+	}
+	names := make([]string, 0, len(target.Vectors))
+	for name := range target.Vectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field, ok := c.VectorFields[name]
+		if !ok || target.Vectors[name].Length != field.VectorDimension || field.DiagonalLength == 0 {
+			continue
+		}
+		spaces = append(spaces, hybridSpace{name: name, target: target.Vectors[name], vectors: field.Space,
+			space: searchSpace{nodes: []*Node.Node{field.Nodes}, distanceFunc: field.DistanceFunc, distanceFuncName: field.DistanceFuncName,
+				dimensionDiff: field.DimensionDiff, diagonalLength: field.DiagonalLength}})
+	}
+
+	// Collect the dense candidates through the KD-Trees
+	for _, s := range spaces {
+		queue := Utils.NewHeapControl(depth * hybridOversample)
+		queue.StartThreads()
+		queue.AddToWaitGroup()
+		s.space.search(s.target, queue, only)
+		for _, item := range queue.GetNodes() {
+			candidates[item.Node.Vector.Id] = 0
+		}
+	}
+
+	// Collect the multi vector candidates - every query vector fetches its nearest token vectors through the KD-Tree
+	multiFields := make(map[string]*Collection.MultiVectorField)
+	for name, query := range target.MultiVectors {
+		field, ok := c.MultiFields[name]
+		if !ok || field.DiagonalLength == 0 {
+			continue
+		}
+		multiFields[name] = field
+		for _, q := range query {
+			if q.Length != field.VectorDimension {
+				continue
+			}
+			queue := Utils.NewHeapControl(depth * hybridOversample)
+			queue.StartThreads()
+			queue.AddToWaitGroup()
+			Utils.NewSearchUnit(field.Nodes, q, queue, only, field.DistanceFunc, field.DimensionDiff, 0.1)
+			queue.CloseChannel()
+			queue.Wg.Wait()
+			for _, item := range queue.GetNodes() {
+				candidates[item.Node.Vector.Id] = 0
+			}
+		}
+	}
+
+	// Collect the binary candidates - the hamming scan over the packed bits is the pre-filter stage
+	binaryFields := make(map[string]*Collection.BinaryField)
+	for name, binary := range target.Binary {
+		field, ok := c.BinaryFields[name]
+		if !ok || binary.Length != field.VectorDimension {
+			continue
+		}
+		binaryFields[name] = field
+		for _, id := range field.Nearest(binary.Bits, depth*hybridOversample, matched) {
+			candidates[id] = 0
+		}
+	}
+
+	// Score the candidates - they all passed the filter
+	scored := make([]hybridCandidate, 0, len(candidates))
+	for id := range candidates {
+		vector, ok := (*c.Space)[id]
+		// Skip expired vectors, they are not yet removed by the reaper
+		if !ok || vector.IsExpired() {
+			continue
+		}
+		candidate := hybridCandidate{vector: vector}
+		for name, scores := range sparseScores {
+			candidate.score += fieldWeight(weights, name) * scores[id]
+		}
+		for name, field := range multiFields {
+			candidate.score += fieldWeight(weights, name) * field.MaxSim(id, target.MultiVectors[name])
+		}
+		for name, field := range binaryFields {
+			// Points without a vector in this field get no score from it
+			if _, ok := field.Points[id]; !ok {
+				continue
+			}
+			similarity, err := field.Similarity(id, target.Binary[name])
+			if err != nil {
+				Logger.Log.Log("Error calculating binary similarity: " + err.Error())
+				continue
+			}
+			candidate.score += fieldWeight(weights, name) * similarity
+		}
+		for i, s := range spaces {
+			// Points without a vector in this field get no score from it
+			named, ok := s.vectors[id]
+			if !ok {
+				continue
+			}
+			data := named.GetData()
+			distance, err := s.space.distanceFunc(&Vector.Vector{Data: *data, Length: len(*data)}, s.target)
+			if err != nil {
+				Logger.Log.Log("Error calculating distance: " + err.Error())
+				continue
+			}
+			// The distance of the first dense space is reported with the result
+			if i == 0 {
+				candidate.distance = distance
+			}
+			if s.space.distanceFuncName == "euclid" {
+				candidate.score -= fieldWeight(weights, s.name) * distance
+			} else {
+				candidate.score += fieldWeight(weights, s.name) * (1 - distance)
+			}
+		}
+		scored = append(scored, candidate)
+	}
+
+	// Sort the candidates by score, highest first and cut them to the requested depth
+	sort.Slice(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	if len(scored) > depth {
+		scored = scored[:depth]
+	}
+
+	// Get the Payloads back from the Memory Map
+	results := make([]*Utils.ResultSet, 0, len(scored))
+	for _, candidate := range scored {
+		m, err := FileMapper.Mapper.ReadPayload(candidate.vector.PayloadStart, candidate.vector.Collection)
+		if err != nil {
+			Logger.Log.Log("Error reading payload: " + err.Error())
+			continue
+		}
+		results = append(results, &Utils.ResultSet{Payload: m, Distance: candidate.distance, Score: candidate.score})
+	}
+
+	Logger.Log.Log("Hybrid search took: " + time.Since(t).String())
+	return results
+}
+
+// hybridSpace is a dense field that takes part in a hybrid search
+type hybridSpace struct {
+	name    string
+	target  *Vector.Vector
+	vectors map[string]*Vector.Vector
+	space   searchSpace
+}
+
+// hybridCandidate is a vector that was found by at least one field of a hybrid search
+type hybridCandidate struct {
+	vector   *Vector.Vector
+	distance float64
+	score    float64
+}
+
+// fieldWeight returns the weight of the given field, fields without a weight count 1
+func fieldWeight(weights map[string]float64, name string) float64 {
+	if w, ok := weights[name]; ok {
+		return w
+	}
+	return 1
+}
+
+// topScores returns the ids of the n highest scores
+func topScores(scores map[string]float64, n int) []string {
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return scores[ids[i]] > scores[ids[j]]
+	})
+	if len(ids) > n {
+		ids = ids[:n]
+	}
+	return ids
+}
+
+// validateFilters checks if a vector passes all filters
+func validateFilters(vector *Vector.Vector, filter *[]Filter.Filter) bool {
+	if filter == nil {
+		return true
+	}
+	for i := range *filter {
+		ok, err := (*filter)[i].ValidateFilter(vector)
+		if err != nil {
+			Logger.Log.Log("Error validating filters: " + err.Error())
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
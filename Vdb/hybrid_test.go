@@ -0,0 +1,60 @@
+package Vdb
+
+import (
+	"VreeDB/Filter"
+	"VreeDB/Utils"
+	"VreeDB/Vector"
+	"fmt"
+	"testing"
+)
+
+func TestHybridSearchRanksBySparseDotProduct(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "hybridsparse", VectorDimension: 2,
+		SparseFields: []string{"text"}})
+	for i, weight := range []float64{1, 3, 2} {
+		addTestPoint(t, "hybridsparse", PointItem{Id: fmt.Sprint(i), Vector: []float64{0, 0},
+			Payload:       map[string]interface{}{"n": float64(i)},
+			SparseVectors: map[string]*Vector.SparseVector{"text": {Indices: []int{7}, Values: []float64{weight}}}})
+	}
+
+	target := Vector.NewVector("", nil, nil, "")
+	target.SetSparse("text", &Vector.SparseVector{Indices: []int{7}, Values: []float64{1}})
+	results := DB.HybridSearch("hybridsparse", target, 3, nil, nil)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for i, want := range []float64{1, 2, 0} {
+		if got := (*results[i].Payload)["n"]; got != want {
+			t.Errorf("result %d is point %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestHybridSearchFilterFindsDepthMatches(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "hybridfilter", VectorDimension: 2,
+		SparseFields: []string{"text"}})
+	// The matching points are the farthest ones, the nearest candidates of every field do not contain them
+	for i := 0; i < 300; i++ {
+		keep := "no"
+		if i >= 295 {
+			keep = "yes"
+		}
+		addTestPoint(t, "hybridfilter", PointItem{Id: fmt.Sprint(i), Vector: []float64{float64(i), 0},
+			Payload: map[string]interface{}{"keep": keep},
+			SparseVectors: map[string]*Vector.SparseVector{
+				"text": {Indices: []int{1}, Values: []float64{float64(300 - i)}}}})
+	}
+
+	filter := []Filter.Filter{{Field: "keep", Op: Filter.Equal, Value: "yes"}}
+	target := Vector.NewVector("", []float64{0, 0}, nil, "")
+	target.SetSparse("text", &Vector.SparseVector{Indices: []int{1}, Values: []float64{1}})
+	results := DB.HybridSearch("hybridfilter", target, 5, nil, &filter)
+	if len(results) != 5 {
+		t.Fatalf("got %d results, want 5", len(results))
+	}
+	for _, result := range results {
+		if (*result.Payload)["keep"] != "yes" {
+			t.Errorf("result %v does not pass the filter", *result.Payload)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package Vdb
+
+import (
+	"VreeDB/Utils"
+	"testing"
+)
+
+func TestInfoReportsTheStatisticsOfTheCollection(t *testing.T) {
+	c := newTestCollection(t, Utils.CollectionConfig{Name: "infostats", VectorDimension: 2})
+	info, err := c.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Points != 0 || info.LastInsert != nil || len(info.Dimensions) != 2 || info.Dimensions[0].Mean != 0 {
+		t.Errorf("the empty collection reports %+v", info)
+	}
+
+	for _, p := range []PointItem{
+		{Id: "a", Vector: []float64{0, 10}, Payload: map[string]interface{}{"color": "red"}},
+		{Id: "b", Vector: []float64{2, 20}, Payload: map[string]interface{}{"color": "blue"}},
+		{Id: "c", Vector: []float64{4, 30}, Payload: map[string]interface{}{"color": "red"}},
+	} {
+		addTestPoint(t, "infostats", p)
+	}
+	if err := c.CreateIndex("colors", "color"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("c"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err = c.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "infostats" || info.Dimension != 2 || info.DistanceFunc != "euclid" {
+		t.Errorf("the collection is reported as %s with %d dimensions and %s", info.Name, info.Dimension,
+			info.DistanceFunc)
+	}
+	if info.Points != 2 || info.Deleted != 1 {
+		t.Errorf("%d points and %d deleted are reported", info.Points, info.Deleted)
+	}
+	if info.LastInsert == nil {
+		t.Error("the last insert is missing")
+	}
+	if info.DiskBytes != c.DiskBytes() || info.DiskBytes == 0 {
+		t.Errorf("%d bytes on disk are reported, the files have %d", info.DiskBytes, c.DiskBytes())
+	}
+	var bytes int64
+	for _, f := range info.Files {
+		bytes += f.Bytes
+	}
+	if bytes != info.DiskBytes {
+		t.Errorf("the files have %d bytes, the total is %d", bytes, info.DiskBytes)
+	}
+
+	// The mean is taken over the points left, the bounds are kept for the diagonal length
+	if d := info.Dimensions[1]; d.Mean != 15 || d.Max != 30 || d.Range != d.Max-d.Min {
+		t.Errorf("the second dimension is reported as %+v", d)
+	}
+	if len(info.Indexes) != 1 || info.Indexes[0].Key != "color" || info.Indexes[0].Values != 2 {
+		t.Errorf("the indexes are reported as %+v", info.Indexes)
+	}
+	points := 0
+	for _, s := range info.Segments {
+		points += s.Points
+		if s.Points > 0 && (s.Depth == 0 || s.Balance <= 0 || s.Balance > 1) {
+			t.Errorf("the segment is reported as %+v", s)
+		}
+	}
+	if points != 2 {
+		t.Errorf("the segments hold %d points", points)
+	}
+}
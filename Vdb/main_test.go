@@ -0,0 +1,20 @@
+package Vdb
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain runs the tests in a temporary directory, the files of the database and the log file are removed afterwards
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "vreedb")
+	if err != nil {
+		panic(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,153 @@
+package Vdb
+
+import (
+	"VreeDB/Collection"
+	"VreeDB/Utils"
+	"VreeDB/Vector"
+	"fmt"
+	"time"
+)
+
+// PointItem is a point with all its fields, it is added to a Collection and it is a line of an export
+type PointItem struct {
+	Id            string                          `json:"id"` // Optional - a random id is created if empty
+	Vector        []float64                       `json:"vector"`
+	Payload       map[string]interface{}          `json:"payload,omitempty"`        // Optional
+	SparseVectors map[string]*Vector.SparseVector `json:"sparse_vectors,omitempty"` // Optional
+	Vectors       map[string][]float64            `json:"vectors,omitempty"`        // Optional
+	MultiVectors  map[string][][]float64          `json:"multi_vectors,omitempty"`  // Optional
+	BinaryVectors map[string][]float64            `json:"binary_vectors,omitempty"` // Optional
+	TTLSeconds    int64                           `json:"ttl_seconds,omitempty"`    // Optional
+	ExpiresAt     int64                           `json:"expires_at,omitempty"`     // Optional
+}
+
+// AddPoint creates the Vector of the PointItem and inserts it into the Collection. The tenant of the point has to stay
+// within its quotas, the point is reserved in them until it is inserted. The Vector is returned if it was created.
+func (v *Vdb) AddPoint(collectionName string, p *PointItem) (*Vector.Vector, error) {
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return nil, fmt.Errorf("Collection with name %s does not exist", collectionName)
+	}
+	release, err := v.reserveQuota(c, p)
+	if err != nil {
+		return nil, err
+	}
+	vector, err := v.newPointVector(c, p)
+	if err == nil {
+		err = c.Insert(vector)
+	}
+	release(err == nil)
+	return vector, err
+}
+
+// AddPoints creates the Vectors of the PointItems and inserts them into the Collection as a batch, the records of the
+// batch are written to the meta file at once. Every point gets its own error, the Vector of a point is returned if it
+// was created.
+func (v *Vdb) AddPoints(collectionName string, points []PointItem) ([]*Vector.Vector, []error) {
+	vectors := make([]*Vector.Vector, len(points))
+	errs := make([]error, len(points))
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		for i := range errs {
+			errs[i] = fmt.Errorf("Collection with name %s does not exist", collectionName)
+		}
+		return vectors, errs
+	}
+
+	releases := make([]func(bool), 0, len(points))
+	created := make([]*Vector.Vector, 0, len(points))
+	indexes := make([]int, 0, len(points))
+	for i := range points {
+		release, err := v.reserveQuota(c, &points[i])
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		vectors[i], errs[i] = v.newPointVector(c, &points[i])
+		if errs[i] != nil {
+			release(false)
+			continue
+		}
+		releases = append(releases, release)
+		created = append(created, vectors[i])
+		indexes = append(indexes, i)
+	}
+	for j, err := range c.InsertBatch(created) {
+		errs[indexes[j]] = err
+		releases[j](err == nil)
+	}
+	return vectors, errs
+}
+
+// newPointVector checks the PointItem against the Collection and creates its Vector with all fields, AddPoint inserts
+// it
+func (v *Vdb) newPointVector(c *Collection.Collection, p *PointItem) (*Vector.Vector, error) {
+	// Check everything before anything is written to the file
+	if len(p.Vector) != c.VectorDimension {
+		return nil, fmt.Errorf("Vector length is %d, expected %d", len(p.Vector), c.VectorDimension)
+	}
+	if err := c.CheckSparseFields(p.SparseVectors); err != nil {
+		return nil, err
+	}
+	for _, sparse := range p.SparseVectors {
+		if err := sparse.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.CheckVectorFields(p.Vectors); err != nil {
+		return nil, err
+	}
+	if err := c.CheckMultiFields(p.MultiVectors); err != nil {
+		return nil, err
+	}
+	if err := c.CheckBinaryFields(p.BinaryVectors); err != nil {
+		return nil, err
+	}
+	if err := c.GetSchema().Validate(p.Payload); err != nil {
+		return nil, err
+	}
+	if p.TTLSeconds < 0 || p.ExpiresAt < 0 {
+		return nil, fmt.Errorf("ttl_seconds and expires_at must not be negative")
+	}
+	// Create the vector and add the fields
+	// New vectors are written to the active segment of the collection, it is chosen under the lock that seals it
+	var vector *Vector.Vector
+	err := c.WriteActiveSegment(func(key string) error {
+		vector = Vector.NewVector(p.Id, p.Vector, &p.Payload, key)
+		for name, sparse := range p.SparseVectors {
+			if err := vector.SetSparse(name, sparse); err != nil {
+				return err
+			}
+		}
+		for name, data := range p.Vectors {
+			if err := vector.SetNamed(name, data); err != nil {
+				return err
+			}
+		}
+		for name, data := range p.MultiVectors {
+			if err := vector.SetMulti(name, data); err != nil {
+				return err
+			}
+		}
+		for name, data := range p.BinaryVectors {
+			if err := vector.SetBinary(name, data, Utils.Utils.PackBits(data), c.BinaryFields[name].Rescore); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the expiry time - expires_at wins over ttl_seconds, the default TTL of the Collection is the fallback
+	switch {
+	case p.ExpiresAt > 0:
+		vector.ExpiresAt = p.ExpiresAt
+	case p.TTLSeconds > 0:
+		vector.ExpiresAt = time.Now().Unix() + p.TTLSeconds
+	case c.DefaultTTL > 0:
+		vector.ExpiresAt = time.Now().Unix() + c.DefaultTTL
+	}
+	return vector, nil
+}
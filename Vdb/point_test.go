@@ -0,0 +1,93 @@
+package Vdb
+
+import (
+	"VreeDB/Utils"
+	"fmt"
+	"testing"
+)
+
+func BenchmarkBulkIngest(b *testing.B) {
+	newTestCollection(b, Utils.CollectionConfig{Name: "benchingest", VectorDimension: 128})
+	data := make([]float64, 128)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data[i%128] = float64(i)
+		p := PointItem{Id: fmt.Sprint(i), Vector: append([]float64(nil), data...),
+			Payload: map[string]interface{}{"n": float64(i)}}
+		addTestPoint(b, "benchingest", p)
+	}
+}
+
+func BenchmarkBatchIngest(b *testing.B) {
+	newTestCollection(b, Utils.CollectionConfig{Name: "benchbatch", VectorDimension: 128})
+	points := make([]PointItem, 0, 256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data := make([]float64, 128)
+		data[i%128] = float64(i)
+		points = append(points, PointItem{Id: fmt.Sprint(i), Vector: data,
+			Payload: map[string]interface{}{"n": float64(i)}})
+		if len(points) == cap(points) || i == b.N-1 {
+			if _, errs := DB.AddPoints("benchbatch", points); errs[0] != nil {
+				b.Fatal(errs[0])
+			}
+			points = points[:0]
+		}
+	}
+}
+
+func TestAddPointsReportsEveryPoint(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "batch", VectorDimension: 2})
+	points := []PointItem{
+		{Id: "a", Vector: []float64{1, 2}, Payload: map[string]interface{}{"n": float64(1)}},
+		{Id: "b", Vector: []float64{1}},
+		{Id: "a", Vector: []float64{3, 4}},
+		{Id: "c", Vector: []float64{5, 6}},
+	}
+	vectors, errs := DB.AddPoints("batch", points)
+	for i, failed := range []bool{false, true, true, false} {
+		if (errs[i] != nil) != failed {
+			t.Errorf("point %d: %v", i, errs[i])
+		}
+	}
+	if vectors[1] != nil || vectors[0] == nil || vectors[0].Id != "a" {
+		t.Errorf("the vectors of the points are %v", vectors)
+	}
+
+	// The records of the batch are in the meta file
+	c := reloadTestCollection(t, "batch")
+	if len(*c.Space) != 2 || (*c.Space)["c"] == nil {
+		t.Fatalf("the reloaded collection holds %d points", len(*c.Space))
+	}
+	if got := *(*c.Space)["a"].GetData(); got[1] != 2 {
+		t.Errorf("the first point a reads %v", got)
+	}
+
+	if _, errs = DB.AddPoints("nobatch", points[:1]); errs[0] == nil {
+		t.Error("points were added to a collection that does not exist")
+	}
+}
+
+func TestAddPointWhileTheCollectionIsDeleted(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "deleted", VectorDimension: 2})
+	done := make(chan error)
+	go func() {
+		// The points that are added after the delete fail
+		for i := 0; ; i++ {
+			p := PointItem{Id: fmt.Sprint(i), Vector: []float64{1, float64(i)}}
+			if _, err := DB.AddPoint("deleted", &p); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	if err := DB.DeleteCollection("deleted"); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	p := PointItem{Vector: []float64{1, 1}}
+	if _, err := DB.AddPoint("deleted", &p); err == nil || err.Error() != "Collection with name deleted does not exist" {
+		t.Errorf("a point was added to the deleted collection: %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package Vdb
+
+import (
+	"VreeDB/Datetime"
+	"VreeDB/FileMapper"
+	"VreeDB/Filter"
+	"VreeDB/Formula"
+	"VreeDB/Utils"
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OrderBy sorts results by a payload field, results without the field come last
+type OrderBy struct {
+	Field     string `json:"field"`
+	Direction string `json:"direction"` // asc (default) or desc
+}
+
+// Check checks the field and the direction of the OrderBy
+func (o *OrderBy) Check() error {
+	if o.Field == "" {
+		return fmt.Errorf("order_by needs a field")
+	}
+	switch strings.ToLower(o.Direction) {
+	case "", "asc", "desc":
+		return nil
+	}
+	return fmt.Errorf("invalid direction %s", o.Direction)
+}
+
+// Query returns the points of a Collection that pass the filter sorted by a payload field, a limit > 0 returns only
+// the first points - only that many payloads are kept while the points are read
+func (v *Vdb) Query(collectionName string, filter *[]Filter.Filter, orderBy *OrderBy, limit int) ([]*Utils.ResultSet,
+	error) {
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return nil, fmt.Errorf("Collection with name %s does not exist", collectionName)
+	}
+	if err := orderBy.Check(); err != nil {
+		return nil, err
+	}
+	v.prepareFilter(collectionName, filter)
+
+	c.Mut.RLock()
+	// The last of the kept results is on top of the heap, it is replaced by a result that sorts before it
+	kept := &orderHeap{orderBy: orderBy}
+	for _, vector := range *c.Space {
+		if vector.IsExpired() || !validateFilters(vector, filter) {
+			continue
+		}
+		payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
+		if err != nil {
+			c.Mut.RUnlock()
+			return nil, err
+		}
+		result := &Utils.ResultSet{Payload: payload}
+		switch {
+		case limit <= 0 || kept.Len() < limit:
+			heap.Push(kept, result)
+		case orderLess(result, kept.results[0], orderBy):
+			kept.results[0] = result
+			heap.Fix(kept, 0)
+		}
+	}
+	c.Mut.RUnlock()
+
+	results := kept.results
+	if results == nil {
+		results = []*Utils.ResultSet{}
+	}
+	Order(results, orderBy)
+	return results, nil
+}
+
+// orderHeap is a heap of results with the result that sorts last by the OrderBy on top
+type orderHeap struct {
+	results []*Utils.ResultSet
+	orderBy *OrderBy
+}
+
+func (h *orderHeap) Len() int           { return len(h.results) }
+func (h *orderHeap) Less(i, j int) bool { return orderLess(h.results[j], h.results[i], h.orderBy) }
+func (h *orderHeap) Swap(i, j int)      { h.results[i], h.results[j] = h.results[j], h.results[i] }
+func (h *orderHeap) Push(x interface{}) { h.results = append(h.results, x.(*Utils.ResultSet)) }
+func (h *orderHeap) Pop() interface{} {
+	last := h.results[len(h.results)-1]
+	h.results = h.results[:len(h.results)-1]
+	return last
+}
+
+// Order sorts results by a payload field. Numbers are compared by value, RFC 3339 datetimes by their time and
+// everything else by its text, numbers come before datetimes and datetimes before text. Results without the field come
+// last in both directions.
+func Order(results []*Utils.ResultSet, orderBy *OrderBy) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return orderLess(results[i], results[j], orderBy)
+	})
+}
+
+// orderLess returns true if the result a sorts before the result b by the OrderBy
+func orderLess(a, b *Utils.ResultSet, orderBy *OrderBy) bool {
+	av, aok := payloadValue(a, orderBy.Field)
+	bv, bok := payloadValue(b, orderBy.Field)
+	if !aok || !bok {
+		return aok && !bok
+	}
+	if strings.ToLower(orderBy.Direction) == "desc" {
+		return compareValues(bv, av)
+	}
+	return compareValues(av, bv)
+}
+
+// Rerank scores results with a formula and returns the n best, the highest score first. The formula can use the
+// distance, the score of a hybrid search, now (unix seconds) and the numeric payload fields - timestamps in RFC 3339
+// are unix seconds too. Results whose score is not a number, e.g. because a field is missing, come last.
+func Rerank(results []*Utils.ResultSet, formula *Formula.Formula, n int) []*Utils.ResultSet {
+	now := float64(time.Now().Unix())
+	valid := make(map[*Utils.ResultSet]bool, len(results))
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		score, err := formula.Eval(func(name string) (float64, bool) {
+			switch name {
+			case "distance":
+				return result.Distance, true
+			case "score":
+				return result.Score, true
+			case "now":
+				return now, true
+			}
+			value, ok := payloadValue(result, name)
+			if !ok {
+				return 0, false
+			}
+			return numberOf(value)
+		})
+		result.Score = 0
+		if err == nil && !math.IsNaN(score) && !math.IsInf(score, 0) {
+			result.Score = score
+			valid[result] = true
+		}
+	}
+
+	ranked := make([]*Utils.ResultSet, 0, len(results))
+	for _, result := range results {
+		if result != nil {
+			ranked = append(ranked, result)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if valid[ranked[i]] != valid[ranked[j]] {
+			return valid[ranked[i]]
+		}
+		return ranked[i].Score > ranked[j].Score
+	})
+	if n > 0 && len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// payloadValue returns the value of a payload field of a result
+func payloadValue(result *Utils.ResultSet, field string) (interface{}, bool) {
+	if result == nil || result.Payload == nil {
+		return nil, false
+	}
+	value, ok := (*result.Payload)[field]
+	return value, ok && value != nil
+}
+
+// numberOf returns a payload value as number, RFC 3339 timestamps are returned as unix seconds
+func numberOf(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		return Datetime.Parse(v)
+	}
+	return 0, false
+}
+
+// Kinds of the payload values in the order they are sorted
+const (
+	numberValue = iota
+	datetimeValue
+	textValue
+)
+
+// compareValues returns true if a sorts before b
+func compareValues(a, b interface{}) bool {
+	aKind, an := kindOf(a)
+	bKind, bn := kindOf(b)
+	switch {
+	case aKind != bKind:
+		return aKind < bKind
+	case aKind == textValue:
+		return fmt.Sprint(a) < fmt.Sprint(b)
+	}
+	return an < bn
+}
+
+// kindOf returns the kind of a payload value and the number of numbers and datetimes
+func kindOf(value interface{}) (int, float64) {
+	if s, ok := value.(string); ok {
+		if t, ok := Datetime.Parse(s); ok {
+			return datetimeValue, t
+		}
+		return textValue, 0
+	}
+	if n, ok := numberOf(value); ok {
+		return numberValue, n
+	}
+	return textValue, 0
+}
@@ -0,0 +1,117 @@
+package Vdb
+
+import (
+	"VreeDB/Filter"
+	"VreeDB/Formula"
+	"VreeDB/Utils"
+	"reflect"
+	"testing"
+)
+
+// resultIDs returns the ids in the payloads of the results
+func resultIDs(results []*Utils.ResultSet) []interface{} {
+	ids := []interface{}{}
+	for _, result := range results {
+		ids = append(ids, (*result.Payload)["id"])
+	}
+	return ids
+}
+
+func TestQueryIsOrderedByAPayloadField(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "orderby", VectorDimension: 2})
+	for id, payload := range map[string]map[string]interface{}{
+		"a": {"price": 3.0, "shop": "x"},
+		"b": {"price": 1.0, "shop": "x"},
+		"c": {"price": "cheap", "shop": "x"},
+		"d": {"shop": "x"},
+		"e": {"price": 2.0, "shop": "y"},
+		"f": {"price": 0.5, "shop": "x"},
+	} {
+		payload["id"] = id
+		addTestPoint(t, "orderby", PointItem{Id: id, Vector: []float64{1, 1}, Payload: payload})
+	}
+
+	// Numbers come before text, points without the field come last in both directions
+	filter := []Filter.Filter{{Field: "shop", Op: Filter.Equal, Value: "x"}}
+	for _, test := range []struct {
+		orderBy OrderBy
+		limit   int
+		want    []interface{}
+	}{
+		{OrderBy{Field: "price"}, 0, []interface{}{"f", "b", "a", "c", "d"}},
+		{OrderBy{Field: "price", Direction: "DESC"}, 0, []interface{}{"c", "a", "b", "f", "d"}},
+		{OrderBy{Field: "price", Direction: "asc"}, 2, []interface{}{"f", "b"}},
+	} {
+		results, err := DB.Query("orderby", &filter, &test.orderBy, test.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resultIDs(results); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%+v with limit %d: %v, want %v", test.orderBy, test.limit, got, test.want)
+		}
+	}
+
+	for _, orderBy := range []OrderBy{{}, {Field: "price", Direction: "up"}} {
+		if _, err := DB.Query("orderby", nil, &orderBy, 0); err == nil {
+			t.Errorf("the query was ordered by %+v", orderBy)
+		}
+	}
+}
+
+func TestQueryOrdersDatetimesByTheirTime(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "orderbytime", VectorDimension: 2})
+	// The text of the datetimes sorts the other way around
+	for id, created := range map[string]interface{}{
+		"first":  "2024-01-01T10:00:00+05:00",
+		"second": "2024-01-01T06:00:00Z",
+		"third":  "2024-01-01T01:30:00-05:00",
+		"number": 7.0,
+		"text":   "yesterday",
+	} {
+		addTestPoint(t, "orderbytime", PointItem{Id: id, Vector: []float64{1, 1},
+			Payload: map[string]interface{}{"id": id, "created": created}})
+	}
+
+	for _, test := range []struct {
+		orderBy OrderBy
+		limit   int
+		want    []interface{}
+	}{
+		{OrderBy{Field: "created"}, 0, []interface{}{"number", "first", "second", "third", "text"}},
+		{OrderBy{Field: "created"}, 3, []interface{}{"number", "first", "second"}},
+		{OrderBy{Field: "created", Direction: "desc"}, 2, []interface{}{"text", "third"}},
+		{OrderBy{Field: "created", Direction: "desc"}, 10, []interface{}{"text", "third", "second", "first", "number"}},
+	} {
+		results, err := DB.Query("orderbytime", nil, &test.orderBy, test.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resultIDs(results); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%+v with limit %d: %v, want %v", test.orderBy, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestRerankScoresTheResultsWithAFormula(t *testing.T) {
+	result := func(id string, distance float64, popularity interface{}) *Utils.ResultSet {
+		payload := map[string]interface{}{"id": id}
+		if popularity != nil {
+			payload["popularity"] = popularity
+		}
+		return &Utils.ResultSet{Distance: distance, Payload: &payload}
+	}
+	formula, err := Formula.Parse("-distance + log(popularity)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A point whose score can not be computed comes last
+	results := []*Utils.ResultSet{result("near", 1, 1.0), nil, result("missing", 0, nil), result("far", 2, 100.0),
+		result("text", 0, "x")}
+	ranked := Rerank(results, formula, 3)
+	if got := resultIDs(ranked); !reflect.DeepEqual(got, []interface{}{"far", "near", "missing"}) {
+		t.Fatalf("the results are ranked %v", got)
+	}
+	if ranked[1].Score != -1 || ranked[2].Score != 0 {
+		t.Errorf("the scores are %v and %v", ranked[1].Score, ranked[2].Score)
+	}
+}
@@ -0,0 +1,63 @@
+package Vdb
+
+import (
+	"VreeDB/Filter"
+	"VreeDB/Schema"
+	"VreeDB/Utils"
+	"bytes"
+	"testing"
+)
+
+func TestSchemaIsEnforcedOnInsert(t *testing.T) {
+	c := newTestCollection(t, Utils.CollectionConfig{Name: "schema", VectorDimension: 2})
+	for id, label := range map[string]interface{}{"a": 5.0, "b": "x"} {
+		addTestPoint(t, "schema", PointItem{Id: id, Vector: []float64{1, 1},
+			Payload: map[string]interface{}{"label": label}})
+	}
+
+	// The points that are already in the Collection have to match
+	schema := func() *Schema.Schema {
+		return &Schema.Schema{Fields: map[string]Schema.Field{"label": {Type: Schema.Integer, Required: true}}}
+	}
+	if err := c.SetSchema(schema()); err == nil {
+		t.Fatal("a schema the point b does not match was set")
+	}
+	if c.GetSchema() != nil {
+		t.Fatal("the refused schema was kept")
+	}
+	if err := c.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	for version := 1; version <= 2; version++ {
+		if err := c.SetSchema(schema()); err != nil {
+			t.Fatal(err)
+		}
+		if got := c.GetSchema().Version; got != version {
+			t.Errorf("the schema has version %d, want %d", got, version)
+		}
+	}
+
+	for name, payload := range map[string]map[string]interface{}{
+		"no label":     {"color": "red"},
+		"string label": {"label": "5"},
+		"fraction":     {"label": 1.5},
+	} {
+		if _, err := DB.AddPoint("schema", &PointItem{Vector: []float64{3, 3}, Payload: payload}); err == nil {
+			t.Errorf("a point with %s was added", name)
+		}
+	}
+	addTestPoint(t, "schema", PointItem{Id: "c", Vector: []float64{3, 3},
+		Payload: map[string]interface{}{"label": 7.0}})
+
+	// The literal of a filter is converted to the declared type
+	var out bytes.Buffer
+	filter := []Filter.Filter{{Field: "label", Op: Filter.Equal, Value: "5"}}
+	if n, err := DB.Export("schema", &filter, &out); err != nil || n != 1 {
+		t.Errorf("the filter on \"5\" exported %d points: %v", n, err)
+	}
+
+	got := reloadTestCollection(t, "schema").GetSchema()
+	if got == nil || got.Version != 2 || !got.Fields["label"].Required {
+		t.Errorf("the reloaded schema is %+v", got)
+	}
+}
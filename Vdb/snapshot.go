@@ -0,0 +1,381 @@
+package Vdb
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/Boot"
+	"VreeDB/Collection"
+	"VreeDB/Logger"
+	"VreeDB/Utils"
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// snapshotSuffix is the file extension of the snapshot archives
+const snapshotSuffix = ".tar.gz"
+
+// restoreLock serializes the restores into a Collection, it is forgotten when nobody waits for it
+type restoreLock struct {
+	sync.Mutex
+	waiting int
+}
+
+// CreateSnapshot writes a point-in-time snapshot of a Collection into the snapshot directory
+func (v *Vdb) CreateSnapshot(collectionName string) (*Collection.SnapshotManifest, error) {
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return nil, fmt.Errorf("Collection with name %s does not exist", collectionName)
+	}
+	err := os.MkdirAll(*ArgsParser.Ap.SnapshotDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots are named after the collection and the time, snapshots within the same second get a counter
+	name := collectionName + "_" + time.Now().Format("20060102150405")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(snapshotPath(name)); os.IsNotExist(err) {
+			break
+		}
+		name = collectionName + "_" + time.Now().Format("20060102150405") + "_" + strconv.Itoa(i)
+	}
+
+	// The archive is written next to its final name, so a listed snapshot is always complete
+	file, err := os.Create(snapshotPath(name) + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := c.WriteSnapshot(file, name)
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(snapshotPath(name)+".tmp", snapshotPath(name))
+	}
+	if err != nil {
+		os.Remove(snapshotPath(name) + ".tmp")
+		return nil, err
+	}
+	Logger.Log.Log("Snapshot " + name + " of Collection " + collectionName + " created")
+	return manifest, nil
+}
+
+// ListSnapshots returns the manifests of all snapshots sorted by their creation time, with a collection name only the
+// snapshots of that Collection are returned
+func (v *Vdb) ListSnapshots(collectionName string) ([]*Collection.SnapshotManifest, error) {
+	entries, err := os.ReadDir(*ArgsParser.Ap.SnapshotDir)
+	if os.IsNotExist(err) {
+		return []*Collection.SnapshotManifest{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	manifests := []*Collection.SnapshotManifest{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), snapshotSuffix) {
+			continue
+		}
+		manifest, err := readSnapshotManifest(*ArgsParser.Ap.SnapshotDir + entry.Name())
+		if err != nil {
+			Logger.Log.Log("Error reading snapshot " + entry.Name() + ": " + err.Error())
+			continue
+		}
+		if collectionName == "" || manifest.Collection == collectionName {
+			manifests = append(manifests, manifest)
+		}
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].Created.Before(manifests[j].Created)
+	})
+	return manifests, nil
+}
+
+// RestoreSnapshot restores a snapshot into the Collection with the given name, an empty name restores it into the
+// Collection it was taken from. Restoring into the own Collection replaces it and keeps its aliases, other Collections
+// must not exist. The snapshot is loaded under a temporary name first, the Collection is only replaced if it loads.
+func (v *Vdb) RestoreSnapshot(snapshot string, collectionName string) (*Collection.SnapshotManifest, error) {
+	if !validSnapshotName(snapshot) {
+		return nil, fmt.Errorf("invalid snapshot name %q", snapshot)
+	}
+	file, err := os.Open(snapshotPath(snapshot))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("Snapshot with name %s does not exist", snapshot)
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	manifest, err := nextSnapshotManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	if collectionName == "" {
+		collectionName = manifest.Collection
+	}
+	if err := validCollectionName(collectionName); err != nil {
+		return nil, err
+	}
+	// Restores into the same Collection share the temporary names, they run one after the other
+	unlock := v.lockRestore(collectionName)
+	defer unlock()
+	old, exists := v.GetCollection(collectionName)
+	if exists && collectionName != manifest.Collection {
+		return nil, fmt.Errorf("Collection with name %s allready exists", collectionName)
+	}
+	if !exists && v.IsAlias(collectionName) {
+		return nil, fmt.Errorf("Alias with name %s allready exists", collectionName)
+	}
+
+	// Collections from before the names were reserved must not lose their files
+	temp := collectionName + Collection.RestoreSuffix
+	replaced := collectionName + Collection.ReplacedSuffix
+	for _, name := range []string{temp, replaced} {
+		if _, ok := v.GetCollection(name); ok {
+			return nil, fmt.Errorf("Collection with name %s allready exists", name)
+		}
+	}
+
+	// The files are extracted into a staging directory under the temporary name of the restored Collection
+	staging := *ArgsParser.Ap.FileStore + "restore/" + collectionName + "/"
+	err = os.RemoveAll(staging)
+	if err == nil {
+		err = os.MkdirAll(staging, 0755)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		os.RemoveAll(staging)
+		// The parent is only removed if no other restore is running
+		os.Remove(*ArgsParser.Ap.FileStore + "restore/")
+	}()
+
+	config, files, err := extractSnapshot(tr, manifest, temp, staging)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", snapshot, err)
+	}
+
+	// Load the restored Collection under its temporary name - the files of an earlier failed restore are replaced
+	v.deleteCollectionFiles(temp, config.Segments)
+	for _, f := range files {
+		err = os.Rename(staging+f, *ArgsParser.Ap.FileStore+f)
+		if err != nil {
+			v.deleteCollectionFiles(temp, config.Segments)
+			return nil, err
+		}
+	}
+	c, err := Boot.NewBootUp().RestoreCollection(config)
+	if err != nil {
+		v.deleteCollectionFiles(temp, config.Segments)
+		os.Remove(*ArgsParser.Ap.FileStore + temp + "_classifiers.gob")
+		return nil, fmt.Errorf("snapshot %s: %w", snapshot, err)
+	}
+
+	err = v.swapRestoredCollection(collectionName, old, c)
+	if err != nil {
+		v.deleteRestoredCollection(c)
+		return nil, err
+	}
+	if exists {
+		v.deleteRestoredCollection(old)
+	}
+	Logger.Log.Log("Snapshot " + snapshot + " restored into Collection " + collectionName)
+	return manifest, nil
+}
+
+// swapRestoredCollection gives the restored Collection the name of the Collection old, which is nil if there is none.
+// The replaced Collection is moved aside and only deleted by the caller once the restored one has its name.
+func (v *Vdb) swapRestoredCollection(collectionName string, old, c *Collection.Collection) error {
+	v.collectionsMut.Lock()
+	defer v.collectionsMut.Unlock()
+	// The Collection may have been created or deleted while the snapshot was loaded
+	if current, ok := v.Collections[collectionName]; current != old || (ok && old == nil) {
+		return fmt.Errorf("Collection with name %s was changed while the snapshot was restored", collectionName)
+	}
+	if old != nil {
+		err := old.Rename(collectionName + Collection.ReplacedSuffix)
+		if err != nil {
+			return err
+		}
+	}
+	err := c.Rename(collectionName)
+	if err != nil {
+		if old != nil {
+			if rerr := old.Rename(collectionName); rerr != nil {
+				Logger.Log.Log("Error renaming Collection " + old.Name + " back: " + rerr.Error())
+			}
+		}
+		return err
+	}
+	v.Collections[collectionName] = c
+	return nil
+}
+
+// lockRestore locks the restores into the Collection with the given name and returns the unlock function
+func (v *Vdb) lockRestore(collectionName string) func() {
+	v.restoreMut.Lock()
+	if v.restores == nil {
+		v.restores = make(map[string]*restoreLock)
+	}
+	l, ok := v.restores[collectionName]
+	if !ok {
+		l = &restoreLock{}
+		v.restores[collectionName] = l
+	}
+	l.waiting++
+	v.restoreMut.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		v.restoreMut.Lock()
+		defer v.restoreMut.Unlock()
+		// The last restore forgets the lock
+		l.waiting--
+		if l.waiting == 0 {
+			delete(v.restores, collectionName)
+		}
+	}
+}
+
+// deleteRestoredCollection deletes the files and the classifiers of a Collection that is not part of the Vdb
+func (v *Vdb) deleteRestoredCollection(c *Collection.Collection) {
+	v.deleteCollectionFiles(c.Name, c.SegmentKeys())
+	err := os.Remove(*ArgsParser.Ap.FileStore + c.Name + "_classifiers.gob")
+	if err != nil && !os.IsNotExist(err) {
+		Logger.Log.Log("Error deleting classifiers: " + err.Error())
+	}
+}
+
+// DeleteSnapshot deletes a snapshot from the snapshot directory
+func (v *Vdb) DeleteSnapshot(snapshot string) error {
+	if !validSnapshotName(snapshot) {
+		return fmt.Errorf("invalid snapshot name %q", snapshot)
+	}
+	err := os.Remove(snapshotPath(snapshot))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("Snapshot with name %s does not exist", snapshot)
+	} else if err != nil {
+		return err
+	}
+	Logger.Log.Log("Snapshot " + snapshot + " deleted")
+	return nil
+}
+
+// extractSnapshot extracts the files of a snapshot into the staging directory under the names of the target
+// Collection and returns its config and the names of the extracted files
+func extractSnapshot(tr *tar.Reader, manifest *Collection.SnapshotManifest, collectionName string,
+	staging string) (Utils.CollectionConfig, []string, error) {
+	var config Utils.CollectionConfig
+	listed := make(map[string]bool)
+	for _, f := range manifest.Files {
+		listed[f] = true
+	}
+	// The segments of the collection are renamed with it - segmentKey(name, 0) is the name of the collection
+	rename := func(key string) string {
+		return collectionName + strings.TrimPrefix(key, manifest.Collection)
+	}
+
+	var files []string
+	seenConfig := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return config, nil, err
+		}
+		name := header.Name
+		if !listed[name] || !validSnapshotName(name) || !strings.HasPrefix(name, manifest.Collection) {
+			return config, nil, fmt.Errorf("unexpected file %s", name)
+		}
+		if name == manifest.Collection+".json" {
+			err = json.NewDecoder(tr).Decode(&config)
+			if err != nil {
+				return config, nil, fmt.Errorf("config: %w", err)
+			}
+			seenConfig = true
+			continue
+		}
+		out, err := os.Create(staging + rename(name))
+		if err != nil {
+			return config, nil, err
+		}
+		_, err = io.Copy(out, tr)
+		if err == nil {
+			err = out.Sync()
+		}
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return config, nil, err
+		}
+		files = append(files, rename(name))
+	}
+	if !seenConfig {
+		return config, nil, fmt.Errorf("config is missing")
+	}
+
+	config.Name = collectionName
+	for i, key := range config.Segments {
+		config.Segments[i] = rename(key)
+	}
+	return config, files, nil
+}
+
+// readSnapshotManifest reads the manifest of a snapshot archive
+func readSnapshotManifest(path string) (*Collection.SnapshotManifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	return nextSnapshotManifest(tar.NewReader(gz))
+}
+
+// nextSnapshotManifest reads the manifest from the first entry of a snapshot archive
+func nextSnapshotManifest(tr *tar.Reader) (*Collection.SnapshotManifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, err
+	}
+	if header.Name != Collection.ManifestFile {
+		return nil, fmt.Errorf("snapshot does not start with a manifest")
+	}
+	manifest := &Collection.SnapshotManifest{}
+	err = json.NewDecoder(tr).Decode(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// snapshotPath returns the path of the snapshot archive with the given name
+func snapshotPath(name string) string {
+	return *ArgsParser.Ap.SnapshotDir + name + snapshotSuffix
+}
+
+// validSnapshotName checks that a name of a snapshot, a collection or an archived file stays within its directory
+func validSnapshotName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "/\\") && !strings.Contains(name, "..")
+}
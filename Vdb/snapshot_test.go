@@ -0,0 +1,179 @@
+package Vdb
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/Utils"
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// newTestSnapshot snapshots the Collection, the snapshot is deleted after the test
+func newTestSnapshot(t *testing.T, collectionName string) string {
+	t.Helper()
+	manifest, err := DB.CreateSnapshot(collectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		DB.DeleteSnapshot(manifest.Name)
+	})
+	return manifest.Name
+}
+
+// checkNoRestoreFiles fails the test if a file of a temporary Collection of a restore is left in the FileStore
+func checkNoRestoreFiles(t *testing.T) {
+	t.Helper()
+	entries, err := os.ReadDir(*ArgsParser.Ap.FileStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), "_restore") || strings.Contains(entry.Name(), "_replaced") {
+			t.Errorf("file %s is left behind", entry.Name())
+		}
+	}
+}
+
+func TestRestoreSnapshotReplacesCollectionAndKeepsAliases(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "snaprestore", VectorDimension: 2})
+	addTestPoint(t, "snaprestore", PointItem{Id: "a", Vector: []float64{1, 2}, Payload: map[string]interface{}{"n": 1.0}})
+	if err := DB.CreateAlias("snaprestorealias", "snaprestore"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		DB.DeleteAlias("snaprestorealias")
+	})
+	snapshot := newTestSnapshot(t, "snaprestore")
+	addTestPoint(t, "snaprestore", PointItem{Id: "b", Vector: []float64{3, 4}})
+
+	manifest, err := DB.RestoreSnapshot(snapshot, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Points != 1 {
+		t.Errorf("manifest has %d points", manifest.Points)
+	}
+	c := DB.Collections[DB.ResolveAlias("snaprestorealias")]
+	if c == nil || c.Name != "snaprestore" {
+		t.Fatal("the alias lost its Collection")
+	}
+	points, err := readPointItems(c, []string{"a", "b"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 || points[0].Id != "a" || points[0].Payload["n"] != 1.0 {
+		t.Fatalf("restored points %v", points)
+	}
+	checkNoRestoreFiles(t)
+
+	// The restored Collection is written to its own files
+	addTestPoint(t, "snaprestore", PointItem{Id: "c", Vector: []float64{5, 6}})
+	if _, ok := DB.Collections["snaprestore"].Segments[0].Space["c"]; !ok {
+		t.Error("a new point is not in the restored Collection")
+	}
+}
+
+func TestFailedRestoreKeepsCollection(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "snapbroken", VectorDimension: 2})
+	addTestPoint(t, "snapbroken", PointItem{Id: "a", Vector: []float64{1, 2}})
+	snapshot := newTestSnapshot(t, "snapbroken")
+	corruptSnapshotData(t, snapshot)
+	addTestPoint(t, "snapbroken", PointItem{Id: "b", Vector: []float64{3, 4}})
+
+	if _, err := DB.RestoreSnapshot(snapshot, ""); err == nil {
+		t.Fatal("a snapshot with a broken data file was restored")
+	}
+	c, ok := DB.Collections["snapbroken"]
+	if !ok {
+		t.Fatal("the Collection is lost")
+	}
+	points, err := readPointItems(c, []string{"a", "b"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("the Collection has %d points", len(points))
+	}
+	checkNoRestoreFiles(t)
+}
+
+// corruptSnapshotData rewrites the snapshot with a data file that has no valid header
+func corruptSnapshotData(t *testing.T, snapshot string) {
+	t.Helper()
+	in, err := os.Open(snapshotPath(snapshot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.Create(snapshotPath(snapshot) + ".tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gzw)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasSuffix(header.Name, ".bin") && !strings.HasSuffix(header.Name, "_meta.bin") {
+			for i := range data {
+				data[i] = 0xff
+			}
+		}
+		if err = tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gzw.Close()
+	out.Close()
+	if err = os.Rename(snapshotPath(snapshot)+".tmp", snapshotPath(snapshot)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentRestoresOfTheSameCollection(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "snapconcurrent", VectorDimension: 2})
+	addTestPoint(t, "snapconcurrent", PointItem{Id: "a", Vector: []float64{1, 2}})
+	snapshot := newTestSnapshot(t, "snapconcurrent")
+
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := DB.RestoreSnapshot(snapshot, "")
+			errs <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	c, ok := DB.GetCollection("snapconcurrent")
+	if !ok || len(*c.Space) != 1 {
+		t.Fatal("the restored Collection is lost")
+	}
+	if got := *(*c.Space)["a"].GetData(); got[1] != 2 {
+		t.Errorf("the restored point reads %v", got)
+	}
+	checkNoRestoreFiles(t)
+}
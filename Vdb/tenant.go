@@ -0,0 +1,402 @@
+package Vdb
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/Collection"
+	"VreeDB/FileMapper"
+	"VreeDB/Filter"
+	"VreeDB/Logger"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	// tenantFile is the file of the tenants in the file store
+	tenantFile = "__tenants"
+	// tenantUsageAge is the time the usage of a tenant is cached for the quota checks
+	tenantUsageAge = 5 * time.Second
+)
+
+// Access of the ApiKeys of a tenant to a Collection
+const (
+	NoAccess        = iota // The Collection belongs to another tenant or to no tenant
+	PartitionAccess        // The Collection is shared, only the points of the tenant are visible
+	FullAccess             // The Collection belongs to the tenant
+)
+
+// Tenant is a customer whose ApiKeys only see the Collections of the tenant and its partition of the shared
+// Collections. A quota of 0 is unlimited.
+type Tenant struct {
+	Name      string `json:"name"`
+	MaxPoints int    `json:"max_points,omitempty"`
+	MaxBytes  int64  `json:"max_bytes,omitempty"`
+}
+
+// TenantUsage is a Tenant with the points and the disk bytes it uses. The bytes of a shared Collection are split by
+// the points of the tenants.
+type TenantUsage struct {
+	Tenant
+	Points      int      `json:"points"`
+	Bytes       int64    `json:"bytes"`
+	Collections []string `json:"collections"`
+}
+
+// tenantUsage is the cached usage of a tenant, the points that are added after the count are added to it. The
+// pending points are reserved by inserts that are still running.
+type tenantUsage struct {
+	points        int
+	bytes         int64
+	pendingPoints int
+	pendingBytes  int64
+	counted       time.Time
+}
+
+// LoadTenants reads the tenants from the file store
+func (v *Vdb) LoadTenants() error {
+	v.tenantMut.Lock()
+	defer v.tenantMut.Unlock()
+	data, err := os.ReadFile(*ArgsParser.Ap.FileStore + tenantFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	tenants := make(map[string]*Tenant)
+	err = json.Unmarshal(data, &tenants)
+	if err != nil {
+		return err
+	}
+	v.Tenants = tenants
+	return nil
+}
+
+// SetTenant creates a tenant or sets the quotas of an existing one
+func (v *Vdb) SetTenant(t Tenant) error {
+	if !validSnapshotName(t.Name) {
+		return fmt.Errorf("invalid tenant name %q", t.Name)
+	}
+	if t.MaxPoints < 0 || t.MaxBytes < 0 {
+		return fmt.Errorf("quotas must not be negative")
+	}
+	v.tenantMut.Lock()
+	defer v.tenantMut.Unlock()
+	old := v.Tenants[t.Name]
+	v.Tenants[t.Name] = &t
+	if err := v.writeTenants(); err != nil {
+		if old == nil {
+			delete(v.Tenants, t.Name)
+		} else {
+			v.Tenants[t.Name] = old
+		}
+		return err
+	}
+	Logger.Log.Log(fmt.Sprintf("Tenant %s set with %d max points and %d max bytes", t.Name, t.MaxPoints, t.MaxBytes))
+	return nil
+}
+
+// DeleteTenant deletes a tenant, a tenant that still owns Collections can not be deleted
+func (v *Vdb) DeleteTenant(name string) error {
+	for collection, c := range v.GetCollections() {
+		if c.Tenant == name {
+			return fmt.Errorf("Tenant %s still owns Collection %s", name, collection)
+		}
+	}
+	v.tenantMut.Lock()
+	defer v.tenantMut.Unlock()
+	t, ok := v.Tenants[name]
+	if !ok {
+		return fmt.Errorf("Tenant with name %s does not exist", name)
+	}
+	delete(v.Tenants, name)
+	if err := v.writeTenants(); err != nil {
+		v.Tenants[name] = t
+		return err
+	}
+	delete(v.tenantUsage, name)
+	Logger.Log.Log("Tenant " + name + " deleted")
+	return nil
+}
+
+// TenantExists returns true if the tenant exists
+func (v *Vdb) TenantExists(name string) bool {
+	v.tenantMut.RLock()
+	defer v.tenantMut.RUnlock()
+	_, ok := v.Tenants[name]
+	return ok
+}
+
+// ListTenants returns all tenants with their usage sorted by their name, an empty name lists all tenants
+func (v *Vdb) ListTenants(name string) []TenantUsage {
+	v.tenantMut.RLock()
+	tenants := make([]Tenant, 0, len(v.Tenants))
+	for _, t := range v.Tenants {
+		if name == "" || t.Name == name {
+			tenants = append(tenants, *t)
+		}
+	}
+	v.tenantMut.RUnlock()
+
+	usages := make([]TenantUsage, 0, len(tenants))
+	for _, t := range tenants {
+		points, bytes := v.countUsage(t.Name)
+		usages = append(usages, TenantUsage{Tenant: t, Points: points, Bytes: bytes,
+			Collections: v.ListCollections(t.Name)})
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Name < usages[j].Name
+	})
+	return usages
+}
+
+// TenantAccess returns the access of the ApiKeys of a tenant to a Collection. Keys without a tenant and unknown
+// Collections get full access, the Collection is checked by the caller.
+func (v *Vdb) TenantAccess(tenant string, collectionName string) int {
+	c, ok := v.GetCollection(collectionName)
+	switch {
+	case tenant == "" || !ok || c.Tenant == tenant:
+		return FullAccess
+	case c.TenantField != "":
+		return PartitionAccess
+	}
+	return NoAccess
+}
+
+// TenantFilter returns the filter of a search of a tenant, in a shared Collection only the points of the tenant pass
+func (v *Vdb) TenantFilter(tenant string, collectionName string, filter *[]Filter.Filter) *[]Filter.Filter {
+	c, ok := v.GetCollection(collectionName)
+	if !ok || v.TenantAccess(tenant, collectionName) != PartitionAccess {
+		return filter
+	}
+	filters := []Filter.Filter{{Field: c.TenantField, Op: Filter.Equal, Value: tenant}}
+	if filter != nil {
+		filters = append(filters, *filter...)
+	}
+	return &filters
+}
+
+// PartitionPoint puts a point of a tenant into the partition of the tenant of a shared Collection, a point of another
+// tenant can not be replaced
+func (v *Vdb) PartitionPoint(tenant string, collectionName string, p *PointItem) error {
+	c, ok := v.GetCollection(collectionName)
+	if !ok || v.TenantAccess(tenant, collectionName) != PartitionAccess {
+		return nil
+	}
+	field := c.TenantField
+	if p.Payload == nil {
+		p.Payload = make(map[string]interface{})
+	}
+	if value, ok := p.Payload[field]; !ok {
+		p.Payload[field] = tenant
+	} else if value != tenant {
+		return fmt.Errorf("payload field %s must be the tenant %s", field, tenant)
+	}
+	if p.Id != "" {
+		if owner, ok := v.pointTenant(collectionName, p.Id); ok && owner != tenant {
+			return fmt.Errorf("Point with ID %s belongs to another tenant", p.Id)
+		}
+	}
+	return nil
+}
+
+// CheckPartition checks that a point of a shared Collection belongs to the tenant, the points of other tenants do
+// not exist for it
+func (v *Vdb) CheckPartition(tenant string, collectionName string, id string) error {
+	if v.TenantAccess(tenant, collectionName) != PartitionAccess {
+		return nil
+	}
+	if owner, ok := v.pointTenant(collectionName, id); !ok || owner != tenant {
+		return fmt.Errorf("Vector with ID %s does not exist", id)
+	}
+	return nil
+}
+
+// pointTenant returns the tenant of a point of a shared Collection
+func (v *Vdb) pointTenant(collectionName string, id string) (string, bool) {
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return "", false
+	}
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	vector, ok := (*c.Space)[id]
+	if !ok {
+		return "", false
+	}
+	payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
+	if err != nil {
+		return "", false
+	}
+	tenant, ok := (*payload)[c.TenantField].(string)
+	return tenant, ok
+}
+
+// reserveQuota checks that the tenant of a new point stays within its quotas and reserves the point and its bytes
+// until the returned release is called with the result of the insert. The point belongs to the tenant of the
+// Collection or, in a shared Collection, to the tenant of its payload.
+func (v *Vdb) reserveQuota(c *Collection.Collection, p *PointItem) (func(inserted bool), error) {
+	tenant := c.Tenant
+	if c.TenantField != "" {
+		t, ok := p.Payload[c.TenantField].(string)
+		if !ok || t == "" {
+			return nil, fmt.Errorf("payload field %s with the tenant is required", c.TenantField)
+		}
+		tenant = t
+	}
+	noQuota := func(bool) {}
+	if tenant == "" {
+		return noQuota, nil
+	}
+
+	if !v.hasQuota(tenant) {
+		return noQuota, nil
+	}
+	usage := v.lockUsage(tenant)
+	defer v.tenantMut.Unlock()
+	t, ok := v.Tenants[tenant]
+	if !ok || t.MaxPoints == 0 && t.MaxBytes == 0 {
+		return noQuota, nil
+	}
+	// A point that replaces another one adds nothing
+	c.Mut.RLock()
+	_, replaces := (*c.Space)[p.Id]
+	c.Mut.RUnlock()
+	if replaces {
+		return noQuota, nil
+	}
+	if t.MaxPoints > 0 && usage.points+usage.pendingPoints >= t.MaxPoints {
+		return nil, fmt.Errorf("tenant %s reached its quota of %d points", tenant, t.MaxPoints)
+	}
+	size := pointBytes(p)
+	if t.MaxBytes > 0 && usage.bytes+usage.pendingBytes+size > t.MaxBytes {
+		return nil, fmt.Errorf("tenant %s reached its quota of %d bytes", tenant, t.MaxBytes)
+	}
+	usage.pendingPoints++
+	usage.pendingBytes += size
+
+	return func(inserted bool) {
+		v.tenantMut.Lock()
+		defer v.tenantMut.Unlock()
+		current, ok := v.tenantUsage[tenant]
+		if !ok {
+			// The tenant was deleted
+			return
+		}
+		current.pendingPoints--
+		current.pendingBytes -= size
+		// A usage that was counted while the point was inserted may already hold it, the next count corrects it
+		if inserted && current == usage {
+			current.points++
+			current.bytes += size
+		}
+	}, nil
+}
+
+// hasQuota returns true if the tenant has a quota
+func (v *Vdb) hasQuota(tenant string) bool {
+	v.tenantMut.RLock()
+	defer v.tenantMut.RUnlock()
+	t, ok := v.Tenants[tenant]
+	return ok && (t.MaxPoints > 0 || t.MaxBytes > 0)
+}
+
+// lockUsage locks tenantMut and returns the usage of the tenant, a usage that is too old is counted again. The usage
+// is counted before the lock is taken, counting reads the payloads of the shared Collections.
+func (v *Vdb) lockUsage(tenant string) *tenantUsage {
+	for {
+		v.tenantMut.RLock()
+		usage, ok := v.tenantUsage[tenant]
+		fresh := ok && time.Since(usage.counted) <= tenantUsageAge
+		v.tenantMut.RUnlock()
+		var counted *tenantUsage
+		if !fresh {
+			start := time.Now()
+			points, bytes := v.countUsage(tenant)
+			counted = &tenantUsage{points: points, bytes: bytes, counted: start}
+		}
+
+		v.tenantMut.Lock()
+		usage, ok = v.tenantUsage[tenant]
+		switch {
+		case counted != nil && (!ok || usage.counted.Before(counted.counted)):
+			// The points that are inserted right now are not counted yet
+			if ok {
+				counted.pendingPoints, counted.pendingBytes = usage.pendingPoints, usage.pendingBytes
+			}
+			v.tenantUsage[tenant] = counted
+			return counted
+		case ok:
+			return usage
+		}
+		// The usage was dropped while it was fresh, count it again
+		v.tenantMut.Unlock()
+	}
+}
+
+// pointBytes returns an estimate of the disk bytes of a point - its vectors and its payload
+func pointBytes(p *PointItem) int64 {
+	floats := len(p.Vector)
+	for _, data := range p.Vectors {
+		floats += len(data)
+	}
+	for _, tokens := range p.MultiVectors {
+		for _, data := range tokens {
+			floats += len(data)
+		}
+	}
+	size := int64(floats) * 8
+	for _, sparse := range p.SparseVectors {
+		size += 4 + int64(len(sparse.Indices))*12
+	}
+	for _, data := range p.BinaryVectors {
+		size += int64((len(data)+63)/64) * 8
+	}
+	if payload, err := json.Marshal(p.Payload); err == nil {
+		size += int64(len(payload))
+	}
+	return size
+}
+
+// countUsage counts the points and the disk bytes of a tenant
+func (v *Vdb) countUsage(tenant string) (int, int64) {
+	points := 0
+	var bytes int64
+	for name, c := range v.GetCollections() {
+		switch {
+		case c.Tenant == tenant:
+			c.Mut.RLock()
+			points += len(*c.Space)
+			c.Mut.RUnlock()
+			bytes += c.DiskBytes()
+		case c.TenantField != "":
+			ids, err := v.Match(name, &[]Filter.Filter{{Field: c.TenantField, Op: Filter.Equal, Value: tenant}})
+			if err != nil || len(ids) == 0 {
+				continue
+			}
+			points += len(ids)
+			c.Mut.RLock()
+			total := len(*c.Space)
+			c.Mut.RUnlock()
+			if total > 0 {
+				bytes += c.DiskBytes() * int64(len(ids)) / int64(total)
+			}
+		}
+	}
+	return points, bytes
+}
+
+// writeTenants writes the tenants to the file store, the file is replaced at once - the caller holds the lock
+func (v *Vdb) writeTenants() error {
+	data, err := json.Marshal(v.Tenants)
+	if err != nil {
+		return err
+	}
+	path := *ArgsParser.Ap.FileStore + tenantFile
+	err = os.WriteFile(path+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,71 @@
+package Vdb
+
+import (
+	"VreeDB/Utils"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestTenant creates a tenant with the quotas and a Collection of it, both are deleted after the test
+func newTestTenant(t *testing.T, tenant Tenant, collectionName string) {
+	t.Helper()
+	if err := DB.SetTenant(tenant); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		DB.DeleteTenant(tenant.Name)
+	})
+	newTestCollection(t, Utils.CollectionConfig{Name: collectionName, VectorDimension: 2, Tenant: tenant.Name})
+}
+
+func TestQuotaCountsTheBytesOfInsertedPoints(t *testing.T) {
+	p := PointItem{Vector: []float64{1, 2}, Payload: map[string]interface{}{"text": strings.Repeat("x", 100)}}
+	size := pointBytes(&p)
+	newTestTenant(t, Tenant{Name: "quotabytes", MaxBytes: 1 << 40}, "quotabytes")
+	_, used := DB.countUsage("quotabytes")
+	if err := DB.SetTenant(Tenant{Name: "quotabytes", MaxBytes: used + 2*size}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		p.Id = strconv.Itoa(i)
+		if _, err := DB.AddPoint("quotabytes", &p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Id = "2"
+	if _, err := DB.AddPoint("quotabytes", &p); err == nil || !strings.Contains(err.Error(), "bytes") {
+		t.Fatalf("the third point was not refused: %v", err)
+	}
+}
+
+func TestFailedInsertReleasesQuota(t *testing.T) {
+	newTestTenant(t, Tenant{Name: "quotarelease", MaxPoints: 1}, "quotarelease")
+	if _, err := DB.AddPoint("quotarelease", &PointItem{Id: "a", Vector: []float64{1, 2, 3}}); err == nil {
+		t.Fatal("a point with the wrong dimension was added")
+	}
+	if _, err := DB.AddPoint("quotarelease", &PointItem{Id: "a", Vector: []float64{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DB.AddPoint("quotarelease", &PointItem{Id: "b", Vector: []float64{1, 2}}); err == nil {
+		t.Fatal("the quota was exceeded")
+	}
+}
+
+func TestConcurrentInsertsStayWithinQuota(t *testing.T) {
+	newTestTenant(t, Tenant{Name: "quotarace", MaxPoints: 10}, "quotarace")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			DB.AddPoint("quotarace", &PointItem{Id: strconv.Itoa(i), Vector: []float64{float64(i), 1}})
+		}(i)
+	}
+	wg.Wait()
+	if n := len(*DB.Collections["quotarace"].Space); n != 10 {
+		t.Fatalf("the tenant has %d points, its quota is 10", n)
+	}
+}
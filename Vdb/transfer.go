@@ -0,0 +1,192 @@
+package Vdb
+
+import (
+	"VreeDB/Collection"
+	"VreeDB/FileMapper"
+	"VreeDB/Filter"
+	"VreeDB/Utils"
+	"VreeDB/Vector"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// exportChunk is the number of points that are read under one read lock of the Collection during an export
+const exportChunk = 256
+
+// Export streams the points of a Collection that pass the filter to w, one PointItem as JSON per line. The points are
+// written in the order of their ids and the Collection is only locked while a chunk of points is read, points that
+// are deleted during the export are left out. It returns the number of exported points.
+func (v *Vdb) Export(collectionName string, filter *[]Filter.Filter, w io.Writer) (int, error) {
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return 0, fmt.Errorf("Collection with name %s does not exist", collectionName)
+	}
+	if err := v.CheckFilter(collectionName, filter); err != nil {
+		return 0, err
+	}
+	v.prepareFilter(collectionName, filter)
+
+	// The ids of the points at the start of the export
+	c.Mut.RLock()
+	ids := make([]string, 0, len(*c.Space))
+	for id := range *c.Space {
+		ids = append(ids, id)
+	}
+	c.Mut.RUnlock()
+	sort.Strings(ids)
+
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	exported := 0
+	for start := 0; start < len(ids); start += exportChunk {
+		end := start + exportChunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+		points, err := readPointItems(c, ids[start:end], filter)
+		if err != nil {
+			return exported, err
+		}
+		for _, p := range points {
+			if err = encoder.Encode(p); err != nil {
+				return exported, err
+			}
+			exported++
+		}
+		if err = bw.Flush(); err != nil {
+			return exported, err
+		}
+	}
+	return exported, nil
+}
+
+// readPointItems reads the points with the given ids and all their fields from the files of the Collection
+func readPointItems(c *Collection.Collection, ids []string, filter *[]Filter.Filter) ([]*PointItem, error) {
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	points := make([]*PointItem, 0, len(ids))
+	for _, id := range ids {
+		vector, ok := (*c.Space)[id]
+		if !ok || vector.IsExpired() || !validateFilters(vector, filter) {
+			continue
+		}
+		p, err := readPointItem(c, vector)
+		if err != nil {
+			return nil, fmt.Errorf("point %s: %w", id, err)
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// readPointItem reads a point and all its fields from the files of the Collection - the caller holds the read lock
+func readPointItem(c *Collection.Collection, vector *Vector.Vector) (*PointItem, error) {
+	p := &PointItem{Id: vector.Id, ExpiresAt: vector.ExpiresAt}
+	if c.VectorDimension > 0 {
+		p.Vector = *vector.GetData()
+	}
+	payload, err := FileMapper.Mapper.ReadPayload(vector.PayloadStart, vector.Collection)
+	if err != nil {
+		return nil, err
+	}
+	p.Payload = *payload
+
+	for name, start := range vector.SparseStart {
+		indices, values, err := FileMapper.Mapper.ReadSparseVector(start, vector.Collection)
+		if err != nil {
+			return nil, err
+		}
+		if p.SparseVectors == nil {
+			p.SparseVectors = make(map[string]*Vector.SparseVector)
+		}
+		p.SparseVectors[name] = &Vector.SparseVector{Indices: indices, Values: values}
+	}
+	for name, start := range vector.VectorStart {
+		field, ok := c.VectorFields[name]
+		if !ok {
+			continue
+		}
+		if p.Vectors == nil {
+			p.Vectors = make(map[string][]float64)
+		}
+		p.Vectors[name] = *FileMapper.Mapper.ReadVector(start, field.VectorDimension, vector.Collection)
+	}
+	for name, starts := range vector.MultiVectorStart {
+		field, ok := c.MultiFields[name]
+		if !ok {
+			continue
+		}
+		if p.MultiVectors == nil {
+			p.MultiVectors = make(map[string][][]float64)
+		}
+		for _, start := range starts {
+			p.MultiVectors[name] = append(p.MultiVectors[name],
+				*FileMapper.Mapper.ReadVector(start, field.VectorDimension, vector.Collection))
+		}
+	}
+	// Binary fields that rescore keep the float vector, the others only have their bits
+	for name, start := range vector.BinaryStart {
+		field, ok := c.BinaryFields[name]
+		if !ok {
+			continue
+		}
+		if p.BinaryVectors == nil {
+			p.BinaryVectors = make(map[string][]float64)
+		}
+		if rescoreStart, ok := vector.RescoreStart[name]; ok {
+			p.BinaryVectors[name] = *FileMapper.Mapper.ReadVector(rescoreStart, field.VectorDimension, vector.Collection)
+			continue
+		}
+		bits, err := FileMapper.Mapper.ReadBinaryVector(start, (field.VectorDimension+63)/64, vector.Collection)
+		if err != nil {
+			return nil, err
+		}
+		p.BinaryVectors[name] = Utils.Utils.UnpackBits(bits, field.VectorDimension)
+	}
+	return p, nil
+}
+
+// Import reads points as JSON lines from r and inserts them into a Collection. The first skip lines are only counted,
+// so an interrupted import can be resumed at the line it stopped. Every line is reported with its number (starting
+// at 1), the id of the point and its error, empty lines are counted but not reported. It returns the number of lines
+// read, an error is only returned if r fails. The points of a tenant are put into its partition of a shared Collection.
+func (v *Vdb) Import(collectionName string, tenant string, r io.Reader, skip int,
+	report func(line int, id string, err error)) (int, error) {
+	if _, ok := v.GetCollection(collectionName); !ok {
+		return 0, fmt.Errorf("Collection with name %s does not exist", collectionName)
+	}
+	br := bufio.NewReader(r)
+	lines := 0
+	for {
+		data, err := br.ReadBytes('\n')
+		if len(data) == 0 && err == io.EOF {
+			return lines, nil
+		} else if err != nil && err != io.EOF {
+			return lines, err
+		}
+		lines++
+		if lines <= skip || len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		p := &PointItem{}
+		if err := json.Unmarshal(data, p); err != nil {
+			report(lines, "", err)
+			continue
+		}
+		if err := v.PartitionPoint(tenant, collectionName, p); err != nil {
+			report(lines, p.Id, err)
+			continue
+		}
+		vector, err := v.AddPoint(collectionName, p)
+		if vector == nil {
+			report(lines, p.Id, err)
+			continue
+		}
+		report(lines, vector.Id, err)
+	}
+}
@@ -0,0 +1,85 @@
+package Vdb
+
+import (
+	"VreeDB/Filter"
+	"VreeDB/Utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportStreamsThePointsOfTheFilter(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "export", VectorDimension: 2})
+	for i := 0; i < 300; i++ {
+		addTestPoint(t, "export", PointItem{Id: fmt.Sprintf("%03d", i), Vector: []float64{float64(i), 1},
+			Payload: map[string]interface{}{"even": fmt.Sprint(i%2 == 0)}})
+	}
+
+	var out bytes.Buffer
+	filter := []Filter.Filter{{Field: "even", Op: Filter.Equal, Value: "true"}}
+	n, err := DB.Export("export", &filter, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if n != 150 || len(lines) != 150 {
+		t.Fatalf("exported %d points in %d lines", n, len(lines))
+	}
+	// One point per line in the order of the ids
+	for i, line := range lines {
+		p := PointItem{}
+		if err := json.Unmarshal([]byte(line), &p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Id != fmt.Sprintf("%03d", 2*i) || p.Vector[0] != float64(2*i) || p.Payload["even"] != "true" {
+			t.Fatalf("line %d is %s", i, line)
+		}
+	}
+}
+
+func TestImportReportsEveryLineAndResumes(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "import", VectorDimension: 2})
+	stream := `{"id":"a","vector":[1,2],"payload":{"n":1}}
+{"id":"b","vector":[1]}
+
+not json
+{"id":"c","vector":[3,4]}`
+
+	type result struct {
+		line int
+		id   string
+		ok   bool
+	}
+	var results []result
+	report := func(line int, id string, err error) {
+		results = append(results, result{line, id, err == nil})
+	}
+	lines, err := DB.Import("import", "", strings.NewReader(stream), 0, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []result{{1, "a", true}, {2, "b", false}, {4, "", false}, {5, "c", true}}
+	if lines != 5 || !reflect.DeepEqual(results, want) {
+		t.Fatalf("%d lines with the results %v", lines, results)
+	}
+
+	// A resumed import skips the lines that were read before
+	results = nil
+	if _, err = DB.Import("import", "", strings.NewReader(stream+"\n"+`{"id":"d","vector":[5,6]}`), 5,
+		report); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, []result{{6, "d", true}}) {
+		t.Errorf("the resumed import reports %v", results)
+	}
+	points, err := readPointItems(DB.Collections["import"], []string{"a", "b", "c", "d"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 || points[0].Payload["n"] != 1.0 {
+		t.Errorf("the collection holds %v", points)
+	}
+}
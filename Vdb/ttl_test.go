@@ -0,0 +1,91 @@
+package Vdb
+
+import (
+	"VreeDB/Utils"
+	"VreeDB/Vector"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExpiredPointsAreSkippedAndReaped(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "ttl", VectorDimension: 2, DefaultTTL: 3600})
+	now := time.Now().Unix()
+	// The expired point is the nearest one
+	addTestPoint(t, "ttl", PointItem{Id: "expired", Vector: []float64{0, 0}, ExpiresAt: now - 10,
+		Payload: map[string]interface{}{"id": "expired"}})
+	addTestPoint(t, "ttl", PointItem{Id: "ttl", Vector: []float64{1, 1}, TTLSeconds: 60,
+		Payload: map[string]interface{}{"id": "ttl"}})
+	addTestPoint(t, "ttl", PointItem{Id: "default", Vector: []float64{2, 2},
+		Payload: map[string]interface{}{"id": "default"}})
+	if _, err := DB.AddPoint("ttl", &PointItem{Id: "negative", Vector: []float64{3, 3}, TTLSeconds: -1}); err == nil {
+		t.Error("a negative ttl was accepted")
+	}
+
+	c := DB.Collections["ttl"]
+	for id, want := range map[string]int64{"ttl": now + 60, "default": now + 3600} {
+		if got := (*c.Space)[id].ExpiresAt; got < want || got > want+5 {
+			t.Errorf("point %s expires at %d, want %d", id, got, want)
+		}
+	}
+
+	search := func() []string {
+		t.Helper()
+		results := DB.Search("ttl", Vector.NewVector("", []float64{0, 0}, nil, ""), Utils.NewHeapControl(3), 0, nil)
+		ids := make([]string, len(results))
+		for i, result := range results {
+			ids[i] = (*result.Payload)["id"].(string)
+		}
+		return ids
+	}
+	// The search tree is pruned, it does not have to find all points
+	ids := search()
+	if len(ids) == 0 || ids[0] != "ttl" {
+		t.Errorf("the search finds %v before the reaper ran", ids)
+	}
+	for _, id := range ids {
+		if id == "expired" {
+			t.Error("the search finds the expired point")
+		}
+	}
+
+	DB.Reap()
+	if _, ok := (*c.Space)["expired"]; ok || len(*c.Space) != 2 {
+		t.Fatalf("the reaper left %d points", len(*c.Space))
+	}
+	// The delete is durable
+	c = reloadTestCollection(t, "ttl")
+	if _, ok := (*c.Space)["expired"]; ok || len(*c.Space) != 2 {
+		t.Errorf("%d points are restored", len(*c.Space))
+	}
+	if got := (*c.Space)["ttl"].ExpiresAt; got < now+60 {
+		t.Errorf("the restored point expires at %d", got)
+	}
+}
+
+func TestReapWhileCollectionsChange(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "reaprace", VectorDimension: 2})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			name := "reaprace" + strconv.Itoa(i)
+			if err := DB.AddCollection(name, 2, "euclid"); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := DB.DeleteCollection(name); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			DB.Reap()
+		}
+	}
+}
@@ -5,17 +5,29 @@ import (
 	"VreeDB/FileMapper"
 	"VreeDB/Filter"
 	"VreeDB/Logger"
+	"VreeDB/Node"
+	"VreeDB/Schema"
 	"VreeDB/Utils"
 	"VreeDB/Vector"
 	"fmt"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
 // Vdb is the main struct of the VectorDatabase
 type Vdb struct {
-	Collections map[string]*Collection.Collection
-	Mapper      *FileMapper.FileMapper
+	Collections    map[string]*Collection.Collection
+	collectionsMut sync.RWMutex // Guards the Collections map, not the Collections themselves
+	Mapper         *FileMapper.FileMapper
+	Aliases        map[string]string // Alias names and the Collections they point to
+	aliasMut       sync.RWMutex
+	Tenants        map[string]*Tenant // Tenant names and their quotas
+	tenantUsage    map[string]*tenantUsage
+	tenantMut      sync.RWMutex
+	restores       map[string]*restoreLock // The locks of the Collections a snapshot is restored into
+	restoreMut     sync.Mutex
 }
 
 // DB is the global Vdb
@@ -23,130 +35,447 @@ var DB *Vdb
 
 // init initializes the Vdb
 func init() {
-	DB = &Vdb{Mapper: FileMapper.Mapper}
-	Logger.Log.Log("VectorDatabase initialized")
+	DB = &Vdb{Mapper: FileMapper.Mapper, Collections: make(map[string]*Collection.Collection),
+		Aliases: make(map[string]string), Tenants: make(map[string]*Tenant),
+		tenantUsage: make(map[string]*tenantUsage)}
 }
 
 // InitFileMapper initializes the FileMapper
-func (v *Vdb) InitFileMapper() {
-	// Create a slice out of the collections map
-	var collections []string
-	for _, key := range v.Collections {
-		collections = append(collections, key.Name)
+func (v *Vdb) InitFileMapper() error {
+	// Create a map of the collection names and their dimensions
+	collections := make(map[string]int)
+	for _, key := range v.GetCollections() {
+		collections[key.Name] = key.VectorDimension
+	}
+	return FileMapper.Mapper.Start(collections)
+}
+
+// GetCollection returns the Collection with the given name
+func (v *Vdb) GetCollection(name string) (*Collection.Collection, bool) {
+	v.collectionsMut.RLock()
+	defer v.collectionsMut.RUnlock()
+	c, ok := v.Collections[name]
+	return c, ok
+}
+
+// GetCollections returns a copy of the Collections, it can be iterated while Collections are added or deleted
+func (v *Vdb) GetCollections() map[string]*Collection.Collection {
+	v.collectionsMut.RLock()
+	defer v.collectionsMut.RUnlock()
+	collections := make(map[string]*Collection.Collection, len(v.Collections))
+	for name, c := range v.Collections {
+		collections[name] = c
 	}
-	FileMapper.Mapper.Start(collections)
+	return collections
+}
+
+// collection returns the Collection with the given name, nil if it does not exist
+func (v *Vdb) collection(name string) *Collection.Collection {
+	c, _ := v.GetCollection(name)
+	return c
 }
 
 // AddCollection creates a new Collection
 func (v *Vdb) AddCollection(name string, vectorDimension int, distanceFunc string) error {
+	return v.AddCollectionFromConfig(Utils.CollectionConfig{Name: name, VectorDimension: vectorDimension,
+		DistanceFuncName: distanceFunc})
+}
+
+// AddCollectionFromConfig creates a new Collection described by the given CollectionConfig
+func (v *Vdb) AddCollectionFromConfig(config Utils.CollectionConfig) error {
+	name := config.Name
+	if err := validCollectionName(name); err != nil {
+		return err
+	}
+	if config.Tenant != "" && !v.TenantExists(config.Tenant) {
+		return fmt.Errorf("Tenant with name %s does not exist", config.Tenant)
+	}
+	v.collectionsMut.Lock()
+	defer v.collectionsMut.Unlock()
 	// Check if collection allready exists
 	if _, ok := v.Collections[name]; ok {
 		return fmt.Errorf("Collection with name %s allready exists", name)
 	}
-	v.Collections[name] = Collection.NewCollection(name, vectorDimension, distanceFunc)
+	// Collections and aliases share their names
+	if v.IsAlias(name) {
+		return fmt.Errorf("Alias with name %s allready exists", name)
+	}
 	// Add the collection to the FileMapper
-	v.Mapper.AddCollection(name)
+	err := v.Mapper.AddCollection(name, config.VectorDimension)
+	if err != nil {
+		return err
+	}
+	c := Collection.NewCollectionFromConfig(config)
+	v.Collections[name] = c
 	// Write the Collection to the FS
-	err := v.Collections[name].WriteConfig()
+	err = c.WriteConfig()
 	if err != nil {
 		return err
 	}
+	// The partition of every tenant of a shared Collection is found by the index of the tenant field
+	if config.TenantField != "" {
+		err = c.CreateIndex(config.TenantField, config.TenantField)
+		if err != nil {
+			return err
+		}
+	}
 	Logger.Log.Log("Collection " + name + " added")
 	return nil
 }
 
 // DeleteCollection deletes a Collection
 func (v *Vdb) DeleteCollection(name string) error {
-	if _, ok := v.Collections[name]; !ok {
+	v.collectionsMut.Lock()
+	defer v.collectionsMut.Unlock()
+	c, ok := v.Collections[name]
+	if !ok {
 		return fmt.Errorf("Collection with name %s does not exist", name)
 	}
+	// The aliases have to be switched or deleted first, their clients would lose the Collection
+	if aliases := v.aliasesOf(name); len(aliases) > 0 {
+		return fmt.Errorf("Collection %s is the target of the aliases %s", name, strings.Join(aliases, ", "))
+	}
+	keys := c.SegmentKeys()
 	delete(v.Collections, name)
-	// Delete the Collection from the FileMapper
-	v.Mapper.DelCollection(name)
+	v.deleteCollectionFiles(name, keys)
 	Logger.Log.Log("Collection " + name + " deleted")
 	return nil
 }
 
-// ListCollections returns a list of all collections names
-func (v *Vdb) ListCollections() []string {
-	var collections []string
-	for key := range v.Collections {
-		collections = append(collections, key)
+// deleteCollectionFiles deletes the segments and the Collection from the FileMapper - the first segment uses the files
+// of the Collection
+func (v *Vdb) deleteCollectionFiles(name string, keys []string) {
+	for _, key := range keys {
+		if key != name {
+			v.Mapper.DelSegment(key)
+		}
 	}
-	return collections
+	v.Mapper.DelCollection(name)
 }
 
-// Search searches for the nearest neighbours of the given target vector
-func (v *Vdb) Search(collectionName string, target *Vector.Vector, queue *Utils.HeapControl, maxDistancePercent float64,
-	filter *[]Filter.Filter) []*Utils.ResultSet {
-	v.Collections[collectionName].Mut.RLock()
-	defer v.Collections[collectionName].Mut.RUnlock()
-
-	// if the collection is empty we return an empty slice
-	if v.Collections[collectionName].DiagonalLength == 0 {
-		return []*Utils.ResultSet{}
+// RenameCollection renames a Collection and moves its files, the aliases of the Collection follow it
+func (v *Vdb) RenameCollection(name string, newName string) error {
+	v.collectionsMut.Lock()
+	defer v.collectionsMut.Unlock()
+	c, ok := v.Collections[name]
+	if !ok {
+		return fmt.Errorf("Collection with name %s does not exist", name)
 	}
+	if err := validCollectionName(newName); err != nil {
+		return err
+	}
+	if _, ok := v.Collections[newName]; ok {
+		return fmt.Errorf("Collection with name %s allready exists", newName)
+	}
+	if v.IsAlias(newName) {
+		return fmt.Errorf("Alias with name %s allready exists", newName)
+	}
+	err := c.Rename(newName)
+	if err != nil {
+		return err
+	}
+	v.Collections[newName] = c
+	delete(v.Collections, name)
+	return v.retargetAliases(name, newName)
+}
 
-	// Start the Queue Thread
-	queue.StartThreads()
-
-	// Add 1 to the queue waitgroup
-	queue.AddToWaitGroup()
+// CloneCollection copies the points of a Collection that pass the filter into a new Collection. The clone has the
+// fields of the source, an empty distance function keeps the one of the source and nil indexes keep its payload
+// indexes. The classifiers are not cloned, they can be trained on the clone. It returns the number of cloned points.
+func (v *Vdb) CloneCollection(name string, target string, distanceFunc string, filter *[]Filter.Filter,
+	indexes map[string]string) (int, error) {
+	c, ok := v.GetCollection(name)
+	if !ok {
+		return 0, fmt.Errorf("Collection with name %s does not exist", name)
+	}
+	if err := validCollectionName(target); err != nil {
+		return 0, err
+	}
+	if err := v.CheckFilter(name, filter); err != nil {
+		return 0, err
+	}
 
-	// Get the starting time
-	t := time.Now()
-	Utils.NewSearchUnit(v.Collections[collectionName].Nodes, target, queue, filter, v.Collections[collectionName].DistanceFunc,
-		v.Collections[collectionName].DimensionDiff, 0.1)
+	// The clone starts with the config of the source but without its segments
+	config := c.Config()
+	config.Name = target
+	config.DiagonalLength = 0
+	config.Segments = nil
+	config.NextSegment = 0
+	// Without indexes of the request the clone gets the payload and geo indexes of the source
+	geoIndexes := config.GeoIndexes
+	if indexes == nil {
+		indexes = config.Indexes
+	} else {
+		geoIndexes = nil
+	}
+	config.Indexes = nil
+	config.GeoIndexes = nil
+	if distanceFunc != "" {
+		// Choose distance function from Distancefunction string
+		if strings.ToLower(distanceFunc) != "euclid" {
+			config.DistanceFuncName = "cosine"
+		} else {
+			config.DistanceFuncName = "euclid"
+		}
+	}
+	err := v.AddCollectionFromConfig(config)
+	if err != nil {
+		return 0, err
+	}
 
-	// Close the channel and wait for the Queue to finish
-	queue.CloseChannel()
-	queue.Wg.Wait()
+	v.prepareFilter(name, filter)
+	cloned, err := v.clonePoints(c, target, filter)
+	clone, ok := v.GetCollection(target)
+	if err == nil && !ok {
+		err = fmt.Errorf("Collection with name %s does not exist", target)
+	}
+	if err == nil {
+		err = clone.RestoreIndexes(indexes)
+	}
+	if err == nil {
+		err = clone.RestoreGeoIndexes(geoIndexes)
+	}
+	if err == nil {
+		err = clone.WriteConfig()
+	}
+	if err != nil {
+		// A partial clone is of no use
+		if derr := v.DeleteCollection(target); derr != nil {
+			Logger.Log.Log("Error deleting partial clone " + target + ": " + derr.Error())
+		}
+		return 0, err
+	}
+	Logger.Log.Log(fmt.Sprintf("Cloned %d points of Collection %s into %s", cloned, name, target))
+	return cloned, nil
+}
 
-	// Print the time it took
-	Logger.Log.Log("Search took: " + time.Since(t).String())
+// clonePoints inserts the points of a Collection that pass the filter into the target Collection, the source is only
+// read locked while a chunk of points is read
+func (v *Vdb) clonePoints(c *Collection.Collection, target string, filter *[]Filter.Filter) (int, error) {
+	c.Mut.RLock()
+	ids := make([]string, 0, len(*c.Space))
+	for id := range *c.Space {
+		ids = append(ids, id)
+	}
+	c.Mut.RUnlock()
+	sort.Strings(ids)
+
+	cloned := 0
+	for start := 0; start < len(ids); start += exportChunk {
+		end := start + exportChunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+		points, err := readPointItems(c, ids[start:end], filter)
+		if err != nil {
+			return cloned, err
+		}
+		for _, p := range points {
+			_, err := v.AddPoint(target, p)
+			if err != nil {
+				return cloned, fmt.Errorf("point %s: %w", p.Id, err)
+			}
+			cloned++
+		}
+	}
+	return cloned, nil
+}
 
-	// Get the nodes from the queue
-	data := queue.GetNodes()
+// validCollectionName checks that the files of a new Collection stay within the FileStore and do not collide with the
+// files of another Collection
+func validCollectionName(name string) error {
+	if !validSnapshotName(name) {
+		return fmt.Errorf("invalid collection name %q", name)
+	}
+	return Collection.ReservedName(name)
+}
 
-	// If this collection uses euclid and we have a maxDistancePercent > 0 we need to filter the results
-	if v.Collections[collectionName].DistanceFuncName == "euclid" && maxDistancePercent > 0 {
-		// If a result is greater than maxDistancePercent * DiagonalLength we remove it
-		for i := 0; i < len(data); i++ {
-			if data[i].Distance > maxDistancePercent*v.Collections[collectionName].DiagonalLength {
-				data = append(data[:i], data[i+1:]...)
-				i--
-			}
+// ListCollections returns a list of all collections names, a tenant only gets its own and the shared Collections
+func (v *Vdb) ListCollections(tenant string) []string {
+	var collections []string
+	for key := range v.GetCollections() {
+		if v.TenantAccess(tenant, key) != NoAccess {
+			collections = append(collections, key)
 		}
 	}
+	return collections
+}
 
-	// Create the ResultSet
-	results := make([]*Utils.ResultSet, len(data))
+// StartReaper starts a goroutine that deletes the expired points of all collections in the given interval
+func (v *Vdb) StartReaper(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			v.Reap()
+		}
+	}()
+}
 
-	// Get the Payloads back from the Memory Map
-	for i := 0; i < len(data); i++ {
-		m, err := FileMapper.Mapper.ReadPayload(data[i].Node.Vector.PayloadStart, collectionName)
+// Reap deletes the expired points of all collections
+func (v *Vdb) Reap() {
+	for name, c := range v.GetCollections() {
+		ids := c.ExpiredIDs()
+		if len(ids) == 0 {
+			continue
+		}
+		deleted, err := c.DeleteBatch(ids)
 		if err != nil {
-			Logger.Log.Log("Error reading payload: " + err.Error())
+			Logger.Log.Log("Error deleting expired points from Collection " + name + ": " + err.Error())
 			continue
 		}
-		results[i] = &Utils.ResultSet{Payload: m, Distance: data[i].Distance}
+		Logger.Log.Log(fmt.Sprintf("Deleted %d expired points from Collection %s", len(deleted), name))
 	}
+}
 
-	// Sort the results by distance, smallest first
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Distance < results[j].Distance
-	})
+// StartMerger starts a goroutine that merges the small sealed segments of all collections in the given interval
+func (v *Vdb) StartMerger(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			v.Merge()
+		}
+	}()
+}
 
-	return results
+// Merge merges the small sealed segments of all collections
+func (v *Vdb) Merge() {
+	for name, c := range v.GetCollections() {
+		err := c.MergeSegments()
+		if err != nil {
+			Logger.Log.Log("Error merging segments of Collection " + name + ": " + err.Error())
+		}
+	}
+}
+
+// Search searches for the nearest neighbours of the given target vector
+func (v *Vdb) Search(collectionName string, target *Vector.Vector, queue *Utils.HeapControl, maxDistancePercent float64,
+	filter *[]Filter.Filter) []*Utils.ResultSet {
+	v.prepareFilter(collectionName, filter)
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return []*Utils.ResultSet{}
+	}
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	return v.searchTree(collectionName, searchSpace{nodes: c.SegmentNodes(), distanceFunc: c.DistanceFunc,
+		distanceFuncName: c.DistanceFuncName, dimensionDiff: c.DimensionDiff, diagonalLength: c.DiagonalLength},
+		target, queue, maxDistancePercent, filter)
 }
 
+// IndexSearch searches for the nearest neighbours of the given target vector in the subtree of an Index value
 func (v *Vdb) IndexSearch(collectionName string, target *Vector.Vector, queue *Utils.HeapControl, maxDistancePercent float64, filter *[]Filter.Filter,
 	indexName string, indexValue any) []*Utils.ResultSet {
-	v.Collections[collectionName].Mut.RLock()
-	defer v.Collections[collectionName].Mut.RUnlock()
+	v.prepareFilter(collectionName, filter)
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return []*Utils.ResultSet{}
+	}
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	return v.searchTree(collectionName, searchSpace{nodes: []*Node.Node{c.Indexes[indexName].Entries[indexValue]}, distanceFunc: c.DistanceFunc,
+		distanceFuncName: c.DistanceFuncName, dimensionDiff: c.DimensionDiff, diagonalLength: c.DiagonalLength},
+		target, queue, maxDistancePercent, filter)
+}
+
+// FieldSearch searches for the nearest neighbours of the given target vector in a named vector field
+func (v *Vdb) FieldSearch(collectionName string, fieldName string, target *Vector.Vector, queue *Utils.HeapControl,
+	maxDistancePercent float64, filter *[]Filter.Filter) []*Utils.ResultSet {
+	v.prepareFilter(collectionName, filter)
+	c, ok := v.GetCollection(collectionName)
+	if !ok {
+		return []*Utils.ResultSet{}
+	}
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	f := c.VectorFields[fieldName]
+	return v.searchTree(collectionName, searchSpace{nodes: []*Node.Node{f.Nodes}, distanceFunc: f.DistanceFunc,
+		distanceFuncName: f.DistanceFuncName, dimensionDiff: f.DimensionDiff, diagonalLength: f.DiagonalLength},
+		target, queue, maxDistancePercent, filter)
+}
+
+// CheckFilter checks the filter the way the Collection compares it, fields the schema declares as datetime take
+// datetime literals. The filter is not changed.
+func (v *Vdb) CheckFilter(collectionName string, filter *[]Filter.Filter) error {
+	if filter == nil {
+		return nil
+	}
+	var schema *Schema.Schema
+	if c, ok := v.GetCollection(collectionName); ok {
+		schema = c.GetSchema()
+	}
+	for _, f := range *filter {
+		var err error
+		if schema.Type(f.Field) == Schema.DateTime {
+			// f is a copy of the filter
+			err = f.UseDatetime()
+		} else {
+			err = f.Check()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// prepareFilter converts the literals of the filter to the types the schema of the Collection declares for their fields
+// and parses the geo, between and datetime values once. Geo and range filters are restricted to the points a geo or a
+// payload index finds for them.
+func (v *Vdb) prepareFilter(collectionName string, filter *[]Filter.Filter) {
+	c, ok := v.GetCollection(collectionName)
+	if !ok || filter == nil {
+		return
+	}
+	schema := c.GetSchema()
+	for i := range *filter {
+		f := &(*filter)[i]
+		if schema.Type(f.Field) == Schema.DateTime {
+			f.UseDatetime()
+		} else if !f.Op.IsGeo() && f.Op != Filter.Between {
+			f.Value = schema.Coerce(f.Field, f.Value)
+		}
+		if f.Prepare() != nil || f.Restricted() != nil {
+			continue
+		}
+		if shape := f.Shape(); shape != nil {
+			f.Restrict(c.GeoCandidates(f.Field, shape.Bounds()))
+		} else if from, to, ok := f.Range(); ok {
+			f.Restrict(c.RangeCandidates(f.Field, from, to, f.IsDatetime()))
+		}
+	}
+}
+
+// searchSpace is a set of KD-Trees (e.g. the segments of a collection) together with the metric they are searched with
+type searchSpace struct {
+	nodes            []*Node.Node
+	distanceFunc     func(*Vector.Vector, *Vector.Vector) (float64, error)
+	distanceFuncName string
+	dimensionDiff    *Vector.Vector
+	diagonalLength   float64
+}
+
+// search fans out over all KD-Trees of the searchSpace, every tree pushes its neighbours into the same queue where they
+// are merged. It closes the channel of the queue and waits until the queue is done.
+func (s searchSpace) search(target *Vector.Vector, queue *Utils.HeapControl, filter *[]Filter.Filter) {
+	var wg sync.WaitGroup
+	for _, nodes := range s.nodes {
+		wg.Add(1)
+		go func(nodes *Node.Node) {
+			defer wg.Done()
+			Utils.NewSearchUnit(nodes, target, queue, filter, s.distanceFunc, s.dimensionDiff, 0.1)
+		}(nodes)
+	}
+	wg.Wait()
+
+	// Close the channel and wait for the Queue to finish
+	queue.CloseChannel()
+	queue.Wg.Wait()
+}
+
+// searchTree searches for the nearest neighbours of the given target vector in a searchSpace - the caller has to hold the
+// read lock of the collection
+func (v *Vdb) searchTree(collectionName string, space searchSpace, target *Vector.Vector, queue *Utils.HeapControl,
+	maxDistancePercent float64, filter *[]Filter.Filter) []*Utils.ResultSet {
 	// if the collection is empty we return an empty slice
-	if v.Collections[collectionName].DiagonalLength == 0 {
+	if space.diagonalLength == 0 {
 		return []*Utils.ResultSet{}
 	}
 
@@ -158,12 +487,7 @@ func (v *Vdb) IndexSearch(collectionName string, target *Vector.Vector, queue *U
 
 	// Get the starting time
 	t := time.Now()
-	Utils.NewSearchUnit(v.Collections[collectionName].Indexes[indexName].Entries[indexValue], target, queue, filter, v.Collections[collectionName].DistanceFunc,
-		v.Collections[collectionName].DimensionDiff, 0.1)
-
-	// Close the channel and wait for the Queue to finish
-	queue.CloseChannel()
-	queue.Wg.Wait()
+	space.search(target, queue, filter)
 
 	// Print the time it took
 	Logger.Log.Log("Search took: " + time.Since(t).String())
@@ -171,11 +495,11 @@ func (v *Vdb) IndexSearch(collectionName string, target *Vector.Vector, queue *U
 	// Get the nodes from the queue
 	data := queue.GetNodes()
 
-	// If this collection uses euclid and we have a maxDistancePercent > 0 we need to filter the results
-	if v.Collections[collectionName].DistanceFuncName == "euclid" && maxDistancePercent > 0 {
+	// If this space uses euclid and we have a maxDistancePercent > 0 we need to filter the results
+	if space.distanceFuncName == "euclid" && maxDistancePercent > 0 {
 		// If a result is greater than maxDistancePercent * DiagonalLength we remove it
 		for i := 0; i < len(data); i++ {
-			if data[i].Distance > maxDistancePercent*v.Collections[collectionName].DiagonalLength {
+			if data[i].Distance > maxDistancePercent*space.diagonalLength {
 				data = append(data[:i], data[i+1:]...)
 				i--
 			}
@@ -187,7 +511,7 @@ func (v *Vdb) IndexSearch(collectionName string, target *Vector.Vector, queue *U
 
 	// Get the Payloads back from the Memory Map
 	for i := 0; i < len(data); i++ {
-		m, err := FileMapper.Mapper.ReadPayload(data[i].Node.Vector.PayloadStart, collectionName)
+		m, err := FileMapper.Mapper.ReadPayload(data[i].Node.Vector.PayloadStart, data[i].Node.Vector.Collection)
 		if err != nil {
 			Logger.Log.Log("Error reading payload: " + err.Error())
 			continue
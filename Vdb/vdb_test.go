@@ -0,0 +1,153 @@
+package Vdb
+
+import (
+	"VreeDB/ArgsParser"
+	"VreeDB/Collection"
+	"VreeDB/Utils"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// newTestCollection creates a Collection of the config that is deleted after the test
+func newTestCollection(t testing.TB, config Utils.CollectionConfig) *Collection.Collection {
+	t.Helper()
+	if err := os.MkdirAll(*ArgsParser.Ap.FileStore, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if config.DistanceFuncName == "" {
+		config.DistanceFuncName = "euclid"
+	}
+	if err := DB.AddCollectionFromConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, ok := DB.Collections[config.Name]; ok {
+			DB.DeleteCollection(config.Name)
+		}
+	})
+	return DB.Collections[config.Name]
+}
+
+// addTestPoint adds the point to the Collection and fails the test if it can not be added
+func addTestPoint(t testing.TB, collectionName string, p PointItem) {
+	t.Helper()
+	if _, err := DB.AddPoint(collectionName, &p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reloadTestCollection replaces the Collection with a snapshot of itself, so it is read back from its files like on boot
+func reloadTestCollection(t *testing.T, collectionName string) *Collection.Collection {
+	t.Helper()
+	if _, err := DB.RestoreSnapshot(newTestSnapshot(t, collectionName), ""); err != nil {
+		t.Fatal(err)
+	}
+	return DB.Collections[collectionName]
+}
+
+func TestNamesOfSegmentFilesAreRejected(t *testing.T) {
+	newTestCollection(t, Utils.CollectionConfig{Name: "reserved", VectorDimension: 2})
+	for _, name := range []string{"reserved_seg1", "reserved_meta"} {
+		if err := DB.AddCollection(name, 2, "euclid"); err == nil {
+			DB.DeleteCollection(name)
+			t.Errorf("collection %s was created", name)
+		}
+		if err := DB.RenameCollection("reserved", name); err == nil {
+			t.Fatalf("collection was renamed to %s", name)
+		}
+		if _, err := DB.CloneCollection("reserved", name, "", nil, nil); err == nil {
+			DB.DeleteCollection(name)
+			t.Errorf("collection was cloned into %s", name)
+		}
+	}
+}
+
+func TestConcurrentInsertsAcrossSeals(t *testing.T) {
+	c := newTestCollection(t, Utils.CollectionConfig{Name: "sealrace", VectorDimension: 2})
+	c.SegmentSize = 5
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := DB.AddPoint("sealrace", &PointItem{Id: strconv.Itoa(i), Vector: []float64{float64(i), 1}})
+			if err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	// Every point is in the segment it was written to and reads its own data
+	c.Mut.RLock()
+	defer c.Mut.RUnlock()
+	if len(c.Segments) < 2 {
+		t.Fatal("no segment was sealed")
+	}
+	for id, v := range *c.Space {
+		found := false
+		for _, segment := range c.Segments {
+			if _, ok := segment.Space[id]; ok {
+				found = segment.Key == v.Collection
+			}
+		}
+		if !found {
+			t.Fatalf("point %s is not in segment %s", id, v.Collection)
+		}
+		if data := *v.GetData(); strconv.Itoa(int(data[0])) != id {
+			t.Fatalf("point %s reads %v", id, data)
+		}
+	}
+}
+
+func TestMergeWhileCollectionsChange(t *testing.T) {
+	c := newTestCollection(t, Utils.CollectionConfig{Name: "mergerace", VectorDimension: 2})
+	c.SegmentSize = 2
+	for i := 0; i < 9; i++ {
+		addTestPoint(t, "mergerace", PointItem{Id: strconv.Itoa(i), Vector: []float64{float64(i), 1}})
+	}
+	// The sealed segments are small for a larger segment size
+	c.SegmentSize = 10
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			name := "mergerace" + strconv.Itoa(i)
+			if err := DB.AddCollection(name, 2, "euclid"); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := DB.DeleteCollection(name); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for merged := false; !merged; {
+		select {
+		case <-done:
+			merged = true
+		default:
+		}
+		DB.Merge()
+	}
+
+	// The config of the merged segments replaced the old one, no temporary file is left behind
+	if _, err := os.Stat(*ArgsParser.Ap.FileStore + "mergerace.json.tmp"); !os.IsNotExist(err) {
+		t.Errorf("the temporary config is left behind: %v", err)
+	}
+	reloaded := reloadTestCollection(t, "mergerace")
+	if len(*reloaded.Space) != 9 || len(reloaded.Segments) >= 5 {
+		t.Errorf("the reloaded collection holds %d points in %d segments", len(*reloaded.Space),
+			len(reloaded.Segments))
+	}
+}
@@ -5,19 +5,44 @@ import (
 	"crypto/rand"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // Vector is a struct that holds a slice of float64
 type Vector struct {
-	Id           string
-	Collection   string
-	Data         []float64
-	Length       int
-	Payload      *map[string]interface{}
-	DataStart    int64
-	PayloadStart int64
-	Indexed      bool
-	mut          *sync.RWMutex
+	Id               string
+	Collection       string
+	Data             []float64
+	Length           int
+	Payload          *map[string]interface{}
+	DataStart        int64
+	PayloadStart     int64
+	Indexed          bool
+	SparseStart      map[string]int64
+	Sparse           map[string]*SparseVector
+	VectorStart      map[string]int64
+	Vectors          map[string]*Vector
+	MultiVectorStart map[string][]int64
+	MultiVectors     map[string][]*Vector
+	BinaryStart      map[string]int64
+	RescoreStart     map[string]int64
+	Binary           map[string]*BinaryVector
+	ExpiresAt        int64 // Unix time in seconds after which the vector is expired, 0 never expires
+	mut              *sync.RWMutex
+}
+
+// BinaryVector is a binary quantised vector, every dimension is one bit packed into uint64 words
+type BinaryVector struct {
+	Bits   []uint64
+	Length int
+	// Data is the float vector, it is only kept for search targets and for fields that rescore
+	Data []float64
+}
+
+// SparseVector holds the non zero entries of a sparse vector as index/value pairs
+type SparseVector struct {
+	Indices []int     `json:"indices"`
+	Values  []float64 `json:"values"`
 }
 
 // NewVector returns a new Vector
@@ -75,7 +100,167 @@ func (v *Vector) GetData() *[]float64 {
 	return &v.Data
 }
 
+// IsExpired returns true if the vector has an expiry time that has passed
+func (v *Vector) IsExpired() bool {
+	return v.ExpiresAt > 0 && v.ExpiresAt <= time.Now().Unix()
+}
+
 // RecreateMut will recreate the mut
 func (v *Vector) RecreateMut() {
 	v.mut = &sync.RWMutex{}
 }
+
+// SetSparse will add a sparse vector to the Vector, if the Vector belongs to a collection it will be written to the file
+func (v *Vector) SetSparse(name string, sparse *SparseVector) error {
+	// Check if the sparse vector is valid
+	if err := sparse.Validate(); err != nil {
+		return err
+	}
+	if v.Sparse == nil {
+		v.Sparse = make(map[string]*SparseVector)
+	}
+	v.Sparse[name] = sparse
+
+	// Vectors without a collection (e.g. search targets) are only held in memory
+	if v.Collection == "" {
+		return nil
+	}
+	start, err := FileMapper.Mapper.WriteSparseVector(sparse.Indices, sparse.Values, v.Collection)
+	if err != nil {
+		return err
+	}
+	if v.SparseStart == nil {
+		v.SparseStart = make(map[string]int64)
+	}
+	v.SparseStart[name] = start
+	return nil
+}
+
+// SetNamed will add a named vector to the Vector, if the Vector belongs to a collection it will be written to the file.
+// The named vector shares the id, the collection and the payload with its parent.
+func (v *Vector) SetNamed(name string, data []float64) error {
+	if len(data) == 0 {
+		return fmt.Errorf("vector %s is empty", name)
+	}
+	named := NewVector(v.Id, data, nil, "")
+	named.Collection = v.Collection
+	named.PayloadStart = v.PayloadStart
+	if v.Vectors == nil {
+		v.Vectors = make(map[string]*Vector)
+	}
+	v.Vectors[name] = named
+
+	// Vectors without a collection (e.g. search targets) are only held in memory
+	if v.Collection == "" {
+		return nil
+	}
+	start, _, err := FileMapper.Mapper.WriteVector(data, v.Collection)
+	if err != nil {
+		return err
+	}
+	named.DataStart = start
+	named.Indexed = true
+	if v.VectorStart == nil {
+		v.VectorStart = make(map[string]int64)
+	}
+	v.VectorStart[name] = start
+	return nil
+}
+
+// SetMulti will add a list of vectors (e.g. token embeddings) to the Vector, if the Vector belongs to a collection they
+// will be written to the file. Every vector of the list shares the id, the collection and the payload with its parent.
+func (v *Vector) SetMulti(name string, data [][]float64) error {
+	if len(data) == 0 {
+		return fmt.Errorf("multi vector %s is empty", name)
+	}
+	tokens := make([]*Vector, len(data))
+	starts := make([]int64, len(data))
+	for i, d := range data {
+		token := NewVector(v.Id, d, nil, "")
+		token.Collection = v.Collection
+		token.PayloadStart = v.PayloadStart
+		// Vectors without a collection (e.g. search targets) are only held in memory
+		if v.Collection != "" {
+			start, _, err := FileMapper.Mapper.WriteVector(d, v.Collection)
+			if err != nil {
+				return err
+			}
+			token.DataStart = start
+			token.Indexed = true
+			starts[i] = start
+		}
+		tokens[i] = token
+	}
+	if v.MultiVectors == nil {
+		v.MultiVectors = make(map[string][]*Vector)
+	}
+	v.MultiVectors[name] = tokens
+	if v.Collection != "" {
+		if v.MultiVectorStart == nil {
+			v.MultiVectorStart = make(map[string][]int64)
+		}
+		v.MultiVectorStart[name] = starts
+	}
+	return nil
+}
+
+// SetBinary will add a binary quantised vector to the Vector, every value > 0 becomes a 1 bit. If the Vector belongs to a
+// collection the packed bits will be written to the file, with rescore the float vector will be written alongside.
+func (v *Vector) SetBinary(name string, data []float64, bits []uint64, rescore bool) error {
+	if len(data) == 0 {
+		return fmt.Errorf("binary vector %s is empty", name)
+	}
+	binary := &BinaryVector{Bits: bits, Length: len(data), Data: data}
+	if v.Binary == nil {
+		v.Binary = make(map[string]*BinaryVector)
+	}
+	v.Binary[name] = binary
+
+	// Vectors without a collection (e.g. search targets) are only held in memory
+	if v.Collection == "" {
+		return nil
+	}
+	start, err := FileMapper.Mapper.WriteBinaryVector(bits, v.Collection)
+	if err != nil {
+		return err
+	}
+	if v.BinaryStart == nil {
+		v.BinaryStart = make(map[string]int64)
+	}
+	v.BinaryStart[name] = start
+
+	// Only the bits are held in memory, the float vector is read from the file when rescoring
+	binary.Data = nil
+	if rescore {
+		start, _, err = FileMapper.Mapper.WriteVector(data, v.Collection)
+		if err != nil {
+			return err
+		}
+		if v.RescoreStart == nil {
+			v.RescoreStart = make(map[string]int64)
+		}
+		v.RescoreStart[name] = start
+	}
+	return nil
+}
+
+// Validate checks if the SparseVector has matching indices and values
+func (s *SparseVector) Validate() error {
+	if s == nil {
+		return fmt.Errorf("sparse vector is empty")
+	}
+	if len(s.Indices) != len(s.Values) {
+		return fmt.Errorf("sparse vector has %d indices but %d values", len(s.Indices), len(s.Values))
+	}
+	seen := make(map[int]bool, len(s.Indices))
+	for _, idx := range s.Indices {
+		if idx < 0 {
+			return fmt.Errorf("sparse vector index %d is negative", idx)
+		}
+		if seen[idx] {
+			return fmt.Errorf("sparse vector index %d is duplicated", idx)
+		}
+		seen[idx] = true
+	}
+	return nil
+}
@@ -2,11 +2,70 @@ package main
 
 import (
 	"VreeDB/AccessDataHUB"
+	"VreeDB/ApiKeyHandler"
 	"VreeDB/ArgsParser"
+	"VreeDB/Boot"
+	"VreeDB/Dataset"
+	"VreeDB/Filter"
+	"VreeDB/Fsck"
+	"VreeDB/Logger"
 	"VreeDB/Server"
+	"VreeDB/Vdb"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
 )
 
 func main() {
+	ArgsParser.Parse()
+	ApiKeyHandler.ApiHandler.Start()
+	Logger.Log.Log("VectorDatabase initialized")
+
+	// Check the collection files and exit - the collections are not restored
+	if *ArgsParser.Ap.Fsck {
+		reports, err := Fsck.Run(*ArgsParser.Ap.FsckRepair)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "fsck failed: "+err.Error())
+			os.Exit(2)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(reports)
+		for _, report := range reports {
+			if !report.Ok {
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	// Export, import, load or evaluate a collection and exit - the server must not run on the same file store
+	if *ArgsParser.Ap.Export != "" || *ArgsParser.Ap.Import != "" || *ArgsParser.Ap.Load != "" ||
+		*ArgsParser.Ap.Evaluate != "" {
+		Vdb.DB.Collections = Boot.NewBootUp().Boot()
+		err := Vdb.DB.LoadAliases()
+		if err == nil {
+			err = Vdb.DB.LoadTenants()
+		}
+		switch {
+		case err != nil:
+		case *ArgsParser.Ap.Export != "":
+			err = export(Vdb.DB.ResolveAlias(*ArgsParser.Ap.Export))
+		case *ArgsParser.Ap.Import != "":
+			err = importPoints(Vdb.DB.ResolveAlias(*ArgsParser.Ap.Import))
+		case *ArgsParser.Ap.Load != "":
+			err = load(Vdb.DB.ResolveAlias(*ArgsParser.Ap.Load))
+		default:
+			err = evaluate(Vdb.DB.ResolveAlias(*ArgsParser.Ap.Evaluate))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Start the Server
 	server := Server.NewServer(*ArgsParser.Ap.Ip, *ArgsParser.Ap.Port, *ArgsParser.Ap.CertFile,
 		*ArgsParser.Ap.KeyFile, *ArgsParser.Ap.Secure)
@@ -14,3 +73,101 @@ func main() {
 	AccessDataHUB.AccessList.ReadChan <- "SYSTEMEVENT"
 	server.Start()
 }
+
+// export writes the points of the collection that pass the -filter to the -file or to stdout
+func export(collection string) error {
+	var filter *[]Filter.Filter
+	if *ArgsParser.Ap.Filter != "" {
+		filter = &[]Filter.Filter{}
+		err := json.Unmarshal([]byte(*ArgsParser.Ap.Filter), filter)
+		if err != nil {
+			return fmt.Errorf("filter: %w", err)
+		}
+	}
+	var w io.Writer = os.Stdout
+	if *ArgsParser.Ap.File != "" {
+		file, err := os.Create(*ArgsParser.Ap.File)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	n, err := Vdb.DB.Export(collection, filter, w)
+	fmt.Fprintf(os.Stderr, "exported %d points of collection %s\n", n, collection)
+	return err
+}
+
+// importPoints inserts the points of the -file or of stdin into the collection, the first -importoffset lines are
+// skipped. The errors of the lines and the progress are written to stderr.
+func importPoints(collection string) error {
+	var r io.Reader = os.Stdin
+	if *ArgsParser.Ap.File != "" {
+		file, err := os.Open(*ArgsParser.Ap.File)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	imported, failed := 0, 0
+	lines, err := Vdb.DB.Import(collection, "", r, *ArgsParser.Ap.ImportOffset, func(line int, id string, err error) {
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "line %d (%s): %s\n", line, id, err.Error())
+		} else {
+			imported++
+		}
+		if (imported+failed)%10000 == 0 {
+			fmt.Fprintf(os.Stderr, "line %d: %d points imported, %d failed\n", line, imported, failed)
+		}
+	})
+	fmt.Fprintf(os.Stderr, "%d lines read, %d points imported, %d failed - resume with -importoffset %d\n", lines,
+		imported, failed, lines)
+	if err == nil && failed > 0 {
+		err = fmt.Errorf("%d points failed", failed)
+	}
+	return err
+}
+
+// load inserts the vectors of the dataset -file into the collection, the progress is written to stderr
+func load(collection string) error {
+	reader, file, err := Dataset.Open(*ArgsParser.Ap.File)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	n, err := Vdb.DB.LoadDataset(collection, *ArgsParser.Ap.Distance, reader, !*ArgsParser.Ap.RandomIds,
+		*ArgsParser.Ap.Limit, func(n int) {
+			if n%10000 == 0 {
+				fmt.Fprintf(os.Stderr, "%d vectors loaded\n", n)
+			}
+		})
+	fmt.Fprintf(os.Stderr, "%d vectors of dimension %d loaded into collection %s\n", n, reader.Dimension(), collection)
+	return err
+}
+
+// evaluate searches the -queries in the collection and prints the recall@k against the -groundtruth as JSON
+func evaluate(collection string) error {
+	queries, file, err := Dataset.Open(*ArgsParser.Ap.Queries)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	truthFile, err := os.Open(*ArgsParser.Ap.GroundTruth)
+	if err != nil {
+		return err
+	}
+	defer truthFile.Close()
+	truth, err := Dataset.ReadIvecs(truthFile)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *ArgsParser.Ap.GroundTruth, err)
+	}
+	evaluation, err := Vdb.DB.Evaluate(collection, queries, truth, *ArgsParser.Ap.K, *ArgsParser.Ap.Limit)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(evaluation)
+}